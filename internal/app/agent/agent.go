@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultHostMetaPath is where the deploy workflow writes hostMeta.yaml on each node.
+const DefaultHostMetaPath = types.DefaultHostMetaPath
+
+// Task is a unit of work the agent runs on every tick.
+// Tasks receive the node's current view of the cluster so they can act on its role.
+type Task interface {
+	Name() string
+	Run(ctx context.Context, meta *types.HostMeta) error
+}
+
+// Agent is the long-running process on each node that watches local state
+// and keeps it in line with the cluster configuration.
+type Agent struct {
+	log          logger.Logger
+	sysOp        api.SystemOperator
+	hostMetaPath string
+	interval     time.Duration
+	tasks        []Task
+	trigger      chan struct{}
+	now          func() time.Time
+
+	mu       sync.Mutex
+	lastTick time.Time // When the last tick finished
+	tickErr  error     // Why the last tick could not run the tasks
+}
+
+// New creates a new agent that runs the given tasks every interval.
+func New(log logger.Logger, sysOp api.SystemOperator, hostMetaPath string, interval time.Duration, tasks ...Task) *Agent {
+	return &Agent{
+		log:          log,
+		sysOp:        sysOp,
+		hostMetaPath: hostMetaPath,
+		interval:     interval,
+		tasks:        tasks,
+		trigger:      make(chan struct{}, 1),
+		now:          time.Now,
+	}
+}
+
+// Trigger makes the agent run its tasks now instead of waiting for the next
+// tick. Triggers that arrive while a run is pending are merged into it.
+func (a *Agent) Trigger() {
+	select {
+	case a.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run executes all tasks on every tick until the context is cancelled.
+// A failing task is logged and retried on the next tick; it does not stop the agent.
+func (a *Agent) Run(ctx context.Context) error {
+	a.log.Infof("Agent started with %d task(s), interval %s", len(a.tasks), a.interval)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.tick(ctx)
+		select {
+		case <-ctx.Done():
+			a.log.Infof("Agent stopped.")
+			return nil
+		case <-ticker.C:
+		case <-a.trigger:
+		}
+	}
+}
+
+// tick runs every task once.
+func (a *Agent) tick(ctx context.Context) {
+	meta, err := a.loadHostMeta()
+	defer a.ticked(err)
+	if err != nil {
+		a.log.Errorf("Failed to load host metadata: %v", err)
+		return
+	}
+	for _, t := range a.tasks {
+		if err := t.Run(ctx, meta); err != nil {
+			a.log.WithField("task", t.Name()).Errorf("Task failed: %v", err)
+		}
+	}
+}
+
+// loadHostMeta reads this node's hostMeta.yaml.
+func (a *Agent) loadHostMeta() (*types.HostMeta, error) {
+	data, err := a.sysOp.ReadFile(a.hostMetaPath)
+	if err != nil {
+		return nil, err
+	}
+	var meta types.HostMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "failed to parse host metadata: %s", a.hostMetaPath)
+	}
+	return &meta, nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// roleEventsTask records a Kubernetes Event when this node's role changes.
+type roleEventsTask struct {
+	clusterName string
+	recorder    api.EventRecorder
+	seen        bool
+	role        types.NodeRole
+	epoch       int64
+}
+
+// NewRoleEventsTask creates the task that records role transitions of this node
+// as Events on its Node, and leader changes as Events on the GeminiCluster.
+func NewRoleEventsTask(clusterName string, recorder api.EventRecorder) Task {
+	return &roleEventsTask{clusterName: clusterName, recorder: recorder}
+}
+
+// Name returns the name of the task.
+func (t *roleEventsTask) Name() string {
+	return "role-events"
+}
+
+// Run compares the role and epoch with the previous tick. The role the agent
+// starts with is not a transition and is not recorded.
+func (t *roleEventsTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	if !t.seen {
+		t.seen, t.role, t.epoch = true, meta.MyID.Role, meta.Epoch
+		return nil
+	}
+
+	node := types.NodeObject(meta.MyID.Name)
+	if meta.MyID.Role != t.role {
+		switch meta.MyID.Role {
+		case types.RoleLeader:
+			t.recorder.Record(ctx, types.Event{Object: node, Type: types.EventNormal, Reason: "Promoted",
+				Message: fmt.Sprintf("Node %s became leader in epoch %d", meta.MyID.Name, meta.Epoch)})
+		default:
+			t.recorder.Record(ctx, types.Event{Object: node, Type: types.EventWarning, Reason: "Demoted",
+				Message: fmt.Sprintf("Node %s is no longer leader (now %s) in epoch %d", meta.MyID.Name, meta.MyID.Role, meta.Epoch)})
+		}
+	}
+	// Only the new leader reports the change for the cluster, so it is recorded once.
+	if meta.Epoch > t.epoch && meta.MyID.Role == types.RoleLeader {
+		t.recorder.Record(ctx, types.Event{Object: types.GeminiClusterObject(t.clusterName), Type: types.EventNormal, Reason: "LeaderChanged",
+			Message: fmt.Sprintf("%s is leader in epoch %d (was epoch %d)", meta.MyID.Name, meta.Epoch, t.epoch)})
+	}
+
+	t.role, t.epoch = meta.MyID.Role, meta.Epoch
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeRecorder struct {
+	events []types.Event
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, event types.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestRoleEventsTask(t *testing.T) {
+	recorder := &fakeRecorder{}
+	task := NewRoleEventsTask("demo", recorder)
+	ctx := context.Background()
+	run := func(meta *types.HostMeta) {
+		meta.MyID.Name = "node1"
+		if err := task.Run(ctx, meta); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	run(hostMeta("10.0.0.2", 1)) // Starting as follower is not a transition
+	run(hostMeta("10.0.0.2", 1))
+	if len(recorder.events) != 0 {
+		t.Fatalf("expected no events without a transition, got %+v", recorder.events)
+	}
+
+	run(hostMeta("10.0.0.1", 2))
+	if len(recorder.events) != 2 {
+		t.Fatalf("expected a Promoted and a LeaderChanged event, got %+v", recorder.events)
+	}
+	if e := recorder.events[0]; e.Reason != "Promoted" || e.Object != types.NodeObject("node1") {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e := recorder.events[1]; e.Reason != "LeaderChanged" || e.Object != types.GeminiClusterObject("demo") {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	run(hostMeta("10.0.0.2", 3))
+	if e := recorder.events[len(recorder.events)-1]; len(recorder.events) != 3 || e.Reason != "Demoted" || e.Type != types.EventWarning {
+		t.Errorf("expected only a Demoted warning on the old leader, got %+v", recorder.events)
+	}
+}
+
+//Personal.AI order the ending
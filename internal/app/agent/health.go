@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+)
+
+// DefaultHealthListenAddress is where the agent serves its health endpoint.
+const DefaultHealthListenAddress = "127.0.0.1:9440"
+
+// HealthPath is the path of the agent's health endpoint.
+const HealthPath = "/healthz"
+
+// ticked records the outcome of a tick for the health endpoint.
+func (a *Agent) ticked(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastTick, a.tickErr = a.now(), err
+}
+
+// Healthy returns nil if the agent could read its host metadata on the last
+// tick and has ticked within the last three intervals. Failing tasks do not
+// make the agent unhealthy; they are retried on the next tick.
+func (a *Agent) Healthy() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case a.lastTick.IsZero():
+		return errors.New(errors.OrchestratorError, "agent has not run yet")
+	case a.tickErr != nil:
+		return errors.Wrap(a.tickErr, errors.OrchestratorError, "agent cannot run its tasks")
+	case a.now().Sub(a.lastTick) > 3*a.interval:
+		return errors.Newf(errors.OrchestratorError, "agent has not run since %s", a.lastTick.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ServeHealth serves the health endpoint on listenAddr until ctx is cancelled.
+// It answers 200 while the agent is healthy and 503 with the reason otherwise.
+func (a *Agent) ServeHealth(ctx context.Context, listenAddr string) error {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to listen on %s", listenAddr)
+	}
+	server := &http.Server{Handler: a.healthHandler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	a.log.Infof("Health endpoint listening on http://%s%s", listener.Addr(), HealthPath)
+	if err := server.Serve(listener); err != nil && ctx.Err() == nil {
+		return errors.Wrap(err, errors.NetworkError, "health endpoint stopped")
+	}
+	return nil
+}
+
+// healthHandler serves HealthPath.
+func (a *Agent) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Healthy(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+//Personal.AI order the ending
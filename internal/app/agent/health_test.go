@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+)
+
+// hostMetaFile serves hostMeta.yaml, or an error while missing is set.
+type hostMetaFile struct {
+	api.SystemOperator
+	missing bool
+}
+
+func (f *hostMetaFile) ReadFile(path string) ([]byte, error) {
+	if f.missing {
+		return nil, fmt.Errorf("open %s: no such file or directory", path)
+	}
+	return []byte("myId: {ip: 10.0.0.1, role: Leader}\npeerId: {ip: 10.0.0.2, role: Follower}\nepoch: 1\n"), nil
+}
+
+func TestAgentHealth(t *testing.T) {
+	file := &hostMetaFile{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := New(logger.NewLogger("error", io.Discard, "text"), file, DefaultHostMetaPath, 5*time.Second)
+	a.now = func() time.Time { return now }
+
+	status := func() int {
+		rec := httptest.NewRecorder()
+		a.healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+		return rec.Code
+	}
+
+	if status() != http.StatusServiceUnavailable {
+		t.Errorf("expected the agent to be unhealthy before its first run")
+	}
+	a.tick(context.Background())
+	if status() != http.StatusOK {
+		t.Errorf("expected the agent to be healthy after a run, got %v", a.Healthy())
+	}
+
+	// The agent is stuck.
+	now = now.Add(20 * time.Second)
+	if status() != http.StatusServiceUnavailable {
+		t.Errorf("expected the agent to be unhealthy after three missed intervals")
+	}
+
+	// The agent runs but cannot read its host metadata.
+	file.missing = true
+	a.tick(context.Background())
+	if err := a.Healthy(); err == nil {
+		t.Errorf("expected the agent to be unhealthy without host metadata")
+	}
+	file.missing = false
+	a.tick(context.Background())
+	if status() != http.StatusOK {
+		t.Errorf("expected the agent to recover, got %v", a.Healthy())
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// PeerHeartbeat exchanges heartbeats with the peer over several paths.
+type PeerHeartbeat interface {
+	// SetPeer sets the peer's addresses, one heartbeat path each.
+	SetPeer(addresses []string)
+	// Status returns how this node sees the peer over the paths.
+	Status() types.HeartbeatStatus
+}
+
+// heartbeatTask points the heartbeat at the peer of hostMeta.yaml, over its
+// IP and the heartbeat addresses of both hostMeta.yaml and the cluster
+// configuration.
+type heartbeatTask struct {
+	heartbeat PeerHeartbeat
+	spec      *types.ClusterSpec
+}
+
+// NewHeartbeatTask creates the task that keeps the heartbeat paths in line
+// with the peer.
+func NewHeartbeatTask(heartbeat PeerHeartbeat, spec *types.ClusterSpec) Task {
+	return &heartbeatTask{heartbeat: heartbeat, spec: spec}
+}
+
+// Name returns the name of the task.
+func (t *heartbeatTask) Name() string {
+	return "heartbeat"
+}
+
+// Run sets the peer's heartbeat paths. A node without a peer has none.
+func (t *heartbeatTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	if meta.PeerID.IP == "" {
+		t.heartbeat.SetPeer(nil)
+		return nil
+	}
+	var paths []string
+	seen := map[string]bool{}
+	for _, address := range append(t.spec.HeartbeatPaths(meta.PeerID.IP), meta.PeerID.HeartbeatAddresses...) {
+		if !seen[address] {
+			seen[address] = true
+			paths = append(paths, address)
+		}
+	}
+	t.heartbeat.SetPeer(paths)
+	return nil
+}
+
+//Personal.AI order the ending
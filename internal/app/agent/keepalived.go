@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/internal/infrastructure/network"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// keepalivedTask keeps the keepalived configuration in line with the node's
+// role in the vrrp VIP mode: keepalived, not the agent, then holds the VIPs.
+type keepalivedTask struct {
+	log        logger.Logger
+	keepalived *network.Keepalived
+}
+
+// NewKeepalivedTask creates the task that configures keepalived for the VIPs.
+func NewKeepalivedTask(log logger.Logger, keepalived *network.Keepalived) Task {
+	return &keepalivedTask{log: log.WithField("task", "keepalived"), keepalived: keepalived}
+}
+
+// Name returns the name of the task.
+func (t *keepalivedTask) Name() string {
+	return "keepalived"
+}
+
+// Run rewrites the configuration and reloads keepalived when the node's role
+// has changed the VRRP priorities.
+func (t *keepalivedTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	reloaded, err := t.keepalived.Apply(meta)
+	if err != nil {
+		return err
+	}
+	if reloaded {
+		t.log.Infof("keepalived reconfigured for role %s in epoch %d.", meta.MyID.Role, meta.Epoch)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/internal/domain/node"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// nodeLabelsTask keeps the role labels and taints of the Kubernetes nodes in
+// line with hostMeta.yaml. Each agent labels its own node; the leader also
+// labels its peer, so a failed old leader does not keep its leader label. The
+// peer is labeled follower before the leader labels itself, so the two nodes
+// never both carry role=leader.
+type nodeLabelsTask struct {
+	client api.K8sClient
+	cfg    *types.NodeLabelsConfig
+}
+
+// NewNodeLabelsTask creates the task that labels and taints the nodes by role.
+func NewNodeLabelsTask(client api.K8sClient, cfg *types.NodeLabelsConfig) Task {
+	return &nodeLabelsTask{client: client, cfg: cfg}
+}
+
+// Name returns the name of the task.
+func (t *nodeLabelsTask) Name() string {
+	return "node-labels"
+}
+
+// Run brings the labels of the nodes in line with their role and epoch. The
+// nodes are compared against the API server on every run, so labels changed or
+// removed by hand are restored; a node that fails is retried on the next tick.
+func (t *nodeLabelsTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	nodes := []types.NodeIdentity{meta.MyID}
+	if meta.MyID.Role == types.RoleLeader {
+		nodes = append(nodes, meta.PeerID)
+	}
+	return node.ApplyRoleLabels(ctx, t.client, nodes, meta.Epoch, t.cfg)
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeNodeLabeler struct {
+	api.K8sClient
+	calls []string
+	fail  bool
+}
+
+func (f *fakeNodeLabeler) SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error {
+	f.calls = append(f.calls, fmt.Sprintf("%s=%s@%d", nodeName, role, epoch))
+	if f.fail {
+		return fmt.Errorf("api server unavailable")
+	}
+	return nil
+}
+
+func TestNodeLabelsTask(t *testing.T) {
+	client := &fakeNodeLabeler{}
+	task := NewNodeLabelsTask(client, nil)
+	ctx := context.Background()
+	run := func(meta *types.HostMeta) error {
+		meta.MyID.Name, meta.PeerID.Name = "node1", "node2"
+		return task.Run(ctx, meta)
+	}
+
+	// A follower labels only itself.
+	if err := run(hostMeta("10.0.0.2", 1)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(client.calls) != 1 || client.calls[0] != "node1=Follower@1" {
+		t.Errorf("unexpected calls: %v", client.calls)
+	}
+
+	// Every run compares against the node again, so labels removed by hand are restored.
+	client.calls = nil
+	if err := run(hostMeta("10.0.0.2", 1)); err != nil || len(client.calls) != 1 {
+		t.Errorf("expected the node to be labeled on every run, got %v (%v)", client.calls, err)
+	}
+
+	// After a promotion the leader labels its peer follower before itself.
+	client.calls = nil
+	if err := run(hostMeta("10.0.0.1", 2)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(client.calls) != 2 || client.calls[0] != "node2=Follower@2" || client.calls[1] != "node1=Leader@2" {
+		t.Errorf("expected the follower to be labeled first, got %v", client.calls)
+	}
+
+	// While the follower label fails, the leader does not label itself.
+	client.fail, client.calls = true, nil
+	if err := run(hostMeta("10.0.0.1", 2)); err == nil {
+		t.Errorf("expected the failure to be returned")
+	}
+	if len(client.calls) != 1 || client.calls[0] != "node2=Follower@2" {
+		t.Errorf("expected only the follower to be attempted, got %v", client.calls)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/node"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// A node cache that fails to start is retried after nodeCacheInitialBackoff,
+// doubling with every further failure up to nodeCacheMaxBackoff.
+var (
+	nodeCacheInitialBackoff = time.Second
+	nodeCacheMaxBackoff     = time.Minute
+)
+
+// StartNodeCache starts client's node cache and passes it to use. While it
+// fails to start, for example because the API server is not up yet, it is
+// retried with a backoff until ctx is cancelled. It blocks until then.
+func StartNodeCache(ctx context.Context, log logger.Logger, client api.K8sClient, use func(api.NodeCache)) {
+	backoff := nodeCacheInitialBackoff
+	for {
+		// A failed attempt's watches are stopped with its own context.
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cache, err := client.StartNodeCache(attemptCtx)
+		if err == nil {
+			// The cache's watches run until ctx is cancelled.
+			context.AfterFunc(ctx, cancel)
+			use(cache)
+			return
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("Failed to start the node cache, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > nodeCacheMaxBackoff {
+			backoff = nodeCacheMaxBackoff
+		}
+	}
+}
+
+// NodeWatcher receives node changes from the node cache. When a node's health
+// changes it logs the transition and calls trigger, so the agent reacts at once
+// instead of on its next tick. Heartbeats that change nothing are ignored.
+type NodeWatcher struct {
+	log     logger.Logger
+	trigger func()
+
+	mu     sync.Mutex
+	status map[string]types.NodeStatusType
+}
+
+// NewNodeWatcher creates a watcher; pass its OnNode to NodeCache.Subscribe.
+func NewNodeWatcher(log logger.Logger, trigger func()) *NodeWatcher {
+	return &NodeWatcher{log: log, trigger: trigger, status: make(map[string]types.NodeStatusType)}
+}
+
+// OnNode handles one node change. The first status seen for a node is recorded
+// without triggering.
+func (w *NodeWatcher) OnNode(node types.Node) {
+	w.mu.Lock()
+	previous, seen := w.status[node.Config.Name]
+	w.status[node.Config.Name] = node.Status.Status
+	w.mu.Unlock()
+
+	if !seen || previous == node.Status.Status {
+		return
+	}
+	log := w.log.WithField("node", node.Config.Name)
+	if node.Status.Status == types.NodeStatusHealthy {
+		log.Infof("Node is healthy again (was %s)", previous)
+	} else {
+		log.Warnf("Node is %s (was %s): %s", node.Status.Status, previous, node.Status.Message)
+	}
+	w.trigger()
+}
+
+// NodeHealthTask checks that the Kubernetes nodes of both cluster nodes are
+// healthy and reports the results. Until UseNodeCache is called it lists the
+// nodes from the API server on every check; afterwards it reads the cache.
+// Only the leader runs it.
+type NodeHealthTask struct {
+	client api.K8sClient
+
+	mu     sync.Mutex
+	nodes  node.ServiceInterface
+	checks []types.HealthCheckResult // Last checks, one per cluster node
+}
+
+// NewNodeHealthTask creates the task that checks node health through client.
+func NewNodeHealthTask(client api.K8sClient) *NodeHealthTask {
+	return &NodeHealthTask{client: client, nodes: node.NewService(nil, nil, client)}
+}
+
+// UseNodeCache makes the following checks read the nodes from cache instead
+// of the API server.
+func (t *NodeHealthTask) UseNodeCache(cache api.NodeCache) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nodes = node.NewCachedService(nil, nil, t.client, cache)
+}
+
+// Name returns the name of the task.
+func (t *NodeHealthTask) Name() string {
+	return "node-health"
+}
+
+// Run checks this node and its peer.
+func (t *NodeHealthTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	t.mu.Lock()
+	nodes := t.nodes
+	t.mu.Unlock()
+
+	var checks []types.HealthCheckResult
+	if meta.MyID.Role == types.RoleLeader {
+		for _, id := range []types.NodeIdentity{meta.MyID, meta.PeerID} {
+			if id.IP != "" {
+				checks = append(checks, nodeHealthCheck(ctx, nodes, id))
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checks = checks
+	return nil
+}
+
+// HealthChecks returns the last node checks.
+func (t *NodeHealthTask) HealthChecks() []types.HealthCheckResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]types.HealthCheckResult(nil), t.checks...)
+}
+
+// nodeHealthCheck checks the Kubernetes node of id.
+func nodeHealthCheck(ctx context.Context, nodes node.ServiceInterface, id types.NodeIdentity) types.HealthCheckResult {
+	start := time.Now()
+	healthy, err := nodes.CheckNodeHealth(ctx, id.IP)
+	check := types.HealthCheckResult{
+		CheckName:  "node.ready." + id.Name,
+		Success:    err == nil && healthy,
+		Timestamp:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		check.Message = err.Error()
+	case healthy:
+		check.Message = fmt.Sprintf("node %s is ready", id.IP)
+	default:
+		check.Message = fmt.Sprintf("node %s is not ready", id.IP)
+	}
+	return check
+}
+
+//Personal.AI order the ending
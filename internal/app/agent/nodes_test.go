@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+func TestNodeWatcher(t *testing.T) {
+	triggers := 0
+	w := NewNodeWatcher(logger.NewLogger("error", io.Discard, "text"), func() { triggers++ })
+	node := func(status types.NodeStatusType) types.Node {
+		return types.Node{Config: types.NodeConfig{Name: "node2"}, Status: types.NodeStatus{Status: status}}
+	}
+
+	w.OnNode(node(types.NodeStatusHealthy)) // First sighting
+	w.OnNode(node(types.NodeStatusHealthy)) // Heartbeat
+	if triggers != 0 {
+		t.Fatalf("expected no trigger without a health change, got %d", triggers)
+	}
+	w.OnNode(node(types.NodeStatusUnhealthy))
+	w.OnNode(node(types.NodeStatusHealthy))
+	if triggers != 2 {
+		t.Errorf("expected a trigger per health change, got %d", triggers)
+	}
+}
+
+type fakeNodeLister struct {
+	api.K8sClient
+	nodes []types.Node
+	lists int
+}
+
+func (f *fakeNodeLister) GetNodes(ctx context.Context) ([]types.Node, error) {
+	f.lists++
+	return f.nodes, nil
+}
+
+func (f *fakeNodeLister) Subscribe(handler func(types.Node)) {}
+
+// flakyCacheClient fails to start the node cache a number of times.
+type flakyCacheClient struct {
+	api.K8sClient
+	failures int
+	attempts []context.Context
+}
+
+func (f *flakyCacheClient) StartNodeCache(ctx context.Context) (api.NodeCache, error) {
+	f.attempts = append(f.attempts, ctx)
+	if len(f.attempts) <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeNodeLister{}, nil
+}
+
+func TestStartNodeCacheRetries(t *testing.T) {
+	defer func(initial, max time.Duration) { nodeCacheInitialBackoff, nodeCacheMaxBackoff = initial, max }(nodeCacheInitialBackoff, nodeCacheMaxBackoff)
+	nodeCacheInitialBackoff, nodeCacheMaxBackoff = time.Millisecond, 2*time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &flakyCacheClient{failures: 3}
+	var started api.NodeCache
+	StartNodeCache(ctx, logger.NewLogger("error", io.Discard, "text"), client, func(cache api.NodeCache) { started = cache })
+	if started == nil || len(client.attempts) != 4 {
+		t.Fatalf("expected the cache to start on the fourth attempt, got %d attempts", len(client.attempts))
+	}
+	// The watches of the failed attempts are stopped; those of the cache in use are not.
+	for i, attempt := range client.attempts {
+		if failed := i < client.failures; (attempt.Err() != nil) != failed {
+			t.Errorf("attempt %d: expected its context cancelled to be %v, got %v", i+1, failed, attempt.Err())
+		}
+	}
+
+	// Once ctx is cancelled it gives up.
+	cancel()
+	client = &flakyCacheClient{failures: 1 << 30}
+	StartNodeCache(ctx, logger.NewLogger("error", io.Discard, "text"), client, func(api.NodeCache) { t.Error("unexpected cache") })
+	if len(client.attempts) != 1 {
+		t.Errorf("expected no retry after ctx was cancelled, got %d attempts", len(client.attempts))
+	}
+}
+
+func TestNodeHealthTask(t *testing.T) {
+	nodes := []types.Node{
+		{Config: types.NodeConfig{Name: "node1", IP: "10.0.0.1"}, Status: types.NodeStatus{Status: types.NodeStatusHealthy}},
+		{Config: types.NodeConfig{Name: "node2", IP: "10.0.0.2"}, Status: types.NodeStatus{Status: types.NodeStatusUnhealthy}},
+	}
+	client := &fakeNodeLister{nodes: nodes}
+	task := NewNodeHealthTask(client)
+	meta := hostMeta("10.0.0.1", 1)
+	meta.MyID.Name, meta.PeerID.Name = "node1", "node2"
+
+	if err := task.Run(context.Background(), meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	checks := task.HealthChecks()
+	if len(checks) != 2 || !checks[0].Success || checks[1].Success || checks[1].CheckName != "node.ready.node2" {
+		t.Fatalf("expected node1 ready and node2 not ready, got %+v", checks)
+	}
+	if client.lists != 2 {
+		t.Errorf("expected a list per check before the cache is used, got %d", client.lists)
+	}
+
+	task.UseNodeCache(&fakeNodeLister{nodes: nodes})
+	if err := task.Run(context.Background(), meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if client.lists != 2 {
+		t.Errorf("expected no list from the API server once the cache is used, got %d", client.lists)
+	}
+
+	if err := task.Run(context.Background(), hostMeta("10.0.0.2", 1)); err != nil || len(task.HealthChecks()) != 0 {
+		t.Errorf("expected a follower to report no node checks, got %+v (%v)", task.HealthChecks(), err)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// DefaultProxyListenAddress is where Kine reaches the current primary database.
+const DefaultProxyListenAddress = storage.KineProxyAddress
+
+// proxyDialTimeout bounds how long a client waits for the primary to accept.
+const proxyDialTimeout = 5 * time.Second
+
+// PrimaryProxy is a TCP proxy on a fixed local address that forwards to the
+// current primary database. Kine connects to it instead of a node IP, so a role
+// change only moves the proxy's target and Kine does not have to restart.
+// It is also a Task: every tick it picks the primary from HostMeta.
+type PrimaryProxy struct {
+	log        logger.Logger
+	listenAddr string
+	port       int
+	dial       func(ctx context.Context, address string) (net.Conn, error)
+
+	mu     sync.Mutex
+	target string // Address of the primary; empty while there is none
+	epoch  int64
+	conns  map[*proxyConn]struct{}
+}
+
+// proxyConn is one client connection and its upstream.
+type proxyConn struct {
+	target   string
+	client   net.Conn
+	upstream net.Conn
+}
+
+// close closes both sides of the connection.
+func (c *proxyConn) close() {
+	c.client.Close()
+	c.upstream.Close()
+}
+
+// NewPrimaryProxy creates a proxy that listens on listenAddr and forwards to
+// port on the primary node.
+func NewPrimaryProxy(log logger.Logger, listenAddr string, port int) *PrimaryProxy {
+	dialer := &net.Dialer{Timeout: proxyDialTimeout}
+	return &PrimaryProxy{
+		log:        log.WithField("task", "proxy"),
+		listenAddr: listenAddr,
+		port:       port,
+		dial: func(ctx context.Context, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		},
+		conns: make(map[*proxyConn]struct{}),
+	}
+}
+
+// Name returns the name of the task.
+func (p *PrimaryProxy) Name() string {
+	return "proxy"
+}
+
+// Run points the proxy at the primary named in meta. A HostMeta from an older
+// epoch than the one already seen is stale and ignored. When the target
+// changes, connections to the old primary are dropped: it has been fenced.
+func (p *PrimaryProxy) Run(ctx context.Context, meta *types.HostMeta) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if meta.Epoch < p.epoch {
+		p.log.Warnf("Ignoring host metadata from epoch %d; already at epoch %d.", meta.Epoch, p.epoch)
+		return nil
+	}
+	p.epoch = meta.Epoch
+
+	target := ""
+	if primary, ok := meta.Primary(); ok {
+		target = net.JoinHostPort(primary.IP, strconv.Itoa(p.port))
+	}
+	if target == p.target {
+		return nil
+	}
+
+	dropped := 0
+	for c := range p.conns {
+		if c.target != target {
+			c.close()
+			delete(p.conns, c)
+			dropped++
+		}
+	}
+	p.target = target
+	if target == "" {
+		p.log.Errorf("No single primary in epoch %d; refusing connections (%d dropped).", meta.Epoch, dropped)
+		return errors.Newf(errors.OrchestratorError, "host metadata names no single primary in epoch %d", meta.Epoch)
+	}
+	p.log.Infof("Forwarding to primary %s in epoch %d (%d connections to the fenced primary dropped).", target, meta.Epoch, dropped)
+	return nil
+}
+
+// Serve accepts connections until ctx is cancelled.
+func (p *PrimaryProxy) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", p.listenAddr)
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to listen on %s", p.listenAddr)
+	}
+	return p.serve(ctx, listener)
+}
+
+// serve accepts connections on listener until ctx is cancelled.
+func (p *PrimaryProxy) serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		p.closeAll()
+	}()
+
+	p.log.Infof("Proxy listening on %s", listener.Addr())
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, errors.NetworkError, "proxy accept failed")
+		}
+		go p.handle(ctx, client)
+	}
+}
+
+// handle forwards one client connection to the current primary.
+func (p *PrimaryProxy) handle(ctx context.Context, client net.Conn) {
+	p.mu.Lock()
+	target := p.target
+	p.mu.Unlock()
+	if target == "" {
+		client.Close()
+		return
+	}
+
+	upstream, err := p.dial(ctx, target)
+	if err != nil {
+		p.log.Warnf("Failed to connect to primary %s: %v", target, err)
+		client.Close()
+		return
+	}
+
+	c := &proxyConn{target: target, client: client, upstream: upstream}
+	p.mu.Lock()
+	if p.target != target {
+		// The primary was fenced while we were dialing.
+		p.mu.Unlock()
+		c.close()
+		return
+	}
+	p.conns[c] = struct{}{}
+	p.mu.Unlock()
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, client); done <- struct{}{} }()
+	go func() { io.Copy(client, upstream); done <- struct{}{} }()
+	<-done
+
+	c.close()
+	p.mu.Lock()
+	delete(p.conns, c)
+	p.mu.Unlock()
+}
+
+// closeAll drops every open connection.
+func (p *PrimaryProxy) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for c := range p.conns {
+		c.close()
+		delete(p.conns, c)
+	}
+}
+
+//Personal.AI order the ending
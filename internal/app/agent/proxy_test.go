@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// namedServer answers every line it receives with its name.
+func namedServer(t *testing.T, name string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					io.WriteString(conn, name+"\n")
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func hostMeta(leaderIP string, epoch int64) *types.HostMeta {
+	meta := &types.HostMeta{
+		MyID:   types.NodeIdentity{IP: "10.0.0.1", Role: types.RoleFollower},
+		PeerID: types.NodeIdentity{IP: "10.0.0.2", Role: types.RoleFollower},
+		Epoch:  epoch,
+	}
+	if leaderIP == meta.MyID.IP {
+		meta.MyID.Role = types.RoleLeader
+	} else if leaderIP == meta.PeerID.IP {
+		meta.PeerID.Role = types.RoleLeader
+	}
+	return meta
+}
+
+func ask(t *testing.T, conn net.Conn) (string, error) {
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.WriteString(conn, "who\n"); err != nil {
+		return "", err
+	}
+	return bufio.NewReader(conn).ReadString('\n')
+}
+
+func TestPrimaryProxy(t *testing.T) {
+	servers := map[string]net.Listener{
+		"10.0.0.1:5432": namedServer(t, "node1"),
+		"10.0.0.2:5432": namedServer(t, "node2"),
+	}
+	proxy := NewPrimaryProxy(logger.NewLogger("error", io.Discard, "text"), "", 5432)
+	proxy.dial = func(ctx context.Context, address string) (net.Conn, error) {
+		return net.Dial("tcp", servers[address].Addr().String())
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go proxy.serve(ctx, listener)
+
+	if err := proxy.Run(ctx, hostMeta("10.0.0.1", 1)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	first, _ := net.Dial("tcp", listener.Addr().String())
+	defer first.Close()
+	if got, err := ask(t, first); err != nil || got != "node1\n" {
+		t.Fatalf("expected node1 to answer, got %q (%v)", got, err)
+	}
+
+	t.Run("stale epoch is ignored", func(t *testing.T) {
+		proxy.Run(ctx, hostMeta("10.0.0.2", 0))
+		if got, err := ask(t, first); err != nil || got != "node1\n" {
+			t.Fatalf("expected node1 to keep answering, got %q (%v)", got, err)
+		}
+	})
+
+	t.Run("failover drops connections to the fenced primary", func(t *testing.T) {
+		if err := proxy.Run(ctx, hostMeta("10.0.0.2", 2)); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if _, err := ask(t, first); err == nil {
+			t.Errorf("expected the connection to the fenced primary to be closed")
+		}
+		second, _ := net.Dial("tcp", listener.Addr().String())
+		defer second.Close()
+		if got, err := ask(t, second); err != nil || got != "node2\n" {
+			t.Fatalf("expected node2 to answer, got %q (%v)", got, err)
+		}
+	})
+
+	t.Run("no primary refuses connections", func(t *testing.T) {
+		if err := proxy.Run(ctx, hostMeta("", 3)); err == nil {
+			t.Errorf("expected an error without a single primary")
+		}
+		conn, _ := net.Dial("tcp", listener.Addr().String())
+		defer conn.Close()
+		if _, err := ask(t, conn); err == nil {
+			t.Errorf("expected the connection to be refused")
+		}
+	})
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// ReplicationReporter provides the replication mode that the leader publishes
+// with the GeminiCluster status.
+type ReplicationReporter interface {
+	// Replication returns the mode in effect, empty until it was first
+	// enforced, and whether it falls short of the configured mode.
+	Replication() (mode types.ReplicationMode, degraded bool)
+}
+
+// ReplicationTask keeps the leader's replication mode in line with
+// spec.storage.replication, degrading to async while the follower is away.
+type ReplicationTask struct {
+	log        logger.Logger
+	storageSvc storage.ServiceInterface
+	cfg        *types.ReplicationConfig
+	lastMode   types.ReplicationMode
+}
+
+// NewReplicationTask creates the task that enforces the replication mode on the leader.
+func NewReplicationTask(log logger.Logger, storageSvc storage.ServiceInterface, cfg *types.ReplicationConfig) *ReplicationTask {
+	return &ReplicationTask{
+		log:        log.WithField("task", "replication"),
+		storageSvc: storageSvc,
+		cfg:        cfg,
+	}
+}
+
+// Name returns the name of the task.
+func (t *ReplicationTask) Name() string {
+	return "replication"
+}
+
+// Run enforces the replication mode. Only the leader runs the primary database,
+// so the task does nothing on the follower.
+func (t *ReplicationTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	if meta.MyID.Role != types.RoleLeader {
+		t.lastMode = ""
+		return nil
+	}
+
+	mode, err := t.storageSvc.EnforceReplicationMode(ctx, meta.MyID.IP, meta.PeerID.IP, t.cfg)
+	if err != nil {
+		return err
+	}
+	if mode == t.lastMode {
+		return nil
+	}
+
+	desired := t.cfg.EffectiveMode()
+	switch {
+	case desired == types.ReplicationModeSync && mode == types.ReplicationModeAsync && t.lastMode == "":
+		t.log.Warnf("Follower is not streaming; replicating asynchronously until it catches up. Cluster is %s until then.", types.StatusDegraded)
+	case desired == types.ReplicationModeSync && mode == types.ReplicationModeAsync:
+		t.log.Warnf("Follower unreachable for more than %s; fell back to async replication. Cluster is %s until it catches up.",
+			t.cfg.DegradeTimeoutOrDefault(), types.StatusDegraded)
+	case desired == types.ReplicationModeSync && t.lastMode != "":
+		t.log.Infof("Follower caught up; synchronous replication restored.")
+	default:
+		t.log.Infof("Replication mode is %s.", mode)
+	}
+	t.lastMode = mode
+	return nil
+}
+
+// Replication returns the mode the task last enforced and whether it is
+// degraded from the configured sync mode.
+func (t *ReplicationTask) Replication() (types.ReplicationMode, bool) {
+	degraded := t.cfg.EffectiveMode() == types.ReplicationModeSync && t.lastMode == types.ReplicationModeAsync
+	return t.lastMode, degraded
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// StatusRefreshInterval is how often the leader rewrites an unchanged
+// GeminiCluster status, so lastUpdateTime shows the leader is still alive.
+const StatusRefreshInterval = time.Minute
+
+// clusterStatusTask publishes the HA state of the cluster to the status of its
+// GeminiCluster custom resource. Only the leader runs it.
+type clusterStatusTask struct {
+	log         logger.Logger
+	clusterName string
+	vips        []types.VIPConfig
+	client      api.K8sClient
+	storageSvc  storage.ServiceInterface
+	hasAddress  func(ip string) (bool, error)
+	heartbeat   PeerHeartbeat
+	replication ReplicationReporter
+	reporters   []CheckReporter
+	installed   bool
+	published   types.GeminiClusterStatus // Last written status, without lastUpdateTime
+	publishedAt time.Time
+}
+
+// NewClusterStatusTask creates the task that keeps the GeminiCluster status up
+// to date, including the given VIPs, the heartbeat paths to the follower if
+// heartbeat is not nil, the replication mode if replication is not nil and the
+// health checks of reporters.
+func NewClusterStatusTask(log logger.Logger, clusterName string, vips []types.VIPConfig, client api.K8sClient, storageSvc storage.ServiceInterface, heartbeat PeerHeartbeat, replication ReplicationReporter, reporters ...CheckReporter) Task {
+	return &clusterStatusTask{
+		log:         log.WithField("task", "cluster-status"),
+		clusterName: clusterName,
+		vips:        vips,
+		client:      client,
+		storageSvc:  storageSvc,
+		hasAddress:  hasLocalAddress,
+		heartbeat:   heartbeat,
+		replication: replication,
+		reporters:   reporters,
+	}
+}
+
+// Name returns the name of the task.
+func (t *clusterStatusTask) Name() string {
+	return "cluster-status"
+}
+
+// Run installs the GeminiCluster CRD and object once, then writes the status
+// whenever it changes and at least every StatusRefreshInterval.
+func (t *clusterStatusTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	if meta.MyID.Role != types.RoleLeader {
+		// A new leader must publish right away, even if it led before.
+		t.publishedAt = time.Time{}
+		return nil
+	}
+
+	if !t.installed {
+		if _, err := t.client.Apply(ctx, kubernetes.GeminiClusterManifest(t.clusterName)); err != nil {
+			return err
+		}
+		t.installed = true
+	}
+
+	observed := t.observe(ctx, meta)
+	now := time.Now()
+	if reflect.DeepEqual(withoutTimes(observed), withoutTimes(t.published)) && now.Sub(t.publishedAt) < StatusRefreshInterval {
+		return nil
+	}
+
+	err := t.client.UpdateClusterStatus(ctx, t.clusterName, func(s *types.GeminiClusterStatus) {
+		switch {
+		case meta.Epoch > s.Epoch && !s.LastUpdateTime.IsZero():
+			failover := now
+			s.LastFailoverTime = &failover
+		case s.LastFailoverTime == nil && meta.Epoch > 0 && !meta.LastModified.IsZero():
+			// First status of a cluster that has failed over before.
+			failover := meta.LastModified
+			s.LastFailoverTime = &failover
+		}
+		s.Leader = observed.Leader
+		s.Epoch = observed.Epoch
+		s.Nodes = observed.Nodes
+		s.VIP = observed.VIP
+		s.VIPHolder = observed.VIPHolder
+		s.VIPs = observed.VIPs
+		s.ReplicationStreaming = observed.ReplicationStreaming
+		s.ReplicationLag = observed.ReplicationLag
+		s.ReplicationMode = observed.ReplicationMode
+		s.Degraded = observed.Degraded
+		s.HealthChecks = observed.HealthChecks
+		s.LastUpdateTime = now
+	})
+	if err != nil {
+		return err
+	}
+	t.published, t.publishedAt = observed, now
+	return nil
+}
+
+// observe collects the parts of the status this node knows as leader.
+func (t *clusterStatusTask) observe(ctx context.Context, meta *types.HostMeta) types.GeminiClusterStatus {
+	status := types.GeminiClusterStatus{
+		Leader: meta.MyID.Name,
+		Epoch:  meta.Epoch,
+		VIP:    meta.VIP,
+		Nodes:  []types.GeminiClusterNodeStatus{{Name: meta.MyID.Name, IP: meta.MyID.IP, Role: meta.MyID.Role}},
+	}
+	if meta.PeerID.IP != "" {
+		peer := types.GeminiClusterNodeStatus{Name: meta.PeerID.Name, IP: meta.PeerID.IP, Role: meta.PeerID.Role}
+		if t.heartbeat != nil {
+			heartbeat := t.heartbeat.Status()
+			peer.Heartbeat = &heartbeat
+		}
+		status.Nodes = append(status.Nodes, peer)
+	}
+
+	if meta.VIP != "" && t.holds(meta.VIP) {
+		status.VIPHolder = meta.MyID.Name
+	}
+	for _, vip := range t.vips {
+		vs := types.GeminiClusterVIPStatus{Name: vip.Name, Address: vip.Address, Purpose: vip.Purpose}
+		switch {
+		case vip.HeldBy(meta.MyID.IP, meta.MyID.Role):
+			vs.Owner = meta.MyID.Name
+		case vip.HeldBy(meta.PeerID.IP, meta.PeerID.Role):
+			vs.Owner = meta.PeerID.Name
+		}
+		if t.holds(vip.Address) {
+			vs.Holder = meta.MyID.Name
+		}
+		status.VIPs = append(status.VIPs, vs)
+	}
+
+	if t.storageSvc != nil && meta.PeerID.IP != "" {
+		state, err := t.storageSvc.ReplicationState(ctx, meta.MyID.IP, meta.PeerID.IP)
+		if err != nil {
+			t.log.Warnf("Failed to query replication state: %v", err)
+		} else {
+			status.ReplicationStreaming = state.Streaming
+			// Rounded so that jitter in the lag does not rewrite the status on every tick.
+			status.ReplicationLag = state.Lag.Round(100 * time.Millisecond).String()
+		}
+	}
+	if t.replication != nil {
+		status.ReplicationMode, status.Degraded = t.replication.Replication()
+	}
+	for _, r := range t.reporters {
+		status.HealthChecks = append(status.HealthChecks, r.HealthChecks()...)
+	}
+	return status
+}
+
+// holds reports whether this node holds the VIP.
+func (t *clusterStatusTask) holds(vip string) bool {
+	held, err := t.hasAddress(vip)
+	if err != nil {
+		t.log.Warnf("Failed to check whether VIP %s is assigned: %v", vip, err)
+	}
+	return held
+}
+
+// withoutTimes returns the status without the times of its health checks, which
+// change with every run and alone do not warrant a rewrite.
+func withoutTimes(status types.GeminiClusterStatus) types.GeminiClusterStatus {
+	checks := make([]types.HealthCheckResult, len(status.HealthChecks))
+	for i, check := range status.HealthChecks {
+		check.Timestamp, check.DurationMs = time.Time{}, 0
+		checks[i] = check
+	}
+	status.HealthChecks = checks
+	return status
+}
+
+// hasLocalAddress reports whether ip is assigned to an interface of this host.
+func hasLocalAddress(ip string) (bool, error) {
+	want := net.ParseIP(ip)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// fakeStatusClient keeps a single GeminiCluster status in memory.
+type fakeStatusClient struct {
+	api.K8sClient
+	applied int
+	updates int
+	status  types.GeminiClusterStatus
+}
+
+func (c *fakeStatusClient) Apply(ctx context.Context, manifest []byte) ([]types.ObjectResult, error) {
+	c.applied++
+	return nil, nil
+}
+
+func (c *fakeStatusClient) UpdateClusterStatus(ctx context.Context, name string, update func(*types.GeminiClusterStatus)) error {
+	c.updates++
+	update(&c.status)
+	return nil
+}
+
+type fakeReplicationState struct {
+	storage.ServiceInterface
+	state storage.ReplicaState
+}
+
+func (s *fakeReplicationState) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	state := s.state
+	return &state, nil
+}
+
+func newTestStatusTask(client api.K8sClient, storageSvc storage.ServiceInterface) *clusterStatusTask {
+	vips := []types.VIPConfig{
+		{Name: "api", Address: "10.0.0.100", Purpose: types.VIPPurposeAPI, Ownership: types.VIPFollowsLeader},
+		{Name: "ingress", Address: "10.0.0.101", Purpose: types.VIPPurposeIngress, Ownership: types.VIPPinned, Node: "10.0.0.2"},
+	}
+	task := NewClusterStatusTask(logger.NewLogger("error", io.Discard, "text"), "demo", vips, client, storageSvc, nil, nil).(*clusterStatusTask)
+	task.hasAddress = func(ip string) (bool, error) { return ip == "10.0.0.100", nil }
+	return task
+}
+
+func TestClusterStatusTask(t *testing.T) {
+	client := &fakeStatusClient{}
+	repl := &fakeReplicationState{state: storage.ReplicaState{Streaming: true, Lag: 230 * time.Millisecond}}
+	task := newTestStatusTask(client, repl)
+	ctx := context.Background()
+
+	meta := hostMeta("10.0.0.1", 1)
+	meta.MyID.Name, meta.PeerID.Name, meta.VIP = "node1", "node2", "10.0.0.100"
+
+	if err := task.Run(ctx, meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	s := client.status
+	if client.applied != 1 || s.Leader != "node1" || s.Epoch != 1 || s.VIPHolder != "node1" {
+		t.Errorf("unexpected status after first run: %+v", s)
+	}
+	if !s.ReplicationStreaming || s.ReplicationLag != "200ms" || len(s.Nodes) != 2 || s.Nodes[1].Role != types.RoleFollower {
+		t.Errorf("unexpected replication or node status: %+v", s)
+	}
+	if s.LastFailoverTime != nil {
+		t.Errorf("expected no failover without a previous status or LastModified")
+	}
+	if len(s.VIPs) != 2 || s.VIPs[0].Owner != "node1" || s.VIPs[0].Holder != "node1" ||
+		s.VIPs[1].Owner != "node2" || s.VIPs[1].Holder != "" || s.VIPs[1].Purpose != types.VIPPurposeIngress {
+		t.Errorf("unexpected VIP status: %+v", s.VIPs)
+	}
+
+	// Nothing changed: the status is not rewritten.
+	if err := task.Run(ctx, meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if client.applied != 1 || client.updates != 1 {
+		t.Errorf("expected one apply and one update, got %d and %d", client.applied, client.updates)
+	}
+
+	// The follower does nothing.
+	if err := task.Run(ctx, hostMeta("10.0.0.2", 2)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if client.updates != 1 {
+		t.Errorf("expected the follower not to write the status")
+	}
+
+	// This node leads again in a new epoch: the failover is recorded.
+	meta.Epoch = 3
+	if err := task.Run(ctx, meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if client.status.Epoch != 3 || client.status.LastFailoverTime == nil {
+		t.Errorf("expected a failover to epoch 3 to be recorded, got %+v", client.status)
+	}
+}
+
+func TestClusterStatusTaskKeepsLastBackup(t *testing.T) {
+	backup := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &fakeStatusClient{status: types.GeminiClusterStatus{LastBackupTime: &backup}}
+	task := newTestStatusTask(client, nil)
+
+	if err := task.Run(context.Background(), hostMeta("10.0.0.1", 0)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if client.status.LastBackupTime == nil || !client.status.LastBackupTime.Equal(backup) {
+		t.Errorf("expected lastBackupTime to be kept, got %v", client.status.LastBackupTime)
+	}
+	if client.status.VIPHolder != "" {
+		t.Errorf("expected no VIP holder without a VIP")
+	}
+}
+
+type fakeReporter struct {
+	check types.HealthCheckResult
+}
+
+func (r *fakeReporter) HealthChecks() []types.HealthCheckResult {
+	return []types.HealthCheckResult{r.check}
+}
+
+func TestClusterStatusTaskHealthChecks(t *testing.T) {
+	client := &fakeStatusClient{}
+	reporter := &fakeReporter{check: types.HealthCheckResult{CheckName: "network.duplicateVIP", Success: true, Timestamp: time.Now()}}
+	task := NewClusterStatusTask(logger.NewLogger("error", io.Discard, "text"), "demo", nil, client, nil, nil, nil, reporter).(*clusterStatusTask)
+	task.hasAddress = func(ip string) (bool, error) { return false, nil }
+	ctx := context.Background()
+	meta := hostMeta("10.0.0.1", 1)
+
+	if err := task.Run(ctx, meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if checks := client.status.HealthChecks; len(checks) != 1 || !checks[0].Success {
+		t.Errorf("expected the reporter's check in the status, got %+v", checks)
+	}
+
+	// A newer run of the same check does not rewrite the status; a changed result does.
+	reporter.check.Timestamp = time.Now().Add(time.Second)
+	if err := task.Run(ctx, meta); err != nil || client.updates != 1 {
+		t.Errorf("expected no rewrite for a new timestamp, got %d updates (%v)", client.updates, err)
+	}
+	reporter.check.Success = false
+	if err := task.Run(ctx, meta); err != nil || client.updates != 2 || client.status.HealthChecks[0].Success {
+		t.Errorf("expected the failed check to be published, got %+v (%v)", client.status.HealthChecks, err)
+	}
+}
+
+type fakeHeartbeat struct {
+	peer   []string
+	status types.HeartbeatStatus
+}
+
+func (h *fakeHeartbeat) SetPeer(addresses []string) {
+	h.peer = addresses
+}
+
+func (h *fakeHeartbeat) Status() types.HeartbeatStatus {
+	return h.status
+}
+
+func TestClusterStatusTaskHeartbeat(t *testing.T) {
+	client := &fakeStatusClient{}
+	heartbeat := &fakeHeartbeat{status: types.HeartbeatStatus{Alive: true, Paths: []types.HeartbeatPathStatus{
+		{Address: "10.0.0.2", Up: true},
+		{Address: "192.168.100.2", Error: "no reply within 5s"},
+	}}}
+	task := NewClusterStatusTask(logger.NewLogger("error", io.Discard, "text"), "demo", nil, client, nil, heartbeat, nil).(*clusterStatusTask)
+	task.hasAddress = func(ip string) (bool, error) { return false, nil }
+
+	if err := task.Run(context.Background(), hostMeta("10.0.0.1", 1)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	nodes := client.status.Nodes
+	if len(nodes) != 2 || nodes[0].Heartbeat != nil || nodes[1].Heartbeat == nil || len(nodes[1].Heartbeat.Paths) != 2 {
+		t.Fatalf("expected the heartbeat paths on the follower, got %+v", nodes)
+	}
+
+	// A path going up is published.
+	heartbeat.status.Paths = []types.HeartbeatPathStatus{{Address: "10.0.0.2", Up: true}, {Address: "192.168.100.2", Up: true}}
+	if err := task.Run(context.Background(), hostMeta("10.0.0.1", 1)); err != nil || client.updates != 2 || !client.status.Nodes[1].Heartbeat.Paths[1].Up {
+		t.Errorf("expected the path to be published as up, got %+v (%v)", client.status.Nodes[1].Heartbeat, err)
+	}
+}
+
+type fakeReplicationMode struct {
+	storage.ServiceInterface
+	mode types.ReplicationMode
+}
+
+func (s *fakeReplicationMode) EnforceReplicationMode(ctx context.Context, primaryIP, replicaIP string, cfg *types.ReplicationConfig) (types.ReplicationMode, error) {
+	return s.mode, nil
+}
+
+func TestClusterStatusTaskReplication(t *testing.T) {
+	client := &fakeStatusClient{}
+	storageSvc := &fakeReplicationMode{mode: types.ReplicationModeAsync}
+	replication := NewReplicationTask(logger.NewLogger("error", io.Discard, "text"), storageSvc, &types.ReplicationConfig{Mode: types.ReplicationModeSync})
+	task := NewClusterStatusTask(logger.NewLogger("error", io.Discard, "text"), "demo", nil, client, nil, nil, replication).(*clusterStatusTask)
+	task.hasAddress = func(ip string) (bool, error) { return false, nil }
+	ctx := context.Background()
+	meta := hostMeta("10.0.0.1", 1)
+
+	for _, run := range []Task{replication, task} {
+		if err := run.Run(ctx, meta); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if client.status.ReplicationMode != types.ReplicationModeAsync || !client.status.Degraded {
+		t.Errorf("expected degraded async replication, got %q (degraded %v)", client.status.ReplicationMode, client.status.Degraded)
+	}
+
+	storageSvc.mode = types.ReplicationModeSync
+	for _, run := range []Task{replication, task} {
+		if err := run.Run(ctx, meta); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if client.status.ReplicationMode != types.ReplicationModeSync || client.status.Degraded {
+		t.Errorf("expected sync replication, got %q (degraded %v)", client.status.ReplicationMode, client.status.Degraded)
+	}
+}
+
+func TestHeartbeatTask(t *testing.T) {
+	heartbeat := &fakeHeartbeat{}
+	spec := &types.ClusterSpec{Nodes: []types.NodeInfo{
+		{IP: "10.0.0.1", Addresses: []string{"fd00::1"}},
+		{IP: "10.0.0.2", Addresses: []string{"fd00::2"}, HeartbeatAddresses: []string{"192.168.100.2"}},
+	}}
+	task := NewHeartbeatTask(heartbeat, spec)
+	meta := hostMeta("10.0.0.1", 1)
+	meta.PeerID.HeartbeatAddresses = []string{"192.168.100.2", "172.16.0.2"}
+
+	if err := task.Run(context.Background(), meta); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"10.0.0.2", "fd00::2", "192.168.100.2", "172.16.0.2"}; !reflect.DeepEqual(heartbeat.peer, want) {
+		t.Errorf("expected paths %v, got %v", want, heartbeat.peer)
+	}
+
+	meta.PeerID = types.NodeIdentity{}
+	if err := task.Run(context.Background(), meta); err != nil || heartbeat.peer != nil {
+		t.Errorf("expected no paths without a peer, got %v (%v)", heartbeat.peer, err)
+	}
+}
+
+//Personal.AI order the ending
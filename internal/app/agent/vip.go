@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// CheckReporter provides health checks that the leader publishes with the
+// GeminiCluster status.
+type CheckReporter interface {
+	HealthChecks() []types.HealthCheckResult
+}
+
+// VIPTask makes each node hold the VIPs it owns and release the others: VIPs
+// that follow the leader are held by the leader, pinned VIPs by their node.
+// Each VIP is held through the provider it is configured with. The task also
+// checks that the VIPs this node should hold are held by it and by no other
+// host, and reports the results.
+type VIPTask struct {
+	providers map[types.VIPProviderType]api.VIPProvider
+	vips      []types.VIPConfig
+
+	mu     sync.Mutex
+	checks []types.HealthCheckResult // Last checks of the VIPs this node holds
+}
+
+// NewVIPTask creates the task that manages the given VIPs through providers.
+func NewVIPTask(providers map[types.VIPProviderType]api.VIPProvider, vips []types.VIPConfig) *VIPTask {
+	return &VIPTask{providers: providers, vips: vips}
+}
+
+// Name returns the name of the task.
+func (t *VIPTask) Name() string {
+	return "vip"
+}
+
+// Run acquires or releases every VIP according to its ownership. Both are
+// idempotent, so the task simply repeats them on every tick. Providers refuse
+// to acquire a VIP while another host still holds it. A failing VIP does not
+// keep the others from being managed.
+func (t *VIPTask) Run(ctx context.Context, meta *types.HostMeta) error {
+	var checks []types.HealthCheckResult
+	var failed []string
+	for _, vip := range t.vips {
+		provider, ok := t.providers[vip.Provider]
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s: no %s provider", vip.Name, vip.Provider))
+			continue
+		}
+		var err error
+		if vip.HeldBy(meta.MyID.IP, meta.MyID.Role) {
+			err = provider.Acquire(ctx, vip, meta.MyID)
+			checks = append(checks, holderCheck(ctx, provider, vip, meta.MyID))
+		} else {
+			err = provider.Release(ctx, vip, meta.MyID)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", vip.Name, err))
+		}
+	}
+
+	t.mu.Lock()
+	t.checks = checks
+	t.mu.Unlock()
+	if len(failed) > 0 {
+		return errors.Newf(errors.NetworkError, "failed to manage VIPs: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// HealthChecks returns the last holder checks, one per VIP this node holds.
+func (t *VIPTask) HealthChecks() []types.HealthCheckResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]types.HealthCheckResult(nil), t.checks...)
+}
+
+// holderCheck checks that self holds the VIP.
+func holderCheck(ctx context.Context, provider api.VIPProvider, vip types.VIPConfig, self types.NodeIdentity) types.HealthCheckResult {
+	start := time.Now()
+	holder, err := provider.Holder(ctx, vip, self)
+	check := types.HealthCheckResult{
+		CheckName:  "network.vipHolder." + vip.Name,
+		Success:    err == nil && holder == self.IP,
+		Timestamp:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		check.Message = err.Error()
+	case holder == self.IP:
+		check.Message = fmt.Sprintf("VIP %s is held by this node", vip.Address)
+	case holder == "":
+		check.Message = fmt.Sprintf("VIP %s is not held by any host", vip.Address)
+	default:
+		check.Message = fmt.Sprintf("VIP %s is held by %s, not by this node", vip.Address, holder)
+	}
+	return check
+}
+
+//Personal.AI order the ending
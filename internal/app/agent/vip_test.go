@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeVIPProvider struct {
+	actions []string
+	held    map[string]string // VIP -> holder
+	other   string            // Another host that still holds every VIP
+}
+
+func (f *fakeVIPProvider) Acquire(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	if f.other != "" {
+		return fmt.Errorf("VIP %s is still held by %s", vip.Address, f.other)
+	}
+	f.actions = append(f.actions, "acquire "+vip.Address)
+	f.held[vip.Address] = self.IP
+	return nil
+}
+func (f *fakeVIPProvider) Release(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	f.actions = append(f.actions, "release "+vip.Address)
+	delete(f.held, vip.Address)
+	return nil
+}
+func (f *fakeVIPProvider) Holder(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) (string, error) {
+	if f.other != "" {
+		return f.other, nil
+	}
+	return f.held[vip.Address], nil
+}
+
+func TestVIPTask(t *testing.T) {
+	local := &fakeVIPProvider{held: map[string]string{}}
+	cloud := &fakeVIPProvider{held: map[string]string{}}
+	task := NewVIPTask(map[types.VIPProviderType]api.VIPProvider{types.VIPProviderLocal: local, types.VIPProviderCloud: cloud}, []types.VIPConfig{
+		{Name: "api", Address: "10.0.0.100", Ownership: types.VIPFollowsLeader, Provider: types.VIPProviderLocal},
+		{Name: "ingress", Address: "10.0.0.101", Ownership: types.VIPPinned, Node: "10.0.0.1", Provider: types.VIPProviderCloud},
+	})
+	ctx := context.Background()
+	run := func(leader string) error {
+		local.actions, cloud.actions = nil, nil
+		return task.Run(ctx, hostMeta(leader, 1))
+	}
+
+	// As leader this node holds both VIPs, each through its provider.
+	if err := run("10.0.0.1"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fmt.Sprint(local.actions, cloud.actions) != "[acquire 10.0.0.100] [acquire 10.0.0.101]" {
+		t.Errorf("expected the leader to acquire both VIPs, got %v %v", local.actions, cloud.actions)
+	}
+	checks := task.HealthChecks()
+	if len(checks) != 2 || checks[1].CheckName != "network.vipHolder.ingress" || !checks[0].Success || !checks[1].Success {
+		t.Errorf("expected a successful check per held VIP, got %+v", checks)
+	}
+
+	// As follower it releases the API VIP but keeps the one pinned to it.
+	if err := run("10.0.0.2"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if fmt.Sprint(local.actions, cloud.actions) != "[release 10.0.0.100] [acquire 10.0.0.101]" {
+		t.Errorf("expected the follower to keep only the pinned VIP, got %v %v", local.actions, cloud.actions)
+	}
+
+	// The old leader still holds the VIPs: the takeover fails and is reported.
+	local.other = "52:54:00:12:34:56"
+	if err := run("10.0.0.1"); err == nil {
+		t.Errorf("expected the takeover to fail")
+	}
+	checks = task.HealthChecks()
+	if len(checks) != 2 || checks[0].Success || checks[0].Message != "VIP 10.0.0.100 is held by 52:54:00:12:34:56, not by this node" || !checks[1].Success {
+		t.Errorf("expected a failed check of the API VIP only, got %+v", checks)
+	}
+
+	// A VIP whose provider is not set up is reported without affecting the others.
+	task = NewVIPTask(map[types.VIPProviderType]api.VIPProvider{types.VIPProviderLocal: cloud}, []types.VIPConfig{
+		{Name: "lb", Address: "10.0.0.102", Provider: types.VIPProviderHAProxy},
+		{Name: "api", Address: "10.0.0.100", Provider: types.VIPProviderLocal},
+	})
+	if err := run("10.0.0.1"); err == nil || len(cloud.actions) != 1 {
+		t.Errorf("expected the missing provider to be reported and the other VIP acquired, got %v %v", err, cloud.actions)
+	}
+}
+
+//Personal.AI order the ending
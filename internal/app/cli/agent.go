@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/internal/app/agent"
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/infrastructure/database"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/infrastructure/network"
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// NewAgentCmd creates the 'agent' command.
+func NewAgentCmd(appCtx *AppContext) *cobra.Command {
+	var (
+		hostMetaPath string
+		proxyListen  string
+		healthListen string
+		kubeconfig   string
+		interval     time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the node agent",
+		Long:  `Runs the long-lived agent on a cluster node. It reads the node's hostMeta.yaml and keeps local services in line with the cluster configuration.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appCtx.Logger.Infof("Loading configuration from '%s'", cfgFile)
+			cfg, err := appCtx.ConfigManager.Load(cfgFile)
+			if err != nil {
+				appCtx.Logger.Errorf("Failed to load configuration: %v", err)
+				return err
+			}
+
+			backend, err := database.NewBackend(cfg.Spec.Storage, func(nodeIP string) api.SystemOperator {
+				return system.NewRemoteOperator(nodeIP)
+			})
+			if err != nil {
+				appCtx.Logger.Errorf("Failed to set up the %s datastore: %v", cfg.Spec.Storage.BackendType(), err)
+				return err
+			}
+
+			storageSvc := storage.NewService(database.NewMemoryStorageRepository(), backend)
+			replication := agent.NewReplicationTask(appCtx.Logger, storageSvc, cfg.Spec.Storage.Replication)
+			tasks := []agent.Task{replication}
+			// A heartbeat path going up or down is published at once.
+			var a *agent.Agent
+			heartbeat := network.NewHeartbeat(appCtx.Logger, cfg.Spec.Heartbeat, func() { a.Trigger() })
+			tasks = append(tasks, agent.NewHeartbeatTask(heartbeat, &cfg.Spec))
+			var reporters []agent.CheckReporter
+			var speaker *network.BGPSpeaker
+			providers := map[types.VIPProviderType]api.VIPProvider{}
+			vips := cfg.Spec.Network.AllVIPs()
+			switch cfg.Spec.Network.EffectiveVIPMode() {
+			case types.VIPModeVRRP:
+				if healthListen == "" {
+					return errors.New(errors.ValidationError, "--health-listen must be set when spec.network.vipMode is 'vrrp': keepalived tracks the agent through it")
+				}
+				keepalived := network.NewKeepalived(system.NewSystemOperator(), cfg.Spec.Network, "http://"+healthListen+agent.HealthPath)
+				tasks = append(tasks, agent.NewKeepalivedTask(appCtx.Logger, keepalived))
+				// keepalived holds the local VIPs; the task only manages the others.
+				var others []types.VIPConfig
+				for _, vip := range vips {
+					if vip.Provider != types.VIPProviderLocal {
+						others = append(others, vip)
+					}
+				}
+				vips = others
+			case types.VIPModeBGP:
+				speaker, err = network.NewBGPSpeaker(appCtx.Logger, *cfg.Spec.Network.BGP)
+				if err != nil {
+					appCtx.Logger.Errorf("Failed to set up the BGP speaker: %v", err)
+					return err
+				}
+				providers[types.VIPProviderLocal] = network.NewBGPProvider(speaker)
+			default:
+				providers[types.VIPProviderLocal] = network.NewNetlinkProvider()
+			}
+			if cloud := cfg.Spec.Network.Cloud; cloud != nil {
+				providers[types.VIPProviderCloud], err = network.NewCloudProvider(*cloud)
+				if err != nil {
+					appCtx.Logger.Errorf("Failed to set up the cloud VIP provider: %v", err)
+					return err
+				}
+			}
+			if h := cfg.Spec.Network.HAProxy; h != nil {
+				providers[types.VIPProviderHAProxy] = network.NewHAProxyProvider(system.NewRemoteOperator(h.Host), *h)
+			}
+			if len(vips) > 0 {
+				vipTask := agent.NewVIPTask(providers, vips)
+				tasks = append(tasks, vipTask)
+				reporters = append(reporters, vipTask)
+			}
+
+			var client api.K8sClient
+			var nodeHealth *agent.NodeHealthTask
+			if kubeconfig != "" {
+				client, err = kubernetes.NewK8sClient(kubeconfig)
+				if err != nil {
+					appCtx.Logger.Errorf("Failed to create Kubernetes client: %v", err)
+					return err
+				}
+				recorder := client.EventRecorder("geminik8s-agent", kubernetes.AgentEventSpoolPath)
+				nodeHealth = agent.NewNodeHealthTask(client)
+				tasks = append(tasks, nodeHealth)
+				reporters = append(reporters, nodeHealth)
+				tasks = append(tasks,
+					agent.NewClusterStatusTask(appCtx.Logger, cfg.Metadata.Name, cfg.Spec.Network.AllVIPs(), client, storageSvc, heartbeat, replication, reporters...),
+					agent.NewRoleEventsTask(cfg.Metadata.Name, recorder))
+				if cfg.Spec.NodeLabels.Enabled() {
+					tasks = append(tasks, agent.NewNodeLabelsTask(client, cfg.Spec.NodeLabels))
+				}
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			// A background service that fails stops the agent, which then exits with its error.
+			failed := make(chan error, 3)
+			fail := func(err error) {
+				failed <- err
+				cancel()
+			}
+			if speaker != nil {
+				// On shutdown the sessions are closed first, so that the peers drop the routes at once.
+				stopped := make(chan struct{})
+				go func() {
+					speaker.Run(ctx)
+					close(stopped)
+				}()
+				defer func() {
+					cancel()
+					<-stopped
+				}()
+			}
+			if port := cfg.Spec.Storage.ServerPort(); port != 0 && proxyListen != "" {
+				proxy := agent.NewPrimaryProxy(appCtx.Logger, proxyListen, port)
+				tasks = append(tasks, proxy)
+				go func() {
+					if err := proxy.Serve(ctx); err != nil {
+						appCtx.Logger.Errorf("Primary proxy stopped: %v", err)
+						fail(err)
+					}
+				}()
+			}
+
+			a = agent.New(appCtx.Logger, system.NewSystemOperator(), hostMetaPath, interval, tasks...)
+			go func() {
+				if err := heartbeat.Run(ctx); err != nil {
+					appCtx.Logger.Errorf("Heartbeat stopped: %v", err)
+					fail(err)
+				}
+			}()
+			if healthListen != "" {
+				go func() {
+					if err := a.ServeHealth(ctx, healthListen); err != nil {
+						appCtx.Logger.Errorf("Health endpoint stopped: %v", err)
+						fail(err)
+					}
+				}()
+			}
+			if client != nil {
+				// The API server may still be starting, so the cache is filled in the background.
+				watcher := agent.NewNodeWatcher(appCtx.Logger, a.Trigger)
+				go agent.StartNodeCache(ctx, appCtx.Logger, client, func(cache api.NodeCache) {
+					nodeHealth.UseNodeCache(cache)
+					cache.Subscribe(watcher.OnNode)
+				})
+			}
+			appCtx.Logger.Infof("Starting agent for cluster '%s'", cfg.Metadata.Name)
+			if err := a.Run(ctx); err != nil {
+				return err
+			}
+			select {
+			case err := <-failed:
+				return err
+			default:
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&hostMetaPath, "host-meta", agent.DefaultHostMetaPath, "Path to this node's hostMeta.yaml")
+	cmd.Flags().StringVar(&proxyListen, "proxy-listen", agent.DefaultProxyListenAddress, "Local address of the proxy to the primary database; empty disables it")
+	cmd.Flags().StringVar(&healthListen, "health-listen", agent.DefaultHealthListenAddress, "Local address of the agent's health endpoint; empty disables it")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", kubernetes.K3sKubeconfigPath, "Kubeconfig used to publish the GeminiCluster status, Events and node role labels, and to watch nodes; empty disables all of these")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "How often the agent reconciles local state")
+
+	return cmd
+}
+
+//Personal.AI order the ending
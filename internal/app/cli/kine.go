@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/internal/infrastructure/database"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+)
+
+// NewKineCmd creates the 'kine' command.
+func NewKineCmd(appCtx *AppContext) *cobra.Command {
+	var (
+		kinePath      string
+		endpoint      string
+		listenAddress string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "kine",
+		Short: "Run Kine under supervision",
+		Long: `Runs Kine on this node and keeps it running. A crashed Kine is restarted with
+exponential backoff, its output is written to the log, and a Kine that keeps failing its
+etcd health probe is restarted too. The datastore endpoint defaults to $KINE_ENDPOINT,
+which the geminik8s-kine systemd unit reads from /etc/geminik8s/kine.env.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endpoint == "" {
+				endpoint = os.Getenv("KINE_ENDPOINT")
+			}
+			if endpoint == "" {
+				return errors.New(errors.ValidationError, "no datastore endpoint: set --endpoint or KINE_ENDPOINT")
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			manager := database.NewKineManager(appCtx.Logger)
+			if err := manager.Start(ctx, kinePath, endpoint, listenAddress); err != nil {
+				appCtx.Logger.Errorf("Failed to start Kine: %v", err)
+				return err
+			}
+			<-ctx.Done()
+			return manager.Stop()
+		},
+	}
+
+	cmd.Flags().StringVar(&kinePath, "kine-path", "/usr/local/bin/kine", "Path to the kine binary")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Datastore endpoint of Kine (default is $KINE_ENDPOINT)")
+	cmd.Flags().StringVar(&listenAddress, "listen-address", "", "Address Kine serves the etcd API on (default is 0.0.0.0:2379)")
+
+	return cmd
+}
+
+//Personal.AI order the ending
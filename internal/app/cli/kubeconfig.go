@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NewKubeconfigCmd creates the 'kubeconfig' command group.
+func NewKubeconfigCmd(appCtx *AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Get a kubeconfig for the cluster",
+		Long: `Fetches the admin kubeconfig from the current leader, points it at the cluster VIP
+and names its context after metadata.name.`,
+	}
+
+	cmd.AddCommand(NewKubeconfigGetCmd(appCtx))
+	cmd.AddCommand(NewKubeconfigMergeCmd(appCtx))
+
+	return cmd
+}
+
+// NewKubeconfigGetCmd creates the 'kubeconfig get' command.
+func NewKubeconfigGetCmd(appCtx *AppContext) *cobra.Command {
+	var (
+		opts   types.KubeconfigOptions
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Print the cluster's kubeconfig",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeconfig, err := fetchKubeconfig(cmd, appCtx, opts)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(kubeconfig)
+				return err
+			}
+			if err := os.WriteFile(output, kubeconfig, 0o600); err != nil {
+				return errors.Wrapf(err, errors.IOError, "failed to write kubeconfig to %s", output)
+			}
+			appCtx.Logger.Infof("Kubeconfig written to '%s'", output)
+			return nil
+		},
+	}
+
+	addKubeconfigFlags(cmd, &opts)
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the kubeconfig to this file instead of stdout")
+
+	return cmd
+}
+
+// NewKubeconfigMergeCmd creates the 'kubeconfig merge' command.
+func NewKubeconfigMergeCmd(appCtx *AppContext) *cobra.Command {
+	var (
+		opts       types.KubeconfigOptions
+		path       string
+		setCurrent bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge the cluster's kubeconfig into an existing kubeconfig file",
+		Long: `Adds the cluster, user and context to the kubeconfig file. Entries of other clusters
+are kept; entries with the same names are replaced.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeconfig, err := fetchKubeconfig(cmd, appCtx, opts)
+			if err != nil {
+				return err
+			}
+			if err := kubernetes.MergeKubeconfig(path, kubeconfig, setCurrent); err != nil {
+				appCtx.Logger.Errorf("Failed to merge kubeconfig: %v", err)
+				return err
+			}
+			appCtx.Logger.Infof("Kubeconfig merged into '%s'", path)
+			return nil
+		},
+	}
+
+	addKubeconfigFlags(cmd, &opts)
+	cmd.Flags().StringVar(&path, "kubeconfig", clientcmd.RecommendedHomeFile, "Kubeconfig file to merge into")
+	cmd.Flags().BoolVar(&setCurrent, "set-current-context", false, "Switch the current context to the cluster")
+
+	return cmd
+}
+
+// addKubeconfigFlags adds the flags shared by 'kubeconfig get' and 'kubeconfig merge'.
+func addKubeconfigFlags(cmd *cobra.Command, opts *types.KubeconfigOptions) {
+	cmd.Flags().DurationVar(&opts.TTL, "ttl", 0, "Issue a service account token valid for this long instead of the admin credential")
+	cmd.Flags().StringVar(&opts.ClusterRole, "cluster-role", "view", "Cluster role granted to the token issued with --ttl")
+}
+
+// fetchKubeconfig loads the cluster configuration and fetches the kubeconfig.
+func fetchKubeconfig(cmd *cobra.Command, appCtx *AppContext, opts types.KubeconfigOptions) ([]byte, error) {
+	appCtx.Logger.Infof("Loading configuration from '%s'", cfgFile)
+	cfg, err := appCtx.ConfigManager.Load(cfgFile)
+	if err != nil {
+		appCtx.Logger.Errorf("Failed to load configuration: %v", err)
+		return nil, err
+	}
+
+	if opts.TTL != 0 && opts.TTL < 10*time.Minute {
+		// The TokenRequest API does not issue tokens shorter than ten minutes.
+		return nil, errors.New(errors.ValidationError, "--ttl must be at least 10m")
+	}
+
+	result, err := appCtx.Orchestrator.Kubeconfig(cmd.Context(), cfg, opts)
+	if err != nil {
+		appCtx.Logger.Errorf("Failed to get kubeconfig: %v", err)
+		return nil, err
+	}
+	appCtx.Logger.Infof("%s", result.Message)
+	kubeconfig, _ := result.Data["kubeconfig"].([]byte)
+	return kubeconfig, nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/internal/app/operator"
+	"github.com/turtacn/geminik8s/internal/app/orchestrator"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+)
+
+// NewOperatorCmd creates the 'operator' command.
+func NewOperatorCmd(appCtx *AppContext) *cobra.Command {
+	var (
+		kubeconfig string
+		identity   string
+		resync     time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Reconcile ClusterConfig custom resources",
+		Long: `Runs the operator, which manages geminik8s declaratively from inside the cluster.
+It watches ClusterConfig custom resources and brings the cluster to the version, preferred
+leader and backup schedule they describe, recording the outcome as status conditions.
+Run it on both nodes; the instances elect a leader through a Lease and only the leader reconciles.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if identity == "" {
+				hostname, err := os.Hostname()
+				if err != nil {
+					return errors.Wrap(err, errors.ValidationError, "failed to determine hostname; set --identity")
+				}
+				identity = hostname
+			}
+
+			client, err := kubernetes.NewK8sClient(kubeconfig)
+			if err != nil {
+				appCtx.Logger.Errorf("Failed to create Kubernetes client: %v", err)
+				return err
+			}
+			if _, err := client.Apply(cmd.Context(), []byte(kubernetes.ClusterConfigCRD)); err != nil {
+				appCtx.Logger.Errorf("Failed to install the ClusterConfig CRD: %v", err)
+				return err
+			}
+
+			// Stopping on a signal releases the lease, so the other instance takes over at once.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			appCtx.Logger.Infof("Starting operator as '%s'", identity)
+			// Events are recorded once, as the operator's, instead of also as the command's.
+			orch := orchestrator.WithEvents(appCtx.Engine, client.EventRecorder("geminik8s-operator", kubernetes.OperatorEventSpoolPath))
+			return operator.New(appCtx.Logger, orch, client, appCtx.ConfigManager, identity, resync).Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", kubernetes.K3sKubeconfigPath, "Kubeconfig of the cluster")
+	cmd.Flags().StringVar(&identity, "identity", "", "Name of this instance in leader election (default is the hostname)")
+	cmd.Flags().DurationVar(&resync, "resync", time.Minute, "How often every ClusterConfig is reconciled even if it did not change")
+
+	return cmd
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/internal/infrastructure/network"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// NewPreflightCmd creates the 'preflight' command group.
+func NewPreflightCmd(appCtx *AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check the nodes before deploying",
+	}
+
+	cmd.AddCommand(NewPreflightNetworkCmd(appCtx))
+	cmd.AddCommand(newPreflightServeCmd())
+	cmd.AddCommand(newPreflightProbeCmd())
+
+	return cmd
+}
+
+// NewPreflightNetworkCmd creates the 'preflight network' command.
+func NewPreflightNetworkCmd(appCtx *AppContext) *cobra.Command {
+	var opts types.PreflightOptions
+
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Check the network between the nodes",
+		Long: `Tests every port the nodes need on each other in both directions, the round-trip
+time and jitter between them and the MTU with pings that must not be fragmented, and
+checks that each VIP is unused and inside the nodes' subnet. Nothing is changed on the nodes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appCtx.Logger.Infof("Loading configuration from '%s'", cfgFile)
+			cfg, err := appCtx.ConfigManager.Load(cfgFile)
+			if err != nil {
+				appCtx.Logger.Errorf("Failed to load configuration: %v", err)
+				return err
+			}
+			return runPreflight(cmd, appCtx, cfg, opts)
+		},
+	}
+
+	addPreflightFlags(cmd, &opts)
+
+	return cmd
+}
+
+// addPreflightFlags adds the flags shared by 'preflight network' and 'deploy'.
+func addPreflightFlags(cmd *cobra.Command, opts *types.PreflightOptions) {
+	cmd.Flags().DurationVar(&opts.MaxRTT, "max-rtt", 20*time.Millisecond, "Highest acceptable average round-trip time between the nodes")
+	cmd.Flags().DurationVar(&opts.MaxJitter, "max-jitter", 10*time.Millisecond, "Highest acceptable jitter between the nodes")
+	cmd.Flags().IntVar(&opts.Samples, "samples", 10, "Number of round trips to measure")
+}
+
+// runPreflight runs the network preflight and prints its matrix. It fails if any check failed.
+func runPreflight(cmd *cobra.Command, appCtx *AppContext, cfg *types.ClusterConfig, opts types.PreflightOptions) error {
+	appCtx.Logger.Infof("🔎 Checking the network between the nodes of cluster '%s'...", cfg.Metadata.Name)
+	report, err := appCtx.Orchestrator.Preflight(cmd.Context(), cfg, opts)
+	if err != nil {
+		appCtx.Logger.Errorf("❌ Network preflight failed to run: %v", err)
+		return err
+	}
+	printPreflightMatrix(cmd.OutOrStdout(), cfg, report)
+	if !report.Passed() {
+		appCtx.Logger.Errorf("❌ Network preflight failed. Fix the failed checks before deploying.")
+		return errors.New(errors.NetworkError, "network preflight failed")
+	}
+	appCtx.Logger.Infof("✅ Network preflight passed.")
+	return nil
+}
+
+// printPreflightMatrix prints one row per check and one column per direction
+// between the nodes, followed by the checks of each node on its own.
+func printPreflightMatrix(out io.Writer, cfg *types.ClusterConfig, report *types.PreflightReport) {
+	purposes := map[string]string{"rtt": "round-trip time", "mtu": "path MTU"}
+	for _, p := range cfg.RequiredPorts() {
+		purposes[p.String()] = p.Purpose
+	}
+	label := func(check string) string {
+		if purpose := purposes[check]; purpose != "" {
+			return check + " (" + purpose + ")"
+		}
+		return check
+	}
+	cell := func(c types.PreflightCheck) string {
+		s := strings.ToUpper(string(c.Status))
+		if c.Message != "" {
+			s += " " + c.Message
+		}
+		return s
+	}
+
+	// Directions between nodes form the columns of the first table, nodes
+	// those of the second.
+	var (
+		pairCols, nodeCols, pairRows, nodeRows []string
+		pairs                                  = map[string]map[string]string{}
+		locals                                 = map[string]map[string]string{}
+	)
+	add := func(cols, rows *[]string, cells map[string]map[string]string, col, row, value string) {
+		if cells[row] == nil {
+			cells[row] = map[string]string{}
+			*rows = append(*rows, row)
+		}
+		if !contains(*cols, col) {
+			*cols = append(*cols, col)
+		}
+		cells[row][col] = value
+	}
+	for _, c := range report.Checks {
+		if c.To != "" {
+			add(&pairCols, &pairRows, pairs, c.From+" → "+c.To, label(c.Check), cell(c))
+		} else {
+			add(&nodeCols, &nodeRows, locals, c.From, label(c.Check), cell(c))
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	table := func(header string, cols, rows []string, cells map[string]map[string]string) {
+		if len(rows) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s\t%s\n", header, strings.Join(cols, "\t"))
+		for _, row := range rows {
+			line := []string{row}
+			for _, col := range cols {
+				value := cells[row][col]
+				if value == "" {
+					value = "-"
+				}
+				line = append(line, value)
+			}
+			fmt.Fprintln(w, strings.Join(line, "\t"))
+		}
+		fmt.Fprintln(w)
+	}
+	table("CHECK", pairCols, pairRows, pairs)
+	table("NODE CHECK", nodeCols, nodeRows, locals)
+	w.Flush()
+}
+
+// contains reports whether s is one of list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// newPreflightServeCmd creates the hidden 'preflight serve' command, which
+// 'preflight network' runs on each node to answer the probes of the others.
+func newPreflightServeCmd() *cobra.Command {
+	var (
+		ports   []string
+		peers   []string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:    "serve",
+		Short:  "Answer preflight probes on the required ports",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := parsePorts(ports)
+			if err != nil {
+				return err
+			}
+			l, err := network.ListenPorts(specs, peers)
+			if err != nil {
+				return err
+			}
+			defer l.Close()
+			select {
+			case <-l.Done():
+			case <-time.After(timeout):
+			case <-cmd.Context().Done():
+			}
+			busy := []string{}
+			for _, p := range l.Busy {
+				busy = append(busy, p.String())
+			}
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(map[string][]string{"busy": busy})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&ports, "ports", nil, "Ports to listen on, e.g. tcp/6443,udp/8472")
+	cmd.Flags().StringSliceVar(&peers, "peers", nil, "IPs of the nodes that probe the ports")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "Stop listening after this long even if not every peer has probed")
+
+	return cmd
+}
+
+// newPreflightProbeCmd creates the hidden 'preflight probe' command, which
+// 'preflight network' runs on each node to probe the others and check the VIPs.
+func newPreflightProbeCmd() *cobra.Command {
+	var (
+		node, networkJSON string
+		ports, peers      []string
+		opts              types.PreflightOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:    "probe",
+		Short:  "Probe the other nodes and check the VIPs from this node",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := parsePorts(ports)
+			if err != nil {
+				return err
+			}
+			var cfg types.NetworkConfig
+			if err := json.Unmarshal([]byte(networkJSON), &cfg); err != nil {
+				return errors.Wrap(err, errors.ValidationError, "invalid --network")
+			}
+			checks := []types.PreflightCheck{}
+			for _, peer := range peers {
+				checks = append(checks, network.ProbePeer(node, peer, specs, opts)...)
+			}
+			checks = append(checks, network.CheckVIPs(node, cfg)...)
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(checks)
+		},
+	}
+
+	cmd.Flags().StringVar(&node, "node", "", "IP of this node")
+	cmd.Flags().StringSliceVar(&peers, "peers", nil, "IPs of the nodes to probe")
+	cmd.Flags().StringSliceVar(&ports, "ports", nil, "Ports to probe, e.g. tcp/6443,udp/8472")
+	cmd.Flags().StringVar(&networkJSON, "network", "{}", "spec.network of the cluster, as JSON")
+	addPreflightFlags(cmd, &opts)
+
+	return cmd
+}
+
+// parsePorts parses ports written as "tcp/6443".
+func parsePorts(ports []string) ([]types.PortSpec, error) {
+	specs := make([]types.PortSpec, 0, len(ports))
+	for _, s := range ports {
+		p, err := types.ParsePortSpec(s)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ValidationError, "invalid --ports")
+		}
+		specs = append(specs, p)
+	}
+	return specs, nil
+}
+
+//Personal.AI order the ending
@@ -1,25 +1,40 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/turtacn/geminik8s/internal/app/config"
 	"github.com/turtacn/geminik8s/internal/app/orchestrator"
+	"github.com/turtacn/geminik8s/internal/infrastructure/database"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/infrastructure/network"
 	"github.com/turtacn/geminik8s/internal/pkg/logger"
 	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/turtacn/geminik8s/plugins/credentials"
+	"github.com/turtacn/geminik8s/plugins/deploy"
+	"github.com/turtacn/geminik8s/plugins/health"
+	"github.com/turtacn/geminik8s/plugins/kubeconfig"
+	"github.com/turtacn/geminik8s/plugins/preflight"
 )
 
 var (
-	cfgFile  string
-	logLevel string
-	logFile  string
+	cfgFile          string
+	logLevel         string
+	logFile          string
+	eventsKubeconfig string
 )
 
 // AppContext holds the services that are shared across commands.
 type AppContext struct {
-	Orchestrator  api.Orchestrator
+	Orchestrator api.Orchestrator
+	// Engine is Orchestrator without Events, for commands that record them
+	// under their own name.
+	Engine        api.Orchestrator
 	ConfigManager api.ConfigManager
 	Logger        logger.Logger
 }
@@ -34,8 +49,9 @@ func NewRootCmd() *cobra.Command {
 		Long: `A command-line tool to manage geminik8s clusters,
 providing cost-effective high availability for Kubernetes.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Initialize logger
-			var output = os.Stdout
+			// Initialize logger. Logs go to stderr, so that what a command
+			// prints, such as a kubeconfig or a JSON status, can be redirected.
+			var output = os.Stderr
 			if logFile != "" {
 				f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0660)
 				if err != nil {
@@ -49,7 +65,41 @@ providing cost-effective high availability for Kubernetes.`,
 			appCtx.ConfigManager = config.NewManager()
 			pluginManager := orchestrator.NewPluginManager()
 			// TODO: Register actual plugins here
-			appCtx.Orchestrator = orchestrator.NewEngine(pluginManager, appCtx.ConfigManager, nil) // Pass nil for domain services for now
+			// Generated database credentials live next to the cluster configuration.
+			credentialStore := database.NewCredentialFileStore(filepath.Join(filepath.Dir(cfgFile), "credentials.yaml"))
+			if err := pluginManager.Register(credentials.New(credentialStore)); err != nil {
+				return err
+			}
+			if err := pluginManager.Register(deploy.New()); err != nil {
+				return err
+			}
+			if err := pluginManager.Register(kubeconfig.New(network.NewNetworkOperator())); err != nil {
+				return err
+			}
+			if err := pluginManager.Register(health.New(appCtx.clusterClient, network.NewNetworkOperator())); err != nil {
+				return err
+			}
+			if err := pluginManager.Register(preflight.New()); err != nil {
+				return err
+			}
+			appCtx.Engine = orchestrator.NewEngine(pluginManager, appCtx.ConfigManager, nil) // Pass nil for domain services for now
+			appCtx.Orchestrator = appCtx.Engine
+			// Events are best-effort. Off a k3s node the default kubeconfig does
+			// not exist, and on one it is only readable by root; only an
+			// explicitly given one has to load.
+			if eventsKubeconfig != "" {
+				client, err := kubernetes.NewK8sClient(eventsKubeconfig)
+				switch {
+				case err != nil && cmd.Flags().Changed("events-kubeconfig"):
+					return err
+				case err != nil:
+					appCtx.Logger.Debugf("not recording events: %v", err)
+				default:
+					// Like the credentials, events that cannot be sent are kept next to the cluster configuration.
+					recorder := client.EventRecorder("gemin_k8s", filepath.Join(filepath.Dir(cfgFile), "events.spool"))
+					appCtx.Orchestrator = orchestrator.WithEvents(appCtx.Orchestrator, recorder)
+				}
+			}
 
 			return nil
 		},
@@ -58,7 +108,8 @@ providing cost-effective high availability for Kubernetes.`,
 	// Global flags
 	cmd.PersistentFlags().StringVar(&cfgFile, "config", "cluster.yaml", "config file (default is cluster.yaml)")
 	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
-	cmd.PersistentFlags().StringVar(&logFile, "log-file", "", "log file path (default is stdout)")
+	cmd.PersistentFlags().StringVar(&logFile, "log-file", "", "log file path (default is stderr)")
+	cmd.PersistentFlags().StringVar(&eventsKubeconfig, "events-kubeconfig", kubernetes.K3sKubeconfigPath, "record operations as Kubernetes Events in the cluster reached with this kubeconfig (empty disables them)")
 
 	// Add subcommands
 	cmd.AddCommand(NewInitCmd(appCtx))
@@ -69,9 +120,26 @@ providing cost-effective high availability for Kubernetes.`,
 	cmd.AddCommand(NewReplaceNodeCmd(appCtx))
 	cmd.AddCommand(NewBackupCmd(appCtx))
 	cmd.AddCommand(NewRestoreCmd(appCtx))
+	cmd.AddCommand(NewStorageCmd(appCtx))
+	cmd.AddCommand(NewAgentCmd(appCtx))
+	cmd.AddCommand(NewKineCmd(appCtx))
+	cmd.AddCommand(NewKubeconfigCmd(appCtx))
+	cmd.AddCommand(NewOperatorCmd(appCtx))
+	cmd.AddCommand(NewPreflightCmd(appCtx))
 	cmd.AddCommand(NewVersionCmd()) // Version doesn't need the context
 
 	return cmd
 }
 
+// clusterClient returns a client for the cluster, using the admin kubeconfig
+// fetched from the current leader.
+func (a *AppContext) clusterClient(ctx context.Context, cfg *types.ClusterConfig) (api.K8sClient, error) {
+	result, err := a.Orchestrator.Kubeconfig(ctx, cfg, types.KubeconfigOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data, _ := result.Data["kubeconfig"].([]byte)
+	return kubernetes.NewK8sClientFromKubeconfig(data)
+}
+
 //Personal.AI order the ending
@@ -3,14 +3,19 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/turtacn/geminik8s/pkg/types"
 	"sigs.k8s.io/yaml"
 )
 
 // NewStatusCmd creates the 'status' command.
 func NewStatusCmd(appCtx *AppContext) *cobra.Command {
-	var outputFormat string
+	var (
+		outputFormat string
+		details      bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "status",
@@ -33,12 +38,34 @@ func NewStatusCmd(appCtx *AppContext) *cobra.Command {
 
 			// Create a printable status object
 			printableStatus := struct {
-				ClusterName string `json:"clusterName"`
-				Status      string `json:"status"`
+				ClusterName string                          `json:"clusterName"`
+				Status      string                          `json:"status"`
+				Replication *replicationStatus              `json:"replication,omitempty"`
+				Nodes       []types.GeminiClusterNodeStatus `json:"nodes,omitempty"`
 			}{
 				ClusterName: cfg.Metadata.Name,
 				Status:      string(*status),
 			}
+			if details {
+				// The replication state, the nodes and the heartbeat paths come from the status the leader's agent publishes.
+				client, err := appCtx.clusterClient(cmd.Context(), cfg)
+				if err != nil {
+					appCtx.Logger.Errorf("Failed to connect to the cluster: %v", err)
+					return err
+				}
+				clusterStatus, err := client.GetClusterStatus(cmd.Context(), cfg.Metadata.Name)
+				if err != nil {
+					appCtx.Logger.Errorf("Failed to get the GeminiCluster status: %v", err)
+					return err
+				}
+				printableStatus.Replication = &replicationStatus{
+					Mode:      clusterStatus.ReplicationMode,
+					Degraded:  clusterStatus.Degraded,
+					Streaming: clusterStatus.ReplicationStreaming,
+					Lag:       clusterStatus.ReplicationLag,
+				}
+				printableStatus.Nodes = clusterStatus.Nodes
+			}
 
 			switch outputFormat {
 			case "json":
@@ -50,6 +77,38 @@ func NewStatusCmd(appCtx *AppContext) *cobra.Command {
 			default: // table
 				fmt.Printf("Cluster: %s\n", printableStatus.ClusterName)
 				fmt.Printf("Status:  %s\n", printableStatus.Status)
+				if r := printableStatus.Replication; r != nil {
+					streaming := "not streaming"
+					if r.Streaming {
+						streaming = "streaming, lag " + r.Lag
+					}
+					fmt.Printf("Replication: %s, %s", r.Mode, streaming)
+					if r.Degraded {
+						fmt.Printf(" (%s: sync is configured)", types.StatusDegraded)
+					}
+					fmt.Println()
+				}
+				for _, node := range printableStatus.Nodes {
+					fmt.Printf("\nNode %s (%s): %s\n", node.Name, node.IP, node.Role)
+					if hb := node.Heartbeat; hb != nil {
+						peer := "alive"
+						if !hb.Alive {
+							peer = "dead"
+						}
+						fmt.Printf("  Heartbeat: %s\n", peer)
+						for _, path := range hb.Paths {
+							state := "up"
+							if !path.Up {
+								state = "down"
+							}
+							fmt.Printf("    %-39s %-4s since %s", path.Address, state, path.Since.Format(time.RFC3339))
+							if path.Error != "" {
+								fmt.Printf(" (%s)", path.Error)
+							}
+							fmt.Println()
+						}
+					}
+				}
 			}
 
 			return nil
@@ -57,8 +116,17 @@ func NewStatusCmd(appCtx *AppContext) *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	cmd.Flags().BoolVar(&details, "details", false, "Also show the replication mode, the nodes and the leader's heartbeat paths to the follower")
 
 	return cmd
 }
 
+// replicationStatus is the replication state 'status --details' shows.
+type replicationStatus struct {
+	Mode      types.ReplicationMode `json:"mode"`
+	Degraded  bool                  `json:"degraded"`
+	Streaming bool                  `json:"streaming"`
+	Lag       string                `json:"lag,omitempty"`
+}
+
 //Personal.AI order the ending
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewStorageCmd creates the 'storage' command group.
+func NewStorageCmd(appCtx *AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Manage the cluster's datastore",
+		Long:  `Commands that operate on the PostgreSQL datastore behind Kine.`,
+	}
+
+	cmd.AddCommand(NewRotateCredentialsCmd(appCtx))
+
+	return cmd
+}
+
+// NewRotateCredentialsCmd creates the 'storage rotate-credentials' command.
+func NewRotateCredentialsCmd(appCtx *AppContext) *cobra.Command {
+	var onlyIfDue bool
+
+	cmd := &cobra.Command{
+		Use:   "rotate-credentials",
+		Short: "Rotate the Kine and replication database credentials",
+		Long: `Creates new PostgreSQL roles for Kine and replication on both nodes, switches the
+replication subscription and both Kine instances over to them, and drops the old roles.
+The admin password for PostgreSQL is read from the PGPASSWORD environment variable.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appCtx.Logger.Infof("Loading configuration from '%s'", cfgFile)
+			cfg, err := appCtx.ConfigManager.Load(cfgFile)
+			if err != nil {
+				appCtx.Logger.Errorf("Failed to load configuration: %v", err)
+				return err
+			}
+
+			appCtx.Logger.Infof("Rotating database credentials of cluster '%s'", cfg.Metadata.Name)
+			result, err := appCtx.Orchestrator.RotateCredentials(cmd.Context(), cfg, onlyIfDue)
+			if err != nil {
+				appCtx.Logger.Errorf("Credential rotation failed: %v", err)
+				return err
+			}
+
+			appCtx.Logger.Infof("%s", result.Message)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&onlyIfDue, "only-if-due", false, "Only rotate if the credentials are older than spec.storage.credentials.rotationInterval")
+
+	return cmd
+}
+
+//Personal.AI order the ending
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"net"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/internal/pkg/utils"
@@ -61,16 +64,320 @@ func (m *Manager) Validate(cfg *types.ClusterConfig) error {
 	if cfg.Metadata.Name == "" {
 		return errors.New(errors.ValidationError, "metadata.name must be set")
 	}
-	if len(cfg.Spec.Nodes) != 2 {
-		return errors.New(errors.ValidationError, "exactly two nodes must be defined in spec.nodes")
+	switch cfg.Spec.Storage.BackendType() {
+	case types.StorageTypePostgreSQL, types.StorageTypeMySQL:
+		if len(cfg.Spec.Nodes) != 2 {
+			return errors.New(errors.ValidationError, "exactly two nodes must be defined in spec.nodes")
+		}
+	case types.StorageTypeSQLite:
+		// SQLite cannot replicate, so it is only meant for single-node dev and lab setups.
+		if len(cfg.Spec.Nodes) != 1 {
+			return errors.New(errors.ValidationError, "exactly one node must be defined in spec.nodes when spec.storage.type is 'sqlite'")
+		}
+	default:
+		return errors.Newf(errors.ValidationError, "spec.storage.type must be 'postgresql', 'mysql' or 'sqlite', got '%s'", cfg.Spec.Storage.Type)
 	}
-	if cfg.Spec.Network.VIP == "" {
-		return errors.New(errors.ValidationError, "spec.network.vip must be set")
+	if err := validateNodes(cfg); err != nil {
+		return err
+	}
+	if err := validateVIPs(cfg); err != nil {
+		return err
+	}
+	if err := validateHeartbeat(cfg); err != nil {
+		return err
+	}
+	switch mode := cfg.Spec.Storage.Replication.EffectiveMode(); mode {
+	case types.ReplicationModeAsync:
+	case types.ReplicationModeSync:
+		if cfg.Spec.Storage.BackendType() != types.StorageTypePostgreSQL {
+			return errors.New(errors.ValidationError, "spec.storage.replication.mode 'sync' is only supported with the 'postgresql' storage type")
+		}
+	default:
+		return errors.Newf(errors.ValidationError, "spec.storage.replication.mode must be 'async' or 'sync', got '%s'", mode)
+	}
+	if nl := cfg.Spec.NodeLabels; nl != nil {
+		for _, taint := range append(append([]types.Taint{}, nl.LeaderTaints...), nl.FollowerTaints...) {
+			if taint.Key == "" {
+				return errors.New(errors.ValidationError, "spec.nodeLabels taints must have a key")
+			}
+			switch taint.Effect {
+			case "NoSchedule", "PreferNoSchedule", "NoExecute":
+			default:
+				return errors.Newf(errors.ValidationError, "spec.nodeLabels taint '%s' must have effect 'NoSchedule', 'PreferNoSchedule' or 'NoExecute', got '%s'", taint.Key, taint.Effect)
+			}
+		}
 	}
 	// Add more validation rules here...
 	return nil
 }
 
+// validateNodes checks the addresses of spec.nodes and the cluster and
+// service CIDRs: a node has at most one address per IP family, and in a
+// dual-stack cluster every node and CIDR list covers both families.
+func validateNodes(cfg *types.ClusterConfig) error {
+	families := cfg.Spec.IPFamilies()
+	seen := map[string]bool{}
+	for _, n := range cfg.Spec.Nodes {
+		nodeFamilies := map[types.IPFamily]bool{}
+		for _, address := range n.AllAddresses() {
+			if net.ParseIP(address) == nil {
+				return errors.Newf(errors.ValidationError, "spec.nodes: address '%s' of node '%s' must be an IP address", address, n.IP)
+			}
+			family := types.FamilyOf(address)
+			if seen[address] {
+				return errors.Newf(errors.ValidationError, "spec.nodes: address '%s' is used more than once", address)
+			}
+			if nodeFamilies[family] {
+				return errors.Newf(errors.ValidationError, "spec.nodes: node '%s' has more than one %s address", n.IP, family)
+			}
+			seen[address], nodeFamilies[family] = true, true
+		}
+		if len(nodeFamilies) != len(families) {
+			return errors.Newf(errors.ValidationError, "spec.nodes: node '%s' must have an address of each IP family of the cluster (%s)", n.IP, joinFamilies(families))
+		}
+	}
+	for _, list := range []struct {
+		field string
+		cidrs []string
+	}{{"podCIDRs", cfg.Spec.Network.PodCIDRs}, {"serviceCIDRs", cfg.Spec.Network.ServiceCIDRs}} {
+		field := list.field
+		cidrFamilies := map[types.IPFamily]bool{}
+		for _, cidr := range list.cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return errors.Newf(errors.ValidationError, "spec.network.%s: '%s' is not a CIDR", field, cidr)
+			}
+			family := types.FamilyOf(cidr)
+			if cidrFamilies[family] {
+				return errors.Newf(errors.ValidationError, "spec.network.%s: more than one %s CIDR", field, family)
+			}
+			if !hasFamily(families, family) {
+				return errors.Newf(errors.ValidationError, "spec.network.%s: the nodes have no %s address for '%s'", field, family, cidr)
+			}
+			cidrFamilies[family] = true
+		}
+	}
+	if p := cfg.Spec.Network.PrefixLengthV6; p < 0 || p > 128 {
+		return errors.Newf(errors.ValidationError, "spec.network.prefixLengthV6 must be between 1 and 128 when set, got %d", p)
+	}
+	return nil
+}
+
+// hasFamily reports whether family is one of families.
+func hasFamily(families []types.IPFamily, family types.IPFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// joinFamilies lists families for messages, e.g. "IPv4 and IPv6".
+func joinFamilies(families []types.IPFamily) string {
+	var names []string
+	for _, f := range families {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, " and ")
+}
+
+// validateVIPs checks spec.network.vip and spec.network.vips: there must be a
+// VIP for the API server, and every VIP needs a unique name and address.
+func validateVIPs(cfg *types.ClusterConfig) error {
+	network := cfg.Spec.Network
+	if network.APIVIP() == "" {
+		return errors.New(errors.ValidationError, "spec.network.vip or a VIP with purpose 'api' in spec.network.vips must be set")
+	}
+	if p := network.PrefixLength; p < 0 || p > 32 {
+		// prefixLength only applies to IPv4 VIPs; an IPv6 prefix belongs in prefixLengthV6.
+		return errors.Newf(errors.ValidationError, "spec.network.prefixLength is the prefix length of IPv4 VIPs and must be between 1 and 32 when set, got %d; set the prefix length of IPv6 VIPs with spec.network.prefixLengthV6", p)
+	}
+	switch network.EffectiveVIPMode() {
+	case types.VIPModeNetlink:
+	case types.VIPModeVRRP:
+		vrrp := network.VRRP.WithDefaults()
+		if last := vrrp.VirtualRouterID + len(network.AllVIPs()) - 1; vrrp.VirtualRouterID < 1 || last > 255 {
+			return errors.Newf(errors.ValidationError, "spec.network.vrrp.virtualRouterID must leave one ID between 1 and 255 per VIP, got %d for %d VIPs", vrrp.VirtualRouterID, len(network.AllVIPs()))
+		}
+		if vrrp.AdvertInterval < 1 || vrrp.AdvertInterval > 255 {
+			return errors.Newf(errors.ValidationError, "spec.network.vrrp.advertInterval must be between 1 and 255 seconds, got %d", vrrp.AdvertInterval)
+		}
+	case types.VIPModeBGP:
+		if err := validateBGP(network.BGP); err != nil {
+			return err
+		}
+	default:
+		return errors.Newf(errors.ValidationError, "spec.network.vipMode must be 'netlink', 'vrrp' or 'bgp', got '%s'", network.VIPMode)
+	}
+	names, addresses := map[string]bool{}, map[string]bool{}
+	for _, v := range network.AllVIPs() {
+		if v.Name == "" {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' must have a name", v.Address)
+		}
+		if names[v.Name] {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: duplicate VIP name '%s'", v.Name)
+		}
+		names[v.Name] = true
+		ip := net.ParseIP(v.Address)
+		if ip == nil {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' must have an IP address, got '%s'", v.Name, v.Address)
+		}
+		if addresses[ip.String()] {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' reuses address %s", v.Name, v.Address)
+		}
+		addresses[ip.String()] = true
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		if v.PrefixLength < 0 || v.PrefixLength > bits {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' prefix length must be between 1 and %d when set, got %d", v.Name, bits, v.PrefixLength)
+		}
+		// Local VIPs are added next to an address of their family; in bgp mode
+		// they are routed instead.
+		if family := types.FamilyOf(v.Address); v.Provider == types.VIPProviderLocal && network.EffectiveVIPMode() != types.VIPModeBGP && !hasFamily(cfg.Spec.IPFamilies(), family) {
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' is %s, but the nodes have no %s address", v.Name, family, family)
+		}
+		switch v.Purpose {
+		case types.VIPPurposeAPI, types.VIPPurposeIngress, types.VIPPurposeCustom:
+		default:
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' purpose must be 'api', 'ingress' or 'custom', got '%s'", v.Name, v.Purpose)
+		}
+		switch v.Ownership {
+		case types.VIPFollowsLeader:
+			if v.Node != "" {
+				return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' follows the leader and cannot set node", v.Name)
+			}
+		case types.VIPPinned:
+			if !hasNode(cfg, v.Node) {
+				return errors.Newf(errors.ValidationError, "spec.network.vips: pinned VIP '%s' must name a node of spec.nodes, got '%s'", v.Name, v.Node)
+			}
+		default:
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' ownership must be 'leader' or 'pinned', got '%s'", v.Name, v.Ownership)
+		}
+		switch v.Provider {
+		case types.VIPProviderLocal:
+		case types.VIPProviderCloud:
+			if err := validateCloud(cfg); err != nil {
+				return err
+			}
+		case types.VIPProviderHAProxy:
+			if network.HAProxy == nil || network.HAProxy.Host == "" {
+				return errors.Newf(errors.ValidationError, "spec.network.haproxy.host must be set for VIP '%s' with provider 'haproxy'", v.Name)
+			}
+			if len(v.Ports) == 0 {
+				return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' with provider 'haproxy' must have ports", v.Name)
+			}
+		default:
+			return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' provider must be 'local', 'cloud' or 'haproxy', got '%s'", v.Name, v.Provider)
+		}
+		for _, port := range v.Ports {
+			if port < 1 || port > 65535 {
+				return errors.Newf(errors.ValidationError, "spec.network.vips: VIP '%s' has invalid port %d", v.Name, port)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCloud checks spec.network.cloud for VIPs with provider "cloud".
+func validateCloud(cfg *types.ClusterConfig) error {
+	cloud := cfg.Spec.Network.Cloud
+	if cloud == nil {
+		return errors.New(errors.ValidationError, "spec.network.cloud must be set when a VIP has provider 'cloud'")
+	}
+	if u, err := url.Parse(cloud.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Newf(errors.ValidationError, "spec.network.cloud.endpoint must be an http or https URL, got '%s'", cloud.Endpoint)
+	}
+	for _, n := range cfg.Spec.Nodes {
+		if cloud.Instances[n.IP] == "" {
+			return errors.Newf(errors.ValidationError, "spec.network.cloud.instances must have an instance for node %s", n.IP)
+		}
+	}
+	return nil
+}
+
+// validateBGP checks spec.network.bgp for the bgp VIP mode.
+func validateBGP(bgp *types.BGPConfig) error {
+	if bgp == nil {
+		return errors.New(errors.ValidationError, "spec.network.bgp must be set when spec.network.vipMode is 'bgp'")
+	}
+	if bgp.LocalASN == 0 {
+		return errors.New(errors.ValidationError, "spec.network.bgp.localASN must be set")
+	}
+	if bgp.RouterID != "" {
+		if ip := net.ParseIP(bgp.RouterID); ip == nil || ip.To4() == nil {
+			return errors.Newf(errors.ValidationError, "spec.network.bgp.routerID must be an IPv4 address, got '%s'", bgp.RouterID)
+		}
+	}
+	if bgp.HoldTime != 0 && (bgp.HoldTime < 3 || bgp.HoldTime > 65535) {
+		return errors.Newf(errors.ValidationError, "spec.network.bgp.holdTime must be between 3 and 65535 seconds, got %d", bgp.HoldTime)
+	}
+	if len(bgp.Peers) == 0 {
+		return errors.New(errors.ValidationError, "spec.network.bgp.peers must have at least one peer")
+	}
+	for _, peer := range bgp.Peers {
+		if net.ParseIP(peer.Address) == nil {
+			return errors.Newf(errors.ValidationError, "spec.network.bgp.peers: invalid address '%s'", peer.Address)
+		}
+		if peer.ASN == 0 {
+			return errors.Newf(errors.ValidationError, "spec.network.bgp.peers: peer %s must have an asn", peer.Address)
+		}
+		if peer.Port < 0 || peer.Port > 65535 {
+			return errors.Newf(errors.ValidationError, "spec.network.bgp.peers: invalid port %d for peer %s", peer.Port, peer.Address)
+		}
+	}
+	for _, c := range bgp.Communities {
+		if _, err := types.ParseCommunity(c); err != nil {
+			return errors.Wrap(err, errors.ValidationError, "spec.network.bgp.communities")
+		}
+	}
+	return nil
+}
+
+// validateHeartbeat checks spec.heartbeat and the heartbeat addresses of
+// spec.nodes: every address must be an IP that is used only once.
+func validateHeartbeat(cfg *types.ClusterConfig) error {
+	seen := map[string]bool{}
+	for _, n := range cfg.Spec.Nodes {
+		for _, address := range n.AllAddresses() {
+			seen[address] = true
+		}
+	}
+	for _, n := range cfg.Spec.Nodes {
+		for _, address := range n.HeartbeatAddresses {
+			if net.ParseIP(address) == nil {
+				return errors.Newf(errors.ValidationError, "heartbeat address '%s' of node '%s' must be an IP address", address, n.IP)
+			}
+			if seen[address] {
+				return errors.Newf(errors.ValidationError, "heartbeat address '%s' of node '%s' is used more than once", address, n.IP)
+			}
+			seen[address] = true
+		}
+	}
+	hb := cfg.Spec.Heartbeat
+	if hb == nil {
+		return nil
+	}
+	if hb.Port < 0 || hb.Port > 65535 {
+		return errors.Newf(errors.ValidationError, "spec.heartbeat.port must be between 1 and 65535, got %d", hb.Port)
+	}
+	if hb.IntervalOrDefault() <= 0 || hb.IntervalOrDefault() >= hb.TimeoutOrDefault() {
+		return errors.Newf(errors.ValidationError, "spec.heartbeat.interval must be positive and shorter than spec.heartbeat.timeout, got %s and %s", hb.IntervalOrDefault(), hb.TimeoutOrDefault())
+	}
+	return nil
+}
+
+// hasNode reports whether ip is the IP of one of spec.nodes.
+func hasNode(cfg *types.ClusterConfig, ip string) bool {
+	for _, n := range cfg.Spec.Nodes {
+		if ip != "" && n.IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
 // Render renders a configuration template.
 func (m *Manager) Render(templatePath string, data interface{}) (string, error) {
 	templateContent, err := utils.ReadFile(templatePath)
@@ -0,0 +1,374 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/node"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// Lease used to elect the operator instance that reconciles.
+const (
+	LeaseNamespace = "kube-system"
+	LeaseName      = "geminik8s-operator"
+)
+
+// Condition types recorded on a ClusterConfig.
+const (
+	// ConditionValid is False, and nothing is reconciled, while the spec fails validation.
+	ConditionValid        = "Valid"
+	ConditionUpgraded     = "Upgraded"
+	ConditionLeaderPlaced = "LeaderPlaced"
+	ConditionBackedUp     = "BackedUp"
+	// ConditionReady is True when all other conditions are True.
+	ConditionReady = "Ready"
+)
+
+// observedLeaderMaxAge is how old the GeminiCluster status may be for its leader
+// to be trusted. The leader's agent rewrites it at least every minute.
+const observedLeaderMaxAge = 3 * time.Minute
+
+// A failed upgrade, failover or backup is retried after retryInitialBackoff,
+// doubling with every further failure up to retryMaxBackoff.
+const (
+	retryInitialBackoff = time.Minute
+	retryMaxBackoff     = 30 * time.Minute
+)
+
+// Operator reconciles ClusterConfig custom resources through the orchestrator.
+// One instance runs on each node; the one holding the lease reconciles.
+type Operator struct {
+	log          logger.Logger
+	orchestrator api.Orchestrator
+	client       api.K8sClient
+	configs      api.ConfigManager
+	identity     string
+	resync       time.Duration
+	now          func() time.Time
+}
+
+// New creates an operator that competes for the lease as identity and
+// reconciles every ClusterConfig that passes configs' validation at least
+// every resync.
+func New(log logger.Logger, orchestrator api.Orchestrator, client api.K8sClient, configs api.ConfigManager, identity string, resync time.Duration) *Operator {
+	return &Operator{
+		log:          log,
+		orchestrator: orchestrator,
+		client:       client,
+		configs:      configs,
+		identity:     identity,
+		resync:       resync,
+		now:          time.Now,
+	}
+}
+
+// Run blocks until ctx is cancelled, reconciling while this instance is leader.
+func (o *Operator) Run(ctx context.Context) error {
+	return o.client.RunWithLeaderElection(ctx, LeaseNamespace, LeaseName, o.identity, func(ctx context.Context) {
+		o.log.Infof("Operator '%s' is leading; reconciling ClusterConfigs.", o.identity)
+		err := o.client.WatchClusterConfigs(ctx, o.resync, func(res types.ClusterConfigResource, err error) {
+			if err != nil {
+				err = o.reject(ctx, res, err)
+			} else {
+				err = o.Reconcile(ctx, res)
+			}
+			if err != nil {
+				o.log.WithField("clusterConfig", res.Config.Metadata.Name).Errorf("Reconcile failed: %v", err)
+			}
+		})
+		if err != nil {
+			o.log.Errorf("Watching ClusterConfigs failed: %v", err)
+		}
+		o.log.Infof("Operator '%s' stopped leading.", o.identity)
+	})
+}
+
+// reasonNotSupported marks a condition whose step the orchestrator cannot
+// perform. The step is not attempted again until the spec changes.
+const reasonNotSupported = "NotSupported"
+
+// Reconcile brings the cluster described by res to its desired version, leader
+// and backup schedule, and records the outcome as conditions. A failed step is
+// recorded and retried with a backoff, or at once if the spec changes; it does
+// not stop the others. A step the orchestrator does not implement is recorded
+// as NotSupported and not retried for this spec. A spec that fails validation
+// is not reconciled at all.
+func (o *Operator) Reconcile(ctx context.Context, res types.ClusterConfigResource) error {
+	cfg := res.Config
+	if err := o.configs.Validate(&cfg); err != nil {
+		return o.reject(ctx, res, err)
+	}
+	status := res.Status
+	status.Conditions = append([]types.Condition(nil), res.Status.Conditions...)
+	now := o.now()
+
+	set := func(conditionType string, err error, okReason, failReason, message string) {
+		c := types.Condition{Type: conditionType, Status: types.ConditionTrue, Reason: okReason, Message: message, ObservedGeneration: res.Generation, LastTransitionTime: now}
+		if errors.HasCode(err, errors.NotImplemented) {
+			c.Status, c.Reason, c.Message = types.ConditionFalse, reasonNotSupported, err.Error()
+		} else if err != nil {
+			c.Status, c.Reason, c.Message = types.ConditionFalse, failReason, err.Error()
+			// Count the failures in a row of this spec to back off the next attempt.
+			c.Retries = 1
+			if prev := res.Status.Condition(conditionType); prev != nil && prev.Status == types.ConditionFalse && prev.ObservedGeneration == res.Generation {
+				c.Retries = prev.Retries + 1
+			}
+			retryAfter := now.Add(retryBackoff(c.Retries))
+			c.RetryAfter = &retryAfter
+		}
+		status.SetCondition(c)
+	}
+	// backingOff reports whether a step that failed for this spec is not due for
+	// another attempt, or is not supported at all.
+	backingOff := func(conditionType string) bool {
+		c := res.Status.Condition(conditionType)
+		return c != nil && c.Status == types.ConditionFalse && c.ObservedGeneration == res.Generation &&
+			(c.Reason == reasonNotSupported || c.RetryAfter != nil && now.Before(*c.RetryAfter))
+	}
+
+	set(ConditionValid, nil, "SpecValid", "", "spec passed validation")
+
+	// Upgrade. The first time, the version the cluster already runs is taken
+	// from its nodes, so that adopting a running cluster does not upgrade it.
+	version := cfg.Spec.Version
+	var versionErr error
+	if version != "" && status.Version == "" {
+		status.Version, versionErr = o.observedVersion(ctx)
+	}
+	switch {
+	case version == "":
+		set(ConditionUpgraded, nil, "NoVersion", "", "spec.version is not set")
+	case version == status.Version:
+		set(ConditionUpgraded, nil, "UpToDate", "", "cluster runs "+version)
+	case versionErr != nil:
+		status.SetCondition(types.Condition{Type: ConditionUpgraded, Status: types.ConditionUnknown, Reason: "VersionNotObserved",
+			Message: versionErr.Error(), ObservedGeneration: res.Generation, LastTransitionTime: now})
+	case backingOff(ConditionUpgraded):
+	default:
+		o.log.Infof("Upgrading cluster '%s' to %s", cfg.Metadata.Name, version)
+		err := o.orchestrator.Upgrade(ctx, &cfg, version)
+		if err == nil {
+			status.Version = version
+		}
+		set(ConditionUpgraded, err, "UpToDate", "UpgradeFailed", "cluster runs "+version)
+	}
+
+	// Leader placement, against the leader the agents report rather than the one
+	// spec.nodes started with.
+	observed, current, err := o.observedLeader(ctx, cfg.Metadata.Name)
+	failover := false
+	switch preferred := preferredLeader(&cfg); {
+	case preferred == "":
+		status.Leader = current
+		set(ConditionLeaderPlaced, nil, "NoPreference", "", "spec.failover.preferredLeader is not set")
+	case !hasNode(&cfg, preferred):
+		// Nothing is attempted, so there is nothing to back off.
+		status.SetCondition(types.Condition{Type: ConditionLeaderPlaced, Status: types.ConditionFalse, Reason: "UnknownNode",
+			Message: fmt.Sprintf("preferred leader %s is not in spec.nodes", preferred), ObservedGeneration: res.Generation, LastTransitionTime: now})
+	case err != nil:
+		// Without knowing the leader, a failover could demote the preferred one.
+		status.Leader = ""
+		status.SetCondition(types.Condition{Type: ConditionLeaderPlaced, Status: types.ConditionUnknown, Reason: "LeaderNotObserved",
+			Message: err.Error(), ObservedGeneration: res.Generation, LastTransitionTime: now})
+	case preferred == current:
+		status.Leader = current
+		set(ConditionLeaderPlaced, nil, "PreferredLeader", "", preferred+" is leader")
+	case backingOff(ConditionLeaderPlaced):
+		status.Leader = current
+	default:
+		o.log.Infof("Failing cluster '%s' over to preferred leader %s", cfg.Metadata.Name, preferred)
+		failover = true
+		err := o.orchestrator.Failover(ctx, &cfg, preferred)
+		if err == nil {
+			status.Leader = preferred
+		}
+		set(ConditionLeaderPlaced, err, "PreferredLeader", "FailoverFailed", preferred+" is leader")
+	}
+
+	// Node labels, by the roles the agents report. Skipped while a failover
+	// is changing them.
+	if observed != nil && !failover && cfg.Spec.NodeLabels.Enabled() {
+		o.labelNodes(ctx, cfg.Metadata.Name, observed, cfg.Spec.NodeLabels)
+	}
+
+	// Backup schedule
+	if interval := backupInterval(&cfg); interval <= 0 {
+		set(ConditionBackedUp, nil, "NotScheduled", "", "spec.backup.interval is not set")
+	} else if (status.LastBackupTime == nil || now.Sub(*status.LastBackupTime) >= interval) && !backingOff(ConditionBackedUp) {
+		destination := path.Join(cfg.Spec.Backup.Destination, fmt.Sprintf("%s-%s.backup", cfg.Metadata.Name, now.UTC().Format("20060102-150405")))
+		o.log.Infof("Backing up cluster '%s' to %s", cfg.Metadata.Name, destination)
+		err := o.orchestrator.Backup(ctx, &cfg, destination)
+		if err == nil {
+			backupTime := now
+			status.LastBackupTime = &backupTime
+			status.SetCondition(types.Condition{Type: ConditionBackedUp, Status: types.ConditionTrue, Reason: "BackupSucceeded",
+				Message: "last backup written to " + destination, ObservedGeneration: res.Generation, LastTransitionTime: now})
+			o.publishBackup(ctx, cfg.Metadata.Name, backupTime)
+		} else {
+			set(ConditionBackedUp, err, "", "BackupFailed", "")
+		}
+	} else if status.Condition(ConditionBackedUp) == nil && status.LastBackupTime != nil {
+		set(ConditionBackedUp, nil, "BackupSucceeded", "", "last backup at "+status.LastBackupTime.Format(time.RFC3339))
+	}
+
+	ready := true
+	for _, c := range status.Conditions {
+		if c.Type != ConditionReady && c.Status != types.ConditionTrue {
+			ready = false
+		}
+	}
+	if ready {
+		set(ConditionReady, nil, "Reconciled", "", "cluster matches the ClusterConfig")
+	} else {
+		status.SetCondition(types.Condition{Type: ConditionReady, Status: types.ConditionFalse, Reason: "ReconcileFailed",
+			Message: "see the other conditions", ObservedGeneration: res.Generation, LastTransitionTime: now})
+	}
+	status.ObservedGeneration = res.Generation
+
+	if reflect.DeepEqual(status, res.Status) {
+		return nil
+	}
+	return o.client.UpdateClusterConfigStatus(ctx, cfg.Metadata.Name, func(s *types.ClusterConfigStatus) {
+		*s = status
+	})
+}
+
+// publishBackup records the time of a backup in the GeminiCluster status, next
+// to the HA state the leader's agent publishes there. A failure is only logged:
+// the backup itself succeeded and is recorded on the ClusterConfig.
+func (o *Operator) publishBackup(ctx context.Context, name string, backupTime time.Time) {
+	err := o.client.UpdateClusterStatus(ctx, name, func(s *types.GeminiClusterStatus) {
+		s.LastBackupTime = &backupTime
+	})
+	if err != nil {
+		o.log.WithField("clusterConfig", name).Warnf("Failed to record the backup in the GeminiCluster status: %v", err)
+	}
+}
+
+// reject records on the ClusterConfig that its spec is invalid, and returns why.
+func (o *Operator) reject(ctx context.Context, res types.ClusterConfigResource, reason error) error {
+	status := res.Status
+	status.Conditions = append([]types.Condition(nil), res.Status.Conditions...)
+	now := o.now()
+	status.SetCondition(types.Condition{Type: ConditionValid, Status: types.ConditionFalse, Reason: "InvalidSpec",
+		Message: reason.Error(), ObservedGeneration: res.Generation, LastTransitionTime: now})
+	status.SetCondition(types.Condition{Type: ConditionReady, Status: types.ConditionFalse, Reason: "InvalidSpec",
+		Message: "see the Valid condition", ObservedGeneration: res.Generation, LastTransitionTime: now})
+	status.ObservedGeneration = res.Generation
+
+	if !reflect.DeepEqual(status, res.Status) {
+		err := o.client.UpdateClusterConfigStatus(ctx, res.Config.Metadata.Name, func(s *types.ClusterConfigStatus) {
+			*s = status
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return reason
+}
+
+// observedLeader returns the GeminiCluster status and the IP of the leader as
+// its agent last published it. An old status is not trusted, since the agent
+// that wrote it may be gone.
+func (o *Operator) observedLeader(ctx context.Context, name string) (*types.GeminiClusterStatus, string, error) {
+	s, err := o.client.GetClusterStatus(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.LastUpdateTime.IsZero() {
+		return nil, "", fmt.Errorf("no leader has published the GeminiCluster status yet")
+	}
+	if age := o.now().Sub(s.LastUpdateTime); age > observedLeaderMaxAge {
+		return nil, "", fmt.Errorf("the GeminiCluster status was last updated %s ago", age.Round(time.Second))
+	}
+	for _, n := range s.Nodes {
+		if n.Name == s.Leader && n.Role == types.RoleLeader {
+			return s, n.IP, nil
+		}
+	}
+	return nil, "", fmt.Errorf("the GeminiCluster status names no leader")
+}
+
+// observedVersion returns the kubelet version all nodes report. It returns ""
+// while the nodes run different versions, as during an interrupted upgrade.
+func (o *Operator) observedVersion(ctx context.Context) (string, error) {
+	nodes, err := o.client.GetNodes(ctx)
+	if err != nil {
+		return "", err
+	}
+	version := ""
+	for _, n := range nodes {
+		switch v := n.Status.KubeletVersion; {
+		case v == "":
+			return "", fmt.Errorf("node %s reports no kubelet version", n.Config.Name)
+		case version == "":
+			version = v
+		case v != version:
+			return "", nil
+		}
+	}
+	if version == "" {
+		return "", fmt.Errorf("the cluster has no nodes")
+	}
+	return version, nil
+}
+
+// labelNodes sets the role labels of the nodes in the observed status, like
+// the leader's agent does, followers first. A failure is only logged: the
+// agents keep the labels as well, and the next reconcile retries.
+func (o *Operator) labelNodes(ctx context.Context, name string, observed *types.GeminiClusterStatus, cfg *types.NodeLabelsConfig) {
+	nodes := make([]types.NodeIdentity, 0, len(observed.Nodes))
+	for _, n := range observed.Nodes {
+		nodes = append(nodes, types.NodeIdentity{Name: n.Name, IP: n.IP, Role: n.Role})
+	}
+	if err := node.ApplyRoleLabels(ctx, o.client, nodes, observed.Epoch, cfg); err != nil {
+		o.log.WithField("clusterConfig", name).Warnf("Failed to label the nodes by role: %v", err)
+	}
+}
+
+// retryBackoff returns how long to wait after the given number of failures in a row.
+func retryBackoff(failures int) time.Duration {
+	backoff := retryInitialBackoff
+	for i := 1; i < failures && backoff < retryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > retryMaxBackoff {
+		backoff = retryMaxBackoff
+	}
+	return backoff
+}
+
+// preferredLeader returns spec.failover.preferredLeader, or "" if it is not set.
+func preferredLeader(cfg *types.ClusterConfig) string {
+	if cfg.Spec.Failover == nil {
+		return ""
+	}
+	return cfg.Spec.Failover.PreferredLeader
+}
+
+// hasNode reports whether ip is one of spec.nodes.
+func hasNode(cfg *types.ClusterConfig, ip string) bool {
+	for _, n := range cfg.Spec.Nodes {
+		if n.IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// backupInterval returns spec.backup.interval, or 0 if no backups are scheduled.
+func backupInterval(cfg *types.ClusterConfig) time.Duration {
+	if cfg.Spec.Backup == nil {
+		return 0
+	}
+	return cfg.Spec.Backup.Interval.OrDefault(0)
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,405 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeOrchestrator struct {
+	api.Orchestrator
+	upgrades, failovers, backups []string
+	upgradeErr                   error
+}
+
+func (f *fakeOrchestrator) Upgrade(ctx context.Context, cfg *types.ClusterConfig, version string) error {
+	f.upgrades = append(f.upgrades, version)
+	return f.upgradeErr
+}
+
+func (f *fakeOrchestrator) Failover(ctx context.Context, cfg *types.ClusterConfig, promoteNode string) error {
+	f.failovers = append(f.failovers, promoteNode)
+	return nil
+}
+
+func (f *fakeOrchestrator) Backup(ctx context.Context, cfg *types.ClusterConfig, destination string) error {
+	f.backups = append(f.backups, destination)
+	return nil
+}
+
+// fakeStatusClient stores the last written ClusterConfig and GeminiCluster status.
+type fakeStatusClient struct {
+	api.K8sClient
+	writes   int
+	status   types.ClusterConfigStatus
+	cluster  types.GeminiClusterStatus
+	labels   []string // Node role labels set, as name=role@epoch
+	versions []string // Kubelet versions of the nodes; two nodes on an older version if nil
+}
+
+func (c *fakeStatusClient) GetNodes(ctx context.Context) ([]types.Node, error) {
+	versions := c.versions
+	if versions == nil {
+		versions = []string{"v1.28.9+k3s1", "v1.28.9+k3s1"}
+	}
+	var nodes []types.Node
+	for i, v := range versions {
+		nodes = append(nodes, types.Node{Config: types.NodeConfig{Name: fmt.Sprintf("node%d", i+1)}, Status: types.NodeStatus{KubeletVersion: v}})
+	}
+	return nodes, nil
+}
+
+func (c *fakeStatusClient) SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error {
+	c.labels = append(c.labels, fmt.Sprintf("%s=%s@%d", nodeName, role, epoch))
+	return nil
+}
+
+func (c *fakeStatusClient) GetClusterStatus(ctx context.Context, name string) (*types.GeminiClusterStatus, error) {
+	status := c.cluster
+	return &status, nil
+}
+
+// publishLeader makes the GeminiCluster status name ip as leader at now.
+func (c *fakeStatusClient) publishLeader(ip string, now time.Time) {
+	c.cluster.Leader, c.cluster.LastUpdateTime = "node-"+ip, now
+	c.cluster.Nodes = []types.GeminiClusterNodeStatus{{Name: "node-" + ip, IP: ip, Role: types.RoleLeader}}
+}
+
+// fakeConfigs fails validation with err.
+type fakeConfigs struct {
+	api.ConfigManager
+	err error
+}
+
+func (f *fakeConfigs) Validate(cfg *types.ClusterConfig) error {
+	return f.err
+}
+
+func (c *fakeStatusClient) UpdateClusterStatus(ctx context.Context, name string, update func(*types.GeminiClusterStatus)) error {
+	update(&c.cluster)
+	return nil
+}
+
+func (c *fakeStatusClient) UpdateClusterConfigStatus(ctx context.Context, name string, update func(*types.ClusterConfigStatus)) error {
+	c.writes++
+	update(&c.status)
+	return nil
+}
+
+func testResource() types.ClusterConfigResource {
+	return types.ClusterConfigResource{
+		Generation: 2,
+		Config: types.ClusterConfig{
+			Metadata: types.Metadata{Name: "demo"},
+			Spec: types.ClusterSpec{
+				Version: "v1.29.4+k3s1",
+				Nodes: []types.NodeInfo{
+					{IP: "10.0.0.1", Role: types.RoleLeader},
+					{IP: "10.0.0.2", Role: types.RoleFollower},
+				},
+				Backup:   &types.BackupConfig{Interval: &types.Duration{Duration: time.Hour}, Destination: "/backups"},
+				Failover: &types.FailoverConfig{PreferredLeader: "10.0.0.2"},
+			},
+		},
+	}
+}
+
+func newTestOperator(orch api.Orchestrator, client api.K8sClient, now *time.Time) *Operator {
+	op := New(logger.NewLogger("error", io.Discard, "text"), orch, client, &fakeConfigs{}, "node1", time.Minute)
+	op.now = func() time.Time { return *now }
+	return op
+}
+
+func TestReconcile(t *testing.T) {
+	orch := &fakeOrchestrator{}
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	ctx := context.Background()
+	client.publishLeader("10.0.0.1", now)
+
+	res := testResource()
+	if err := op.Reconcile(ctx, res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 1 || len(orch.failovers) != 1 || orch.failovers[0] != "10.0.0.2" {
+		t.Errorf("expected one upgrade and a failover to 10.0.0.2, got %v and %v", orch.upgrades, orch.failovers)
+	}
+	if len(client.labels) != 0 {
+		t.Errorf("expected no node labels during a failover, got %v", client.labels)
+	}
+	if len(orch.backups) != 1 || orch.backups[0] != "/backups/demo-20240501-120000.backup" {
+		t.Errorf("unexpected backups: %v", orch.backups)
+	}
+	s := client.status
+	if s.Version != "v1.29.4+k3s1" || s.Leader != "10.0.0.2" || s.LastBackupTime == nil || s.ObservedGeneration != 2 {
+		t.Errorf("unexpected status: %+v", s)
+	}
+	if b := client.cluster.LastBackupTime; b == nil || !b.Equal(now) {
+		t.Errorf("expected the backup time in the GeminiCluster status, got %v", b)
+	}
+	if c := s.Condition(ConditionReady); c == nil || c.Status != types.ConditionTrue {
+		t.Errorf("expected the Ready condition to be True, got %+v", c)
+	}
+
+	// Reconciling the written status again does nothing until the next backup is due.
+	res.Status = s
+	now = now.Add(30 * time.Minute)
+	client.publishLeader("10.0.0.2", now)
+	if err := op.Reconcile(ctx, res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 1 || len(orch.failovers) != 1 || len(orch.backups) != 1 || client.writes != 1 {
+		t.Errorf("expected no further actions or writes, got %d writes", client.writes)
+	}
+
+	now = now.Add(time.Hour)
+	client.publishLeader("10.0.0.2", now)
+	if err := op.Reconcile(ctx, res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.backups) != 2 || !client.status.LastBackupTime.Equal(now) {
+		t.Errorf("expected a second backup once the interval passed, got %v", orch.backups)
+	}
+}
+
+func TestReconcileRecordsFailures(t *testing.T) {
+	orch := &fakeOrchestrator{upgradeErr: errors.New("node 10.0.0.2 did not come back")}
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	client.publishLeader("10.0.0.1", now)
+
+	res := testResource()
+	res.Config.Spec.Failover.PreferredLeader = "10.0.0.9"
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	s := client.status
+	if s.Version != "v1.28.9+k3s1" {
+		t.Errorf("expected the running version to be kept after a failed upgrade, got %q", s.Version)
+	}
+	for conditionType, reason := range map[string]string{
+		ConditionUpgraded:     "UpgradeFailed",
+		ConditionLeaderPlaced: "UnknownNode",
+		ConditionReady:        "ReconcileFailed",
+	} {
+		c := s.Condition(conditionType)
+		if c == nil || c.Status != types.ConditionFalse || c.Reason != reason {
+			t.Errorf("expected %s to be False with reason %s, got %+v", conditionType, reason, c)
+		}
+	}
+	if c := s.Condition(ConditionBackedUp); c == nil || c.Status != types.ConditionTrue {
+		t.Errorf("expected the backup to go ahead despite the other failures, got %+v", c)
+	}
+	if len(orch.failovers) != 0 {
+		t.Errorf("expected no failover to a node outside spec.nodes")
+	}
+
+	// The failure is retried on the next reconcile, and its transition time is kept.
+	res.Status = s
+	now = now.Add(time.Minute)
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 2 {
+		t.Errorf("expected the upgrade to be retried")
+	}
+	if c := client.status.Condition(ConditionUpgraded); !c.LastTransitionTime.Equal(s.Condition(ConditionUpgraded).LastTransitionTime) {
+		t.Errorf("expected the transition time to be kept while the condition does not change")
+	}
+
+	// After the second failure in a row the next attempt waits two minutes.
+	if c := client.status.Condition(ConditionUpgraded); c.Retries != 2 || !c.RetryAfter.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("expected the second retry to back off two minutes, got %+v", c)
+	}
+	res.Status = client.status
+	now = now.Add(time.Minute)
+	writes := client.writes
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 2 || client.writes != writes {
+		t.Errorf("expected no upgrade and no write while backing off, got %v and %d writes", orch.upgrades, client.writes-writes)
+	}
+
+	// A changed spec is tried at once.
+	res.Generation++
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if c := client.status.Condition(ConditionUpgraded); len(orch.upgrades) != 3 || c.Retries != 1 {
+		t.Errorf("expected the new generation to be upgraded at once and counted afresh, got %v and %+v", orch.upgrades, c)
+	}
+}
+
+func TestReconcileDoesNotRetryUnsupportedSteps(t *testing.T) {
+	orch := &fakeOrchestrator{upgradeErr: custom_errors.New(custom_errors.NotImplemented, "upgrade is not implemented")}
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	client.publishLeader("10.0.0.2", now)
+
+	res := testResource()
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	c := client.status.Condition(ConditionUpgraded)
+	if c == nil || c.Status != types.ConditionFalse || c.Reason != "NotSupported" || c.RetryAfter != nil {
+		t.Fatalf("expected Upgraded to be False with reason NotSupported and no retry, got %+v", c)
+	}
+
+	// Long after any backoff, the same spec is not upgraded again.
+	res.Status = client.status
+	now = now.Add(24 * time.Hour)
+	client.publishLeader("10.0.0.2", now)
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 1 {
+		t.Errorf("expected an unsupported upgrade to be attempted once, got %v", orch.upgrades)
+	}
+
+	// A changed spec is tried again.
+	res.Status = client.status
+	res.Generation++
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 2 {
+		t.Errorf("expected the new generation to be tried, got %v", orch.upgrades)
+	}
+}
+
+func TestReconcileAdoptsRunningVersion(t *testing.T) {
+	orch := &fakeOrchestrator{}
+	client := &fakeStatusClient{versions: []string{"v1.29.4+k3s1", "v1.29.4+k3s1"}}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	client.publishLeader("10.0.0.2", now)
+
+	if err := op.Reconcile(context.Background(), testResource()); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(orch.upgrades) != 0 {
+		t.Errorf("expected a cluster already on spec.version not to be upgraded, got %v", orch.upgrades)
+	}
+	if c := client.status.Condition(ConditionUpgraded); client.status.Version != "v1.29.4+k3s1" || c == nil || c.Reason != "UpToDate" {
+		t.Errorf("expected the running version to be recorded, got %q and %+v", client.status.Version, c)
+	}
+
+	t.Run("version not observed", func(t *testing.T) {
+		client := &fakeStatusClient{versions: []string{"v1.29.4+k3s1", ""}}
+		op := newTestOperator(orch, client, &now)
+		if err := op.Reconcile(context.Background(), testResource()); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		if c := client.status.Condition(ConditionUpgraded); len(orch.upgrades) != 0 || c == nil || c.Status != types.ConditionUnknown || c.Reason != "VersionNotObserved" {
+			t.Errorf("expected no upgrade while the version is unknown, got %v and %+v", orch.upgrades, c)
+		}
+	})
+
+	t.Run("nodes on different versions", func(t *testing.T) {
+		client := &fakeStatusClient{versions: []string{"v1.29.4+k3s1", "v1.28.9+k3s1"}}
+		op := newTestOperator(orch, client, &now)
+		if err := op.Reconcile(context.Background(), testResource()); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		if len(orch.upgrades) != 1 {
+			t.Errorf("expected an interrupted upgrade to be finished, got %v", orch.upgrades)
+		}
+	})
+}
+
+func TestReconcileWithoutObservedLeader(t *testing.T) {
+	orch := &fakeOrchestrator{}
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	// The leader's agent stopped publishing long ago.
+	client.publishLeader("10.0.0.2", now.Add(-time.Hour))
+
+	if err := op.Reconcile(context.Background(), testResource()); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if c := client.status.Condition(ConditionLeaderPlaced); c == nil || c.Status != types.ConditionUnknown || c.Reason != "LeaderNotObserved" {
+		t.Errorf("expected LeaderPlaced to be Unknown, got %+v", c)
+	}
+	if client.status.Leader != "" || len(orch.failovers) != 0 {
+		t.Errorf("expected no leader and no failover, got %q and %v", client.status.Leader, orch.failovers)
+	}
+	if c := client.status.Condition(ConditionReady); c == nil || c.Status != types.ConditionFalse {
+		t.Errorf("expected Ready to be False, got %+v", c)
+	}
+}
+
+func TestReconcileRejectsInvalidSpec(t *testing.T) {
+	orch := &fakeOrchestrator{}
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(orch, client, &now)
+	op.configs = &fakeConfigs{err: errors.New("exactly two nodes must be defined in spec.nodes")}
+
+	res := testResource()
+	if err := op.Reconcile(context.Background(), res); err == nil {
+		t.Fatalf("expected Reconcile to fail for an invalid spec")
+	}
+	if len(orch.upgrades)+len(orch.failovers)+len(orch.backups) != 0 {
+		t.Errorf("expected nothing to be reconciled, got %v %v %v", orch.upgrades, orch.failovers, orch.backups)
+	}
+	c := client.status.Condition(ConditionValid)
+	if c == nil || c.Status != types.ConditionFalse || c.Reason != "InvalidSpec" || c.Message != "exactly two nodes must be defined in spec.nodes" {
+		t.Errorf("expected Valid to be False with the reason, got %+v", c)
+	}
+	if c := client.status.Condition(ConditionReady); c == nil || c.Status != types.ConditionFalse || client.status.ObservedGeneration != 2 {
+		t.Errorf("expected Ready to be False for generation 2, got %+v", client.status)
+	}
+
+	// An unchanged rejection is not written again.
+	res.Status = client.status
+	op.Reconcile(context.Background(), res)
+	if client.writes != 1 {
+		t.Errorf("expected one status write, got %d", client.writes)
+	}
+}
+
+func TestReconcileLabelsNodes(t *testing.T) {
+	client := &fakeStatusClient{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	op := newTestOperator(&fakeOrchestrator{}, client, &now)
+	client.cluster = types.GeminiClusterStatus{
+		Leader:         "node2",
+		Epoch:          4,
+		LastUpdateTime: now,
+		Nodes: []types.GeminiClusterNodeStatus{
+			{Name: "node2", IP: "10.0.0.2", Role: types.RoleLeader},
+			{Name: "node1", IP: "10.0.0.1", Role: types.RoleFollower},
+		},
+	}
+
+	res := testResource()
+	res.Config.Spec.Failover = nil
+	if err := op.Reconcile(context.Background(), res); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(client.labels) != 2 || client.labels[0] != "node1=Follower@4" || client.labels[1] != "node2=Leader@4" {
+		t.Errorf("expected the follower to be labeled before the leader, got %v", client.labels)
+	}
+
+	client.labels = nil
+	res.Config.Spec.NodeLabels = &types.NodeLabelsConfig{Disabled: true}
+	if err := op.Reconcile(context.Background(), res); err != nil || len(client.labels) != 0 {
+		t.Errorf("expected no labels when they are disabled, got %v (%v)", client.labels, err)
+	}
+}
+
+//Personal.AI order the ending
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/turtacn/geminik8s/internal/domain/cluster"
+	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
 )
@@ -80,25 +81,64 @@ func (e *engine) GetStatus(ctx context.Context, cfg *types.ClusterConfig) (*type
 
 // The rest of the methods would follow a similar pattern,
 // typically finding the right plugin and executing it with the given config.
+// Until then they fail with the NotImplemented code, which callers such as the
+// operator take as final rather than as a reason to retry.
 
 func (e *engine) Failover(ctx context.Context, cfg *types.ClusterConfig, promoteNode string) error {
-	return errors.New("not implemented")
+	return custom_errors.New(custom_errors.NotImplemented, "failover is not implemented")
 }
 
 func (e *engine) Upgrade(ctx context.Context, cfg *types.ClusterConfig, version string) error {
-	return errors.New("not implemented")
+	return custom_errors.New(custom_errors.NotImplemented, "upgrade is not implemented")
 }
 
 func (e *engine) ReplaceNode(ctx context.Context, cfg *types.ClusterConfig, oldNode, newNode string) error {
-	return errors.New("not implemented")
+	return custom_errors.New(custom_errors.NotImplemented, "node replacement is not implemented")
 }
 
 func (e *engine) Backup(ctx context.Context, cfg *types.ClusterConfig, destination string) error {
-	return errors.New("not implemented")
+	return custom_errors.New(custom_errors.NotImplemented, "backup is not implemented")
 }
 
 func (e *engine) Restore(ctx context.Context, cfg *types.ClusterConfig, source string) error {
-	return errors.New("not implemented")
+	return custom_errors.New(custom_errors.NotImplemented, "restore is not implemented")
+}
+
+// RotateCredentials replaces the Kine and replication credentials on both nodes.
+func (e *engine) RotateCredentials(ctx context.Context, cfg *types.ClusterConfig, onlyIfDue bool) (*api.PluginResult, error) {
+	params := api.PluginParams{
+		"config":    cfg,
+		"onlyIfDue": onlyIfDue,
+	}
+	return e.pluginManager.Execute(ctx, "credentials", params)
+}
+
+// Kubeconfig fetches the admin kubeconfig from the leader and points it at the VIP.
+// The kubeconfig is returned in the result's Data["kubeconfig"].
+func (e *engine) Kubeconfig(ctx context.Context, cfg *types.ClusterConfig, opts types.KubeconfigOptions) (*api.PluginResult, error) {
+	params := api.PluginParams{
+		"config":  cfg,
+		"options": opts,
+	}
+	return e.pluginManager.Execute(ctx, "kubeconfig", params)
+}
+
+// Preflight checks the ports, round-trip time and MTU between the nodes and the
+// VIPs. A report with failed checks is not an error.
+func (e *engine) Preflight(ctx context.Context, cfg *types.ClusterConfig, opts types.PreflightOptions) (*types.PreflightReport, error) {
+	params := api.PluginParams{
+		"config":  cfg,
+		"options": opts,
+	}
+	result, err := e.pluginManager.Execute(ctx, "preflight", params)
+	if err != nil {
+		return nil, err
+	}
+	report, ok := result.Data["report"].(*types.PreflightReport)
+	if !ok {
+		return nil, errors.New("preflight plugin returned no report")
+	}
+	return report, nil
 }
 
 //Personal.AI order the ending
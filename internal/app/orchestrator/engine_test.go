@@ -2,8 +2,11 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
 )
@@ -189,6 +192,87 @@ func TestEngineInit(t *testing.T) {
 	}
 }
 
+func TestEngineRotateCredentials(t *testing.T) {
+	var gotParams api.PluginParams
+	mockPluginMgr := &mockPluginManager{
+		ExecuteFunc: func(ctx context.Context, name string, params api.PluginParams) (*api.PluginResult, error) {
+			if name != "credentials" {
+				t.Errorf("expected 'credentials' plugin to be called, got '%s'", name)
+			}
+			gotParams = params
+			return &api.PluginResult{Success: true}, nil
+		},
+	}
+
+	engine := NewEngine(mockPluginMgr, nil, nil)
+	cfg := &types.ClusterConfig{Metadata: types.Metadata{Name: "test"}}
+
+	result, err := engine.RotateCredentials(context.Background(), cfg, true)
+	if err != nil {
+		t.Fatalf("RotateCredentials failed: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("expected result to be successful")
+	}
+	if gotParams["config"] != cfg {
+		t.Errorf("expected 'config' in plugin params")
+	}
+	if onlyIfDue, _ := gotParams["onlyIfDue"].(bool); !onlyIfDue {
+		t.Errorf("expected 'onlyIfDue' to be passed to the plugin")
+	}
+}
+
+func TestEngineKubeconfig(t *testing.T) {
+	var gotParams api.PluginParams
+	mockPluginMgr := &mockPluginManager{
+		ExecuteFunc: func(ctx context.Context, name string, params api.PluginParams) (*api.PluginResult, error) {
+			if name != "kubeconfig" {
+				t.Errorf("expected 'kubeconfig' plugin to be called, got '%s'", name)
+			}
+			gotParams = params
+			return &api.PluginResult{Success: true}, nil
+		},
+	}
+
+	engine := NewEngine(mockPluginMgr, nil, nil)
+	cfg := &types.ClusterConfig{Metadata: types.Metadata{Name: "test"}}
+	opts := types.KubeconfigOptions{TTL: time.Hour, ClusterRole: "view"}
+
+	if _, err := engine.Kubeconfig(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("Kubeconfig failed: %v", err)
+	}
+	if gotParams["config"] != cfg {
+		t.Errorf("expected 'config' in plugin params")
+	}
+	if got, _ := gotParams["options"].(types.KubeconfigOptions); got != opts {
+		t.Errorf("expected 'options' to be passed to the plugin, got %+v", got)
+	}
+}
+
+func TestEnginePreflight(t *testing.T) {
+	want := &types.PreflightReport{Checks: []types.PreflightCheck{{Check: "tcp/6443", Status: types.PreflightFail}}}
+	mockPluginMgr := &mockPluginManager{
+		ExecuteFunc: func(ctx context.Context, name string, params api.PluginParams) (*api.PluginResult, error) {
+			if name != "preflight" {
+				t.Errorf("expected 'preflight' plugin to be called, got '%s'", name)
+			}
+			if _, ok := params["options"].(types.PreflightOptions); !ok {
+				t.Errorf("expected 'options' to be passed to the plugin")
+			}
+			return &api.PluginResult{Success: false, Data: map[string]interface{}{"report": want}}, nil
+		},
+	}
+
+	engine := NewEngine(mockPluginMgr, nil, nil)
+	report, err := engine.Preflight(context.Background(), &types.ClusterConfig{}, types.PreflightOptions{})
+	if err != nil {
+		t.Fatalf("a failed check should not be an error, got %v", err)
+	}
+	if report != want || report.Passed() {
+		t.Errorf("expected the plugin's failed report, got %+v", report)
+	}
+}
+
 func TestEngineUnimplementedMethods(t *testing.T) {
 	engine := NewEngine(nil, nil, nil)
 	cfg := &types.ClusterConfig{}
@@ -210,8 +294,8 @@ func TestEngineUnimplementedMethods(t *testing.T) {
 			if tc.err == nil {
 				t.Fatalf("expected error, got nil")
 			}
-			if tc.err.Error() != "not implemented" {
-				t.Fatalf("expected 'not implemented' error, got '%v'", tc.err)
+			if !custom_errors.HasCode(tc.err, custom_errors.NotImplemented) {
+				t.Fatalf("expected a NotImplemented error, got '%v'", tc.err)
 			}
 		})
 	}
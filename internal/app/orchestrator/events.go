@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// eventingOrchestrator records a Kubernetes Event on the GeminiCluster when an
+// operation that changes the cluster starts, succeeds and fails.
+type eventingOrchestrator struct {
+	api.Orchestrator
+	recorder api.EventRecorder
+}
+
+// WithEvents wraps an orchestrator so that its operations are recorded as Events.
+// Read-only operations (GetStatus, Kubeconfig) and Init are not recorded.
+func WithEvents(o api.Orchestrator, recorder api.EventRecorder) api.Orchestrator {
+	return &eventingOrchestrator{Orchestrator: o, recorder: recorder}
+}
+
+// Deploy records the deployment.
+func (e *eventingOrchestrator) Deploy(ctx context.Context, cfg *types.ClusterConfig) error {
+	return e.record(ctx, cfg, "Deploy", "deploying the cluster", func() error {
+		return e.Orchestrator.Deploy(ctx, cfg)
+	})
+}
+
+// Failover records the failover.
+func (e *eventingOrchestrator) Failover(ctx context.Context, cfg *types.ClusterConfig, promoteNode string) error {
+	return e.record(ctx, cfg, "Failover", "promoting "+promoteNode, func() error {
+		return e.Orchestrator.Failover(ctx, cfg, promoteNode)
+	})
+}
+
+// Upgrade records the upgrade.
+func (e *eventingOrchestrator) Upgrade(ctx context.Context, cfg *types.ClusterConfig, version string) error {
+	return e.record(ctx, cfg, "Upgrade", "upgrading to "+version, func() error {
+		return e.Orchestrator.Upgrade(ctx, cfg, version)
+	})
+}
+
+// ReplaceNode records the node replacement.
+func (e *eventingOrchestrator) ReplaceNode(ctx context.Context, cfg *types.ClusterConfig, oldNode, newNode string) error {
+	return e.record(ctx, cfg, "ReplaceNode", fmt.Sprintf("replacing %s with %s", oldNode, newNode), func() error {
+		return e.Orchestrator.ReplaceNode(ctx, cfg, oldNode, newNode)
+	})
+}
+
+// Backup records the backup.
+func (e *eventingOrchestrator) Backup(ctx context.Context, cfg *types.ClusterConfig, destination string) error {
+	return e.record(ctx, cfg, "Backup", "backing up to "+destination, func() error {
+		return e.Orchestrator.Backup(ctx, cfg, destination)
+	})
+}
+
+// Restore records the restore.
+func (e *eventingOrchestrator) Restore(ctx context.Context, cfg *types.ClusterConfig, source string) error {
+	return e.record(ctx, cfg, "Restore", "restoring from "+source, func() error {
+		return e.Orchestrator.Restore(ctx, cfg, source)
+	})
+}
+
+// RotateCredentials records the credential rotation.
+func (e *eventingOrchestrator) RotateCredentials(ctx context.Context, cfg *types.ClusterConfig, onlyIfDue bool) (*api.PluginResult, error) {
+	var result *api.PluginResult
+	err := e.record(ctx, cfg, "RotateCredentials", "rotating database credentials", func() error {
+		var err error
+		result, err = e.Orchestrator.RotateCredentials(ctx, cfg, onlyIfDue)
+		return err
+	})
+	return result, err
+}
+
+// record runs op between a <operation>Started event and a <operation>Succeeded
+// or Warning <operation>Failed event.
+func (e *eventingOrchestrator) record(ctx context.Context, cfg *types.ClusterConfig, operation, description string, op func() error) error {
+	object := types.GeminiClusterObject(cfg.Metadata.Name)
+	e.recorder.Record(ctx, types.Event{Object: object, Type: types.EventNormal, Reason: operation + "Started",
+		Message: fmt.Sprintf("%s: %s", operation, description)})
+
+	err := op()
+	if err != nil {
+		e.recorder.Record(ctx, types.Event{Object: object, Type: types.EventWarning, Reason: operation + "Failed",
+			Message: fmt.Sprintf("%s failed: %v", operation, err)})
+		return err
+	}
+	e.recorder.Record(ctx, types.Event{Object: object, Type: types.EventNormal, Reason: operation + "Succeeded",
+		Message: fmt.Sprintf("%s succeeded: %s", operation, description)})
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeRecorder struct {
+	events []types.Event
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, event types.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestWithEvents(t *testing.T) {
+	recorder := &fakeRecorder{}
+	mockPluginMgr := &mockPluginManager{
+		ExecuteFunc: func(ctx context.Context, name string, params api.PluginParams) (*api.PluginResult, error) {
+			return &api.PluginResult{Success: true}, nil
+		},
+	}
+	o := WithEvents(NewEngine(mockPluginMgr, nil, nil), recorder)
+	cfg := &types.ClusterConfig{Metadata: types.Metadata{Name: "demo"}}
+
+	if _, err := o.RotateCredentials(context.Background(), cfg, false); err != nil {
+		t.Fatalf("RotateCredentials failed: %v", err)
+	}
+	if err := o.Failover(context.Background(), cfg, "10.0.0.2"); err == nil {
+		t.Fatalf("expected Failover to fail")
+	}
+	if _, err := o.GetStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+
+	want := []struct {
+		reason    string
+		eventType types.EventType
+	}{
+		{"RotateCredentialsStarted", types.EventNormal},
+		{"RotateCredentialsSucceeded", types.EventNormal},
+		{"FailoverStarted", types.EventNormal},
+		{"FailoverFailed", types.EventWarning},
+	}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), recorder.events)
+	}
+	for i, w := range want {
+		e := recorder.events[i]
+		if e.Reason != w.reason || e.Type != w.eventType || e.Object != types.GeminiClusterObject("demo") {
+			t.Errorf("event %d: expected %s (%s) on the GeminiCluster, got %+v", i, w.reason, w.eventType, e)
+		}
+	}
+	if recorder.events[3].Message != "Failover failed: NotImplemented: failover is not implemented" {
+		t.Errorf("expected the error in the failure message, got %q", recorder.events[3].Message)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// CurrentLeader asks the nodes who the leader is, since after a failover it is
+// no longer the one in the config. The HostMeta with the highest fencing epoch
+// wins; if no node can be read, the leader from the config is used.
+func CurrentLeader(cfg *types.ClusterConfig, system func(nodeIP string) api.SystemOperator) (string, error) {
+	var (
+		leader string
+		epoch  int64 = -1
+	)
+	for _, n := range cfg.Spec.Nodes {
+		raw, err := system(n.IP).ReadFile(types.DefaultHostMetaPath)
+		if err != nil {
+			continue
+		}
+		var meta types.HostMeta
+		if yaml.Unmarshal(raw, &meta) != nil {
+			continue
+		}
+		if primary, ok := meta.Primary(); ok && meta.Epoch > epoch {
+			leader, epoch = primary.IP, meta.Epoch
+		}
+	}
+	if leader != "" {
+		return leader, nil
+	}
+	for _, n := range cfg.Spec.Nodes {
+		if n.Role == types.RoleLeader {
+			return n.IP, nil
+		}
+	}
+	return "", custom_errors.New(custom_errors.ValidationError, "cluster config has no leader node")
+}
+
+//Personal.AI order the ending
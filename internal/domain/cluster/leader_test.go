@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// hostMetaSystem serves a node's hostMeta.yaml, or fails to read it if it is empty.
+type hostMetaSystem struct {
+	hostMeta string
+}
+
+func (s hostMetaSystem) RunCommand(command string, args ...string) (string, error) { return "", nil }
+func (s hostMetaSystem) WriteFile(path string, content []byte, perm os.FileMode) error {
+	return nil
+}
+func (s hostMetaSystem) ReadFile(path string) ([]byte, error) {
+	if s.hostMeta == "" {
+		return nil, errors.New("unreachable")
+	}
+	return []byte(s.hostMeta), nil
+}
+
+func TestCurrentLeader(t *testing.T) {
+	cfg := &types.ClusterConfig{Spec: types.ClusterSpec{Nodes: []types.NodeInfo{
+		{IP: "10.0.0.1", Role: types.RoleLeader},
+		{IP: "10.0.0.2", Role: types.RoleFollower},
+	}}}
+	// Node 1 still names itself after node 2 took over in a later epoch.
+	stale := `{"myId": {"ip": "10.0.0.1", "role": "Leader"}, "peerId": {"ip": "10.0.0.2", "role": "Follower"}, "epoch": 3}`
+	current := `{"myId": {"ip": "10.0.0.2", "role": "Leader"}, "peerId": {"ip": "10.0.0.1", "role": "Follower"}, "epoch": 4}`
+
+	tests := []struct {
+		name  string
+		metas map[string]string
+		want  string
+	}{
+		{"highest epoch wins", map[string]string{"10.0.0.1": stale, "10.0.0.2": current}, "10.0.0.2"},
+		{"one node unreachable", map[string]string{"10.0.0.1": stale}, "10.0.0.1"},
+		{"no node readable", map[string]string{}, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leader, err := CurrentLeader(cfg, func(nodeIP string) api.SystemOperator { return hostMetaSystem{tt.metas[nodeIP]} })
+			if err != nil || leader != tt.want {
+				t.Errorf("expected %s, got %q (%v)", tt.want, leader, err)
+			}
+		})
+	}
+}
+
+//Personal.AI order the ending
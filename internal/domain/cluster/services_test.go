@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/turtacn/geminik8s/internal/domain/storage"
 	"github.com/turtacn/geminik8s/pkg/types"
 )
 
@@ -45,6 +46,8 @@ func (m *mockNodeService) PromoteNodeToLeader(ctx context.Context, nodeIP string
 type mockStorageService struct {
 	ConfigureReplicationFunc func(ctx context.Context, leaderIP, followerIP string) error
 	IsReplicationHealthyFunc func(ctx context.Context) (bool, error)
+	EnforceModeFunc          func(ctx context.Context, primaryIP, replicaIP string, cfg *types.ReplicationConfig) (types.ReplicationMode, error)
+	PromoteReplicaFunc       func(ctx context.Context, nodeIP string) error
 	BackupFunc               func(ctx context.Context, destination string) error
 	RestoreFunc              func(ctx context.Context, source string) error
 }
@@ -55,6 +58,15 @@ func (m *mockStorageService) ConfigureReplication(ctx context.Context, leaderIP,
 func (m *mockStorageService) IsReplicationHealthy(ctx context.Context) (bool, error) {
 	return m.IsReplicationHealthyFunc(ctx)
 }
+func (m *mockStorageService) EnforceReplicationMode(ctx context.Context, primaryIP, replicaIP string, cfg *types.ReplicationConfig) (types.ReplicationMode, error) {
+	return m.EnforceModeFunc(ctx, primaryIP, replicaIP, cfg)
+}
+func (m *mockStorageService) PromoteReplica(ctx context.Context, nodeIP string) error {
+	return m.PromoteReplicaFunc(ctx, nodeIP)
+}
+func (m *mockStorageService) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	return &storage.ReplicaState{}, nil
+}
 func (m *mockStorageService) Backup(ctx context.Context, destination string) error {
 	return m.BackupFunc(ctx, destination)
 }
@@ -45,13 +45,16 @@ func NewNode(config *types.NodeConfig, hostMeta *types.HostMeta) (*Node, error)
 	}, nil
 }
 
-// Promote sets the node's role to Leader.
+// Promote sets the node's role to Leader and starts a new fencing epoch,
+// which fences the previous leader.
 func (n *Node) Promote() error {
 	if n.Config.Role == types.RoleLeader {
 		return custom_errors.Newf(custom_errors.ValidationError, "node %s is already a leader", n.ID)
 	}
 	n.Config.Role = types.RoleLeader
 	n.HostMeta.MyID.Role = types.RoleLeader
+	n.HostMeta.PeerID.Role = types.RoleFollower
+	n.HostMeta.Epoch++
 	n.UpdatedAt = time.Now()
 	return nil
 }
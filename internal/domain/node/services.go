@@ -2,9 +2,11 @@ package node
 
 import (
 	"context"
+	"sort"
 
 	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
 )
 
 // ServiceInterface defines the public methods of a node service.
@@ -20,6 +22,12 @@ type Service struct {
 	nodeRepo       Repository
 	systemOperator api.SystemOperator // For file operations, etc.
 	k8sClient      api.K8sClient      // For interacting with k8s
+	nodes          nodeLister         // The API server, or a cache of it
+}
+
+// nodeLister lists the cluster's Kubernetes nodes.
+type nodeLister interface {
+	GetNodes(ctx context.Context) ([]types.Node, error)
 }
 
 // NewService creates a new node service.
@@ -28,6 +36,18 @@ func NewService(repo Repository, systemOp api.SystemOperator, k8sClient api.K8sC
 		nodeRepo:       repo,
 		systemOperator: systemOp,
 		k8sClient:      k8sClient,
+		nodes:          k8sClient,
+	}
+}
+
+// NewCachedService creates a node service that checks node health against the
+// node cache instead of listing nodes from the API server on every check.
+func NewCachedService(repo Repository, systemOp api.SystemOperator, k8sClient api.K8sClient, cache api.NodeCache) ServiceInterface {
+	return &Service{
+		nodeRepo:       repo,
+		systemOperator: systemOp,
+		k8sClient:      k8sClient,
+		nodes:          cache,
 	}
 }
 
@@ -76,21 +96,57 @@ func (s *Service) PromoteNodeToLeader(ctx context.Context, nodeIP string) error
 	return s.nodeRepo.Save(ctx, node)
 }
 
-// CheckNodeHealth performs a health check on a single node.
+// CheckNodeHealth reports whether the Kubernetes node with the given IP is healthy.
+// The IP may be any InternalIP of the node, of either family. A node that is
+// not registered with the API server is an error.
 func (s *Service) CheckNodeHealth(ctx context.Context, nodeIP string) (bool, error) {
-	// A real health check would involve multiple steps:
-	// 1. Ping the node.
-	// 2. Check if k3s service is running.
-	// 3. Check if postgres service is running.
-	// 4. Check if k8s API is responsive.
-
-	// For now, we simulate a successful check.
-	_, err := s.k8sClient.GetNodes(ctx)
+	nodes, err := s.nodes.GetNodes(ctx)
 	if err != nil {
 		return false, custom_errors.Wrapf(err, custom_errors.KubernetesError, "failed to get nodes from k8s api on %s", nodeIP)
 	}
 
-	return true, nil
+	for _, n := range nodes {
+		if n.Config.HasAddress(nodeIP) {
+			return n.Status.Status == types.NodeStatusHealthy, nil
+		}
+	}
+	return false, custom_errors.Newf(custom_errors.KubernetesError, "no kubernetes node has internal IP %s", nodeIP)
+}
+
+// roleLabeler sets the role labels and taints of a Kubernetes node.
+type roleLabeler interface {
+	SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error
+}
+
+// ApplyRoleLabels labels the Kubernetes nodes of nodes with their role at epoch.
+// The followers are labeled first, by name, and the leader only once all of
+// them succeeded: a node is never labeled leader while another node this call
+// labels still carries the leader label of an earlier epoch. Each node is
+// compared against the live object, so labels removed by hand are restored.
+func ApplyRoleLabels(ctx context.Context, client roleLabeler, nodes []types.NodeIdentity, epoch int64, cfg *types.NodeLabelsConfig) error {
+	ordered := make([]types.NodeIdentity, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Name != "" {
+			ordered = append(ordered, n)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if leader := ordered[i].Role == types.RoleLeader; leader != (ordered[j].Role == types.RoleLeader) {
+			return !leader
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+
+	var firstErr error
+	for _, n := range ordered {
+		if n.Role == types.RoleLeader && firstErr != nil {
+			break
+		}
+		if err := client.SetNodeRole(ctx, n.Name, n.Role, epoch, cfg); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 //Personal.AI order the ending
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
 )
 
@@ -33,6 +35,55 @@ func (m *mockSystemOperator) WriteFile(path string, content []byte, perm os.File
 }
 func (m *mockSystemOperator) ReadFile(path string) ([]byte, error) { return nil, nil }
 
+type mockK8sClient struct {
+	GetNodesFunc func(ctx context.Context) ([]types.Node, error)
+}
+
+func (m *mockK8sClient) GetNodes(ctx context.Context) ([]types.Node, error) {
+	return m.GetNodesFunc(ctx)
+}
+func (m *mockK8sClient) Apply(ctx context.Context, manifest []byte) ([]types.ObjectResult, error) {
+	return nil, nil
+}
+func (m *mockK8sClient) Delete(ctx context.Context, manifest []byte) ([]types.ObjectResult, error) {
+	return nil, nil
+}
+func (m *mockK8sClient) Cordon(ctx context.Context, nodeName string) error   { return nil }
+func (m *mockK8sClient) Uncordon(ctx context.Context, nodeName string) error { return nil }
+func (m *mockK8sClient) Drain(ctx context.Context, nodeName string, opts types.DrainOptions) error {
+	return nil
+}
+func (m *mockK8sClient) CreateToken(ctx context.Context, namespace, sa string, ttl time.Duration) (string, time.Time, error) {
+	return "", time.Time{}, nil
+}
+func (m *mockK8sClient) UpdateClusterStatus(ctx context.Context, name string, update func(*types.GeminiClusterStatus)) error {
+	return nil
+}
+func (m *mockK8sClient) GetClusterStatus(ctx context.Context, name string) (*types.GeminiClusterStatus, error) {
+	return &types.GeminiClusterStatus{}, nil
+}
+func (m *mockK8sClient) WatchClusterConfigs(ctx context.Context, resync time.Duration, handler func(types.ClusterConfigResource, error)) error {
+	return nil
+}
+func (m *mockK8sClient) UpdateClusterConfigStatus(ctx context.Context, name string, update func(*types.ClusterConfigStatus)) error {
+	return nil
+}
+func (m *mockK8sClient) RunWithLeaderElection(ctx context.Context, namespace, name, identity string, run func(ctx context.Context)) error {
+	return nil
+}
+func (m *mockK8sClient) CheckControlPlane(ctx context.Context) []types.HealthCheckResult {
+	return nil
+}
+func (m *mockK8sClient) EventRecorder(component, spoolPath string) api.EventRecorder {
+	return nil
+}
+func (m *mockK8sClient) StartNodeCache(ctx context.Context) (api.NodeCache, error) {
+	return nil, nil
+}
+func (m *mockK8sClient) SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error {
+	return nil
+}
+
 // --- Tests ---
 
 func TestPromoteNodeToLeader(t *testing.T) {
@@ -69,6 +120,9 @@ func TestPromoteNodeToLeader(t *testing.T) {
 	if nodeToPromote.Config.Role != types.RoleLeader {
 		t.Errorf("expected node role to be updated to Leader, got %s", nodeToPromote.Config.Role)
 	}
+	if nodeToPromote.HostMeta.Epoch != 1 {
+		t.Errorf("expected promotion to start fencing epoch 1, got %d", nodeToPromote.HostMeta.Epoch)
+	}
 }
 
 func TestInitializeNode(t *testing.T) {
@@ -91,4 +145,54 @@ func TestInitializeNode(t *testing.T) {
 	}
 }
 
+func TestCheckNodeHealth(t *testing.T) {
+	k8s := &mockK8sClient{
+		GetNodesFunc: func(ctx context.Context) ([]types.Node, error) {
+			return []types.Node{
+				{Config: types.NodeConfig{IP: "1.2.3.4"}, Status: types.NodeStatus{Status: types.NodeStatusHealthy}},
+				{Config: types.NodeConfig{IP: "1.2.3.5"}, Status: types.NodeStatus{Status: types.NodeStatusUnhealthy}},
+				{Config: types.NodeConfig{IP: "10.0.0.7", Addresses: []string{"fd00::7"}}, Status: types.NodeStatus{Status: types.NodeStatusHealthy}},
+			}, nil
+		},
+	}
+	service := NewService(nil, nil, k8s)
+
+	if healthy, err := service.CheckNodeHealth(context.Background(), "1.2.3.4"); err != nil || !healthy {
+		t.Errorf("expected 1.2.3.4 to be healthy, got %v (%v)", healthy, err)
+	}
+	if healthy, err := service.CheckNodeHealth(context.Background(), "1.2.3.5"); err != nil || healthy {
+		t.Errorf("expected 1.2.3.5 to be unhealthy, got %v (%v)", healthy, err)
+	}
+	if healthy, err := service.CheckNodeHealth(context.Background(), "fd00:0::7"); err != nil || !healthy {
+		t.Errorf("expected the dual-stack node to match its IPv6 address, got %v (%v)", healthy, err)
+	}
+	if _, err := service.CheckNodeHealth(context.Background(), "1.2.3.6"); err == nil {
+		t.Errorf("expected an error for an unknown node")
+	}
+}
+
+type mockNodeCache struct {
+	nodes []types.Node
+}
+
+func (m *mockNodeCache) GetNodes(ctx context.Context) ([]types.Node, error) { return m.nodes, nil }
+func (m *mockNodeCache) Subscribe(handler func(types.Node))                 {}
+
+func TestCheckNodeHealthCached(t *testing.T) {
+	k8s := &mockK8sClient{
+		GetNodesFunc: func(ctx context.Context) ([]types.Node, error) {
+			t.Fatalf("expected the cache to be used instead of the API server")
+			return nil, nil
+		},
+	}
+	cache := &mockNodeCache{nodes: []types.Node{
+		{Config: types.NodeConfig{IP: "1.2.3.4"}, Status: types.NodeStatus{Status: types.NodeStatusUnhealthy}},
+	}}
+	service := NewCachedService(nil, nil, k8s, cache)
+
+	if healthy, err := service.CheckNodeHealth(context.Background(), "1.2.3.4"); err != nil || healthy {
+		t.Errorf("expected 1.2.3.4 to be unhealthy, got %v (%v)", healthy, err)
+	}
+}
+
 //Personal.AI order the ending
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ReplicaState is what the primary knows about its follower.
+type ReplicaState struct {
+	// Streaming is true while the follower is connected and applying changes.
+	Streaming bool
+	// Lag is how far the follower is behind the primary.
+	Lag time.Duration
+}
+
+// Healthy reports whether the follower is streaming and within the lag tolerance.
+func (s *ReplicaState) Healthy() bool {
+	return s.Streaming && s.Lag <= replicationLagTolerance
+}
+
+// Backend is a datastore Kine can run on. Each implementation knows how to
+// replicate between the two nodes, report replication health, back up and
+// promote the follower for its own database engine.
+// Node arguments are the IPs of the nodes as they appear in spec.nodes.
+type Backend interface {
+	// Type returns the storage type, as used in spec.storage.type.
+	Type() string
+	// KineEndpoint returns the datastore endpoint Kine should use to reach the
+	// database at address (host:port), normally KineProxyAddress.
+	KineEndpoint(user, password, address string) string
+	// ConfigureReplication makes the follower replicate everything from the leader.
+	ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error
+	// ReplicationState reports whether the follower is streaming from the primary, and its lag.
+	ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*ReplicaState, error)
+	// SetSynchronous turns synchronous replication to the follower on or off on the primary.
+	SetSynchronous(ctx context.Context, primaryIP string, enabled bool) error
+	// Promote stops replication on the node and makes it a writable primary.
+	Promote(ctx context.Context, nodeIP string) error
+	// Backup writes a backup of the node's database to destination on that node.
+	Backup(ctx context.Context, nodeIP, destination string) error
+}
+
+//Personal.AI order the ending
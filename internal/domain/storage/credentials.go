@@ -0,0 +1,361 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+const (
+	// KineGroupRole owns the privileges on the Kine tables. Every generation of
+	// Kine login role is a member of it, so rotating does not touch grants.
+	KineGroupRole = "geminik8s_kine"
+	// ReplicationGroupRole holds the read privileges the subscription needs.
+	ReplicationGroupRole = "geminik8s_replication"
+
+	// KineEnvPath is the environment file the Kine service reads its endpoint from.
+	KineEnvPath = "/etc/geminik8s/kine.env"
+	// KineServiceName is the systemd unit running Kine on each node.
+	KineServiceName = "geminik8s-kine"
+	// KineUnitPath is where the Kine unit is installed.
+	KineUnitPath = "/etc/systemd/system/" + KineServiceName + ".service"
+	// KineProxyAddress is the agent's local proxy to the primary database. Kine
+	// connects through it, so it follows the leader without a restart.
+	KineProxyAddress = "127.0.0.1:6432"
+
+	// hbaBegin and hbaEnd enclose the entries of HBAEntries in pg_hba.conf.
+	hbaBegin = "# BEGIN geminik8s"
+	hbaEnd   = "# END geminik8s"
+)
+
+// kineUnit runs the supervised Kine of 'gemin_k8s kine' with the endpoint from KineEnvPath.
+const kineUnit = `[Unit]
+Description=Kine datastore for geminik8s
+Wants=network-online.target
+After=network-online.target
+Before=k3s.service
+
+[Service]
+EnvironmentFile=` + KineEnvPath + `
+ExecStart=/usr/local/bin/gemin_k8s kine
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// Credentials is one generation of the Kine and replication credentials.
+type Credentials struct {
+	Generation          int       `json:"generation"`
+	KineUser            string    `json:"kineUser"`
+	KinePassword        string    `json:"kinePassword"`
+	ReplicationUser     string    `json:"replicationUser"`
+	ReplicationPassword string    `json:"replicationPassword"`
+	RotatedAt           time.Time `json:"rotatedAt"`
+}
+
+// CredentialStore persists the current credentials.
+// Load returns nil, nil when no credentials have been generated yet.
+type CredentialStore interface {
+	Load() (*Credentials, error)
+	Save(creds *Credentials) error
+}
+
+// NodeAccess opens the per-node dependencies needed to reconfigure a node.
+type NodeAccess interface {
+	// AdminDB returns a connected client to the node's database with rights to manage roles.
+	AdminDB(nodeIP string) (api.DBClient, error)
+	// System returns an operator that runs commands and writes files on the node.
+	System(nodeIP string) api.SystemOperator
+}
+
+// CredentialRotator replaces the Kine and replication credentials on both nodes.
+type CredentialRotator struct {
+	store   CredentialStore
+	nodes   NodeAccess
+	backend Backend
+	pg      types.PostgresConfig
+	now     func() time.Time
+}
+
+// NewCredentialRotator creates a new credential rotator. The Kine endpoint is
+// built by backend.
+func NewCredentialRotator(store CredentialStore, nodes NodeAccess, backend Backend, pg *types.PostgresConfig) *CredentialRotator {
+	return &CredentialRotator{
+		store:   store,
+		nodes:   nodes,
+		backend: backend,
+		pg:      pg.WithDefaults(),
+		now:     time.Now,
+	}
+}
+
+// Due reports whether the current credentials are older than maxAge.
+// Credentials that were never rotated are always due.
+func (r *CredentialRotator) Due(maxAge time.Duration) (bool, error) {
+	current, err := r.store.Load()
+	if err != nil {
+		return false, err
+	}
+	return current == nil || r.now().Sub(current.RotatedAt) >= maxAge, nil
+}
+
+// Rotate creates a new generation of credentials and switches both nodes to it.
+// The order keeps the cluster writable throughout:
+//  1. install the Kine unit on both nodes, so a node that cannot run it fails the rotation early,
+//  2. create the new roles on both databases,
+//  3. point the follower's subscription at the leader with the new replication role,
+//  4. rewrite the Kine endpoint and restart Kine, follower first, then leader,
+//  5. persist the new credentials,
+//  6. drop the previous generation's roles.
+func (r *CredentialRotator) Rotate(ctx context.Context, leaderIP, followerIP string) (*Credentials, error) {
+	previous, err := r.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	next, err := r.newCredentials(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range []string{leaderIP, followerIP} {
+		if err := installKineUnit(r.nodes.System(ip)); err != nil {
+			return nil, custom_errors.Wrapf(err, custom_errors.OrchestratorError, "failed to install the Kine unit on %s", ip)
+		}
+	}
+
+	for _, ip := range []string{leaderIP, followerIP} {
+		if err := r.withAdminDB(ip, func(db api.DBClient) error { return createRoles(db, r.pg.Database, next) }); err != nil {
+			return nil, custom_errors.Wrapf(err, custom_errors.DatabaseError, "failed to create new roles on %s", ip)
+		}
+	}
+
+	conn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
+		leaderIP, r.pg.Port, r.pg.Database, next.ReplicationUser, next.ReplicationPassword, r.pg.SSLMode)
+	err = r.withAdminDB(followerIP, func(db api.DBClient) error {
+		return db.Execute(fmt.Sprintf("ALTER SUBSCRIPTION %s CONNECTION %s", SubscriptionName, quoteLiteral(conn)))
+	})
+	if err != nil {
+		return nil, custom_errors.Wrapf(err, custom_errors.DatabaseError, "failed to update subscription on %s", followerIP)
+	}
+
+	// Both Kine instances reach the primary through their agent's proxy.
+	env := fmt.Sprintf("KINE_ENDPOINT=%s\n", r.backend.KineEndpoint(next.KineUser, next.KinePassword, KineProxyAddress))
+	for _, ip := range []string{followerIP, leaderIP} {
+		sys := r.nodes.System(ip)
+		if err := sys.WriteFile(KineEnvPath, []byte(env), 0o600); err != nil {
+			return nil, err
+		}
+		if _, err := sys.RunCommand("systemctl", "restart", KineServiceName); err != nil {
+			return nil, custom_errors.Wrapf(err, custom_errors.OrchestratorError, "failed to restart Kine on %s", ip)
+		}
+	}
+
+	if err := r.store.Save(next); err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		for _, ip := range []string{followerIP, leaderIP} {
+			if err := r.withAdminDB(ip, func(db api.DBClient) error { return dropRoles(db, previous) }); err != nil {
+				return next, custom_errors.Wrapf(err, custom_errors.DatabaseError, "new credentials are active, but dropping the old roles on %s failed", ip)
+			}
+		}
+	}
+	return next, nil
+}
+
+// HBAEntries returns the pg_hba.conf lines that let Kine and the other node's
+// subscription log in from every address of the nodes, each as a /32 or /128.
+// They name the group roles, so they hold for every generation of credentials.
+func HBAEntries(pg *types.PostgresConfig, nodes []types.NodeInfo) []string {
+	database := pg.WithDefaults().Database
+	var entries []string
+	for _, n := range nodes {
+		for _, address := range n.AllAddresses() {
+			for _, role := range []string{KineGroupRole, ReplicationGroupRole} {
+				entries = append(entries, fmt.Sprintf("host %s +%s %s scram-sha-256", database, role, types.HostCIDR(address)))
+			}
+		}
+	}
+	return entries
+}
+
+// ApplyHBAEntries puts entries at the top of the node's pg_hba.conf, in a block
+// that replaces the one of an earlier call, and reloads PostgreSQL. The file is
+// located and the reload sent with psql as the admin user on the node.
+func ApplyHBAEntries(sys api.SystemOperator, pg *types.PostgresConfig, entries []string) error {
+	cfg := pg.WithDefaults()
+	psql := func(query string) (string, error) {
+		return sys.RunCommand("psql", "--username="+cfg.AdminUser, "--port="+strconv.Itoa(cfg.Port), "--dbname=postgres",
+			"--tuples-only", "--no-align", "--command="+query)
+	}
+	out, err := psql("SHOW hba_file")
+	if err != nil {
+		return custom_errors.Wrap(err, custom_errors.DatabaseError, "failed to locate pg_hba.conf")
+	}
+	path := strings.TrimSpace(out)
+	raw, err := sys.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := sys.WriteFile(path, []byte(withHBABlock(string(raw), entries)), 0o640); err != nil {
+		return err
+	}
+	if _, err := psql("SELECT pg_reload_conf()"); err != nil {
+		return custom_errors.Wrap(err, custom_errors.DatabaseError, "failed to reload the PostgreSQL configuration")
+	}
+	return nil
+}
+
+// withHBABlock returns content with its geminik8s block, if any, replaced by
+// one with entries at the top, where no other entry can shadow them.
+func withHBABlock(content string, entries []string) string {
+	lines := append(append([]string{hbaBegin}, entries...), hbaEnd)
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case line == hbaBegin:
+			inBlock = true
+		case line == hbaEnd:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// installKineUnit writes the Kine unit and enables it. Both steps are idempotent.
+func installKineUnit(sys api.SystemOperator) error {
+	if err := sys.WriteFile(KineUnitPath, []byte(kineUnit), 0o644); err != nil {
+		return err
+	}
+	if _, err := sys.RunCommand("systemctl", "daemon-reload"); err != nil {
+		return err
+	}
+	_, err := sys.RunCommand("systemctl", "enable", KineServiceName)
+	return err
+}
+
+// withAdminDB runs fn with an admin connection to the node's database.
+func (r *CredentialRotator) withAdminDB(nodeIP string, fn func(db api.DBClient) error) error {
+	db, err := r.nodes.AdminDB(nodeIP)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(db)
+}
+
+// newCredentials generates the generation after previous.
+func (r *CredentialRotator) newCredentials(previous *Credentials) (*Credentials, error) {
+	gen := 1
+	if previous != nil {
+		gen = previous.Generation + 1
+	}
+	kinePassword, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	replPassword, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{
+		Generation:          gen,
+		KineUser:            fmt.Sprintf("%s_g%d", KineGroupRole, gen),
+		KinePassword:        kinePassword,
+		ReplicationUser:     fmt.Sprintf("%s_g%d", ReplicationGroupRole, gen),
+		ReplicationPassword: replPassword,
+		RotatedAt:           r.now().UTC(),
+	}, nil
+}
+
+// createRoles creates (or re-keys, if a previous attempt got that far) the login
+// roles of a generation and makes them members of the group roles.
+func createRoles(db api.DBClient, database string, creds *Credentials) error {
+	statements := []string{
+		ensureRole(KineGroupRole, "NOLOGIN", ""),
+		ensureRole(ReplicationGroupRole, "NOLOGIN", ""),
+		fmt.Sprintf("GRANT ALL ON DATABASE %s TO %s", quoteIdent(database), KineGroupRole),
+		fmt.Sprintf("GRANT ALL ON SCHEMA public TO %s", KineGroupRole),
+		fmt.Sprintf("GRANT ALL ON ALL TABLES IN SCHEMA public TO %s", KineGroupRole),
+		fmt.Sprintf("GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO %s", KineGroupRole),
+		fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA public TO %s", ReplicationGroupRole),
+		ensureRole(creds.KineUser, "LOGIN", creds.KinePassword),
+		// The REPLICATION attribute is not inherited, so it goes on the login role.
+		ensureRole(creds.ReplicationUser, "LOGIN REPLICATION", creds.ReplicationPassword),
+		fmt.Sprintf("GRANT %s TO %s", KineGroupRole, creds.KineUser),
+		fmt.Sprintf("GRANT %s TO %s", ReplicationGroupRole, creds.ReplicationUser),
+	}
+	for _, stmt := range statements {
+		if err := db.Execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropRoles disconnects and removes the login roles of a generation.
+// Anything they own is handed to the group role first.
+func dropRoles(db api.DBClient, creds *Credentials) error {
+	for _, role := range []struct{ user, group string }{
+		{creds.KineUser, KineGroupRole},
+		{creds.ReplicationUser, ReplicationGroupRole},
+	} {
+		statements := []string{
+			fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE usename = %s", quoteLiteral(role.user)),
+			fmt.Sprintf("DO $$ BEGIN IF EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN "+
+				"REASSIGN OWNED BY %s TO %s; DROP OWNED BY %s; END IF; END $$",
+				quoteLiteral(role.user), role.user, role.group, role.user),
+			fmt.Sprintf("DROP ROLE IF EXISTS %s", role.user),
+		}
+		for _, stmt := range statements {
+			if err := db.Execute(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureRole returns a statement that creates the role, or updates it if it already exists.
+// Role names are generated by us and are valid identifiers; passwords are quoted.
+func ensureRole(name, attrs, password string) string {
+	if password != "" {
+		attrs += " PASSWORD " + quoteLiteral(password)
+	}
+	return fmt.Sprintf("DO $$ BEGIN IF EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN ALTER ROLE %s %s; "+
+		"ELSE CREATE ROLE %s %s; END IF; END $$", quoteLiteral(name), name, attrs, name, attrs)
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdent quotes s as a SQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// randomPassword returns a URL-safe password with 192 bits of entropy.
+func randomPassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", custom_errors.Wrap(err, custom_errors.Unknown, "failed to generate password")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// --- Mocks ---
+
+type memoryCredentialStore struct {
+	creds *Credentials
+}
+
+func (m *memoryCredentialStore) Load() (*Credentials, error)   { return m.creds, nil }
+func (m *memoryCredentialStore) Save(creds *Credentials) error { m.creds = creds; return nil }
+
+type recordingSystemOperator struct {
+	ip  string
+	log *[]string
+}
+
+func (m *recordingSystemOperator) RunCommand(command string, args ...string) (string, error) {
+	*m.log = append(*m.log, m.ip+": "+command+" "+strings.Join(args, " "))
+	return "", nil
+}
+func (m *recordingSystemOperator) WriteFile(path string, content []byte, perm os.FileMode) error {
+	*m.log = append(*m.log, m.ip+": write "+path+" "+string(content))
+	return nil
+}
+func (m *recordingSystemOperator) ReadFile(path string) ([]byte, error) { return nil, nil }
+
+type recordingNodeAccess struct {
+	log []string
+	dbs map[string]*mockDBClient
+}
+
+func (m *recordingNodeAccess) AdminDB(nodeIP string) (api.DBClient, error) {
+	if m.dbs[nodeIP] == nil {
+		m.dbs[nodeIP] = &mockDBClient{}
+	}
+	return m.dbs[nodeIP], nil
+}
+func (m *recordingNodeAccess) System(nodeIP string) api.SystemOperator {
+	return &recordingSystemOperator{ip: nodeIP, log: &m.log}
+}
+
+func containsStatement(statements []string, substr string) bool {
+	for _, s := range statements {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Tests ---
+
+func TestCredentialRotator(t *testing.T) {
+	store := &memoryCredentialStore{}
+	nodes := &recordingNodeAccess{dbs: map[string]*mockDBClient{}}
+	rotator := NewCredentialRotator(store, nodes, &mockBackend{}, nil)
+	ctx := context.Background()
+
+	first, err := rotator.Rotate(ctx, "10.0.0.1", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("first Rotate failed: %v", err)
+	}
+	if first.Generation != 1 || store.creds != first {
+		t.Fatalf("expected generation 1 to be stored, got %+v", store.creds)
+	}
+
+	follower := nodes.dbs["10.0.0.2"].executed
+	if !containsStatement(follower, "ALTER SUBSCRIPTION "+SubscriptionName) ||
+		!containsStatement(follower, "user="+first.ReplicationUser) {
+		t.Errorf("expected follower subscription to switch to %s, got %v", first.ReplicationUser, follower)
+	}
+
+	// The unit running 'gemin_k8s kine' is installed on both nodes before anything is restarted.
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		installed := -1
+		for i, entry := range nodes.log {
+			if strings.HasPrefix(entry, ip+": write "+KineUnitPath) && strings.Contains(entry, "ExecStart=/usr/local/bin/gemin_k8s kine") {
+				installed = i
+			}
+			if strings.HasPrefix(entry, ip+": systemctl restart") && installed == -1 {
+				t.Errorf("expected the Kine unit to be installed on %s before the restart", ip)
+			}
+		}
+		if installed == -1 {
+			t.Errorf("expected the Kine unit to be installed on %s, got %v", ip, nodes.log)
+		}
+	}
+
+	// Kine is restarted on the follower before the leader, and both go through the proxy.
+	endpoint := "KINE_ENDPOINT=mock://" + first.KineUser + ":" + first.KinePassword + "@" + KineProxyAddress
+	var restarts []string
+	for _, entry := range nodes.log {
+		if strings.Contains(entry, "systemctl restart") {
+			restarts = append(restarts, entry)
+		}
+		if strings.Contains(entry, "write "+KineEnvPath) && !strings.Contains(entry, endpoint) {
+			t.Errorf("expected Kine endpoint to point at the primary proxy, got %q", entry)
+		}
+	}
+	if len(restarts) != 2 || !strings.HasPrefix(restarts[0], "10.0.0.2") || !strings.HasPrefix(restarts[1], "10.0.0.1") {
+		t.Errorf("expected Kine to restart on follower then leader, got %v", restarts)
+	}
+
+	t.Run("due", func(t *testing.T) {
+		if due, _ := rotator.Due(time.Hour); due {
+			t.Errorf("fresh credentials should not be due")
+		}
+		rotator.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		if due, _ := rotator.Due(time.Hour); !due {
+			t.Errorf("old credentials should be due")
+		}
+	})
+
+	t.Run("second rotation drops the previous roles", func(t *testing.T) {
+		second, err := rotator.Rotate(ctx, "10.0.0.1", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("second Rotate failed: %v", err)
+		}
+		if second.Generation != 2 || second.KinePassword == first.KinePassword {
+			t.Fatalf("expected a new generation with a new password, got %+v", second)
+		}
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+			executed := nodes.dbs[ip].executed
+			if !containsStatement(executed, "DROP ROLE IF EXISTS "+first.KineUser) ||
+				!containsStatement(executed, "DROP ROLE IF EXISTS "+first.ReplicationUser) {
+				t.Errorf("expected old roles to be dropped on %s", ip)
+			}
+		}
+	})
+}
+
+func TestHBAEntries(t *testing.T) {
+	nodes := []types.NodeInfo{
+		{IP: "10.0.0.1", Addresses: []string{"fd00::1"}},
+		{IP: "fd00::2", Addresses: []string{"10.0.0.2"}},
+	}
+	entries := HBAEntries(&types.PostgresConfig{Database: "k8s"}, nodes)
+	want := []string{
+		"host k8s +geminik8s_kine 10.0.0.1/32 scram-sha-256",
+		"host k8s +geminik8s_replication 10.0.0.1/32 scram-sha-256",
+		"host k8s +geminik8s_kine fd00::1/128 scram-sha-256",
+		"host k8s +geminik8s_replication fd00::1/128 scram-sha-256",
+		"host k8s +geminik8s_kine fd00::2/128 scram-sha-256",
+		"host k8s +geminik8s_replication fd00::2/128 scram-sha-256",
+		"host k8s +geminik8s_kine 10.0.0.2/32 scram-sha-256",
+		"host k8s +geminik8s_replication 10.0.0.2/32 scram-sha-256",
+	}
+	if strings.Join(entries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected pg_hba.conf entries:\n%s", strings.Join(entries, "\n"))
+	}
+}
+
+func TestWithHBABlock(t *testing.T) {
+	original := "local all postgres peer\nhost all all 127.0.0.1/32 scram-sha-256\n"
+	once := withHBABlock(original, []string{"host k8s +geminik8s_kine 10.0.0.1/32 scram-sha-256"})
+	want := "# BEGIN geminik8s\nhost k8s +geminik8s_kine 10.0.0.1/32 scram-sha-256\n# END geminik8s\n" + original
+	if once != want {
+		t.Errorf("expected the block on top:\n%s\ngot:\n%s", want, once)
+	}
+	// A second call replaces the block instead of adding another one.
+	twice := withHBABlock(once, []string{"host k8s +geminik8s_kine fd00::1/128 scram-sha-256"})
+	if want := "# BEGIN geminik8s\nhost k8s +geminik8s_kine fd00::1/128 scram-sha-256\n# END geminik8s\n" + original; twice != want {
+		t.Errorf("expected the block to be replaced:\n%s\ngot:\n%s", want, twice)
+	}
+}
+
+//Personal.AI order the ending
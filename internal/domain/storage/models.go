@@ -6,6 +6,7 @@ import (
 	"time"
 
 	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
 )
 
 // ReplicationStatus represents the status of PostgreSQL logical replication.
@@ -56,6 +57,19 @@ type Replication struct {
 	Status         ReplicationStatus
 	LastSyncTime   time.Time
 	ReplicationLag time.Duration
+
+	// Mode is the replication mode currently applied on the primary.
+	Mode types.ReplicationMode
+	// ModeAppliedOn is the primary that Mode was last written to. It is empty
+	// until the mode has been applied since startup, because the primary may
+	// still carry a setting from an earlier run that Mode knows nothing about.
+	ModeAppliedOn string
+	// SyncDegraded is set when sync replication was requested but the primary
+	// fell back to async because the follower was unreachable for too long.
+	SyncDegraded bool
+	// FollowerDownSince is when the follower was last seen dropping off the
+	// primary. It is zero while the follower is streaming.
+	FollowerDownSince time.Time
 }
 
 // Repository defines the interface for storage configuration persistence.
@@ -75,6 +89,7 @@ func NewStorage(id string, pgConfig *PostgresConfig, kineConfig *KineConfig) (*S
 		Kine:     kineConfig,
 		Replication: &Replication{
 			Status: ReplicationUnknown,
+			Mode:   types.ReplicationModeAsync,
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -90,8 +105,55 @@ func (s *Storage) UpdateReplicationStatus(status ReplicationStatus, lag time.Dur
 }
 
 // IsReplicationHealthy checks if the replication is active and lag is within a tolerance.
+// A cluster that had to give up synchronous replication is never considered healthy.
 func (s *Storage) IsReplicationHealthy(tolerance time.Duration) bool {
-	return s.Replication.Status == ReplicationActive && s.Replication.ReplicationLag <= tolerance
+	return s.Replication.Status == ReplicationActive && s.Replication.ReplicationLag <= tolerance && !s.Replication.SyncDegraded
+}
+
+// ObserveFollower records whether the follower is currently streaming from the primary.
+func (s *Storage) ObserveFollower(streaming bool, lag time.Duration, now time.Time) {
+	if streaming {
+		s.Replication.FollowerDownSince = time.Time{}
+		s.UpdateReplicationStatus(ReplicationActive, lag)
+		return
+	}
+	if s.Replication.FollowerDownSince.IsZero() {
+		s.Replication.FollowerDownSince = now
+	}
+	s.Replication.Status = ReplicationInactive
+	s.UpdatedAt = now
+}
+
+// NextReplicationMode decides which mode the primary should run in.
+// A sync cluster drops to async once the follower has been down for longer than
+// degradeTimeout. An async primary, including one that has just started, only
+// switches to sync when the follower streams with a lag within catchUpTolerance.
+func (s *Storage) NextReplicationMode(desired types.ReplicationMode, degradeTimeout, catchUpTolerance time.Duration, now time.Time) types.ReplicationMode {
+	if desired != types.ReplicationModeSync {
+		return types.ReplicationModeAsync
+	}
+
+	r := s.Replication
+	if r.Mode == types.ReplicationModeSync {
+		if !r.FollowerDownSince.IsZero() && now.Sub(r.FollowerDownSince) >= degradeTimeout {
+			return types.ReplicationModeAsync
+		}
+		return types.ReplicationModeSync
+	}
+
+	// Currently async. Only switch (back) to sync once the follower is caught up,
+	// otherwise every commit would block on an absent standby.
+	if r.Status != ReplicationActive || r.ReplicationLag > catchUpTolerance {
+		return types.ReplicationModeAsync
+	}
+	return types.ReplicationModeSync
+}
+
+// ApplyReplicationMode records that the primary now runs in the given mode.
+func (s *Storage) ApplyReplicationMode(desired, applied types.ReplicationMode) {
+	s.Replication.Mode = applied
+	s.Replication.SyncDegraded = desired == types.ReplicationModeSync && applied != types.ReplicationModeSync
+	s.UpdatedAt = time.Now()
 }
 
 //Personal.AI order the ending
@@ -5,13 +5,26 @@ import (
 	"time"
 
 	custom_errors "github.com/turtacn/geminik8s/internal/pkg/errors"
-	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+const (
+	// PublicationName is the PostgreSQL logical replication publication created on the leader.
+	PublicationName = "geminik8s_pub"
+	// SubscriptionName is the PostgreSQL logical replication subscription created on the follower.
+	// Its walsender reports it as application_name, so it doubles as the synchronous standby name.
+	SubscriptionName = "geminik8s_sub"
+	// replicationLagTolerance is the maximum lag for replication to count as healthy.
+	replicationLagTolerance = 5 * time.Second
 )
 
 // ServiceInterface defines the public methods of a storage service.
 type ServiceInterface interface {
 	ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error
 	IsReplicationHealthy(ctx context.Context) (bool, error)
+	EnforceReplicationMode(ctx context.Context, primaryIP, replicaIP string, cfg *types.ReplicationConfig) (types.ReplicationMode, error)
+	PromoteReplica(ctx context.Context, nodeIP string) error
+	ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*ReplicaState, error)
 	Backup(ctx context.Context, destination string) error
 	Restore(ctx context.Context, source string) error
 }
@@ -19,62 +32,118 @@ type ServiceInterface interface {
 // Service provides storage-related business logic.
 type Service struct {
 	storageRepo Repository
-	dbClient    api.DBClient // Interface to the database infrastructure
+	backend     Backend // The datastore selected by spec.storage.type
 }
 
 // NewService creates a new storage service.
-func NewService(repo Repository, dbClient api.DBClient) ServiceInterface {
+func NewService(repo Repository, backend Backend) ServiceInterface {
 	return &Service{
 		storageRepo: repo,
-		dbClient:    dbClient,
+		backend:     backend,
 	}
 }
 
-// ConfigureReplication sets up logical replication between the leader and follower nodes.
+// ConfigureReplication sets up replication from the leader to the follower node.
 func (s *Service) ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error {
-	// This business workflow would involve several steps executed via the dbClient:
-	// 1. On the leader's DB, create a publication.
-	//    e.g., s.dbClient.ExecuteOn(leaderIP, "CREATE PUBLICATION ...")
-	// 2. On the follower's DB, create a subscription.
-	//    e.g., s.dbClient.ExecuteOn(followerIP, "CREATE SUBSCRIPTION ...")
-	// 3. Verify the subscription is active.
-
-	// Placeholder logic
 	storage, err := s.storageRepo.FindByID(ctx, "default") // Assuming a single storage config
 	if err != nil {
 		return custom_errors.Wrap(err, custom_errors.DatabaseError, "could not find storage config")
 	}
 
-	storage.UpdateReplicationStatus(ReplicationActive, 50*time.Millisecond) // Simulate healthy replication
+	if err := s.backend.ConfigureReplication(ctx, leaderIP, followerIP); err != nil {
+		return custom_errors.Wrapf(err, custom_errors.DatabaseError, "failed to configure %s replication", s.backend.Type())
+	}
+
 	storage.Replication.MasterNodeID = leaderIP
 	storage.Replication.ReplicaNodeID = followerIP
+	if state, err := s.backend.ReplicationState(ctx, leaderIP, followerIP); err == nil {
+		storage.ObserveFollower(state.Streaming, state.Lag, time.Now())
+	}
 
 	return s.storageRepo.Save(ctx, storage)
 }
 
 // IsReplicationHealthy checks the status of the replication.
 func (s *Service) IsReplicationHealthy(ctx context.Context) (bool, error) {
-	// In a real implementation, this would query the pg_stat_replication view on the leader
-	// and pg_stat_subscription on the follower.
-	// e.g., result := s.dbClient.Query(...)
-
 	storage, err := s.storageRepo.FindByID(ctx, "default")
 	if err != nil {
 		return false, custom_errors.Wrap(err, custom_errors.DatabaseError, "could not find storage config")
 	}
 
-	// For now, we use the state from our domain model.
-	// A real check would update this state.
-	return storage.IsReplicationHealthy(5 * time.Second), nil
+	// The state is kept current by ConfigureReplication and EnforceReplicationMode.
+	return storage.IsReplicationHealthy(replicationLagTolerance), nil
 }
 
-// Backup performs a backup of the database.
+// EnforceReplicationMode runs on the leader. It observes the follower and turns
+// synchronous replication on or off according to the configured mode.
+// The mode is always written on the first run against a primary, so a setting
+// left behind by an earlier run or configuration is cleared.
+// It returns the mode that is in effect afterwards.
+func (s *Service) EnforceReplicationMode(ctx context.Context, primaryIP, replicaIP string, cfg *types.ReplicationConfig) (types.ReplicationMode, error) {
+	storage, err := s.storageRepo.FindByID(ctx, "default")
+	if err != nil {
+		return "", custom_errors.Wrap(err, custom_errors.DatabaseError, "could not find storage config")
+	}
+
+	state, err := s.backend.ReplicationState(ctx, primaryIP, replicaIP)
+	if err != nil {
+		return "", custom_errors.Wrap(err, custom_errors.DatabaseError, "failed to query replication state")
+	}
+	now := time.Now()
+	storage.ObserveFollower(state.Streaming, state.Lag, now)
+
+	desired := cfg.EffectiveMode()
+	next := storage.NextReplicationMode(desired, cfg.DegradeTimeoutOrDefault(), replicationLagTolerance, now)
+	if next != storage.Replication.Mode || storage.Replication.ModeAppliedOn != primaryIP {
+		if err := s.backend.SetSynchronous(ctx, primaryIP, next == types.ReplicationModeSync); err != nil {
+			return storage.Replication.Mode, err
+		}
+		storage.Replication.ModeAppliedOn = primaryIP
+	}
+	storage.ApplyReplicationMode(desired, next)
+
+	if err := s.storageRepo.Save(ctx, storage); err != nil {
+		return next, custom_errors.Wrap(err, custom_errors.DatabaseError, "failed to save storage state")
+	}
+	return next, nil
+}
+
+// PromoteReplica makes the database on nodeIP the writable primary.
+func (s *Service) PromoteReplica(ctx context.Context, nodeIP string) error {
+	storage, err := s.storageRepo.FindByID(ctx, "default")
+	if err != nil {
+		return custom_errors.Wrap(err, custom_errors.DatabaseError, "could not find storage config")
+	}
+
+	if err := s.backend.Promote(ctx, nodeIP); err != nil {
+		return custom_errors.Wrapf(err, custom_errors.DatabaseError, "failed to promote %s database on %s", s.backend.Type(), nodeIP)
+	}
+
+	storage.Replication.MasterNodeID = nodeIP
+	storage.Replication.ReplicaNodeID = ""
+	storage.UpdateReplicationStatus(ReplicationInactive, 0)
+	return s.storageRepo.Save(ctx, storage)
+}
+
+// ReplicationState reports whether the follower is streaming from the primary, and its lag.
+func (s *Service) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*ReplicaState, error) {
+	state, err := s.backend.ReplicationState(ctx, primaryIP, replicaIP)
+	if err != nil {
+		return nil, custom_errors.Wrap(err, custom_errors.DatabaseError, "failed to query replication state")
+	}
+	return state, nil
+}
+
+// Backup performs a backup of the primary database to destination on the primary node.
 func (s *Service) Backup(ctx context.Context, destination string) error {
-	// 1. Connect to the primary database.
-	// 2. Use pg_dump or similar tool to create a backup.
-	// 3. Save the backup to the specified destination.
-	// 4. Verify the backup file.
-	return custom_errors.New(custom_errors.Unknown, "backup not implemented")
+	storage, err := s.storageRepo.FindByID(ctx, "default")
+	if err != nil {
+		return custom_errors.Wrap(err, custom_errors.DatabaseError, "could not find storage config")
+	}
+	if storage.Replication.MasterNodeID == "" {
+		return custom_errors.New(custom_errors.DatabaseError, "primary database node is not known")
+	}
+	return s.backend.Backup(ctx, storage.Replication.MasterNodeID, destination)
 }
 
 // Restore restores a backup of the database.
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// --- Mocks ---
+
+type mockStorageRepo struct {
+	storage *Storage
+}
+
+func (m *mockStorageRepo) Save(ctx context.Context, storage *Storage) error { return nil }
+func (m *mockStorageRepo) FindByID(ctx context.Context, id string) (*Storage, error) {
+	return m.storage, nil
+}
+
+type mockDBClient struct {
+	QueryFunc func(query string, args ...interface{}) (interface{}, error)
+	executed  []string
+}
+
+func (m *mockDBClient) Connect() error { return nil }
+func (m *mockDBClient) Close() error   { return nil }
+func (m *mockDBClient) Execute(query string, args ...interface{}) error {
+	m.executed = append(m.executed, query)
+	return nil
+}
+func (m *mockDBClient) Query(query string, args ...interface{}) (interface{}, error) {
+	return m.QueryFunc(query, args...)
+}
+
+type mockBackend struct {
+	state       *ReplicaState
+	synchronous []bool
+	promoted    []string
+}
+
+func (m *mockBackend) Type() string { return types.StorageTypePostgreSQL }
+func (m *mockBackend) KineEndpoint(user, password, address string) string {
+	return "mock://" + user + ":" + password + "@" + address
+}
+func (m *mockBackend) ConfigureReplication(ctx context.Context, l, f string) error { return nil }
+func (m *mockBackend) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*ReplicaState, error) {
+	return m.state, nil
+}
+func (m *mockBackend) SetSynchronous(ctx context.Context, primaryIP string, enabled bool) error {
+	m.synchronous = append(m.synchronous, enabled)
+	return nil
+}
+func (m *mockBackend) Promote(ctx context.Context, nodeIP string) error {
+	m.promoted = append(m.promoted, nodeIP)
+	return nil
+}
+func (m *mockBackend) Backup(ctx context.Context, nodeIP, destination string) error { return nil }
+
+func streaming(lag time.Duration) *ReplicaState {
+	return &ReplicaState{Streaming: true, Lag: lag}
+}
+
+// --- Tests ---
+
+func TestEnforceReplicationMode(t *testing.T) {
+	storage, _ := NewStorage("default", &PostgresConfig{}, &KineConfig{})
+	repo := &mockStorageRepo{storage: storage}
+	backend := &mockBackend{}
+	service := NewService(repo, backend)
+	cfg := &types.ReplicationConfig{
+		Mode:           types.ReplicationModeSync,
+		DegradeTimeout: &types.Duration{Duration: time.Minute},
+	}
+	ctx := context.Background()
+
+	t.Run("stays async until the follower streams", func(t *testing.T) {
+		backend.state = &ReplicaState{}
+		mode, err := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg)
+		if err != nil {
+			t.Fatalf("EnforceReplicationMode failed: %v", err)
+		}
+		if mode != types.ReplicationModeAsync {
+			t.Fatalf("expected async mode without a streaming follower, got %s", mode)
+		}
+		if len(backend.synchronous) != 1 || backend.synchronous[0] {
+			t.Errorf("expected synchronous replication to be cleared once, got %v", backend.synchronous)
+		}
+		if !storage.Replication.SyncDegraded {
+			t.Errorf("expected storage to be marked as sync degraded")
+		}
+	})
+
+	t.Run("enables sync while follower streams", func(t *testing.T) {
+		backend.synchronous = nil
+		backend.state = streaming(0)
+		mode, err := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg)
+		if err != nil {
+			t.Fatalf("EnforceReplicationMode failed: %v", err)
+		}
+		if mode != types.ReplicationModeSync {
+			t.Fatalf("expected sync mode, got %s", mode)
+		}
+		if len(backend.synchronous) != 1 || !backend.synchronous[0] {
+			t.Errorf("expected synchronous replication to be enabled, got %v", backend.synchronous)
+		}
+	})
+
+	t.Run("stays sync within the degrade timeout", func(t *testing.T) {
+		backend.synchronous = nil
+		backend.state = &ReplicaState{}
+		mode, _ := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg)
+		if mode != types.ReplicationModeSync {
+			t.Fatalf("expected sync mode, got %s", mode)
+		}
+		if len(backend.synchronous) != 0 {
+			t.Errorf("expected no configuration change, got %v", backend.synchronous)
+		}
+	})
+
+	t.Run("degrades to async after the timeout", func(t *testing.T) {
+		storage.Replication.FollowerDownSince = time.Now().Add(-2 * time.Minute)
+		mode, _ := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg)
+		if mode != types.ReplicationModeAsync {
+			t.Fatalf("expected async mode, got %s", mode)
+		}
+		if !storage.Replication.SyncDegraded {
+			t.Errorf("expected storage to be marked as sync degraded")
+		}
+		if healthy, _ := service.IsReplicationHealthy(ctx); healthy {
+			t.Errorf("expected degraded replication to be reported as unhealthy")
+		}
+	})
+
+	t.Run("waits for the follower to catch up", func(t *testing.T) {
+		backend.state = streaming(30 * time.Second)
+		if mode, _ := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg); mode != types.ReplicationModeAsync {
+			t.Fatalf("expected async mode while follower lags, got %s", mode)
+		}
+	})
+
+	t.Run("restores sync once caught up", func(t *testing.T) {
+		backend.state = streaming(100 * time.Millisecond)
+		if mode, _ := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", cfg); mode != types.ReplicationModeSync {
+			t.Fatalf("expected sync mode, got %s", mode)
+		}
+		if storage.Replication.SyncDegraded {
+			t.Errorf("expected sync degraded flag to be cleared")
+		}
+	})
+}
+
+func TestEnforceReplicationModeClearsStaleSync(t *testing.T) {
+	ctx := context.Background()
+	sync := &types.ReplicationConfig{Mode: types.ReplicationModeSync, DegradeTimeout: &types.Duration{Duration: time.Minute}}
+
+	t.Run("after a restart with the follower down", func(t *testing.T) {
+		// A fresh agent starts async in memory while the primary still carries
+		// synchronous_standby_names from the previous run.
+		storage, _ := NewStorage("default", &PostgresConfig{}, &KineConfig{})
+		backend := &mockBackend{state: &ReplicaState{}}
+		service := NewService(&mockStorageRepo{storage: storage}, backend)
+
+		mode, err := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", sync)
+		if err != nil {
+			t.Fatalf("EnforceReplicationMode failed: %v", err)
+		}
+		if mode != types.ReplicationModeAsync {
+			t.Fatalf("expected async mode, got %s", mode)
+		}
+		if len(backend.synchronous) != 1 || backend.synchronous[0] {
+			t.Fatalf("expected synchronous replication to be cleared, got %v", backend.synchronous)
+		}
+
+		backend.synchronous = nil
+		if _, err := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", sync); err != nil {
+			t.Fatalf("EnforceReplicationMode failed: %v", err)
+		}
+		if len(backend.synchronous) != 0 {
+			t.Errorf("expected no further change once applied, got %v", backend.synchronous)
+		}
+	})
+
+	t.Run("after switching the config to async", func(t *testing.T) {
+		storage, _ := NewStorage("default", &PostgresConfig{}, &KineConfig{})
+		storage.Replication.Mode = types.ReplicationModeSync
+		backend := &mockBackend{state: streaming(0)}
+		service := NewService(&mockStorageRepo{storage: storage}, backend)
+
+		mode, _ := service.EnforceReplicationMode(ctx, "10.0.0.1", "10.0.0.2", &types.ReplicationConfig{Mode: types.ReplicationModeAsync})
+		if mode != types.ReplicationModeAsync {
+			t.Fatalf("expected async mode, got %s", mode)
+		}
+		if len(backend.synchronous) != 1 || backend.synchronous[0] {
+			t.Errorf("expected synchronous replication to be cleared, got %v", backend.synchronous)
+		}
+	})
+
+	t.Run("on a newly promoted primary", func(t *testing.T) {
+		storage, _ := NewStorage("default", &PostgresConfig{}, &KineConfig{})
+		storage.Replication.Mode = types.ReplicationModeAsync
+		storage.Replication.ModeAppliedOn = "10.0.0.1"
+		backend := &mockBackend{state: &ReplicaState{}}
+		service := NewService(&mockStorageRepo{storage: storage}, backend)
+
+		if _, err := service.EnforceReplicationMode(ctx, "10.0.0.2", "10.0.0.1", sync); err != nil {
+			t.Fatalf("EnforceReplicationMode failed: %v", err)
+		}
+		if len(backend.synchronous) != 1 || backend.synchronous[0] {
+			t.Errorf("expected the mode to be written to the new primary, got %v", backend.synchronous)
+		}
+	})
+}
+
+func TestPromoteReplica(t *testing.T) {
+	storage, _ := NewStorage("default", &PostgresConfig{}, &KineConfig{})
+	storage.Replication.MasterNodeID = "10.0.0.1"
+	storage.Replication.ReplicaNodeID = "10.0.0.2"
+	backend := &mockBackend{}
+	service := NewService(&mockStorageRepo{storage: storage}, backend)
+
+	if err := service.PromoteReplica(context.Background(), "10.0.0.2"); err != nil {
+		t.Fatalf("PromoteReplica failed: %v", err)
+	}
+	if len(backend.promoted) != 1 || backend.promoted[0] != "10.0.0.2" {
+		t.Errorf("expected the backend to promote 10.0.0.2, got %v", backend.promoted)
+	}
+	if storage.Replication.MasterNodeID != "10.0.0.2" || storage.Replication.ReplicaNodeID != "" {
+		t.Errorf("expected 10.0.0.2 to be recorded as the only primary, got %+v", storage.Replication)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// NewBackend returns the storage backend selected by cfg.Type.
+// system returns the operator used to run database tools (dumps, sqlite3) on a node.
+func NewBackend(cfg types.StorageConfig, system func(nodeIP string) api.SystemOperator) (storage.Backend, error) {
+	switch cfg.BackendType() {
+	case types.StorageTypePostgreSQL:
+		return newPostgresBackend(cfg.Postgres.WithDefaults(), system), nil
+	case types.StorageTypeMySQL:
+		return newMySQLBackend(cfg.MySQL.WithDefaults(), system), nil
+	case types.StorageTypeSQLite:
+		return newSQLiteBackend(cfg.SQLite.PathOrDefault(), system), nil
+	default:
+		return nil, errors.Newf(errors.ConfigError, "unsupported storage type '%s'", cfg.Type)
+	}
+}
+
+// clientCache keeps one connected admin client per node.
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]api.DBClient
+	open    func(nodeIP string) api.DBClient
+}
+
+// get returns the connected client for the node, connecting on first use.
+func (c *clientCache) get(nodeIP string) (api.DBClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if db, ok := c.clients[nodeIP]; ok {
+		return db, nil
+	}
+	db := c.open(nodeIP)
+	if err := db.Connect(); err != nil {
+		return nil, err
+	}
+	if c.clients == nil {
+		c.clients = make(map[string]api.DBClient)
+	}
+	c.clients[nodeIP] = db
+	return db, nil
+}
+
+// firstRow returns the first row of a Query result, or nil if there is none.
+func firstRow(res interface{}) map[string]interface{} {
+	rows, _ := res.([]map[string]interface{})
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"os"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// credentialFileStore implements storage.CredentialStore on a local file.
+// The file is only ever readable by its owner.
+type credentialFileStore struct {
+	path string
+}
+
+// NewCredentialFileStore creates a credential store backed by the file at path.
+func NewCredentialFileStore(path string) storage.CredentialStore {
+	return &credentialFileStore{path: path}
+}
+
+// Load reads the stored credentials. It returns nil, nil if the file does not exist yet.
+func (s *credentialFileStore) Load() (*storage.Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.IOError, "failed to read credentials file: %s", s.path)
+	}
+	var creds storage.Credentials
+	if err := yaml.Unmarshal(data, &creds); err != nil {
+		return nil, errors.Wrapf(err, errors.ConfigError, "failed to parse credentials file: %s", s.path)
+	}
+	return &creds, nil
+}
+
+// Save replaces the stored credentials. The new file is written next to the old
+// one and renamed over it, so a crash never leaves a half-written file behind.
+func (s *credentialFileStore) Save(creds *storage.Credentials) error {
+	data, err := yaml.Marshal(creds)
+	if err != nil {
+		return errors.Wrap(err, errors.ConfigError, "failed to marshal credentials")
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to write credentials file: %s", tmp)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to replace credentials file: %s", s.path)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to read while the logger writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// fakeKine writes a shell script standing in for the kine binary.
+func fakeKine(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "kine")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake kine: %v", err)
+	}
+	return path
+}
+
+func newTestKineManager(out *syncBuffer) *KineManager {
+	m := NewKineManager(logger.NewLogger("debug", out, "json"))
+	m.InitialBackoff = 10 * time.Millisecond
+	m.MaxBackoff = 20 * time.Millisecond
+	m.StopTimeout = 200 * time.Millisecond
+	m.ProbeInterval = time.Hour
+	m.probe = func(ctx context.Context, target string) error { return nil }
+	return m
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKineManagerRestartsAndForwardsOutput(t *testing.T) {
+	out := &syncBuffer{}
+	m := newTestKineManager(out)
+	kine := fakeKine(t, "echo listening on $4\necho boom >&2\nexit 1\n")
+
+	if err := m.Start(context.Background(), kine, "sqlite://", "127.0.0.1:2379"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "two restarts", func() bool { return m.Status().Restarts >= 2 })
+	if err := m.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	logs := out.String()
+	if !strings.Contains(logs, `"msg":"listening on 127.0.0.1:2379"`) || !strings.Contains(logs, `"stream":"stdout"`) {
+		t.Errorf("expected stdout to be forwarded with fields, got %s", logs)
+	}
+	if !strings.Contains(logs, `"msg":"boom"`) || !strings.Contains(logs, `"stream":"stderr"`) {
+		t.Errorf("expected stderr to be forwarded with fields, got %s", logs)
+	}
+	if status := m.Status(); status.Active || status.Name != KineServiceStatusName {
+		t.Errorf("expected stopped kine status, got %+v", status)
+	}
+}
+
+func TestKineManagerStop(t *testing.T) {
+	t.Run("graceful", func(t *testing.T) {
+		out := &syncBuffer{}
+		m := newTestKineManager(out)
+		kine := fakeKine(t, "trap 'echo terminated; exit 0' TERM\necho ready\nwhile true; do sleep 0.05; done\n")
+		if err := m.Start(context.Background(), kine, "sqlite://", ""); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		waitFor(t, "kine to start", func() bool { return strings.Contains(out.String(), "ready") })
+		if err := m.Stop(); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "terminated") {
+			t.Errorf("expected kine to handle SIGTERM, got %s", out.String())
+		}
+		if strings.Contains(out.String(), "SIGKILL") {
+			t.Errorf("expected no SIGKILL for a process that exits on SIGTERM")
+		}
+		if m.IsRunning() || m.Status().Restarts != 0 {
+			t.Errorf("expected kine to be stopped without restarts, got %+v", m.Status())
+		}
+	})
+
+	t.Run("kills after the timeout", func(t *testing.T) {
+		out := &syncBuffer{}
+		m := newTestKineManager(out)
+		kine := fakeKine(t, "trap '' TERM\necho ready\nwhile true; do sleep 0.05; done\n")
+		if err := m.Start(context.Background(), kine, "sqlite://", ""); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		waitFor(t, "kine to start", func() bool { return strings.Contains(out.String(), "ready") })
+		if err := m.Stop(); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "SIGKILL") {
+			t.Errorf("expected SIGKILL after the stop timeout, got %s", out.String())
+		}
+	})
+}
+
+func TestKineManagerStartsAgainAfterContextCancelled(t *testing.T) {
+	out := &syncBuffer{}
+	m := newTestKineManager(out)
+	kine := fakeKine(t, "echo ready\nwhile true; do sleep 0.05; done\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.Start(ctx, kine, "sqlite://", ""); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "kine to start", func() bool { return strings.Contains(out.String(), "ready") })
+	cancel()
+	waitFor(t, "the supervisor to exit", func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.done == nil
+	})
+	if m.IsRunning() {
+		t.Errorf("expected kine to be stopped with its context")
+	}
+
+	if err := m.Start(context.Background(), kine, "sqlite://", ""); err != nil {
+		t.Fatalf("second Start failed: %v", err)
+	}
+	defer m.Stop()
+	waitFor(t, "kine to start again", func() bool { return strings.Count(out.String(), `"msg":"ready"`) == 2 })
+	if strings.Contains(out.String(), "already running") {
+		t.Errorf("expected the second Start to launch kine, got %s", out.String())
+	}
+}
+
+func TestKineManagerRestartsUnhealthyProcess(t *testing.T) {
+	out := &syncBuffer{}
+	m := newTestKineManager(out)
+	m.ProbeInterval = 10 * time.Millisecond
+	var probes sync.Map
+	m.probe = func(ctx context.Context, target string) error {
+		probes.Store(target, true)
+		return context.DeadlineExceeded
+	}
+	kine := fakeKine(t, "while true; do sleep 0.05; done\n")
+
+	if err := m.Start(context.Background(), kine, "sqlite://", "tcp://0.0.0.0:2379"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "a restart", func() bool { return m.Status().Restarts >= 1 })
+	m.Stop()
+
+	if _, ok := probes.Load("127.0.0.1:2379"); !ok {
+		t.Errorf("expected the probe to target 127.0.0.1:2379")
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+)
+
+// memoryStorageRepository is an in-process storage.Repository.
+// The agent uses it for state it re-observes from the database on every tick.
+type memoryStorageRepository struct {
+	mu      sync.Mutex
+	storage map[string]*storage.Storage
+}
+
+// NewMemoryStorageRepository creates a storage repository that keeps entities in memory.
+// Any ID that is looked up for the first time starts out as an empty Storage entity.
+func NewMemoryStorageRepository() storage.Repository {
+	return &memoryStorageRepository{
+		storage: make(map[string]*storage.Storage),
+	}
+}
+
+// Save stores the entity.
+func (r *memoryStorageRepository) Save(ctx context.Context, s *storage.Storage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.storage[s.ID] = s
+	return nil
+}
+
+// FindByID returns the entity with the given ID, creating it on first use.
+func (r *memoryStorageRepository) FindByID(ctx context.Context, id string) (*storage.Storage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.storage[id]; ok {
+		return s, nil
+	}
+	s, err := storage.NewStorage(id, &storage.PostgresConfig{}, &storage.KineConfig{})
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.DatabaseError, "failed to create storage entity %s", id)
+	}
+	r.storage[id] = s
+	return s, nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql" // registers the "mysql" driver
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+)
+
+// mysqlClient implements the api.DBClient interface for MySQL and MariaDB.
+type mysqlClient struct {
+	db  *sql.DB
+	dsn string
+}
+
+// NewMySQLClient creates a new MySQL client.
+// The DSN should be in the go-sql-driver format: "user:password@tcp(host:port)/database"
+func NewMySQLClient(dsn string) api.DBClient {
+	return &mysqlClient{
+		dsn: dsn,
+	}
+}
+
+// Connect opens the connection pool and checks the server is reachable.
+func (c *mysqlClient) Connect() error {
+	db, err := sql.Open("mysql", c.dsn)
+	if err != nil {
+		return errors.Wrap(err, errors.DatabaseError, "failed to open mysql connection")
+	}
+	if err := db.PingContext(context.Background()); err != nil {
+		db.Close()
+		return errors.Wrap(err, errors.DatabaseError, "failed to connect to mysql")
+	}
+	c.db = db
+	return nil
+}
+
+// Close closes the connection pool.
+func (c *mysqlClient) Close() error {
+	if c.db != nil {
+		return c.db.Close()
+	}
+	return nil
+}
+
+// Execute runs a statement that does not return rows.
+func (c *mysqlClient) Execute(query string, args ...interface{}) error {
+	if c.db == nil {
+		return errors.New(errors.DatabaseError, "database connection is not initialized")
+	}
+	if _, err := c.db.ExecContext(context.Background(), query, args...); err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "failed to execute query")
+	}
+	return nil
+}
+
+// Query runs a statement that returns rows, such as SELECT or SHOW.
+// The rows are returned as a []map[string]interface{} keyed by column name;
+// text values are returned as strings.
+func (c *mysqlClient) Query(query string, args ...interface{}) (interface{}, error) {
+	if c.db == nil {
+		return nil, errors.New(errors.DatabaseError, "database connection is not initialized")
+	}
+	rows, err := c.db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.DatabaseError, "failed to execute query")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.DatabaseError, "failed to read columns")
+	}
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, errors.Wrap(err, errors.DatabaseError, "failed to read row")
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.DatabaseError, "failed to iterate rows")
+	}
+	return result, nil
+}
+
+//Personal.AI order the ending
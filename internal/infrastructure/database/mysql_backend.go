@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// mysqlBackend implements storage.Backend with MySQL or MariaDB GTID replication.
+// The replication statements differ between the two, so the flavor is detected
+// from the server version.
+type mysqlBackend struct {
+	cfg    types.MySQLConfig
+	system func(nodeIP string) api.SystemOperator
+	dbs    clientCache
+}
+
+// newMySQLBackend creates a MySQL backend. Admin connections use cfg.AdminUser
+// and take the password from MYSQL_PWD.
+func newMySQLBackend(cfg types.MySQLConfig, system func(nodeIP string) api.SystemOperator) *mysqlBackend {
+	b := &mysqlBackend{cfg: cfg, system: system}
+	b.dbs.open = func(nodeIP string) api.DBClient {
+		return NewMySQLClient(b.dsn(cfg.AdminUser, os.Getenv("MYSQL_PWD"), net.JoinHostPort(nodeIP, strconv.Itoa(cfg.Port))))
+	}
+	return b
+}
+
+// dsn builds a go-sql-driver DSN.
+func (b *mysqlBackend) dsn(user, password, address string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?tls=%s", user, password, address, b.cfg.Database, b.cfg.TLS)
+}
+
+// Type returns the storage type.
+func (b *mysqlBackend) Type() string {
+	return types.StorageTypeMySQL
+}
+
+// KineEndpoint returns the mysql:// endpoint for Kine.
+func (b *mysqlBackend) KineEndpoint(user, password, address string) string {
+	return "mysql://" + b.dsn(user, password, address)
+}
+
+// isMariaDB reports whether the server is MariaDB rather than MySQL.
+func isMariaDB(db api.DBClient) (bool, error) {
+	res, err := db.Query("SELECT VERSION() AS version")
+	if err != nil {
+		return false, err
+	}
+	version, _ := firstRow(res)["version"].(string)
+	return strings.Contains(strings.ToLower(version), "mariadb"), nil
+}
+
+// ConfigureReplication points the follower at the leader using GTID auto-positioning
+// and makes the follower read-only, so nothing but replication writes to it.
+func (b *mysqlBackend) ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error {
+	leader, err := b.dbs.get(leaderIP)
+	if err != nil {
+		return err
+	}
+	mariadb, err := isMariaDB(leader)
+	if err != nil {
+		return err
+	}
+	if !mariadb {
+		// MariaDB always records GTIDs; MySQL needs them switched on explicitly.
+		res, err := leader.Query("SELECT @@GLOBAL.gtid_mode AS gtid_mode")
+		if err != nil {
+			return err
+		}
+		if mode, _ := firstRow(res)["gtid_mode"].(string); mode != "ON" {
+			return errors.Newf(errors.DatabaseError, "gtid_mode must be ON on %s, got '%s'", leaderIP, mode)
+		}
+	}
+
+	follower, err := b.dbs.get(followerIP)
+	if err != nil {
+		return err
+	}
+	host, port, user, password := quoteLiteral(leaderIP), b.cfg.Port, quoteLiteral(b.cfg.AdminUser), quoteLiteral(os.Getenv("MYSQL_PWD"))
+	var statements []string
+	if mariadb {
+		statements = []string{
+			"STOP SLAVE",
+			fmt.Sprintf("CHANGE MASTER TO MASTER_HOST=%s, MASTER_PORT=%d, MASTER_USER=%s, MASTER_PASSWORD=%s, MASTER_USE_GTID=slave_pos",
+				host, port, user, password),
+			"START SLAVE",
+			"SET GLOBAL read_only = ON",
+		}
+	} else {
+		statements = []string{
+			"STOP REPLICA",
+			fmt.Sprintf("CHANGE REPLICATION SOURCE TO SOURCE_HOST=%s, SOURCE_PORT=%d, SOURCE_USER=%s, SOURCE_PASSWORD=%s, SOURCE_AUTO_POSITION=1",
+				host, port, user, password),
+			"START REPLICA",
+			"SET GLOBAL super_read_only = ON",
+		}
+	}
+	for _, stmt := range statements {
+		if err := follower.Execute(stmt); err != nil {
+			return errors.Wrapf(err, errors.DatabaseError, "failed to configure replication on %s", followerIP)
+		}
+	}
+	return nil
+}
+
+// ReplicationState reads the replica's status. MySQL only knows the lag on the
+// replica side, so an unreachable replica is reported as not streaming.
+func (b *mysqlBackend) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	db, err := b.dbs.get(replicaIP)
+	if err != nil {
+		return &storage.ReplicaState{}, nil
+	}
+	mariadb, err := isMariaDB(db)
+	if err != nil {
+		return &storage.ReplicaState{}, nil
+	}
+	query, prefix, lagColumn := "SHOW REPLICA STATUS", "Replica", "Seconds_Behind_Source"
+	if mariadb {
+		query, prefix, lagColumn = "SHOW SLAVE STATUS", "Slave", "Seconds_Behind_Master"
+	}
+	res, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	row := firstRow(res)
+	if row == nil {
+		return &storage.ReplicaState{}, nil
+	}
+	io, _ := row[prefix+"_IO_Running"].(string)
+	sqlThread, _ := row[prefix+"_SQL_Running"].(string)
+	// The lag is NULL while the replica is not connected to its source, so
+	// there is nothing known about how far behind it is.
+	lag, ok := row[lagColumn].(string)
+	seconds, _ := strconv.Atoi(lag)
+	return &storage.ReplicaState{
+		Streaming: ok && io == "Yes" && sqlThread == "Yes",
+		Lag:       time.Duration(seconds) * time.Second,
+	}, nil
+}
+
+// SetSynchronous is not supported; only async GTID replication is managed for MySQL.
+func (b *mysqlBackend) SetSynchronous(ctx context.Context, primaryIP string, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	return errors.New(errors.DatabaseError, "synchronous replication is not supported with the mysql backend")
+}
+
+// Promote stops replication on the node, forgets the old source and makes it writable.
+func (b *mysqlBackend) Promote(ctx context.Context, nodeIP string) error {
+	db, err := b.dbs.get(nodeIP)
+	if err != nil {
+		return err
+	}
+	mariadb, err := isMariaDB(db)
+	if err != nil {
+		return err
+	}
+	statements := []string{"STOP REPLICA", "RESET REPLICA ALL", "SET GLOBAL super_read_only = OFF", "SET GLOBAL read_only = OFF"}
+	if mariadb {
+		statements = []string{"STOP SLAVE", "RESET SLAVE ALL", "SET GLOBAL read_only = OFF"}
+	}
+	for _, stmt := range statements {
+		if err := db.Execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backup runs mysqldump on the node with a consistent snapshot.
+func (b *mysqlBackend) Backup(ctx context.Context, nodeIP, destination string) error {
+	_, err := b.system(nodeIP).RunCommand("mysqldump", "--single-transaction", "--routines",
+		"--result-file="+destination, "--user="+b.cfg.AdminUser, "--port="+strconv.Itoa(b.cfg.Port),
+		"--host=127.0.0.1", b.cfg.Database)
+	if err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "mysqldump failed on %s", nodeIP)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
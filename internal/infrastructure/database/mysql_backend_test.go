@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// fakeDB is an api.DBClient that records statements and answers queries from rows.
+type fakeDB struct {
+	version  string
+	rows     map[string][]map[string]interface{}
+	executed []string
+}
+
+func (f *fakeDB) Connect() error { return nil }
+func (f *fakeDB) Close() error   { return nil }
+func (f *fakeDB) Execute(query string, args ...interface{}) error {
+	f.executed = append(f.executed, query)
+	return nil
+}
+func (f *fakeDB) Query(query string, args ...interface{}) (interface{}, error) {
+	if strings.HasPrefix(query, "SELECT VERSION()") {
+		return []map[string]interface{}{{"version": f.version}}, nil
+	}
+	return f.rows[query], nil
+}
+
+// fakeSystem is an api.SystemOperator that records the commands it runs.
+type fakeSystem struct {
+	commands [][]string
+}
+
+func (f *fakeSystem) RunCommand(command string, args ...string) (string, error) {
+	f.commands = append(f.commands, append([]string{command}, args...))
+	return "", nil
+}
+func (f *fakeSystem) WriteFile(path string, content []byte, perm os.FileMode) error { return nil }
+func (f *fakeSystem) ReadFile(path string) ([]byte, error)                          { return nil, nil }
+
+const (
+	mysqlVersion   = "8.0.36"
+	mariadbVersion = "10.11.6-MariaDB-log"
+)
+
+// newTestMySQLBackend returns a backend whose nodes are served by dbs.
+func newTestMySQLBackend(dbs map[string]*fakeDB, system *fakeSystem) *mysqlBackend {
+	b := newMySQLBackend((*types.MySQLConfig)(nil).WithDefaults(), func(string) api.SystemOperator { return system })
+	b.dbs.open = func(nodeIP string) api.DBClient { return dbs[nodeIP] }
+	return b
+}
+
+func TestMySQLConfigureReplication(t *testing.T) {
+	t.Setenv("MYSQL_PWD", "s3cr'et")
+
+	t.Run("mysql", func(t *testing.T) {
+		leader := &fakeDB{version: mysqlVersion, rows: map[string][]map[string]interface{}{
+			"SELECT @@GLOBAL.gtid_mode AS gtid_mode": {{"gtid_mode": "ON"}},
+		}}
+		follower := &fakeDB{version: mysqlVersion}
+		b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.1": leader, "10.0.0.2": follower}, nil)
+
+		if err := b.ConfigureReplication(context.Background(), "10.0.0.1", "10.0.0.2"); err != nil {
+			t.Fatalf("ConfigureReplication failed: %v", err)
+		}
+		want := []string{
+			"STOP REPLICA",
+			"CHANGE REPLICATION SOURCE TO SOURCE_HOST='10.0.0.1', SOURCE_PORT=3306, SOURCE_USER='root', SOURCE_PASSWORD='s3cr''et', SOURCE_AUTO_POSITION=1",
+			"START REPLICA",
+			"SET GLOBAL super_read_only = ON",
+		}
+		if !reflect.DeepEqual(follower.executed, want) {
+			t.Errorf("unexpected statements on the follower:\n got %q\nwant %q", follower.executed, want)
+		}
+		if len(leader.executed) != 0 {
+			t.Errorf("expected nothing to run on the leader, got %q", leader.executed)
+		}
+	})
+
+	t.Run("mysql without gtid", func(t *testing.T) {
+		leader := &fakeDB{version: mysqlVersion, rows: map[string][]map[string]interface{}{
+			"SELECT @@GLOBAL.gtid_mode AS gtid_mode": {{"gtid_mode": "OFF"}},
+		}}
+		follower := &fakeDB{version: mysqlVersion}
+		b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.1": leader, "10.0.0.2": follower}, nil)
+
+		if err := b.ConfigureReplication(context.Background(), "10.0.0.1", "10.0.0.2"); err == nil {
+			t.Fatal("expected an error when gtid_mode is not ON")
+		}
+		if len(follower.executed) != 0 {
+			t.Errorf("expected the follower to be left alone, got %q", follower.executed)
+		}
+	})
+
+	t.Run("mariadb", func(t *testing.T) {
+		leader := &fakeDB{version: mariadbVersion}
+		follower := &fakeDB{version: mariadbVersion}
+		b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.1": leader, "10.0.0.2": follower}, nil)
+
+		if err := b.ConfigureReplication(context.Background(), "10.0.0.1", "10.0.0.2"); err != nil {
+			t.Fatalf("ConfigureReplication failed: %v", err)
+		}
+		want := []string{
+			"STOP SLAVE",
+			"CHANGE MASTER TO MASTER_HOST='10.0.0.1', MASTER_PORT=3306, MASTER_USER='root', MASTER_PASSWORD='s3cr''et', MASTER_USE_GTID=slave_pos",
+			"START SLAVE",
+			"SET GLOBAL read_only = ON",
+		}
+		if !reflect.DeepEqual(follower.executed, want) {
+			t.Errorf("unexpected statements on the follower:\n got %q\nwant %q", follower.executed, want)
+		}
+	})
+}
+
+func TestMySQLPromote(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{mysqlVersion, []string{"STOP REPLICA", "RESET REPLICA ALL", "SET GLOBAL super_read_only = OFF", "SET GLOBAL read_only = OFF"}},
+		{mariadbVersion, []string{"STOP SLAVE", "RESET SLAVE ALL", "SET GLOBAL read_only = OFF"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			db := &fakeDB{version: tt.version}
+			b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.2": db}, nil)
+			if err := b.Promote(context.Background(), "10.0.0.2"); err != nil {
+				t.Fatalf("Promote failed: %v", err)
+			}
+			if !reflect.DeepEqual(db.executed, tt.want) {
+				t.Errorf("unexpected statements:\n got %q\nwant %q", db.executed, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLReplicationState(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		query     string
+		row       map[string]interface{}
+		streaming bool
+		lag       time.Duration
+	}{
+		{
+			name:    "mysql streaming",
+			version: mysqlVersion,
+			query:   "SHOW REPLICA STATUS",
+			row: map[string]interface{}{
+				"Replica_IO_Running": "Yes", "Replica_SQL_Running": "Yes", "Seconds_Behind_Source": "3",
+			},
+			streaming: true,
+			lag:       3 * time.Second,
+		},
+		{
+			name:    "mysql io thread stopped",
+			version: mysqlVersion,
+			query:   "SHOW REPLICA STATUS",
+			row: map[string]interface{}{
+				"Replica_IO_Running": "Connecting", "Replica_SQL_Running": "Yes", "Seconds_Behind_Source": nil,
+			},
+		},
+		{
+			name:    "mariadb streaming",
+			version: mariadbVersion,
+			query:   "SHOW SLAVE STATUS",
+			row: map[string]interface{}{
+				"Slave_IO_Running": "Yes", "Slave_SQL_Running": "Yes", "Seconds_Behind_Master": "0",
+			},
+			streaming: true,
+		},
+		{
+			name:    "mariadb lag unknown",
+			version: mariadbVersion,
+			query:   "SHOW SLAVE STATUS",
+			row: map[string]interface{}{
+				"Slave_IO_Running": "Yes", "Slave_SQL_Running": "Yes", "Seconds_Behind_Master": nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replica := &fakeDB{version: tt.version, rows: map[string][]map[string]interface{}{tt.query: {tt.row}}}
+			b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.2": replica}, nil)
+
+			state, err := b.ReplicationState(context.Background(), "10.0.0.1", "10.0.0.2")
+			if err != nil {
+				t.Fatalf("ReplicationState failed: %v", err)
+			}
+			if state.Streaming != tt.streaming || state.Lag != tt.lag {
+				t.Errorf("expected streaming=%v lag=%s, got streaming=%v lag=%s", tt.streaming, tt.lag, state.Streaming, state.Lag)
+			}
+		})
+	}
+
+	t.Run("not a replica", func(t *testing.T) {
+		b := newTestMySQLBackend(map[string]*fakeDB{"10.0.0.2": {version: mysqlVersion}}, nil)
+		state, err := b.ReplicationState(context.Background(), "10.0.0.1", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("ReplicationState failed: %v", err)
+		}
+		if state.Streaming {
+			t.Error("expected a server without replica status not to be streaming")
+		}
+	})
+}
+
+func TestMySQLSetSynchronous(t *testing.T) {
+	b := newTestMySQLBackend(nil, nil)
+	if err := b.SetSynchronous(context.Background(), "10.0.0.1", false); err != nil {
+		t.Errorf("expected turning sync off to succeed, got %v", err)
+	}
+	if err := b.SetSynchronous(context.Background(), "10.0.0.1", true); err == nil {
+		t.Error("expected turning sync on to fail")
+	}
+}
+
+func TestMySQLBackup(t *testing.T) {
+	system := &fakeSystem{}
+	b := newTestMySQLBackend(nil, system)
+	if err := b.Backup(context.Background(), "10.0.0.1", "/var/backups/kine.sql"); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	want := [][]string{{"mysqldump", "--single-transaction", "--routines", "--result-file=/var/backups/kine.sql",
+		"--user=root", "--port=3306", "--host=127.0.0.1", "kubernetes"}}
+	if !reflect.DeepEqual(system.commands, want) {
+		t.Errorf("unexpected commands:\n got %q\nwant %q", system.commands, want)
+	}
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// postgresBackend implements storage.Backend with PostgreSQL logical replication.
+type postgresBackend struct {
+	cfg    types.PostgresConfig
+	system func(nodeIP string) api.SystemOperator
+	dbs    clientCache
+}
+
+// newPostgresBackend creates a PostgreSQL backend. Admin connections use
+// cfg.AdminUser and take the password from PGPASSWORD.
+func newPostgresBackend(cfg types.PostgresConfig, system func(nodeIP string) api.SystemOperator) *postgresBackend {
+	b := &postgresBackend{cfg: cfg, system: system}
+	b.dbs.open = func(nodeIP string) api.DBClient {
+		return NewPostgresClient(fmt.Sprintf("postgres://%s@%s/%s?sslmode=%s",
+			cfg.AdminUser, net.JoinHostPort(nodeIP, strconv.Itoa(cfg.Port)), cfg.Database, cfg.SSLMode))
+	}
+	return b
+}
+
+// Type returns the storage type.
+func (b *postgresBackend) Type() string {
+	return types.StorageTypePostgreSQL
+}
+
+// KineEndpoint returns the postgres:// endpoint for Kine.
+func (b *postgresBackend) KineEndpoint(user, password, address string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s", user, password, address, b.cfg.Database, b.cfg.SSLMode)
+}
+
+// ConfigureReplication publishes all tables on the leader and subscribes to them on the follower.
+func (b *postgresBackend) ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error {
+	leader, err := b.dbs.get(leaderIP)
+	if err != nil {
+		return err
+	}
+	err = leader.Execute(fmt.Sprintf("DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_publication WHERE pubname = '%s') THEN "+
+		"CREATE PUBLICATION %s FOR ALL TABLES; END IF; END $$", storage.PublicationName, storage.PublicationName))
+	if err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "failed to create publication on %s", leaderIP)
+	}
+
+	follower, err := b.dbs.get(followerIP)
+	if err != nil {
+		return err
+	}
+	res, err := follower.Query("SELECT subname FROM pg_subscription WHERE subname = $1", storage.SubscriptionName)
+	if err != nil {
+		return err
+	}
+	if firstRow(res) != nil {
+		return nil
+	}
+	// The initial subscription uses the admin role; 'storage rotate-credentials'
+	// moves it to a dedicated replication role.
+	conn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s sslmode=%s", leaderIP, b.cfg.Port, b.cfg.Database, b.cfg.AdminUser, b.cfg.SSLMode)
+	if password := os.Getenv("PGPASSWORD"); password != "" {
+		conn += " password=" + password
+	}
+	// CREATE SUBSCRIPTION cannot run inside a DO block, so the name is checked above.
+	err = follower.Execute(fmt.Sprintf("CREATE SUBSCRIPTION %s CONNECTION %s PUBLICATION %s",
+		storage.SubscriptionName, quoteLiteral(conn), storage.PublicationName))
+	if err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "failed to create subscription on %s", followerIP)
+	}
+	return nil
+}
+
+// ReplicationState reads the subscription's walsender from pg_stat_replication on the primary.
+func (b *postgresBackend) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	db, err := b.dbs.get(primaryIP)
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Query(`SELECT state, COALESCE(EXTRACT(EPOCH FROM replay_lag), 0)::float8 AS lag_seconds
+		FROM pg_stat_replication WHERE application_name = $1`, storage.SubscriptionName)
+	if err != nil {
+		return nil, err
+	}
+	row := firstRow(res)
+	if row == nil {
+		return &storage.ReplicaState{}, nil
+	}
+	state, _ := row["state"].(string)
+	seconds, _ := row["lag_seconds"].(float64)
+	return &storage.ReplicaState{
+		Streaming: state == "streaming",
+		Lag:       time.Duration(seconds * float64(time.Second)),
+	}, nil
+}
+
+// SetSynchronous points synchronous_standby_names at the subscription, or clears it.
+// The subscription's walsender reports the subscription name as its application_name.
+func (b *postgresBackend) SetSynchronous(ctx context.Context, primaryIP string, enabled bool) error {
+	db, err := b.dbs.get(primaryIP)
+	if err != nil {
+		return err
+	}
+	standbys := ""
+	if enabled {
+		standbys = storage.SubscriptionName
+	}
+	// ALTER SYSTEM does not accept bind parameters; standbys is always one of our own constants.
+	if err := db.Execute(fmt.Sprintf("ALTER SYSTEM SET synchronous_standby_names = '%s'", standbys)); err != nil {
+		return errors.Wrap(err, errors.DatabaseError, "failed to set synchronous_standby_names")
+	}
+	if err := db.Execute("SELECT pg_reload_conf()"); err != nil {
+		return errors.Wrap(err, errors.DatabaseError, "failed to reload postgres configuration")
+	}
+	return nil
+}
+
+// Promote drops the subscription on the node and publishes its tables instead.
+// Logical replication does not carry sequences, so Kine's id sequence is moved
+// past the highest replicated id.
+func (b *postgresBackend) Promote(ctx context.Context, nodeIP string) error {
+	db, err := b.dbs.get(nodeIP)
+	if err != nil {
+		return err
+	}
+	res, err := db.Query("SELECT subname FROM pg_subscription WHERE subname = $1", storage.SubscriptionName)
+	if err != nil {
+		return err
+	}
+	var statements []string
+	if firstRow(res) != nil {
+		// The old primary may be unreachable, so detach the slot instead of dropping it remotely.
+		statements = append(statements,
+			fmt.Sprintf("ALTER SUBSCRIPTION %s DISABLE", storage.SubscriptionName),
+			fmt.Sprintf("ALTER SUBSCRIPTION %s SET (slot_name = NONE)", storage.SubscriptionName),
+			fmt.Sprintf("DROP SUBSCRIPTION %s", storage.SubscriptionName),
+		)
+	}
+	statements = append(statements,
+		"DO $$ BEGIN IF to_regclass('kine_id_seq') IS NOT NULL THEN "+
+			"PERFORM setval('kine_id_seq', COALESCE((SELECT MAX(id) FROM kine), 0) + 1, false); END IF; END $$",
+		fmt.Sprintf("DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_publication WHERE pubname = '%s') THEN "+
+			"CREATE PUBLICATION %s FOR ALL TABLES; END IF; END $$", storage.PublicationName, storage.PublicationName),
+	)
+	for _, stmt := range statements {
+		if err := db.Execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backup runs pg_dump on the node, writing a compressed custom-format archive.
+func (b *postgresBackend) Backup(ctx context.Context, nodeIP, destination string) error {
+	_, err := b.system(nodeIP).RunCommand("pg_dump", "--format=custom", "--file="+destination,
+		"--username="+b.cfg.AdminUser, "--port="+fmt.Sprint(b.cfg.Port), b.cfg.Database)
+	if err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "pg_dump failed on %s", nodeIP)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -53,7 +53,7 @@ func (c *postgresClient) Execute(query string, args ...interface{}) error {
 }
 
 // Query runs a command on the database that is expected to return rows (e.g., SELECT).
-// This is a simplified implementation. A real one would handle row scanning.
+// The rows are returned as a []map[string]interface{} keyed by column name.
 func (c *postgresClient) Query(query string, args ...interface{}) (interface{}, error) {
 	if c.connPool == nil {
 		return nil, errors.New(errors.DatabaseError, "database connection is not initialized")
@@ -64,9 +64,23 @@ func (c *postgresClient) Query(query string, args ...interface{}) (interface{},
 	}
 	defer rows.Close()
 
-	// A real implementation would scan the rows into a struct.
-	// For now, we just return the raw rows object as a placeholder.
-	return rows, nil
+	fields := rows.FieldDescriptions()
+	var result []map[string]interface{}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.DatabaseError, "failed to read row")
+		}
+		row := make(map[string]interface{}, len(fields))
+		for i, f := range fields {
+			row[string(f.Name)] = values[i]
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.DatabaseError, "failed to iterate rows")
+	}
+	return result, nil
 }
 
 //Personal.AI order the ending
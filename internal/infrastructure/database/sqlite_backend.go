@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// sqliteBackend implements storage.Backend for single-node dev and lab clusters.
+// Kine opens the database file itself, so there is no server to connect to and
+// nothing to replicate.
+type sqliteBackend struct {
+	path   string
+	system func(nodeIP string) api.SystemOperator
+}
+
+// newSQLiteBackend creates a SQLite backend for the database file at path.
+func newSQLiteBackend(path string, system func(nodeIP string) api.SystemOperator) *sqliteBackend {
+	return &sqliteBackend{path: path, system: system}
+}
+
+// Type returns the storage type.
+func (b *sqliteBackend) Type() string {
+	return types.StorageTypeSQLite
+}
+
+// KineEndpoint returns the sqlite:// endpoint for Kine. SQLite has no users,
+// and the file is always local, so all arguments are ignored.
+func (b *sqliteBackend) KineEndpoint(user, password, address string) string {
+	return "sqlite://" + b.path + "?_journal=WAL&cache=shared"
+}
+
+// ConfigureReplication always fails: SQLite clusters have a single node.
+func (b *sqliteBackend) ConfigureReplication(ctx context.Context, leaderIP, followerIP string) error {
+	return errors.New(errors.DatabaseError, "the sqlite backend does not support replication")
+}
+
+// ReplicationState reports no follower.
+func (b *sqliteBackend) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	return &storage.ReplicaState{}, nil
+}
+
+// SetSynchronous is not supported.
+func (b *sqliteBackend) SetSynchronous(ctx context.Context, primaryIP string, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	return errors.New(errors.DatabaseError, "synchronous replication is not supported with the sqlite backend")
+}
+
+// Promote is a no-op; the only node is always the primary.
+func (b *sqliteBackend) Promote(ctx context.Context, nodeIP string) error {
+	return nil
+}
+
+// Backup uses the sqlite3 online backup, which is safe while Kine is writing.
+func (b *sqliteBackend) Backup(ctx context.Context, nodeIP, destination string) error {
+	if _, err := b.system(nodeIP).RunCommand("sqlite3", b.path, ".backup "+quoteLiteral(destination)); err != nil {
+		return errors.Wrapf(err, errors.DatabaseError, "sqlite3 backup failed on %s", nodeIP)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+)
+
+func TestSQLiteBackend(t *testing.T) {
+	system := &fakeSystem{}
+	b := newSQLiteBackend("/var/lib/geminik8s/kine.db", func(string) api.SystemOperator { return system })
+	ctx := context.Background()
+
+	if got, want := b.KineEndpoint("kine", "secret", "10.0.0.1:5432"), "sqlite:///var/lib/geminik8s/kine.db?_journal=WAL&cache=shared"; got != want {
+		t.Errorf("expected endpoint %q, got %q", want, got)
+	}
+	if err := b.ConfigureReplication(ctx, "10.0.0.1", "10.0.0.2"); err == nil {
+		t.Error("expected replication to be rejected")
+	}
+	if err := b.SetSynchronous(ctx, "10.0.0.1", false); err != nil {
+		t.Errorf("expected turning sync off to succeed, got %v", err)
+	}
+	if err := b.SetSynchronous(ctx, "10.0.0.1", true); err == nil {
+		t.Error("expected turning sync on to fail")
+	}
+
+	if err := b.Backup(ctx, "10.0.0.1", "/var/backups/it's.db"); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	want := [][]string{{"sqlite3", "/var/lib/geminik8s/kine.db", ".backup '/var/backups/it''s.db'"}}
+	if !reflect.DeepEqual(system.commands, want) {
+		t.Errorf("unexpected commands:\n got %q\nwant %q", system.commands, want)
+	}
+}
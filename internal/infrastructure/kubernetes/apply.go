@@ -0,0 +1,277 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// FieldManager owns the fields geminik8s sets through server-side apply.
+	FieldManager = "geminik8s"
+	// crdEstablishTimeout bounds the wait for an applied CRD to be served.
+	crdEstablishTimeout = time.Minute
+	// crdPollInterval is how often the CRD status is checked while waiting.
+	crdPollInterval = time.Second
+)
+
+var (
+	crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	crdKind     = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+)
+
+// installOrder ranks kinds so that dependencies are applied first: namespaces
+// before what lives in them, CRDs before custom resources, RBAC before workloads.
+// Kinds not listed, including all custom resources, go last.
+var installOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PriorityClass",
+	"ResourceQuota",
+	"LimitRange",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicaSet",
+	"Deployment",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// Apply server-side applies every object in the manifest with the geminik8s
+// field manager, in dependency order. Applied CRDs are waited on until they are
+// established, so custom resources in the same manifest can follow them.
+// Apply stops at the first failure; the remaining objects are reported as skipped.
+func (c *k8sClient) Apply(ctx context.Context, manifest []byte) ([]types.ObjectResult, error) {
+	objs, err := decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	sortForApply(objs)
+
+	results := make([]types.ObjectResult, 0, len(objs))
+	for i, obj := range objs {
+		if err := c.applyObject(ctx, obj); err != nil {
+			results = append(results, objectResult(obj, types.ObjectFailed, err))
+			for _, rest := range objs[i+1:] {
+				results = append(results, objectResult(rest, types.ObjectSkipped, nil))
+			}
+			return results, errors.Wrapf(err, errors.KubernetesError, "failed to apply %s", describe(obj))
+		}
+		results = append(results, objectResult(obj, types.ObjectApplied, nil))
+	}
+	return results, nil
+}
+
+// Delete deletes every object in the manifest in reverse dependency order.
+// Objects that are already gone count as success. It keeps going after a
+// failure and returns an error if any object could not be deleted.
+func (c *k8sClient) Delete(ctx context.Context, manifest []byte) ([]types.ObjectResult, error) {
+	objs, err := decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	sortForApply(objs)
+
+	propagation := metav1.DeletePropagationBackground
+	results := make([]types.ObjectResult, 0, len(objs))
+	failed := 0
+	for i := len(objs) - 1; i >= 0; i-- {
+		obj := objs[i]
+		resource, err := c.resourceFor(obj)
+		if err == nil {
+			err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+		}
+		switch {
+		case err == nil:
+			results = append(results, objectResult(obj, types.ObjectDeleted, nil))
+		case apierrors.IsNotFound(err) || meta.IsNoMatchError(err):
+			// A kind without a mapping means its CRD, and so the object, is gone.
+			results = append(results, objectResult(obj, types.ObjectNotFound, nil))
+		default:
+			results = append(results, objectResult(obj, types.ObjectFailed, err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, errors.Newf(errors.KubernetesError, "failed to delete %d of %d objects", failed, len(objs))
+	}
+	return results, nil
+}
+
+// applyObject server-side applies one object.
+func (c *k8sClient) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	resource, err := c.resourceFor(obj)
+	if err != nil {
+		return err
+	}
+	_, err = resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: FieldManager, Force: true})
+	if err != nil {
+		return err
+	}
+	if obj.GroupVersionKind().GroupKind() == crdKind {
+		return c.waitForCRD(ctx, obj.GetName())
+	}
+	return nil
+}
+
+// resourceFor returns the dynamic client for the object's resource. Namespaced
+// objects without a namespace go to the default namespace.
+func (c *k8sClient) resourceFor(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// The kind may come from a CRD created after discovery was cached.
+		c.mapper.Reset()
+		mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.dynamic.Resource(mapping.Resource), nil
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(metav1.NamespaceDefault)
+	}
+	return c.dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+// waitForCRD waits until the named CRD is established and refreshes the
+// RESTMapper so its kind can be resolved.
+func (c *k8sClient) waitForCRD(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, crdEstablishTimeout)
+	defer cancel()
+	err := wait.PollUntilContextCancel(ctx, crdPollInterval, true, func(ctx context.Context) (bool, error) {
+		crd, err := c.dynamic.Resource(crdResource).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return crdEstablished(crd), nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "CRD %s did not become established", name)
+	}
+	c.mapper.Reset()
+	return nil
+}
+
+// crdEstablished reports whether the CRD has the Established condition set to True.
+func crdEstablished(crd *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, cond := range conditions {
+		m, ok := cond.(map[string]interface{})
+		if ok && m["type"] == "Established" && m["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeManifest parses a multi-document YAML or JSON manifest. Empty documents
+// are skipped and List objects are expanded into their items.
+func decodeManifest(manifest []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	var objs []*unstructured.Unstructured
+	for doc := 1; ; doc++ {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return objs, nil
+			}
+			return nil, errors.Wrapf(err, errors.KubernetesError, "failed to parse document %d of manifest", doc)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				objs = append(objs, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, errors.KubernetesError, "failed to read list in document %d of manifest", doc)
+			}
+			continue
+		}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, errors.Newf(errors.KubernetesError, "document %d of manifest needs apiVersion, kind and metadata.name", doc)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// sortForApply orders objects by installOrder, keeping the manifest order within a kind.
+func sortForApply(objs []*unstructured.Unstructured) {
+	rank := func(kind string) int {
+		for i, k := range installOrder {
+			if k == kind {
+				return i
+			}
+		}
+		return len(installOrder)
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return rank(objs[i].GetKind()) < rank(objs[j].GetKind())
+	})
+}
+
+// objectResult builds the result for one object.
+func objectResult(obj *unstructured.Unstructured, action types.ObjectAction, err error) types.ObjectResult {
+	result := types.ObjectResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Action:     action,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// describe names an object for error messages, e.g. "Deployment kube-system/coredns".
+func describe(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+//Personal.AI order the ending
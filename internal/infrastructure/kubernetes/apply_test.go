@@ -0,0 +1,177 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testManifest = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: geminik8s-system
+---
+apiVersion: geminik8s.io/v1alpha1
+kind: GeminiCluster
+metadata:
+  name: demo
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: geminiclusters.geminik8s.io
+---
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: geminik8s-system
+`
+
+// staticMapper is a fixed RESTMapper. Custom kinds only resolve once
+// established is set, like a discovery cache that has not seen the CRD yet.
+type staticMapper struct {
+	meta.RESTMapper
+	custom      *meta.DefaultRESTMapper
+	established bool
+	resets      int
+}
+
+func (m *staticMapper) Reset() { m.resets++ }
+
+func (m *staticMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	if mapping, err := m.RESTMapper.RESTMapping(gk, versions...); err == nil || !m.established {
+		return mapping, err
+	}
+	return m.custom.RESTMapping(gk, versions...)
+}
+
+func newTestMapper() *staticMapper {
+	builtin := meta.NewDefaultRESTMapper(nil)
+	builtin.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	builtin.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	builtin.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+	custom := meta.NewDefaultRESTMapper(nil)
+	custom.Add(schema.GroupVersionKind{Group: "geminik8s.io", Version: "v1alpha1", Kind: "GeminiCluster"}, meta.RESTScopeRoot)
+	return &staticMapper{RESTMapper: builtin, custom: custom}
+}
+
+func TestApply(t *testing.T) {
+	mapper := newTestMapper()
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	var applied []string
+	dyn.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetResource().Resource == "geminiclusters" && !mapper.established {
+			t.Errorf("custom resource applied before its CRD was established")
+		}
+		applied = append(applied, patch.GetResource().Resource+"/"+patch.GetNamespace()+"/"+patch.GetName())
+		return true, &unstructured.Unstructured{}, nil
+	})
+	dyn.PrependReactor("get", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mapper.established = true
+		crd := &unstructured.Unstructured{}
+		crd.SetAPIVersion("apiextensions.k8s.io/v1")
+		crd.SetKind("CustomResourceDefinition")
+		unstructured.SetNestedSlice(crd.Object, []interface{}{
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}, "status", "conditions")
+		return true, crd, nil
+	})
+	c := &k8sClient{dynamic: dyn, mapper: mapper}
+
+	results, err := c.Apply(context.Background(), []byte(testManifest))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	want := []string{
+		"namespaces//geminik8s-system",
+		"customresourcedefinitions//geminiclusters.geminik8s.io",
+		"configmaps/geminik8s-system/settings",
+		"geminiclusters//demo",
+	}
+	if len(applied) != len(want) {
+		t.Fatalf("expected %v, got %v", want, applied)
+	}
+	for i := range want {
+		if applied[i] != want[i] {
+			t.Errorf("apply #%d: expected %s, got %s", i, want[i], applied[i])
+		}
+	}
+	for _, r := range results {
+		if r.Action != types.ObjectApplied {
+			t.Errorf("expected %s to be applied, got %+v", r.Name, r)
+		}
+	}
+	if mapper.resets == 0 {
+		t.Errorf("expected the RESTMapper to be reset after the CRD was established")
+	}
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		dyn.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "settings", nil)
+		})
+		results, err := c.Apply(context.Background(), []byte(testManifest))
+		if err == nil {
+			t.Fatalf("expected Apply to fail")
+		}
+		actions := map[string]types.ObjectAction{}
+		for _, r := range results {
+			actions[r.Name] = r.Action
+		}
+		if actions["settings"] != types.ObjectFailed || actions["demo"] != types.ObjectSkipped {
+			t.Errorf("expected settings to fail and demo to be skipped, got %v", actions)
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	mapper := newTestMapper()
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	var deleted []string
+	dyn.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteAction)
+		if del.GetResource().Resource == "configmaps" {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, del.GetName())
+		}
+		deleted = append(deleted, del.GetResource().Resource)
+		return true, nil, nil
+	})
+	c := &k8sClient{dynamic: dyn, mapper: mapper}
+
+	results, err := c.Delete(context.Background(), []byte(testManifest))
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// The custom resource has no mapping (its CRD is unknown), so it counts as gone.
+	want := []string{"customresourcedefinitions", "namespaces"}
+	if len(deleted) != len(want) || deleted[0] != want[0] || deleted[1] != want[1] {
+		t.Errorf("expected reverse dependency order %v, got %v", want, deleted)
+	}
+	if results[0].Name != "demo" || results[0].Action != types.ObjectNotFound {
+		t.Errorf("expected the custom resource to be reported as not found first, got %+v", results[0])
+	}
+	if results[1].Name != "settings" || results[1].Action != types.ObjectNotFound {
+		t.Errorf("expected the missing config map to be reported as not found, got %+v", results[1])
+	}
+}
+
+func TestDecodeManifestRejectsIncompleteObjects(t *testing.T) {
+	if _, err := decodeManifest([]byte("apiVersion: v1\nkind: ConfigMap\n")); err == nil {
+		t.Errorf("expected an error for an object without a name")
+	}
+}
+
+//Personal.AI order the ending
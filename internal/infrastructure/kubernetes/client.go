@@ -2,19 +2,27 @@ package kubernetes
 
 import (
 	"context"
+	"time"
 
 	"github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
-	// "k8s.io/client-go/rest"
 )
 
 // k8sClient implements the api.K8sClient interface.
 type k8sClient struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.ResettableRESTMapper // Reset after new CRDs are established
 }
 
 // NewK8sClient creates a new Kubernetes client from a kubeconfig file.
@@ -23,47 +31,62 @@ func NewK8sClient(kubeconfigPath string) (api.K8sClient, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, errors.KubernetesError, "failed to build config from kubeconfig")
 	}
+	return newK8sClient(config)
+}
 
+// NewK8sClientFromKubeconfig creates a new Kubernetes client from kubeconfig contents.
+func NewK8sClientFromKubeconfig(kubeconfig []byte) (api.K8sClient, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to build config from kubeconfig")
+	}
+	return newK8sClient(config)
+}
+
+// newK8sClient creates the typed and dynamic clients for config.
+func newK8sClient(config *rest.Config) (api.K8sClient, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.KubernetesError, "failed to create kubernetes clientset")
 	}
 
-	return &k8sClient{clientset: clientset}, nil
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to create dynamic client")
+	}
+
+	return &k8sClient{
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery())),
+	}, nil
 }
 
-// GetNodes retrieves a list of nodes from the cluster.
-// This is a simplified version; a real one would convert corev1.Node to types.Node.
+// GetNodes retrieves the nodes of the cluster.
 func (c *k8sClient) GetNodes(ctx context.Context) ([]types.Node, error) {
 	corev1Nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, errors.Wrap(err, errors.KubernetesError, "failed to list nodes")
 	}
 
-	// In a real implementation, you would map corev1Nodes.Items to []types.Node
-	var nodes []types.Node
-	for _, n := range corev1Nodes.Items {
-		// Simplified mapping
-		nodes = append(nodes, types.Node{
-			Config: types.NodeConfig{
-				Name: n.Name,
-			},
-		})
+	nodes := make([]types.Node, 0, len(corev1Nodes.Items))
+	for i := range corev1Nodes.Items {
+		nodes = append(nodes, toNode(&corev1Nodes.Items[i]))
 	}
-
 	return nodes, nil
 }
 
-// Apply is a placeholder for applying a Kubernetes manifest.
-// A real implementation would parse the manifest and use the appropriate client
-// (e.g., AppsV1().Deployments().Apply(...))
-func (c *k8sClient) Apply(ctx context.Context, manifest []byte) error {
-	return errors.New(errors.KubernetesError, "Apply not implemented")
-}
-
-// Delete is a placeholder for deleting a resource from a manifest.
-func (c *k8sClient) Delete(ctx context.Context, manifest []byte) error {
-	return errors.New(errors.KubernetesError, "Delete not implemented")
+// CreateToken issues a bound token for the service account that expires after
+// ttl. The API server may shorten the lifetime (--service-account-max-token-expiration),
+// so the expiry it reports is returned.
+func (c *k8sClient) CreateToken(ctx context.Context, namespace, serviceAccount string, ttl time.Duration) (string, time.Time, error) {
+	seconds := int64(ttl.Seconds())
+	req := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}}
+	resp, err := c.clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, req, metav1.CreateOptions{})
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, errors.KubernetesError, "failed to create token for service account %s/%s", namespace, serviceAccount)
+	}
+	return resp.Status.Token, resp.Status.ExpirationTimestamp.Time, nil
 }
 
 //Personal.AI order the ending
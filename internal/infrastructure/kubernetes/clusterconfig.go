@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// clusterConfigResource is the resource of the cluster-scoped ClusterConfig kind.
+var clusterConfigResource = schema.GroupVersionResource{Group: "geminik8s.io", Version: "v1alpha1", Resource: "clusterconfigs"}
+
+// ClusterConfigCRD defines the ClusterConfig kind. Its spec mirrors
+// types.ClusterSpec and its status carries types.ClusterConfigStatus.
+const ClusterConfigCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: clusterconfigs.geminik8s.io
+spec:
+  group: geminik8s.io
+  scope: Cluster
+  names:
+    kind: ClusterConfig
+    listKind: ClusterConfigList
+    plural: clusterconfigs
+    singular: clusterconfig
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    additionalPrinterColumns:
+    - {name: Version, type: string, jsonPath: .status.version}
+    - {name: Leader, type: string, jsonPath: .status.leader}
+    - {name: Ready, type: string, jsonPath: '.status.conditions[?(@.type=="Ready")].status'}
+    - {name: Last Backup, type: date, jsonPath: .status.lastBackupTime}
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: [nodes]
+            properties:
+              version: {type: string}
+              network:
+                type: object
+                properties:
+                  vip: {type: string}
+              nodes:
+                type: array
+                items:
+                  type: object
+                  required: [ip]
+                  properties:
+                    ip: {type: string}
+                    role: {type: string, enum: [Leader, Follower]}
+              storage:
+                type: object
+                properties:
+                  type: {type: string, enum: [postgresql, mysql, sqlite]}
+                  postgres: {type: object, x-kubernetes-preserve-unknown-fields: true}
+                  mysql: {type: object, x-kubernetes-preserve-unknown-fields: true}
+                  sqlite: {type: object, x-kubernetes-preserve-unknown-fields: true}
+                  replication:
+                    type: object
+                    properties:
+                      mode: {type: string, enum: [async, sync]}
+                      degradeTimeout: {type: string}
+                  credentials:
+                    type: object
+                    properties:
+                      rotationInterval: {type: string}
+              backup:
+                type: object
+                properties:
+                  interval: {type: string}
+                  destination: {type: string}
+              failover:
+                type: object
+                properties:
+                  preferredLeader: {type: string}
+          status:
+            type: object
+            properties:
+              observedGeneration: {type: integer, format: int64}
+              version: {type: string}
+              leader: {type: string}
+              lastBackupTime: {type: string, format: date-time}
+              conditions:
+                type: array
+                items:
+                  type: object
+                  required: [type, status]
+                  properties:
+                    type: {type: string}
+                    status: {type: string}
+                    reason: {type: string}
+                    message: {type: string}
+                    observedGeneration: {type: integer, format: int64}
+                    lastTransitionTime: {type: string, format: date-time}
+                    retries: {type: integer}
+                    retryAfter: {type: string, format: date-time}
+`
+
+// WatchClusterConfigs calls handler for every ClusterConfig when it is added or
+// changed, and for all of them again every resync. Calls are sequential. It
+// blocks until ctx is cancelled. A ClusterConfig whose spec or status cannot be
+// decoded is passed with the error, so that it can be reported.
+func (c *k8sClient) WatchClusterConfigs(ctx context.Context, resync time.Duration, handler func(types.ClusterConfigResource, error)) error {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.dynamic, resync)
+	informer := factory.ForResource(clusterConfigResource).Informer()
+
+	deliver := func(obj interface{}) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		handler(toClusterConfigResource(u))
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    deliver,
+		UpdateFunc: func(_, obj interface{}) { deliver(obj) },
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.KubernetesError, "failed to watch ClusterConfigs")
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	<-ctx.Done()
+	return nil
+}
+
+// UpdateClusterConfigStatus reads the status of the named ClusterConfig, lets
+// update change it and writes it back through the status subresource.
+func (c *k8sClient) UpdateClusterConfigStatus(ctx context.Context, name string, update func(*types.ClusterConfigStatus)) error {
+	return c.updateStatus(ctx, clusterConfigResource, name, func(raw interface{}) (interface{}, error) {
+		var status types.ClusterConfigStatus
+		if err := convertJSON(raw, &status); err != nil {
+			return nil, err
+		}
+		update(&status)
+		return status, nil
+	})
+}
+
+// toClusterConfigResource converts a ClusterConfig object. The object's name
+// becomes the cluster name. On error the spec is left empty, and so is the
+// status if it is the part that cannot be decoded.
+func toClusterConfigResource(obj *unstructured.Unstructured) (types.ClusterConfigResource, error) {
+	res := types.ClusterConfigResource{
+		Generation: obj.GetGeneration(),
+		Config:     types.ClusterConfig{Metadata: types.Metadata{Name: obj.GetName()}},
+	}
+	var status types.ClusterConfigStatus
+	if err := convertJSON(obj.Object["status"], &status); err != nil {
+		return res, errors.Wrapf(err, errors.ValidationError, "invalid status in ClusterConfig %s", obj.GetName())
+	}
+	res.Status = status
+	var spec types.ClusterSpec
+	if err := convertJSON(obj.Object["spec"], &spec); err != nil {
+		return res, errors.Wrapf(err, errors.ValidationError, "invalid spec in ClusterConfig %s", obj.GetName())
+	}
+	res.Config.APIVersion, res.Config.Kind, res.Config.Spec = obj.GetAPIVersion(), obj.GetKind(), spec
+	return res, nil
+}
+
+//Personal.AI order the ending
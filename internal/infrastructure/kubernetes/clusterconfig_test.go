@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func testClusterConfig() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "geminik8s.io/v1alpha1",
+		"kind":       "ClusterConfig",
+		"metadata":   map[string]interface{}{"name": "demo", "generation": int64(3)},
+		"spec": map[string]interface{}{
+			"version": "v1.29.4+k3s1",
+			"nodes":   []interface{}{map[string]interface{}{"ip": "10.0.0.1", "role": "Leader"}},
+			"backup":  map[string]interface{}{"interval": "24h", "destination": "/backups"},
+		},
+	}}
+}
+
+func newClusterConfigClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterConfigResource: "ClusterConfigList"}, objects...)
+}
+
+func TestWatchClusterConfigs(t *testing.T) {
+	c := &k8sClient{dynamic: newClusterConfigClient(testClusterConfig())}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan types.ClusterConfigResource, 1)
+	done := make(chan error)
+	go func() {
+		done <- c.WatchClusterConfigs(ctx, time.Hour, func(res types.ClusterConfigResource, err error) {
+			if err != nil {
+				t.Errorf("unexpected error for a valid ClusterConfig: %v", err)
+			}
+			got <- res
+		})
+	}()
+
+	select {
+	case res := <-got:
+		if res.Config.Metadata.Name != "demo" || res.Generation != 3 || res.Config.Spec.Version != "v1.29.4+k3s1" {
+			t.Errorf("unexpected resource: %+v", res)
+		}
+		if res.Config.Spec.Backup.Interval.Duration != 24*time.Hour {
+			t.Errorf("expected the backup interval to be parsed, got %v", res.Config.Spec.Backup.Interval)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ClusterConfig was not delivered")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected WatchClusterConfigs to return cleanly, got %v", err)
+	}
+}
+
+func TestToClusterConfigResourceInvalid(t *testing.T) {
+	obj := testClusterConfig()
+	obj.Object["spec"].(map[string]interface{})["nodes"] = "10.0.0.1"
+	obj.Object["status"] = map[string]interface{}{"version": "v1.28.9+k3s1"}
+
+	res, err := toClusterConfigResource(obj)
+	if err == nil {
+		t.Fatalf("expected an error for an invalid spec")
+	}
+	if res.Config.Metadata.Name != "demo" || res.Generation != 3 || res.Status.Version != "v1.28.9+k3s1" || len(res.Config.Spec.Nodes) != 0 {
+		t.Errorf("expected the name, generation and status without a spec, got %+v", res)
+	}
+}
+
+func TestUpdateClusterConfigStatus(t *testing.T) {
+	dyn := newClusterConfigClient(testClusterConfig())
+	c := &k8sClient{dynamic: dyn}
+
+	err := c.UpdateClusterConfigStatus(context.Background(), "demo", func(s *types.ClusterConfigStatus) {
+		s.ObservedGeneration = 3
+		s.SetCondition(types.Condition{Type: "Ready", Status: types.ConditionTrue, LastTransitionTime: time.Now()})
+	})
+	if err != nil {
+		t.Fatalf("UpdateClusterConfigStatus failed: %v", err)
+	}
+
+	obj, _ := dyn.Resource(clusterConfigResource).Get(context.Background(), "demo", metav1.GetOptions{})
+	res, err := toClusterConfigResource(obj)
+	if err != nil {
+		t.Fatalf("failed to convert ClusterConfig: %v", err)
+	}
+	if res.Status.ObservedGeneration != 3 || res.Status.Condition("Ready") == nil {
+		t.Errorf("unexpected status: %+v", res.Status)
+	}
+	if res.Config.Spec.Version != "v1.29.4+k3s1" {
+		t.Errorf("expected the spec to be left alone")
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,182 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// drainPollInterval is how often a blocked eviction is retried and a
+// terminating pod is checked.
+var drainPollInterval = 2 * time.Second
+
+// Cordon marks the node unschedulable.
+func (c *k8sClient) Cordon(ctx context.Context, nodeName string) error {
+	return c.setUnschedulable(ctx, nodeName, true)
+}
+
+// Uncordon marks the node schedulable again.
+func (c *k8sClient) Uncordon(ctx context.Context, nodeName string) error {
+	return c.setUnschedulable(ctx, nodeName, false)
+}
+
+// setUnschedulable patches spec.unschedulable on the node.
+func (c *k8sClient) setUnschedulable(ctx context.Context, nodeName string, unschedulable bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	_, err := c.clientset.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.StrategicMergePatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "failed to set unschedulable=%t on node %s", unschedulable, nodeName)
+	}
+	return nil
+}
+
+// Drain cordons the node and evicts all of its pods in parallel. Evictions that a
+// PodDisruptionBudget refuses are retried until they succeed or the drain times out.
+// DaemonSet pods, mirror pods and finished pods are skipped.
+func (c *k8sClient) Drain(ctx context.Context, nodeName string, opts types.DrainOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if err := c.Cordon(ctx, nodeName); err != nil {
+		return err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "failed to list pods on node %s", nodeName)
+	}
+
+	var mu sync.Mutex
+	report := func(p types.PodDrainProgress) {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		opts.Progress(p)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		failed []string
+	)
+	for i := range pods.Items {
+		pod := pods.Items[i]
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		if reason := skipReason(&pod); reason != "" {
+			report(types.PodDrainProgress{Namespace: pod.Namespace, Name: pod.Name, Phase: types.PodDrainSkipped, Message: reason})
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.evictPod(ctx, &pod, opts.GracePeriod, report); err != nil {
+				mu.Lock()
+				failed = append(failed, pod.Namespace+"/"+pod.Name)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return errors.Newf(errors.KubernetesError, "failed to drain node %s: %d pod(s) not evicted: %s",
+			nodeName, len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// evictPod evicts one pod and waits until it is gone.
+func (c *k8sClient) evictPod(ctx context.Context, pod *corev1.Pod, gracePeriod *time.Duration, report func(types.PodDrainProgress)) error {
+	var last types.PodDrainPhase
+	set := func(phase types.PodDrainPhase, message string) {
+		if phase != last {
+			last = phase
+			report(types.PodDrainProgress{Namespace: pod.Namespace, Name: pod.Name, Phase: phase, Message: message})
+		}
+	}
+
+	eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+	if gracePeriod != nil {
+		seconds := int64(gracePeriod.Seconds())
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &seconds}
+	}
+
+	set(types.PodDrainEvicting, "")
+	for {
+		err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil {
+			break
+		}
+		if apierrors.IsNotFound(err) {
+			set(types.PodDrainEvicted, "")
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			set(types.PodDrainFailed, err.Error())
+			return err
+		}
+		// 429 means a PodDisruptionBudget does not allow the disruption right now.
+		set(types.PodDrainBlocked, err.Error())
+		select {
+		case <-ctx.Done():
+			set(types.PodDrainFailed, "timed out waiting for the PodDisruptionBudget to allow eviction")
+			return ctx.Err()
+		case <-time.After(drainPollInterval):
+		}
+	}
+
+	err := wait.PollUntilContextCancel(ctx, drainPollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// A pod with the same name but a new UID is a replacement, not the evicted pod.
+		return current.UID != pod.UID, nil
+	})
+	if err != nil {
+		set(types.PodDrainFailed, "pod did not terminate: "+err.Error())
+		return err
+	}
+	set(types.PodDrainEvicted, "")
+	return nil
+}
+
+// skipReason returns why a pod is left alone during a drain, or "" to evict it.
+func skipReason(pod *corev1.Pod) string {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return "mirror pod"
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return "managed by DaemonSet " + ref.Name
+		}
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return "already finished"
+	}
+	return ""
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,168 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testPod(name, node string, mutate func(*corev1.Pod)) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: k8stypes.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if mutate != nil {
+		mutate(pod)
+	}
+	return pod
+}
+
+// newDrainClientset returns a fake clientset where an eviction deletes the pod,
+// unless blocked says a PodDisruptionBudget refuses it.
+func newDrainClientset(blocked func(name string) bool, objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if blocked != nil && blocked(eviction.Name) {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		err := clientset.Tracker().Delete(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, eviction.Namespace, eviction.Name)
+		return true, nil, err
+	})
+	return clientset
+}
+
+func TestDrain(t *testing.T) {
+	drainPollInterval = 10 * time.Millisecond
+	controller := true
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	clientset := newDrainClientset(nil,
+		node,
+		testPod("web", "node1", nil),
+		testPod("other-node", "node2", nil),
+		testPod("agent", "node1", func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: &controller}}
+		}),
+		testPod("static", "node1", func(p *corev1.Pod) {
+			p.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "x"}
+		}),
+		testPod("done", "node1", func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+	)
+	c := &k8sClient{clientset: clientset}
+
+	phases := map[string][]types.PodDrainPhase{}
+	grace := 5 * time.Second
+	err := c.Drain(context.Background(), "node1", types.DrainOptions{
+		GracePeriod: &grace,
+		Timeout:     5 * time.Second,
+		Progress: func(p types.PodDrainProgress) {
+			phases[p.Name] = append(phases[p.Name], p.Phase)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	n, _ := clientset.CoreV1().Nodes().Get(context.Background(), "node1", metav1.GetOptions{})
+	if !n.Spec.Unschedulable {
+		t.Errorf("expected node to be cordoned")
+	}
+	if got := phases["web"]; len(got) != 2 || got[0] != types.PodDrainEvicting || got[1] != types.PodDrainEvicted {
+		t.Errorf("expected web to go Evicting -> Evicted, got %v", got)
+	}
+	for _, name := range []string{"agent", "static", "done"} {
+		if got := phases[name]; len(got) != 1 || got[0] != types.PodDrainSkipped {
+			t.Errorf("expected %s to be skipped, got %v", name, got)
+		}
+	}
+	if _, ok := phases["other-node"]; ok {
+		t.Errorf("expected pods on other nodes to be left alone")
+	}
+	if _, err := clientset.CoreV1().Pods("default").Get(context.Background(), "agent", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected DaemonSet pod to stay, got %v", err)
+	}
+
+	var graceSeconds *int64
+	for _, action := range clientset.Actions() {
+		if action.GetSubresource() == "eviction" {
+			graceSeconds = action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction).DeleteOptions.GracePeriodSeconds
+		}
+	}
+	if graceSeconds == nil || *graceSeconds != 5 {
+		t.Errorf("expected evictions to carry a 5s grace period")
+	}
+
+	if err := c.Uncordon(context.Background(), "node1"); err != nil {
+		t.Fatalf("Uncordon failed: %v", err)
+	}
+	n, _ = clientset.CoreV1().Nodes().Get(context.Background(), "node1", metav1.GetOptions{})
+	if n.Spec.Unschedulable {
+		t.Errorf("expected node to be schedulable after Uncordon")
+	}
+}
+
+func TestDrainHonorsPodDisruptionBudget(t *testing.T) {
+	drainPollInterval = 10 * time.Millisecond
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+	t.Run("retries until the budget allows it", func(t *testing.T) {
+		var mu sync.Mutex
+		attempts := 0
+		clientset := newDrainClientset(func(name string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			return attempts < 3
+		}, node, testPod("db", "node1", nil))
+		c := &k8sClient{clientset: clientset}
+
+		var phases []types.PodDrainPhase
+		err := c.Drain(context.Background(), "node1", types.DrainOptions{
+			Timeout:  5 * time.Second,
+			Progress: func(p types.PodDrainProgress) { phases = append(phases, p.Phase) },
+		})
+		if err != nil {
+			t.Fatalf("Drain failed: %v", err)
+		}
+		want := []types.PodDrainPhase{types.PodDrainEvicting, types.PodDrainBlocked, types.PodDrainEvicted}
+		if len(phases) != len(want) {
+			t.Fatalf("expected %v, got %v", want, phases)
+		}
+		for i := range want {
+			if phases[i] != want[i] {
+				t.Errorf("expected %v, got %v", want, phases)
+			}
+		}
+	})
+
+	t.Run("times out while blocked", func(t *testing.T) {
+		clientset := newDrainClientset(func(string) bool { return true }, node, testPod("db", "node1", nil))
+		c := &k8sClient{clientset: clientset}
+
+		err := c.Drain(context.Background(), "node1", types.DrainOptions{Timeout: 100 * time.Millisecond})
+		if err == nil {
+			t.Fatalf("expected Drain to time out")
+		}
+		if _, err := clientset.CoreV1().Pods("default").Get(context.Background(), "db", metav1.GetOptions{}); err != nil {
+			t.Errorf("expected the protected pod to remain, got %v", err)
+		}
+	})
+}
+
+//Personal.AI order the ending
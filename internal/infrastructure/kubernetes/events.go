@@ -0,0 +1,202 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Where the agent and the operator spool events on a node. They run as
+// separate processes, so each has its own spool.
+const (
+	AgentEventSpoolPath    = "/var/lib/geminik8s/agent-events.spool"
+	OperatorEventSpoolPath = "/var/lib/geminik8s/operator-events.spool"
+)
+
+// Event recorder limits. The burst covers a full failover or upgrade; after
+// that, one event every eventRefill gets through.
+const (
+	eventBurst        = 25
+	eventRefill       = 10 * time.Second
+	eventSendTimeout  = 5 * time.Second
+	eventSpoolMaxSize = 1000 // Events; the oldest are dropped beyond this
+)
+
+// eventNamespace holds the events of cluster-scoped objects, like the kubelet does for nodes.
+const eventNamespace = metav1.NamespaceDefault
+
+// eventRecorder implements api.EventRecorder.
+type eventRecorder struct {
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface // Reads the UIDs of GeminiClusters; may be nil
+	component string
+	host      string
+	spoolPath string
+	limiter   flowcontrol.RateLimiter
+	mu        sync.Mutex // Serializes sends and spool access
+}
+
+// EventRecorder returns a recorder that creates Events as component.
+func (c *k8sClient) EventRecorder(component, spoolPath string) api.EventRecorder {
+	host, _ := os.Hostname()
+	return &eventRecorder{
+		clientset: c.clientset,
+		dynamic:   c.dynamic,
+		component: component,
+		host:      host,
+		spoolPath: spoolPath,
+		limiter:   flowcontrol.NewTokenBucketRateLimiter(float32(time.Second)/float32(eventRefill), eventBurst),
+	}
+}
+
+// Record sends the spooled events and then this one. If the API server cannot
+// be reached, the event is spooled instead.
+func (r *eventRecorder) Record(ctx context.Context, event types.Event) {
+	if !r.limiter.TryAccept() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := append(r.readSpool(), event)
+	for i, e := range pending {
+		if err := r.send(ctx, e); err != nil && retriable(err) {
+			r.writeSpool(pending[i:])
+			return
+		}
+	}
+	r.writeSpool(nil)
+}
+
+// send creates the Event object.
+func (r *eventRecorder) send(ctx context.Context, event types.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, eventSendTimeout)
+	defer cancel()
+
+	eventTime := metav1.NewTime(event.Time)
+	ev := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", event.Object.Name, event.Time.UnixNano()),
+			Namespace: eventNamespace,
+		},
+		InvolvedObject:      r.involvedObject(ctx, event.Object),
+		Reason:              event.Reason,
+		Message:             event.Message,
+		Type:                string(event.Type),
+		Source:              corev1.EventSource{Component: r.component, Host: r.host},
+		FirstTimestamp:      eventTime,
+		LastTimestamp:       eventTime,
+		Count:               1,
+		ReportingController: "geminik8s.io/" + r.component,
+		ReportingInstance:   r.component + "-" + r.host,
+	}
+	_, err := r.clientset.CoreV1().Events(eventNamespace).Create(ctx, ev, metav1.CreateOptions{})
+	return err
+}
+
+// involvedObject returns the reference to the object an event is about.
+// Both kinds are cluster-scoped, so the reference has no namespace.
+func (r *eventRecorder) involvedObject(ctx context.Context, obj types.EventObject) corev1.ObjectReference {
+	ref := corev1.ObjectReference{Kind: obj.Kind, Name: obj.Name}
+	switch obj.Kind {
+	case "Node":
+		ref.APIVersion = "v1"
+		// The kubelet uses the node name as UID in node events, so they show up in 'kubectl describe node'.
+		ref.UID = k8stypes.UID(obj.Name)
+	case "GeminiCluster":
+		ref.APIVersion = geminiClusterResource.GroupVersion().String()
+		// 'kubectl describe geminicluster' only lists events with the object's UID.
+		ref.UID = r.uid(ctx, geminiClusterResource, obj.Name)
+	}
+	return ref
+}
+
+// uid returns the UID of the named cluster-scoped object, or "" if it cannot
+// be read, e.g. before the agent created it. It is looked up for every event,
+// since the object may have been recreated in between.
+func (r *eventRecorder) uid(ctx context.Context, resource schema.GroupVersionResource, name string) k8stypes.UID {
+	if r.dynamic == nil {
+		return ""
+	}
+	obj, err := r.dynamic.Resource(resource).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return obj.GetUID()
+}
+
+// retriable reports whether an event that failed with err should be spooled:
+// the API server was unreachable or overloaded, rather than rejecting the event.
+func retriable(err error) bool {
+	if _, ok := err.(apierrors.APIStatus); !ok {
+		return true // Connection refused, timeouts and other transport errors
+	}
+	return apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err)
+}
+
+// readSpool returns the spooled events, oldest first. A missing or unreadable
+// spool is empty.
+func (r *eventRecorder) readSpool() []types.Event {
+	data, err := os.ReadFile(r.spoolPath)
+	if err != nil {
+		return nil
+	}
+	var events []types.Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e types.Event
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// writeSpool replaces the spool with events, one JSON object per line, keeping
+// at most eventSpoolMaxSize of the newest. No events removes the spool.
+func (r *eventRecorder) writeSpool(events []types.Event) {
+	if len(events) == 0 {
+		os.Remove(r.spoolPath)
+		return
+	}
+	if len(events) > eventSpoolMaxSize {
+		events = events[len(events)-eventSpoolMaxSize:]
+	}
+	var b strings.Builder
+	for _, e := range events {
+		line, _ := json.Marshal(e)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(r.spoolPath), 0o755); err != nil {
+		return
+	}
+	tmp := r.spoolPath + ".tmp"
+	if os.WriteFile(tmp, []byte(b.String()), 0o600) == nil {
+		os.Rename(tmp, r.spoolPath)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+func listEvents(t *testing.T, clientset *fake.Clientset) []corev1.Event {
+	list, err := clientset.CoreV1().Events(eventNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	return list.Items
+}
+
+func TestEventRecorder(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "geminik8s.io/v1alpha1",
+		"kind":       "GeminiCluster",
+		"metadata":   map[string]interface{}{"name": "demo", "uid": "0a1b2c3d"},
+	}}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{geminiClusterResource: "GeminiClusterList"}, cluster)
+	spool := filepath.Join(t.TempDir(), "events.spool")
+	r := (&k8sClient{clientset: clientset, dynamic: dyn}).EventRecorder("geminik8s-agent", spool)
+	ctx := context.Background()
+
+	r.Record(ctx, types.Event{Object: types.NodeObject("node1"), Type: types.EventNormal, Reason: "Promoted", Message: "node1 became leader"})
+	events := listEvents(t, clientset)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.InvolvedObject.Kind != "Node" || ev.InvolvedObject.Name != "node1" || ev.InvolvedObject.UID != "node1" {
+		t.Errorf("unexpected involved object: %+v", ev.InvolvedObject)
+	}
+	if ev.Reason != "Promoted" || ev.Type != "Normal" || ev.Source.Component != "geminik8s-agent" || ev.Count != 1 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	r.Record(ctx, types.Event{Object: types.GeminiClusterObject("demo"), Type: types.EventWarning, Reason: "FailoverFailed"})
+	for _, ev := range listEvents(t, clientset) {
+		ref := ev.InvolvedObject
+		if ev.Reason == "FailoverFailed" && (ref.APIVersion != "geminik8s.io/v1alpha1" || ref.Kind != "GeminiCluster" || ref.UID != "0a1b2c3d" || ref.Namespace != "") {
+			t.Errorf("unexpected involved object: %+v", ev.InvolvedObject)
+		}
+	}
+}
+
+func TestEventRecorderSpool(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	var createErr error
+	clientset.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return createErr != nil, nil, createErr
+	})
+	spool := filepath.Join(t.TempDir(), "events.spool")
+	r := (&k8sClient{clientset: clientset}).EventRecorder("geminik8s-agent", spool)
+	ctx := context.Background()
+
+	// The API server is unavailable mid-failover: events are spooled in order.
+	createErr = apierrors.NewServiceUnavailable("apiserver is starting")
+	r.Record(ctx, types.Event{Object: types.NodeObject("node1"), Reason: "Demoted"})
+	r.Record(ctx, types.Event{Object: types.NodeObject("node2"), Reason: "Promoted"})
+	if got := len(r.(*eventRecorder).readSpool()); got != 2 {
+		t.Fatalf("expected 2 spooled events, got %d", got)
+	}
+	if len(listEvents(t, clientset)) != 0 {
+		t.Fatalf("expected no events while the API server is unavailable")
+	}
+
+	// Once it is back, the spool is sent first.
+	createErr = nil
+	r.Record(ctx, types.Event{Object: types.GeminiClusterObject("demo"), Reason: "LeaderChanged"})
+	events := listEvents(t, clientset)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events after recovery, got %d", len(events))
+	}
+	if _, err := os.Stat(spool); !os.IsNotExist(err) {
+		t.Errorf("expected the spool to be removed once sent")
+	}
+
+	// Events the API server rejects are not spooled.
+	createErr = apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "x", nil)
+	r.Record(ctx, types.Event{Object: types.NodeObject("node1"), Reason: "Promoted"})
+	if _, err := os.Stat(spool); !os.IsNotExist(err) {
+		t.Errorf("expected a rejected event not to be spooled")
+	}
+}
+
+func TestEventRecorderRateLimit(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	r := (&k8sClient{clientset: clientset}).EventRecorder("geminik8s-agent", filepath.Join(t.TempDir(), "events.spool"))
+	r.(*eventRecorder).limiter = flowcontrol.NewFakeNeverRateLimiter()
+
+	r.Record(context.Background(), types.Event{Object: types.NodeObject("node1"), Reason: "Promoted"})
+	if len(listEvents(t, clientset)) != 0 {
+		t.Errorf("expected events over the rate limit to be dropped")
+	}
+}
+
+//Personal.AI order the ending
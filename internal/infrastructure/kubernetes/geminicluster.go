@@ -0,0 +1,188 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+// geminiClusterResource is the resource of the cluster-scoped GeminiCluster kind.
+var geminiClusterResource = schema.GroupVersionResource{Group: "geminik8s.io", Version: "v1alpha1", Resource: "geminiclusters"}
+
+// GeminiClusterCRD defines the GeminiCluster kind. Its status subresource
+// carries types.GeminiClusterStatus.
+const GeminiClusterCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: geminiclusters.geminik8s.io
+spec:
+  group: geminik8s.io
+  scope: Cluster
+  names:
+    kind: GeminiCluster
+    listKind: GeminiClusterList
+    plural: geminiclusters
+    singular: geminicluster
+    shortNames: [gc]
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    additionalPrinterColumns:
+    - {name: Leader, type: string, jsonPath: .status.leader}
+    - {name: Epoch, type: integer, jsonPath: .status.epoch}
+    - {name: VIP Holder, type: string, jsonPath: .status.vipHolder}
+    - {name: Streaming, type: boolean, jsonPath: .status.replicationStreaming}
+    - {name: Lag, type: string, jsonPath: .status.replicationLag}
+    - {name: Mode, type: string, jsonPath: .status.replicationMode}
+    - {name: Degraded, type: boolean, jsonPath: .status.degraded}
+    - {name: Last Failover, type: date, jsonPath: .status.lastFailoverTime}
+    - {name: Last Backup, type: date, jsonPath: .status.lastBackupTime, priority: 1}
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+          status:
+            type: object
+            properties:
+              leader: {type: string}
+              epoch: {type: integer, format: int64}
+              nodes:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name: {type: string}
+                    ip: {type: string}
+                    role: {type: string}
+                    heartbeat:
+                      type: object
+                      properties:
+                        alive: {type: boolean}
+                        paths:
+                          type: array
+                          items:
+                            type: object
+                            properties:
+                              address: {type: string}
+                              up: {type: boolean}
+                              since: {type: string, format: date-time}
+                              error: {type: string}
+              vip: {type: string}
+              vipHolder: {type: string}
+              vips:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name: {type: string}
+                    address: {type: string}
+                    purpose: {type: string}
+                    owner: {type: string}
+                    holder: {type: string}
+              replicationStreaming: {type: boolean}
+              replicationLag: {type: string}
+              replicationMode: {type: string, enum: [sync, async]}
+              degraded: {type: boolean}
+              lastFailoverTime: {type: string, format: date-time}
+              lastBackupTime: {type: string, format: date-time}
+              healthChecks:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    checkName: {type: string}
+                    success: {type: boolean}
+                    message: {type: string}
+                    timestamp: {type: string, format: date-time}
+                    durationMs: {type: integer, format: int64}
+              lastUpdateTime: {type: string, format: date-time}
+`
+
+// GeminiClusterManifest returns the GeminiCluster CRD together with the
+// GeminiCluster object for the named cluster.
+func GeminiClusterManifest(name string) []byte {
+	return []byte(GeminiClusterCRD + fmt.Sprintf(`---
+apiVersion: geminik8s.io/v1alpha1
+kind: GeminiCluster
+metadata:
+  name: %s
+spec: {}
+`, name))
+}
+
+// GetClusterStatus returns the status of the named GeminiCluster.
+func (c *k8sClient) GetClusterStatus(ctx context.Context, name string) (*types.GeminiClusterStatus, error) {
+	obj, err := c.dynamic.Resource(geminiClusterResource).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.KubernetesError, "failed to get %s %s", geminiClusterResource.Resource, name)
+	}
+	var status types.GeminiClusterStatus
+	if err := convertJSON(obj.Object["status"], &status); err != nil {
+		return nil, errors.Wrapf(err, errors.KubernetesError, "invalid status of %s %s", geminiClusterResource.Resource, name)
+	}
+	return &status, nil
+}
+
+// UpdateClusterStatus reads the status of the named GeminiCluster, lets update
+// change it and writes it back through the status subresource.
+func (c *k8sClient) UpdateClusterStatus(ctx context.Context, name string, update func(*types.GeminiClusterStatus)) error {
+	return c.updateStatus(ctx, geminiClusterResource, name, func(raw interface{}) (interface{}, error) {
+		var status types.GeminiClusterStatus
+		if err := convertJSON(raw, &status); err != nil {
+			return nil, err
+		}
+		update(&status)
+		return status, nil
+	})
+}
+
+// updateStatus replaces the status of the named cluster-scoped object with what
+// update returns for the current one. Conflicting writes are retried with a
+// fresh copy of the object.
+func (c *k8sClient) updateStatus(ctx context.Context, gvr schema.GroupVersionResource, name string, update func(status interface{}) (interface{}, error)) error {
+	resource := c.dynamic.Resource(gvr)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		status, err := update(obj.Object["status"])
+		if err != nil {
+			return err
+		}
+		var out map[string]interface{}
+		if err := convertJSON(status, &out); err != nil {
+			return err
+		}
+		obj.Object["status"] = out
+		_, err = resource.UpdateStatus(ctx, obj, metav1.UpdateOptions{FieldManager: FieldManager})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "failed to update status of %s %s", gvr.Resource, name)
+	}
+	return nil
+}
+
+// convertJSON converts in to out through their JSON encoding.
+func convertJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestGeminiClusterManifest(t *testing.T) {
+	objs, err := decodeManifest(GeminiClusterManifest("demo"))
+	if err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if len(objs) != 2 || objs[0].GetKind() != "CustomResourceDefinition" || objs[1].GetName() != "demo" {
+		t.Fatalf("expected the CRD and the 'demo' GeminiCluster, got %d objects", len(objs))
+	}
+	versions, _, _ := unstructured.NestedSlice(objs[0].Object, "spec", "versions")
+	if _, ok, _ := unstructured.NestedMap(versions[0].(map[string]interface{}), "subresources", "status"); !ok {
+		t.Errorf("expected the CRD to enable the status subresource")
+	}
+}
+
+func TestUpdateClusterStatus(t *testing.T) {
+	backup := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "geminik8s.io/v1alpha1",
+		"kind":       "GeminiCluster",
+		"metadata":   map[string]interface{}{"name": "demo"},
+		"status":     map[string]interface{}{"epoch": int64(1), "lastBackupTime": backup.Format(time.RFC3339)},
+	}}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{geminiClusterResource: "GeminiClusterList"}, existing)
+	c := &k8sClient{dynamic: dyn}
+
+	err := c.UpdateClusterStatus(context.Background(), "demo", func(s *types.GeminiClusterStatus) {
+		if s.Epoch != 1 {
+			t.Errorf("expected the current status to be passed in, got epoch %d", s.Epoch)
+		}
+		s.Epoch = 2
+		s.Leader = "node2"
+	})
+	if err != nil {
+		t.Fatalf("UpdateClusterStatus failed: %v", err)
+	}
+
+	obj, _ := dyn.Resource(geminiClusterResource).Get(context.Background(), "demo", metav1.GetOptions{})
+	leader, _, _ := unstructured.NestedString(obj.Object, "status", "leader")
+	lastBackup, _, _ := unstructured.NestedString(obj.Object, "status", "lastBackupTime")
+	if leader != "node2" || lastBackup != backup.Format(time.RFC3339) {
+		t.Errorf("unexpected status: %v", obj.Object["status"])
+	}
+	status, err := c.GetClusterStatus(context.Background(), "demo")
+	if err != nil || status.Leader != "node2" || status.LastBackupTime == nil || !status.LastBackupTime.Equal(backup) {
+		t.Errorf("expected GetClusterStatus to return the updated status, got %+v (%v)", status, err)
+	}
+	if _, err := c.GetClusterStatus(context.Background(), "missing"); err == nil {
+		t.Errorf("expected an error for a missing GeminiCluster")
+	}
+
+	if err := c.UpdateClusterStatus(context.Background(), "missing", func(*types.GeminiClusterStatus) {}); err == nil {
+		t.Errorf("expected an error for a missing GeminiCluster")
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,186 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Workloads of the control plane that must be ready, as namespace/name of their
+// Deployment. An optional workload may be left out with k3s --disable; it is
+// skipped when its Deployment does not exist.
+var criticalDeployments = []struct {
+	namespace, name string
+	optional        bool
+}{
+	{"kube-system", "coredns", false},
+	{"kube-system", "metrics-server", true},
+}
+
+// healthProbeConfigMap is written and read back to prove that writes through Kine work.
+const (
+	healthProbeNamespace = "kube-system"
+	healthProbeName      = "geminik8s-health-probe"
+)
+
+// CheckControlPlane checks the API server's /readyz, the critical workloads and
+// a write/read round trip through a ConfigMap. Every check is reported, with its
+// duration, whether it passed or not.
+func (c *k8sClient) CheckControlPlane(ctx context.Context) []types.HealthCheckResult {
+	results := c.checkReadyz(ctx)
+	results = append(results, c.checkWorkloads(ctx)...)
+	results = append(results, timed("kine.configmapRoundTrip", func() (string, error) {
+		return c.configMapRoundTrip(ctx)
+	}))
+	return results
+}
+
+// checkWorkloads reports one "workload.<name>" result per critical Deployment.
+func (c *k8sClient) checkWorkloads(ctx context.Context) []types.HealthCheckResult {
+	var results []types.HealthCheckResult
+	for _, d := range criticalDeployments {
+		results = append(results, timed("workload."+d.name, func() (string, error) {
+			message, err := c.deploymentReady(ctx, d.namespace, d.name)
+			if d.optional && apierrors.IsNotFound(err) {
+				return "not installed, skipped", nil
+			}
+			return message, err
+		}))
+	}
+	return results
+}
+
+// timed runs check and turns its outcome into a HealthCheckResult.
+func timed(name string, check func() (string, error)) types.HealthCheckResult {
+	start := time.Now()
+	message, err := check()
+	result := types.HealthCheckResult{
+		CheckName:  name,
+		Success:    err == nil,
+		Message:    message,
+		Timestamp:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// checkReadyz queries /readyz?verbose and reports the overall result as
+// "apiserver.readyz", followed by one "apiserver.readyz.<check>" per line of the
+// verbose breakdown.
+func (c *k8sClient) checkReadyz(ctx context.Context) []types.HealthCheckResult {
+	start := time.Now()
+	body, err := c.clientset.Discovery().RESTClient().Get().AbsPath("/readyz").Param("verbose", "true").DoRaw(ctx)
+	duration := time.Since(start).Milliseconds()
+
+	overall := types.HealthCheckResult{CheckName: "apiserver.readyz", Success: err == nil, Message: "ready", Timestamp: start, DurationMs: duration}
+	checks := parseReadyz(body)
+	if err != nil {
+		overall.Message = err.Error()
+		if len(checks) > 0 {
+			var failed []string
+			for _, check := range checks {
+				if !check.ok {
+					failed = append(failed, check.name)
+				}
+			}
+			overall.Message = "not ready: " + strings.Join(failed, ", ")
+		}
+	}
+
+	results := []types.HealthCheckResult{overall}
+	for _, check := range checks {
+		results = append(results, types.HealthCheckResult{
+			CheckName:  "apiserver.readyz." + check.name,
+			Success:    check.ok,
+			Message:    check.message,
+			Timestamp:  start,
+			DurationMs: duration,
+		})
+	}
+	return results
+}
+
+// readyzCheck is one line of the verbose /readyz output, e.g. "[+]etcd ok" or
+// "[-]etcd failed: reason withheld".
+type readyzCheck struct {
+	name    string
+	ok      bool
+	message string
+}
+
+// parseReadyz parses the verbose /readyz output.
+func parseReadyz(body []byte) []readyzCheck {
+	var checks []readyzCheck
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 4 || (!strings.HasPrefix(line, "[+]") && !strings.HasPrefix(line, "[-]")) {
+			continue
+		}
+		name, message, _ := strings.Cut(line[3:], " ")
+		checks = append(checks, readyzCheck{name: name, ok: line[1] == '+', message: message})
+	}
+	return checks
+}
+
+// deploymentReady reports whether all replicas of the Deployment are ready.
+func (c *k8sClient) deploymentReady(ctx context.Context, namespace, name string) (string, error) {
+	d, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	want := int32(1)
+	if d.Spec.Replicas != nil {
+		want = *d.Spec.Replicas
+	}
+	message := fmt.Sprintf("%d/%d replicas ready", d.Status.ReadyReplicas, want)
+	if want == 0 || d.Status.ReadyReplicas < want {
+		return "", fmt.Errorf("deployment %s/%s: %s", namespace, name, message)
+	}
+	return message, nil
+}
+
+// configMapRoundTrip writes a fresh value to the probe ConfigMap and reads it back.
+func (c *k8sClient) configMapRoundTrip(ctx context.Context) (string, error) {
+	configMaps := c.clientset.CoreV1().ConfigMaps(healthProbeNamespace)
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	cm, err := configMaps.Get(ctx, healthProbeName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: healthProbeName, Namespace: healthProbeNamespace},
+			Data:       map[string]string{"value": value},
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{FieldManager: FieldManager})
+	case err == nil:
+		cm.Data = map[string]string{"value": value}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{FieldManager: FieldManager})
+	}
+	if err != nil {
+		return "", fmt.Errorf("write failed: %w", err)
+	}
+
+	read, err := configMaps.Get(ctx, healthProbeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("read failed: %w", err)
+	}
+	if read.Data["value"] != value {
+		return "", fmt.Errorf("read back %q, wrote %q", read.Data["value"], value)
+	}
+	return "write and read back succeeded", nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const readyzFailing = `[+]ping ok
+[+]log ok
+[-]etcd failed: reason withheld
+[+]poststarthook/start-apiextensions-informers ok
+readyz check failed
+`
+
+func TestCheckReadyz(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" || r.URL.Query().Get("verbose") == "" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(readyzFailing))
+	}))
+	defer srv.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("failed to create clientset: %v", err)
+	}
+	c := &k8sClient{clientset: clientset}
+
+	results := c.checkReadyz(context.Background())
+	if len(results) != 5 {
+		t.Fatalf("expected the overall result and 4 checks, got %+v", results)
+	}
+	if results[0].CheckName != "apiserver.readyz" || results[0].Success || results[0].Message != "not ready: etcd" {
+		t.Errorf("unexpected overall result: %+v", results[0])
+	}
+	etcd := results[3]
+	if etcd.CheckName != "apiserver.readyz.etcd" || etcd.Success || etcd.Message != "failed: reason withheld" {
+		t.Errorf("unexpected etcd result: %+v", etcd)
+	}
+	if !results[4].Success || results[4].CheckName != "apiserver.readyz.poststarthook/start-apiextensions-informers" {
+		t.Errorf("unexpected result: %+v", results[4])
+	}
+}
+
+func TestDeploymentReady(t *testing.T) {
+	replicas := int32(2)
+	deployment := func(name string, ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+	c := &k8sClient{clientset: fake.NewSimpleClientset(deployment("coredns", 2), deployment("metrics-server", 1))}
+
+	if msg, err := c.deploymentReady(context.Background(), "kube-system", "coredns"); err != nil || msg != "2/2 replicas ready" {
+		t.Errorf("expected coredns to be ready, got %q, %v", msg, err)
+	}
+	if _, err := c.deploymentReady(context.Background(), "kube-system", "metrics-server"); err == nil {
+		t.Errorf("expected metrics-server with 1/2 ready replicas to fail")
+	}
+	if _, err := c.deploymentReady(context.Background(), "kube-system", "missing"); err == nil {
+		t.Errorf("expected a missing deployment to fail")
+	}
+}
+
+func TestCheckWorkloadsWithoutMetricsServer(t *testing.T) {
+	coredns := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+	}
+	c := &k8sClient{clientset: fake.NewSimpleClientset(coredns)}
+
+	for _, result := range c.checkWorkloads(context.Background()) {
+		switch result.CheckName {
+		case "workload.coredns":
+			if !result.Success {
+				t.Errorf("expected coredns to be ready, got %+v", result)
+			}
+		case "workload.metrics-server":
+			if !result.Success || result.Message != "not installed, skipped" {
+				t.Errorf("expected a missing metrics-server to be skipped, got %+v", result)
+			}
+		}
+	}
+}
+
+func TestConfigMapRoundTrip(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	c := &k8sClient{clientset: clientset}
+
+	for i := 0; i < 2; i++ { // Creates the ConfigMap, then updates it
+		result := timed("kine.configmapRoundTrip", func() (string, error) { return c.configMapRoundTrip(context.Background()) })
+		if !result.Success {
+			t.Fatalf("round trip %d failed: %s", i, result.Message)
+		}
+	}
+	cm, err := clientset.CoreV1().ConfigMaps(healthProbeNamespace).Get(context.Background(), healthProbeName, metav1.GetOptions{})
+	if err != nil || cm.Data["value"] == "" {
+		t.Errorf("expected the probe ConfigMap to hold a value, got %v, %v", cm, err)
+	}
+}
+
+//Personal.AI order the ending
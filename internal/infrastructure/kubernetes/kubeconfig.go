@@ -0,0 +1,141 @@
+package kubernetes
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// K3sKubeconfigPath is where k3s writes the admin kubeconfig on a server node.
+const K3sKubeconfigPath = "/etc/rancher/k3s/k3s.yaml"
+
+// RewriteKubeconfig turns the admin kubeconfig written by k3s into one for the
+// cluster: the server points at the VIP instead of the local node, and the
+// cluster, user and context are named after the cluster.
+func RewriteKubeconfig(kubeconfig []byte, clusterName, vip string) ([]byte, error) {
+	in, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to parse kubeconfig")
+	}
+	current, ok := in.Contexts[in.CurrentContext]
+	if !ok {
+		return nil, errors.Newf(errors.KubernetesError, "kubeconfig has no current context '%s'", in.CurrentContext)
+	}
+	cluster, ok := in.Clusters[current.Cluster]
+	if !ok {
+		return nil, errors.Newf(errors.KubernetesError, "kubeconfig has no cluster '%s'", current.Cluster)
+	}
+	user, ok := in.AuthInfos[current.AuthInfo]
+	if !ok {
+		return nil, errors.Newf(errors.KubernetesError, "kubeconfig has no user '%s'", current.AuthInfo)
+	}
+
+	server, err := url.Parse(cluster.Server)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.KubernetesError, "invalid server URL '%s'", cluster.Server)
+	}
+	port := server.Port()
+	if port == "" {
+		port = "6443"
+	}
+	server.Host = net.JoinHostPort(vip, port)
+	cluster.Server = server.String()
+
+	userName := clusterName + "-admin"
+	out := clientcmdapi.NewConfig()
+	out.Clusters[clusterName] = cluster
+	out.AuthInfos[userName] = user
+	out.Contexts[clusterName] = &clientcmdapi.Context{Cluster: clusterName, AuthInfo: userName}
+	out.CurrentContext = clusterName
+	return clientcmd.Write(*out)
+}
+
+// ServerCA returns the server URL and the CA certificates of the cluster of the
+// current context.
+func ServerCA(kubeconfig []byte) (string, []byte, error) {
+	cfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return "", nil, errors.Wrap(err, errors.KubernetesError, "failed to parse kubeconfig")
+	}
+	current, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return "", nil, errors.Newf(errors.KubernetesError, "kubeconfig has no current context '%s'", cfg.CurrentContext)
+	}
+	cluster, ok := cfg.Clusters[current.Cluster]
+	if !ok {
+		return "", nil, errors.Newf(errors.KubernetesError, "kubeconfig has no cluster '%s'", current.Cluster)
+	}
+	return cluster.Server, cluster.CertificateAuthorityData, nil
+}
+
+// WithTokenCredential replaces the user of the current context with one named
+// userName that authenticates with token only.
+func WithTokenCredential(kubeconfig []byte, userName, token string) ([]byte, error) {
+	cfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to parse kubeconfig")
+	}
+	current, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return nil, errors.Newf(errors.KubernetesError, "kubeconfig has no current context '%s'", cfg.CurrentContext)
+	}
+	delete(cfg.AuthInfos, current.AuthInfo)
+	cfg.AuthInfos[userName] = &clientcmdapi.AuthInfo{Token: token}
+	current.AuthInfo = userName
+	return clientcmd.Write(*cfg)
+}
+
+// MergeKubeconfig merges the clusters, users and contexts of kubeconfig into
+// the file at path, replacing entries with the same names and leaving all other
+// entries alone. The current context only changes if setCurrent is true or the
+// file had none. The file is replaced atomically and kept private to its owner.
+func MergeKubeconfig(path string, kubeconfig []byte, setCurrent bool) error {
+	in, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return errors.Wrap(err, errors.KubernetesError, "failed to parse kubeconfig")
+	}
+
+	existing := clientcmdapi.NewConfig()
+	if _, err := os.Stat(path); err == nil {
+		existing, err = clientcmd.LoadFromFile(path)
+		if err != nil {
+			return errors.Wrapf(err, errors.KubernetesError, "failed to load kubeconfig %s", path)
+		}
+	}
+
+	for name, cluster := range in.Clusters {
+		existing.Clusters[name] = cluster
+	}
+	for name, user := range in.AuthInfos {
+		existing.AuthInfos[name] = user
+	}
+	for name, ctx := range in.Contexts {
+		existing.Contexts[name] = ctx
+	}
+	if setCurrent || existing.CurrentContext == "" {
+		existing.CurrentContext = in.CurrentContext
+	}
+
+	data, err := clientcmd.Write(*existing)
+	if err != nil {
+		return errors.Wrap(err, errors.KubernetesError, "failed to serialize kubeconfig")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to create directory for %s", path)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to write %s", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to replace %s", path)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
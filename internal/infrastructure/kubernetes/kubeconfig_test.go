@@ -0,0 +1,105 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const k3sKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: Q0E=
+    server: https://127.0.0.1:6443
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+users:
+- name: default
+  user:
+    client-certificate-data: Q0VSVA==
+    client-key-data: S0VZ
+`
+
+func TestRewriteKubeconfig(t *testing.T) {
+	for _, tc := range []struct{ vip, server string }{
+		{"10.0.0.100", "https://10.0.0.100:6443"},
+		{"fd00::100", "https://[fd00::100]:6443"},
+	} {
+		out, err := RewriteKubeconfig([]byte(k3sKubeconfig), "prod", tc.vip)
+		if err != nil {
+			t.Fatalf("RewriteKubeconfig failed: %v", err)
+		}
+		cfg, _ := clientcmd.Load(out)
+		if cfg.CurrentContext != "prod" || cfg.Contexts["prod"].AuthInfo != "prod-admin" {
+			t.Errorf("expected context 'prod' with user 'prod-admin', got %+v", cfg.Contexts)
+		}
+		if got := cfg.Clusters["prod"].Server; got != tc.server {
+			t.Errorf("expected server %s, got %s", tc.server, got)
+		}
+		if string(cfg.AuthInfos["prod-admin"].ClientKeyData) != "KEY" {
+			t.Errorf("expected the admin credential to be kept")
+		}
+		if server, ca, err := ServerCA(out); err != nil || server != tc.server || string(ca) != "CA" {
+			t.Errorf("expected server %s with the k3s CA, got %s %q (%v)", tc.server, server, ca, err)
+		}
+	}
+}
+
+func TestWithTokenCredential(t *testing.T) {
+	admin, _ := RewriteKubeconfig([]byte(k3sKubeconfig), "prod", "10.0.0.100")
+	out, err := WithTokenCredential(admin, "prod-view", "secret-token")
+	if err != nil {
+		t.Fatalf("WithTokenCredential failed: %v", err)
+	}
+	cfg, _ := clientcmd.Load(out)
+	if _, ok := cfg.AuthInfos["prod-admin"]; ok {
+		t.Errorf("expected the admin credential to be removed")
+	}
+	if user := cfg.AuthInfos["prod-view"]; user == nil || user.Token != "secret-token" || len(user.ClientKeyData) != 0 {
+		t.Errorf("expected a token-only user, got %+v", user)
+	}
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".kube", "config")
+	other, _ := RewriteKubeconfig([]byte(k3sKubeconfig), "other", "10.0.1.100")
+	if err := MergeKubeconfig(path, other, false); err != nil {
+		t.Fatalf("MergeKubeconfig into a new file failed: %v", err)
+	}
+
+	prod, _ := RewriteKubeconfig([]byte(k3sKubeconfig), "prod", "10.0.0.100")
+	if err := MergeKubeconfig(path, prod, false); err != nil {
+		t.Fatalf("MergeKubeconfig failed: %v", err)
+	}
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to load merged kubeconfig: %v", err)
+	}
+	if cfg.Contexts["other"] == nil || cfg.Contexts["prod"] == nil {
+		t.Errorf("expected both contexts, got %v", cfg.Contexts)
+	}
+	if cfg.CurrentContext != "other" {
+		t.Errorf("expected the current context to be kept, got %s", cfg.CurrentContext)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected kubeconfig to be private, got %v", info.Mode().Perm())
+	}
+
+	if err := MergeKubeconfig(path, prod, true); err != nil {
+		t.Fatalf("MergeKubeconfig failed: %v", err)
+	}
+	cfg, _ = clientcmd.LoadFromFile(path)
+	if cfg.CurrentContext != "prod" {
+		t.Errorf("expected the current context to switch to prod, got %s", cfg.CurrentContext)
+	}
+}
+
+//Personal.AI order the ending
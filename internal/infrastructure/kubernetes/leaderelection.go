@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Leader election timings. A crashed holder is replaced after at most
+// leaseDuration; package vars so tests can shorten them.
+var (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// RunWithLeaderElection competes for the Lease namespace/name under identity
+// and calls run while holding it. run's context is cancelled when the lease is
+// lost; the election then starts over. The lease is released when ctx is
+// cancelled, so the other candidate takes over without waiting for it to expire.
+func (c *k8sClient) RunWithLeaderElection(ctx context.Context, namespace, name, identity string, run func(ctx context.Context)) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Client:     c.clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	config := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {},
+		},
+	}
+	for ctx.Err() == nil {
+		elector, err := leaderelection.NewLeaderElector(config)
+		if err != nil {
+			return errors.Wrapf(err, errors.KubernetesError, "failed to set up leader election for lease %s/%s", namespace, name)
+		}
+		elector.Run(ctx)
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunWithLeaderElection(t *testing.T) {
+	leaseDuration, renewDeadline, retryPeriod = time.Second, 500*time.Millisecond, 50*time.Millisecond
+	c := &k8sClient{clientset: fake.NewSimpleClientset()}
+
+	leading := make(chan string, 2)
+	start := func(identity string) context.CancelFunc {
+		ctx, cancel := context.WithCancel(context.Background())
+		go c.RunWithLeaderElection(ctx, "kube-system", "test", identity, func(ctx context.Context) {
+			leading <- identity
+			<-ctx.Done()
+		})
+		return cancel
+	}
+
+	stopFirst := start("node1")
+	select {
+	case id := <-leading:
+		if id != "node1" {
+			t.Fatalf("expected node1 to lead, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("node1 did not become leader")
+	}
+
+	stopSecond := start("node2")
+	defer stopSecond()
+	select {
+	case id := <-leading:
+		t.Fatalf("expected a single leader, but %s also leads", id)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	stopFirst()
+	select {
+	case id := <-leading:
+		if id != "node2" {
+			t.Fatalf("expected node2 to take over, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("node2 did not take over after node1 stopped")
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeLeaseNamespace holds the Lease each kubelet renews as its heartbeat.
+const NodeLeaseNamespace = "kube-node-lease"
+
+// nodeCache serves nodes from informers on Nodes and node Leases.
+type nodeCache struct {
+	nodes  corelisters.NodeLister
+	leases coordinationlisters.LeaseLister
+
+	mu       sync.Mutex // Serializes handler calls and guards the fields below
+	handlers []func(types.Node)
+	expiries map[string]*time.Timer // Fires when a node's Lease expires without renewal
+}
+
+// StartNodeCache starts watching Nodes and their Leases and waits until both
+// are listed. The watches stop when ctx is cancelled.
+func (c *k8sClient) StartNodeCache(ctx context.Context) (api.NodeCache, error) {
+	factory := informers.NewSharedInformerFactory(c.clientset, 0)
+	leaseFactory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0, informers.WithNamespace(NodeLeaseNamespace))
+	nodeInformer := factory.Core().V1().Nodes()
+	leaseInformer := leaseFactory.Coordination().V1().Leases()
+
+	nc := &nodeCache{
+		nodes:    nodeInformer.Lister(),
+		leases:   leaseInformer.Lister(),
+		expiries: make(map[string]*time.Timer),
+	}
+	if _, err := nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { nc.nodeChanged(obj) },
+		UpdateFunc: func(_, obj interface{}) { nc.nodeChanged(obj) },
+	}); err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to watch nodes")
+	}
+	if _, err := leaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { nc.leaseChanged(obj) },
+		UpdateFunc: func(_, obj interface{}) { nc.leaseChanged(obj) },
+	}); err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to watch node leases")
+	}
+
+	factory.Start(ctx.Done())
+	leaseFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced, leaseInformer.Informer().HasSynced) {
+		return nil, errors.New(errors.KubernetesError, "node cache did not sync")
+	}
+	go func() {
+		<-ctx.Done()
+		nc.mu.Lock()
+		defer nc.mu.Unlock()
+		for name, timer := range nc.expiries {
+			timer.Stop()
+			delete(nc.expiries, name)
+		}
+	}()
+	return nc, nil
+}
+
+// GetNodes returns the cached nodes.
+func (nc *nodeCache) GetNodes(ctx context.Context) ([]types.Node, error) {
+	list, err := nc.nodes.List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KubernetesError, "failed to list cached nodes")
+	}
+	nodes := make([]types.Node, 0, len(list))
+	for _, n := range list {
+		nodes = append(nodes, nc.toNode(n))
+	}
+	return nodes, nil
+}
+
+// Subscribe registers handler for node changes. Handlers are called one at a time.
+func (nc *nodeCache) Subscribe(handler func(types.Node)) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.handlers = append(nc.handlers, handler)
+}
+
+// toNode maps the node and applies its Lease: a renewal is a more recent
+// heartbeat than the Ready condition, and an expired Lease means the kubelet
+// has stopped, before the node controller gets to mark the node NotReady.
+func (nc *nodeCache) toNode(n *corev1.Node) types.Node {
+	node := toNode(n)
+	lease, err := nc.leases.Leases(NodeLeaseNamespace).Get(n.Name)
+	if err != nil || lease.Spec.RenewTime == nil {
+		return node
+	}
+	renewed := lease.Spec.RenewTime.Time
+	if renewed.After(node.Status.LastHeartbeatTime) {
+		node.Status.LastHeartbeatTime = renewed
+	}
+	if expiry, ok := leaseExpiry(lease); ok && !time.Now().Before(expiry) {
+		node.Status.Status = types.NodeStatusUnhealthy
+		node.Status.Message = fmt.Sprintf("kubelet lease expired at %s", expiry.UTC().Format(time.RFC3339))
+	}
+	return node
+}
+
+// leaseExpiry returns when the Lease expires unless renewed again.
+func leaseExpiry(lease *coordinationv1.Lease) (time.Time, bool) {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return time.Time{}, false
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second), true
+}
+
+// nodeChanged notifies the handlers of a Node update.
+func (nc *nodeCache) nodeChanged(obj interface{}) {
+	if n, ok := obj.(*corev1.Node); ok {
+		nc.notify(nc.toNode(n))
+	}
+}
+
+// leaseChanged notifies the handlers of the Lease's node and arms a timer that
+// notifies them again if the Lease expires.
+func (nc *nodeCache) leaseChanged(obj interface{}) {
+	lease, ok := obj.(*coordinationv1.Lease)
+	if !ok {
+		return
+	}
+	name := lease.Name
+	if expiry, ok := leaseExpiry(lease); ok {
+		nc.mu.Lock()
+		if timer := nc.expiries[name]; timer != nil {
+			timer.Stop()
+		}
+		nc.expiries[name] = time.AfterFunc(time.Until(expiry), func() { nc.notifyByName(name) })
+		nc.mu.Unlock()
+	}
+	nc.notifyByName(name)
+}
+
+// notifyByName notifies the handlers of the named node, if it is known.
+func (nc *nodeCache) notifyByName(name string) {
+	n, err := nc.nodes.Get(name)
+	if err != nil {
+		return
+	}
+	nc.notify(nc.toNode(n))
+}
+
+// notify calls every handler with node.
+func (nc *nodeCache) notify(node types.Node) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	for _, handler := range nc.handlers {
+		handler(node)
+	}
+}
+
+//Personal.AI order the ending
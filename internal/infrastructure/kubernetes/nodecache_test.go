@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/node"
+	"github.com/turtacn/geminik8s/pkg/types"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testLease(name string, renewed time.Time, duration int32) *coordinationv1.Lease {
+	renewTime := metav1.NewMicroTime(renewed)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NodeLeaseNamespace},
+		Spec:       coordinationv1.LeaseSpec{RenewTime: &renewTime, LeaseDurationSeconds: &duration},
+	}
+}
+
+func TestNodeCache(t *testing.T) {
+	heartbeat := time.Now().Add(-time.Minute)
+	clientset := fake.NewSimpleClientset(
+		testNode("node1", "10.0.0.1", "leader", corev1.ConditionTrue, corev1.ConditionFalse, heartbeat),
+		testNode("node2", "10.0.0.2", "follower", corev1.ConditionTrue, corev1.ConditionFalse, heartbeat),
+		testLease("node1", time.Now(), 40),
+		testLease("node2", heartbeat, 40), // Expired: the kubelet stopped renewing
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodeCache, err := (&k8sClient{clientset: clientset}).StartNodeCache(ctx)
+	if err != nil {
+		t.Fatalf("StartNodeCache failed: %v", err)
+	}
+	nodes, err := nodeCache.GetNodes(ctx)
+	if err != nil || len(nodes) != 2 {
+		t.Fatalf("expected 2 cached nodes, got %+v (%v)", nodes, err)
+	}
+	status := map[string]types.NodeStatus{}
+	for _, n := range nodes {
+		status[n.Config.Name] = n.Status
+	}
+	if s := status["node1"]; s.Status != types.NodeStatusHealthy || !s.LastHeartbeatTime.After(heartbeat) {
+		t.Errorf("expected node1 to be healthy with the lease renewal as heartbeat, got %+v", s)
+	}
+	if s := status["node2"]; s.Status != types.NodeStatusUnhealthy {
+		t.Errorf("expected node2 to be unhealthy with an expired lease, got %+v", s)
+	}
+
+	changes := make(chan types.Node, 10)
+	nodeCache.Subscribe(func(n types.Node) { changes <- n })
+
+	// node1's kubelet renews once more, with a lease that expires almost at once.
+	if _, err := clientset.CoordinationV1().Leases(NodeLeaseNamespace).Update(ctx, testLease("node1", time.Now(), 1), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to renew lease: %v", err)
+	}
+	want := []types.NodeStatusType{types.NodeStatusHealthy, types.NodeStatusUnhealthy}
+	for _, status := range want {
+		select {
+		case n := <-changes:
+			if n.Config.Name != "node1" || n.Status.Status != status {
+				t.Errorf("expected node1 to be %s, got %+v", status, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for node1 to become %s", status)
+		}
+	}
+}
+
+func TestNodeCacheServesHealthChecksWithoutListing(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		testNode("node1", "10.0.0.1", "leader", corev1.ConditionTrue, corev1.ConditionFalse, time.Now()),
+		testLease("node1", time.Now(), 40),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &k8sClient{clientset: clientset}
+	nodeCache, err := client.StartNodeCache(ctx)
+	if err != nil {
+		t.Fatalf("StartNodeCache failed: %v", err)
+	}
+	clientset.ClearActions()
+
+	service := node.NewCachedService(nil, nil, client, nodeCache)
+	for i := 0; i < 3; i++ {
+		if healthy, err := service.CheckNodeHealth(ctx, "10.0.0.1"); err != nil || !healthy {
+			t.Fatalf("expected node1 to be healthy, got %v (%v)", healthy, err)
+		}
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" {
+			t.Errorf("expected no list once the cache has synced, got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,104 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// EpochLabel is the node label that carries the fencing epoch of the role in RoleLabel.
+	EpochLabel = "geminik8s.io/epoch"
+	// managedTaintsAnnotation lists the keys of the taints geminik8s has set on
+	// the node, so they are removed when the role or the configuration changes.
+	managedTaintsAnnotation = "geminik8s.io/managed-taints"
+)
+
+// SetNodeRole labels the node with its role and epoch and replaces the taints
+// geminik8s manages with those configured for the role, in a single update.
+// Labels from a newer epoch are never overwritten, so a fenced node cannot
+// undo what the current leader has set.
+func (c *k8sClient) SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error {
+	nodes := c.clientset.CoreV1().Nodes()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := nodes.Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current, err := strconv.ParseInt(node.Labels[EpochLabel], 10, 64); err == nil && current > epoch {
+			return errors.Newf(errors.KubernetesError, "node %s is labeled by newer epoch %d, not applying epoch %d", nodeName, current, epoch)
+		}
+
+		updated := node.DeepCopy()
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		updated.Labels[RoleLabel] = strings.ToLower(string(role))
+		updated.Labels[EpochLabel] = strconv.FormatInt(epoch, 10)
+		setManagedTaints(updated, cfg.TaintsFor(role))
+		if reflect.DeepEqual(updated.Labels, node.Labels) && reflect.DeepEqual(updated.Annotations, node.Annotations) &&
+			reflect.DeepEqual(updated.Spec.Taints, node.Spec.Taints) {
+			return nil
+		}
+		_, err = nodes.Update(ctx, updated, metav1.UpdateOptions{FieldManager: FieldManager})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "failed to set role of node %s", nodeName)
+	}
+	return nil
+}
+
+// setManagedTaints removes the taints geminik8s set before and adds taints.
+// Taints added by others are left alone.
+func setManagedTaints(node *corev1.Node, taints []types.Taint) {
+	managed := map[string]bool{}
+	if previous := node.Annotations[managedTaintsAnnotation]; previous != "" {
+		for _, key := range strings.Split(previous, ",") {
+			managed[key] = true
+		}
+	}
+	var keys []string
+	for _, t := range taints {
+		managed[t.Key] = true
+		keys = append(keys, t.Key)
+	}
+
+	var kept []corev1.Taint
+	for _, t := range node.Spec.Taints {
+		if !managed[t.Key] {
+			kept = append(kept, t)
+		}
+	}
+	for _, t := range taints {
+		taint := corev1.Taint{Key: t.Key, Value: t.Value, Effect: corev1.TaintEffect(t.Effect)}
+		// Keep the TimeAdded the API server set on an existing NoExecute taint.
+		for _, existing := range node.Spec.Taints {
+			if existing.MatchTaint(&taint) && existing.Value == taint.Value {
+				taint = existing
+			}
+		}
+		kept = append(kept, taint)
+	}
+	node.Spec.Taints = kept
+
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		delete(node.Annotations, managedTaintsAnnotation)
+		return
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[managedTaintsAnnotation] = strings.Join(keys, ",")
+}
+
+//Personal.AI order the ending
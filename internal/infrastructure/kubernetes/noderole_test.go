@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSetNodeRole(t *testing.T) {
+	node := testNode("node1", "10.0.0.1", "", corev1.ConditionTrue, corev1.ConditionFalse, time.Now())
+	node.Spec.Taints = []corev1.Taint{{Key: "example.com/gpu", Effect: corev1.TaintEffectNoSchedule}}
+	clientset := fake.NewSimpleClientset(node)
+	c := &k8sClient{clientset: clientset}
+	ctx := context.Background()
+	cfg := &types.NodeLabelsConfig{
+		LeaderTaints: []types.Taint{{Key: "geminik8s.io/leader", Value: "true", Effect: "PreferNoSchedule"}},
+	}
+	get := func() *corev1.Node {
+		n, err := clientset.CoreV1().Nodes().Get(ctx, "node1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node: %v", err)
+		}
+		return n
+	}
+
+	if err := c.SetNodeRole(ctx, "node1", types.RoleLeader, 3, cfg); err != nil {
+		t.Fatalf("SetNodeRole failed: %v", err)
+	}
+	n := get()
+	if n.Labels[RoleLabel] != "leader" || n.Labels[EpochLabel] != "3" {
+		t.Errorf("unexpected labels: %v", n.Labels)
+	}
+	if len(n.Spec.Taints) != 2 || n.Spec.Taints[1].Key != "geminik8s.io/leader" || n.Spec.Taints[1].Effect != corev1.TaintEffectPreferNoSchedule {
+		t.Errorf("expected the leader taint next to the existing one, got %+v", n.Spec.Taints)
+	}
+
+	// Demoted in a later epoch: the leader taint goes, the foreign taint stays.
+	if err := c.SetNodeRole(ctx, "node1", types.RoleFollower, 4, cfg); err != nil {
+		t.Fatalf("SetNodeRole failed: %v", err)
+	}
+	n = get()
+	if n.Labels[RoleLabel] != "follower" || n.Labels[EpochLabel] != "4" {
+		t.Errorf("unexpected labels: %v", n.Labels)
+	}
+	if len(n.Spec.Taints) != 1 || n.Spec.Taints[0].Key != "example.com/gpu" {
+		t.Errorf("expected only the foreign taint to remain, got %+v", n.Spec.Taints)
+	}
+	if _, ok := n.Annotations[managedTaintsAnnotation]; ok {
+		t.Errorf("expected no managed taints annotation, got %v", n.Annotations)
+	}
+
+	// A fenced node from an older epoch cannot relabel.
+	if err := c.SetNodeRole(ctx, "node1", types.RoleLeader, 3, cfg); err == nil {
+		t.Errorf("expected an error when applying an older epoch")
+	}
+	if n = get(); n.Labels[RoleLabel] != "follower" {
+		t.Errorf("expected the labels of epoch 4 to remain, got %v", n.Labels)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RoleLabel is the node label that carries the geminik8s role ("leader" or "follower").
+const RoleLabel = "geminik8s.io/role"
+
+// nodeConditionChecks are the node conditions reported as health checks,
+// with the condition status that counts as healthy.
+var nodeConditionChecks = []struct {
+	condition corev1.NodeConditionType
+	healthy   corev1.ConditionStatus
+}{
+	{corev1.NodeReady, corev1.ConditionTrue},
+	{corev1.NodeMemoryPressure, corev1.ConditionFalse},
+	{corev1.NodeDiskPressure, corev1.ConditionFalse},
+}
+
+// toNode maps a Kubernetes node to a types.Node.
+func toNode(n *corev1.Node) types.Node {
+	node := types.Node{
+		Config: types.NodeConfig{
+			Name: n.Name,
+			Role: roleFromLabel(n.Labels[RoleLabel]),
+		},
+		Status: types.NodeStatus{Status: types.NodeStatusUnknown, KubeletVersion: n.Status.NodeInfo.KubeletVersion},
+	}
+
+	// A dual-stack node has an InternalIP of each family.
+	if ips := internalIPs(n); len(ips) > 0 {
+		node.Config.IP, node.Config.Addresses = ips[0], ips[1:]
+	}
+
+	var failed []string
+	ready := corev1.ConditionUnknown
+	for _, check := range nodeConditionChecks {
+		cond := findCondition(n, check.condition)
+		if cond == nil {
+			continue
+		}
+		if check.condition == corev1.NodeReady {
+			ready = cond.Status
+			// The kubelet refreshes the Ready condition with every status update.
+			node.Status.LastHeartbeatTime = cond.LastHeartbeatTime.Time
+		}
+		result := types.HealthCheckResult{
+			CheckName: "node." + string(check.condition),
+			Success:   cond.Status == check.healthy,
+			Message:   conditionMessage(cond),
+			Timestamp: conditionTime(cond),
+		}
+		if !result.Success {
+			failed = append(failed, fmt.Sprintf("%s=%s", cond.Type, cond.Status))
+		}
+		node.Status.HealthChecks = append(node.Status.HealthChecks, result)
+	}
+
+	switch {
+	case ready == corev1.ConditionUnknown:
+		node.Status.Status = types.NodeStatusUnknown
+		node.Status.Message = "node readiness is unknown"
+	case len(failed) > 0:
+		node.Status.Status = types.NodeStatusUnhealthy
+		node.Status.Message = strings.Join(failed, ", ")
+	default:
+		node.Status.Status = types.NodeStatusHealthy
+	}
+	return node
+}
+
+// internalIPs returns the node's InternalIP addresses in the order reported.
+func internalIPs(n *corev1.Node) []string {
+	var ips []string
+	for _, addr := range n.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			ips = append(ips, addr.Address)
+		}
+	}
+	return ips
+}
+
+// roleFromLabel turns the value of RoleLabel into a node role.
+func roleFromLabel(value string) types.NodeRole {
+	switch strings.ToLower(value) {
+	case "leader":
+		return types.RoleLeader
+	case "follower":
+		return types.RoleFollower
+	default:
+		return types.RoleUnknown
+	}
+}
+
+// findCondition returns the node condition of the given type, or nil.
+func findCondition(n *corev1.Node, t corev1.NodeConditionType) *corev1.NodeCondition {
+	for i := range n.Status.Conditions {
+		if n.Status.Conditions[i].Type == t {
+			return &n.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// conditionMessage describes a condition by its message, falling back to its reason.
+func conditionMessage(cond *corev1.NodeCondition) string {
+	if cond.Message != "" {
+		return cond.Message
+	}
+	return cond.Reason
+}
+
+// conditionTime is when the condition was last confirmed.
+func conditionTime(cond *corev1.NodeCondition) time.Time {
+	if !cond.LastHeartbeatTime.IsZero() {
+		return cond.LastHeartbeatTime.Time
+	}
+	return cond.LastTransitionTime.Time
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testNode(name, ip, role string, ready, diskPressure corev1.ConditionStatus, heartbeat time.Time) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{RoleLabel: role}},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.4+k3s1"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: ip},
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: ready, Reason: "KubeletReady", LastHeartbeatTime: metav1.NewTime(heartbeat)},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse, LastHeartbeatTime: metav1.NewTime(heartbeat)},
+				{Type: corev1.NodeDiskPressure, Status: diskPressure, Message: "disk is full", LastHeartbeatTime: metav1.NewTime(heartbeat)},
+			},
+		},
+	}
+}
+
+func TestGetNodes(t *testing.T) {
+	heartbeat := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &k8sClient{clientset: fake.NewSimpleClientset(
+		testNode("node1", "10.0.0.1", "leader", corev1.ConditionTrue, corev1.ConditionFalse, heartbeat),
+		testNode("node2", "10.0.0.2", "follower", corev1.ConditionTrue, corev1.ConditionTrue, heartbeat),
+	)}
+
+	nodes, err := c.GetNodes(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodes failed: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+
+	leader := nodes[0]
+	if leader.Config.Name != "node1" || leader.Config.IP != "10.0.0.1" || leader.Config.Role != types.RoleLeader {
+		t.Errorf("unexpected leader config: %+v", leader.Config)
+	}
+	if leader.Status.Status != types.NodeStatusHealthy || !leader.Status.LastHeartbeatTime.Equal(heartbeat) || leader.Status.KubeletVersion != "v1.29.4+k3s1" {
+		t.Errorf("unexpected leader status: %+v", leader.Status)
+	}
+	if len(leader.Status.HealthChecks) != 3 || leader.Status.HealthChecks[0].CheckName != "node.Ready" ||
+		!leader.Status.HealthChecks[0].Timestamp.Equal(heartbeat) {
+		t.Errorf("unexpected leader health checks: %+v", leader.Status.HealthChecks)
+	}
+
+	follower := nodes[1]
+	if follower.Config.Role != types.RoleFollower {
+		t.Errorf("expected follower role, got %s", follower.Config.Role)
+	}
+	if follower.Status.Status != types.NodeStatusUnhealthy || follower.Status.Message != "DiskPressure=True" {
+		t.Errorf("expected disk pressure to make the follower unhealthy, got %+v", follower.Status)
+	}
+	if disk := follower.Status.HealthChecks[2]; disk.Success || disk.Message != "disk is full" {
+		t.Errorf("unexpected disk pressure check: %+v", disk)
+	}
+}
+
+func TestGetNodesUnknownReadiness(t *testing.T) {
+	n := testNode("node1", "10.0.0.1", "", corev1.ConditionUnknown, corev1.ConditionFalse, time.Now())
+	node := toNode(n)
+	if node.Status.Status != types.NodeStatusUnknown || node.Config.Role != types.RoleUnknown {
+		t.Errorf("expected unknown status and role, got %+v", node)
+	}
+}
+
+func TestGetNodesDualStack(t *testing.T) {
+	n := testNode("node1", "10.0.0.1", "leader", corev1.ConditionTrue, corev1.ConditionFalse, time.Now())
+	n.Status.Addresses = append(n.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "fd00::1"})
+	node := toNode(n)
+	if node.Config.IP != "10.0.0.1" || len(node.Config.Addresses) != 1 || node.Config.Addresses[0] != "fd00::1" {
+		t.Errorf("expected both InternalIPs, got %+v", node.Config)
+	}
+	if !node.Config.HasAddress("fd00::1") || node.Config.HasAddress("10.0.0.2") {
+		t.Errorf("unexpected address match for %+v", node.Config)
+	}
+}
+
+//Personal.AI order the ending
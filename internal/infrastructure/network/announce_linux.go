@@ -0,0 +1,121 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// Announcements are repeated, as single packets may be lost.
+var (
+	announceCount    = 3
+	announceInterval = 100 * time.Millisecond
+)
+
+var (
+	broadcastMAC      = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	allNodesMulticast = net.ParseIP("ff02::1")
+)
+
+// announce tells the hosts on link's segment that ip is now reachable at
+// link's hardware address. Interfaces without ARP, like loopback, are skipped.
+func announce(link netlink.Link, ip net.IP) error {
+	attrs := link.Attrs()
+	if attrs.RawFlags&unix.IFF_NOARP != 0 || len(attrs.HardwareAddr) != 6 {
+		return nil
+	}
+	send := sendGratuitousARP
+	if ip.To4() == nil {
+		send = sendNeighborAdvertisement
+	}
+	for i := 0; i < announceCount; i++ {
+		if i > 0 {
+			time.Sleep(announceInterval)
+		}
+		if err := send(attrs, ip); err != nil {
+			return errors.Wrapf(err, errors.NetworkError, "failed to announce VIP %s on %s", ip, attrs.Name)
+		}
+	}
+	return nil
+}
+
+// sendGratuitousARP broadcasts an ARP request for ip from ip itself.
+func sendGratuitousARP(attrs *netlink.LinkAttrs, ip net.IP) error {
+	frame := arpFrame(arpRequest, attrs.HardwareAddr, ip.To4(), broadcastMAC, net.HardwareAddr(make([]byte, 6)), ip.To4())
+	return sendFrame(attrs.Index, frame)
+}
+
+// arpRequest is the ARP request operation.
+const arpRequest = 1
+
+// arpFrame builds an Ethernet frame carrying an ARP packet for IPv4 over Ethernet.
+func arpFrame(op uint16, senderMAC net.HardwareAddr, senderIP net.IP, dstMAC, targetMAC net.HardwareAddr, targetIP net.IP) []byte {
+	frame := make([]byte, 0, 42)
+	frame = append(frame, dstMAC...)
+	frame = append(frame, senderMAC...)
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_ARP)
+	frame = binary.BigEndian.AppendUint16(frame, 1) // Hardware type Ethernet
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_IP)
+	frame = append(frame, 6, 4)
+	frame = binary.BigEndian.AppendUint16(frame, op)
+	frame = append(frame, senderMAC...)
+	frame = append(frame, senderIP.To4()...)
+	frame = append(frame, targetMAC...)
+	frame = append(frame, targetIP.To4()...)
+	return frame
+}
+
+// sendFrame writes an ARP Ethernet frame to the interface with the given index.
+func sendFrame(ifindex int, frame []byte) error {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	addr := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: ifindex, Halen: 6}
+	copy(addr.Addr[:], frame[:6])
+	return unix.Sendto(fd, frame, 0, addr)
+}
+
+// sendNeighborAdvertisement sends an unsolicited neighbor advertisement for ip
+// to all nodes, with the override flag set so that existing entries are replaced.
+func sendNeighborAdvertisement(attrs *netlink.LinkAttrs, ip net.IP) error {
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	body := make([]byte, 0, 28)
+	body = binary.BigEndian.AppendUint32(body, 1<<29) // Override
+	body = append(body, ip.To16()...)
+	body = append(body, 2, 1) // Target link-layer address option, 8 bytes
+	body = append(body, attrs.HardwareAddr...)
+	msg := icmp.Message{Type: ipv6.ICMPTypeNeighborAdvertisement, Body: &icmp.RawBody{Data: body}}
+	packet, err := msg.Marshal(nil) // The kernel fills in the checksum
+	if err != nil {
+		return err
+	}
+
+	// Neighbor Discovery messages must have a hop limit of 255 (RFC 4861).
+	pc := conn.IPv6PacketConn()
+	if err := pc.SetMulticastHopLimit(255); err != nil {
+		return err
+	}
+	cm := &ipv6.ControlMessage{Src: ip, IfIndex: attrs.Index, HopLimit: 255}
+	_, err = pc.WriteTo(packet, cm, &net.IPAddr{IP: allNodesMulticast, Zone: attrs.Name})
+	return err
+}
+
+// htons converts a short from host to network byte order.
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+//Personal.AI order the ending
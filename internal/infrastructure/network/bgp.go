@@ -0,0 +1,746 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// BGP message types (RFC 4271).
+const (
+	bgpOpen         = 1
+	bgpUpdate       = 2
+	bgpNotification = 3
+	bgpKeepalive    = 4
+)
+
+// Path attribute flags and type codes (RFC 4271, RFC 1997, RFC 4760, RFC 6793).
+const (
+	attrFlagOptional   = 0x80
+	attrFlagTransitive = 0x40
+	attrFlagExtended   = 0x10
+
+	attrOrigin      = 1
+	attrASPath      = 2
+	attrNextHop     = 3
+	attrMED         = 4
+	attrLocalPref   = 5
+	attrCommunities = 8
+	attrMPReach     = 14
+	attrMPUnreach   = 15
+	attrAS4Path     = 17
+)
+
+// Capability codes and address families used in OPEN messages.
+const (
+	capMultiprotocol = 1
+	capFourOctetAS   = 65
+
+	afiIPv4     = 1
+	afiIPv6     = 2
+	safiUnicast = 1
+)
+
+// NOTIFICATION error codes and the subcodes the speaker sends.
+const (
+	notifHeaderError   = 1
+	notifOpenError     = 2
+	notifUpdateError   = 3
+	notifHoldExpired   = 4
+	notifFSMError      = 5
+	notifCease         = 6
+	openBadVersion     = 1
+	openBadPeerAS      = 2
+	openBadHoldTime    = 6
+	ceaseAdminShutdown = 2
+)
+
+// Fixed values of the protocol.
+const (
+	bgpHeaderLen      = 19
+	bgpMaxMessageLen  = 4096
+	bgpVersion        = 4
+	asTrans           = 23456 // Stands in for a four-octet AS number
+	segmentASSequence = 2
+	originIGP         = 0
+	localPrefDefault  = 100
+	nextHopLenIPv6    = 16
+)
+
+// Session states, as reported by Status.
+const (
+	BGPStateIdle        = "Idle"
+	BGPStateConnect     = "Connect"
+	BGPStateOpenSent    = "OpenSent"
+	BGPStateOpenConfirm = "OpenConfirm"
+	BGPStateEstablished = "Established"
+)
+
+var notificationNames = map[byte]string{
+	notifHeaderError: "message header error",
+	notifOpenError:   "OPEN message error",
+	notifUpdateError: "UPDATE message error",
+	notifHoldExpired: "hold timer expired",
+	notifFSMError:    "finite state machine error",
+	notifCease:       "cease",
+}
+
+// A session to a peer is retried bgpConnectRetry after it failed or ended, and
+// the TCP connection is given up after bgpConnectTimeout.
+var (
+	bgpConnectRetry   = 5 * time.Second
+	bgpConnectTimeout = 5 * time.Second
+)
+
+// BGPSpeaker is a minimal BGP-4 speaker that advertises host routes for the
+// VIPs this node holds. It opens a session to each configured peer, keeps it
+// alive and reconnects when it drops; it accepts no sessions and ignores the
+// routes its peers send. IPv6 routes are sent with multiprotocol extensions.
+type BGPSpeaker struct {
+	log         logger.Logger
+	cfg         types.BGPConfig
+	communities []uint32
+	dial        func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mu       sync.Mutex
+	routes   map[string]*net.IPNet
+	sessions []*bgpSession
+}
+
+// NewBGPSpeaker creates a speaker for the sessions in cfg. It advertises
+// nothing until Advertise is called, and opens no session until Run.
+func NewBGPSpeaker(log logger.Logger, cfg types.BGPConfig) (*BGPSpeaker, error) {
+	cfg = cfg.WithDefaults()
+	if cfg.RouterID != "" && net.ParseIP(cfg.RouterID).To4() == nil {
+		return nil, errors.Newf(errors.ConfigError, "BGP router ID must be an IPv4 address, got %q", cfg.RouterID)
+	}
+	s := &BGPSpeaker{
+		log:    log.WithField("component", "bgp"),
+		cfg:    cfg,
+		dial:   (&net.Dialer{Timeout: bgpConnectTimeout}).DialContext,
+		routes: map[string]*net.IPNet{},
+	}
+	for _, c := range cfg.Communities {
+		community, err := types.ParseCommunity(c)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ConfigError, "invalid BGP configuration")
+		}
+		s.communities = append(s.communities, community)
+	}
+	for _, peer := range cfg.Peers {
+		ip := net.ParseIP(peer.Address)
+		if ip == nil {
+			return nil, errors.Newf(errors.ConfigError, "invalid BGP peer address %q", peer.Address)
+		}
+		s.sessions = append(s.sessions, &bgpSession{
+			speaker: s,
+			peer:    peer,
+			ipv4:    ip.To4() != nil,
+			log:     s.log.WithField("peer", peer.Address),
+			changed: make(chan struct{}, 1),
+			state:   BGPStateIdle,
+		})
+	}
+	return s, nil
+}
+
+// Run keeps a session to every peer until ctx is done, then closes them with
+// a Cease notification so that the peers withdraw the routes at once.
+func (s *BGPSpeaker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range s.sessions {
+		wg.Add(1)
+		go func(p *bgpSession) {
+			defer wg.Done()
+			p.run(ctx)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// Advertise starts advertising the host route of ip to the peers of its
+// address family. It is idempotent.
+func (s *BGPSpeaker) Advertise(ip net.IP) {
+	s.setRoute(ip, true)
+}
+
+// Withdraw stops advertising the host route of ip. It is idempotent.
+func (s *BGPSpeaker) Withdraw(ip net.IP) {
+	s.setRoute(ip, false)
+}
+
+func (s *BGPSpeaker) setRoute(ip net.IP, advertise bool) {
+	route := hostRoute(ip)
+	s.mu.Lock()
+	_, advertised := s.routes[route.String()]
+	if advertised == advertise {
+		s.mu.Unlock()
+		return
+	}
+	if advertise {
+		s.routes[route.String()] = route
+	} else {
+		delete(s.routes, route.String())
+	}
+	s.mu.Unlock()
+	for _, p := range s.sessions {
+		select {
+		case p.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// routesFor returns the routes to advertise over a session of the given family.
+func (s *BGPSpeaker) routesFor(ipv4 bool) map[string]*net.IPNet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	routes := map[string]*net.IPNet{}
+	for key, route := range s.routes {
+		if (route.IP.To4() != nil) == ipv4 {
+			routes[key] = route
+		}
+	}
+	return routes
+}
+
+// BGPPeerStatus is the state of the session to one peer.
+type BGPPeerStatus struct {
+	Peer  string
+	State string
+	// Advertised are the routes the peer has been sent.
+	Advertised []string
+	// Error is why the last session ended, if it did.
+	Error string
+}
+
+// Status returns the state of every session.
+func (s *BGPSpeaker) Status() []BGPPeerStatus {
+	var status []BGPPeerStatus
+	for _, p := range s.sessions {
+		p.mu.Lock()
+		st := BGPPeerStatus{Peer: p.peer.Address, State: p.state}
+		for route := range p.sent {
+			st.Advertised = append(st.Advertised, route)
+		}
+		if p.err != nil {
+			st.Error = reason(p.err)
+		}
+		p.mu.Unlock()
+		sort.Strings(st.Advertised)
+		status = append(status, st)
+	}
+	return status
+}
+
+// checkAdvertised returns an error describing the sessions unless the host
+// route of ip has been sent to at least one peer.
+func (s *BGPSpeaker) checkAdvertised(ip net.IP) error {
+	route := hostRoute(ip).String()
+	var down []string
+	for _, st := range s.Status() {
+		switch {
+		case contains(st.Advertised, route):
+			return nil
+		case st.Error != "":
+			down = append(down, fmt.Sprintf("%s %s (%s)", st.Peer, st.State, st.Error))
+		default:
+			down = append(down, fmt.Sprintf("%s %s", st.Peer, st.State))
+		}
+	}
+	return errors.Newf(errors.NetworkError, "VIP %s is not advertised to any BGP peer: %s", ip, strings.Join(down, ", "))
+}
+
+// bgpSession is the session to one peer.
+type bgpSession struct {
+	speaker *BGPSpeaker
+	peer    types.BGPPeer
+	ipv4    bool
+	log     logger.Logger
+	changed chan struct{}
+
+	mu    sync.Mutex
+	state string
+	err   error
+	sent  map[string]bool // Routes the peer has been sent in this session
+}
+
+// run connects to the peer until ctx is done, retrying after failures.
+func (p *bgpSession) run(ctx context.Context) {
+	for {
+		err := p.connect(ctx)
+		p.mu.Lock()
+		wasEstablished := p.state == BGPStateEstablished
+		p.state, p.err, p.sent = BGPStateIdle, err, nil
+		p.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if wasEstablished {
+			p.log.Warnf("BGP session lost: %v; reconnecting in %s.", err, bgpConnectRetry)
+		} else {
+			p.log.Debugf("BGP session failed: %v; retrying in %s.", err, bgpConnectRetry)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(bgpConnectRetry):
+		}
+	}
+}
+
+func (p *bgpSession) setState(state string) {
+	p.mu.Lock()
+	p.state = state
+	p.mu.Unlock()
+}
+
+// connect runs one session: it opens the connection, exchanges OPEN messages,
+// then keeps the session alive and sends route changes until the connection
+// fails or ctx is done.
+func (p *bgpSession) connect(ctx context.Context) error {
+	p.setState(BGPStateConnect)
+	address := net.JoinHostPort(p.peer.Address, strconv.Itoa(p.peer.Port))
+	conn, err := p.speaker.dial(ctx, "tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to connect to BGP peer %s", address)
+	}
+	defer conn.Close()
+	// Until the session is established, ctx ends it by closing the connection.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	local := conn.LocalAddr().(*net.TCPAddr).IP
+	hold, fourOctet, err := p.open(conn, local)
+	if !stop() || err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.state, p.err, p.sent = BGPStateEstablished, nil, map[string]bool{}
+	p.mu.Unlock()
+	p.log.Infof("BGP session established.")
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.receive(conn, hold) }()
+	var keepalive <-chan time.Time
+	if hold > 0 {
+		ticker := time.NewTicker(hold / 3)
+		defer ticker.Stop()
+		keepalive = ticker.C
+	}
+	attrs := p.pathAttributes(fourOctet)
+	if err := p.sync(conn, attrs, local); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			conn.SetWriteDeadline(time.Now().Add(time.Second))
+			p.notify(conn, notifCease, ceaseAdminShutdown)
+			return nil
+		case err := <-errc:
+			var timeout net.Error
+			if stderrors.As(err, &timeout) && timeout.Timeout() {
+				p.notify(conn, notifHoldExpired, 0)
+				return errors.Newf(errors.NetworkError, "hold timer expired after %s", hold)
+			}
+			return err
+		case <-keepalive:
+			if err := writeMessage(conn, bgpKeepalive, nil); err != nil {
+				return err
+			}
+		case <-p.changed:
+			if err := p.sync(conn, attrs, local); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// open sends the OPEN message and reads the peer's, then confirms it with a
+// KEEPALIVE and waits for the peer's. It returns the negotiated hold time and
+// whether the peer supports four-octet AS numbers.
+func (p *bgpSession) open(conn net.Conn, local net.IP) (time.Duration, bool, error) {
+	routerID := net.ParseIP(p.speaker.cfg.RouterID).To4()
+	if routerID == nil {
+		if routerID = local.To4(); routerID == nil {
+			return 0, false, errors.New(errors.ConfigError, "the session runs over IPv6, so spec.network.bgp.routerID must be set")
+		}
+	}
+	if err := writeMessage(conn, bgpOpen, p.openMessage(routerID)); err != nil {
+		return 0, false, err
+	}
+	p.setState(BGPStateOpenSent)
+
+	holdTime := time.Duration(p.speaker.cfg.HoldTime) * time.Second
+	conn.SetReadDeadline(time.Now().Add(holdTime))
+	typ, body, err := readMessage(conn)
+	if err != nil {
+		return 0, false, err
+	}
+	if typ != bgpOpen {
+		return 0, false, p.unexpected(conn, typ, body)
+	}
+	if len(body) < 10 {
+		p.notify(conn, notifOpenError, 0)
+		return 0, false, errors.New(errors.NetworkError, "malformed OPEN message")
+	}
+	if body[0] != bgpVersion {
+		p.notify(conn, notifOpenError, openBadVersion)
+		return 0, false, errors.Newf(errors.NetworkError, "peer speaks BGP version %d", body[0])
+	}
+	peerAS := uint32(binary.BigEndian.Uint16(body[1:3]))
+	fourOctet := false
+	params := body[10:]
+	if n := int(body[9]); n < len(params) {
+		params = params[:n]
+	}
+	if as4, ok := fourOctetAS(params); ok {
+		peerAS, fourOctet = as4, true
+	}
+	if peerAS != p.peer.ASN {
+		p.notify(conn, notifOpenError, openBadPeerAS)
+		return 0, false, errors.Newf(errors.NetworkError, "peer is in AS %d, expected AS %d", peerAS, p.peer.ASN)
+	}
+	peerHold := time.Duration(binary.BigEndian.Uint16(body[3:5])) * time.Second
+	if peerHold > 0 && peerHold < 3*time.Second {
+		p.notify(conn, notifOpenError, openBadHoldTime)
+		return 0, false, errors.Newf(errors.NetworkError, "unacceptable hold time %s", peerHold)
+	}
+	if peerHold < holdTime {
+		holdTime = peerHold
+	}
+
+	if err := writeMessage(conn, bgpKeepalive, nil); err != nil {
+		return 0, false, err
+	}
+	p.setState(BGPStateOpenConfirm)
+	typ, body, err = readMessage(conn)
+	if err != nil {
+		return 0, false, err
+	}
+	if typ != bgpKeepalive {
+		return 0, false, p.unexpected(conn, typ, body)
+	}
+	return holdTime, fourOctet, nil
+}
+
+// openMessage returns the body of the OPEN message. It offers four-octet AS
+// numbers and the unicast routes of the peer's address family.
+func (p *bgpSession) openMessage(routerID net.IP) []byte {
+	myAS := uint16(asTrans)
+	if p.speaker.cfg.LocalASN <= 0xffff {
+		myAS = uint16(p.speaker.cfg.LocalASN)
+	}
+	afi := uint16(afiIPv6)
+	if p.ipv4 {
+		afi = afiIPv4
+	}
+	var caps []byte
+	caps = append(caps, 2, 6, capMultiprotocol, 4)
+	caps = binary.BigEndian.AppendUint16(caps, afi)
+	caps = append(caps, 0, safiUnicast)
+	caps = append(caps, 2, 6, capFourOctetAS, 4)
+	caps = binary.BigEndian.AppendUint32(caps, p.speaker.cfg.LocalASN)
+
+	body := []byte{bgpVersion}
+	body = binary.BigEndian.AppendUint16(body, myAS)
+	body = binary.BigEndian.AppendUint16(body, uint16(p.speaker.cfg.HoldTime))
+	body = append(body, routerID...)
+	body = append(body, byte(len(caps)))
+	return append(body, caps...)
+}
+
+// fourOctetAS returns the AS number of the four-octet AS capability in the
+// optional parameters of an OPEN message, if there is one.
+func fourOctetAS(params []byte) (uint32, bool) {
+	for len(params) >= 2 {
+		typ, n := params[0], int(params[1])
+		if len(params) < 2+n {
+			break
+		}
+		caps := params[2 : 2+n]
+		params = params[2+n:]
+		if typ != 2 {
+			continue
+		}
+		for len(caps) >= 2 {
+			code, m := caps[0], int(caps[1])
+			if len(caps) < 2+m {
+				break
+			}
+			if code == capFourOctetAS && m == 4 {
+				return binary.BigEndian.Uint32(caps[2:6]), true
+			}
+			caps = caps[2+m:]
+		}
+	}
+	return 0, false
+}
+
+// receive reads the peer's messages until the connection fails, the hold
+// timer expires or the peer sends a NOTIFICATION. Routes the peer sends are
+// ignored.
+func (p *bgpSession) receive(conn net.Conn, hold time.Duration) error {
+	for {
+		if hold > 0 {
+			conn.SetReadDeadline(time.Now().Add(hold))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+		typ, body, err := readMessage(conn)
+		if err != nil {
+			return err
+		}
+		if typ == bgpNotification {
+			return notificationError(body)
+		}
+	}
+}
+
+// sync sends the peer the routes it is missing and withdraws those it should
+// no longer have.
+func (p *bgpSession) sync(conn net.Conn, attrs []byte, local net.IP) error {
+	want := p.speaker.routesFor(p.ipv4)
+	p.mu.Lock()
+	var announce, withdraw []*net.IPNet
+	for key, route := range want {
+		if !p.sent[key] {
+			announce = append(announce, route)
+		}
+	}
+	for key := range p.sent {
+		if _, ok := want[key]; !ok {
+			_, route, _ := net.ParseCIDR(key)
+			withdraw = append(withdraw, route)
+		}
+	}
+	p.mu.Unlock()
+	sortRoutes(announce)
+	sortRoutes(withdraw)
+
+	if len(withdraw) > 0 {
+		if err := writeMessage(conn, bgpUpdate, p.updateMessage(nil, withdraw, attrs, local)); err != nil {
+			return err
+		}
+	}
+	if len(announce) > 0 {
+		if err := writeMessage(conn, bgpUpdate, p.updateMessage(announce, nil, attrs, local)); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, route := range withdraw {
+		delete(p.sent, route.String())
+		p.log.Infof("Withdrew %s.", route)
+	}
+	for _, route := range announce {
+		p.sent[route.String()] = true
+		p.log.Infof("Advertised %s.", route)
+	}
+	return nil
+}
+
+// pathAttributes returns the path attributes of the routes, apart from their
+// next hop: ORIGIN, AS_PATH, MULTI_EXIT_DISC, LOCAL_PREF towards internal
+// peers and COMMUNITIES.
+func (p *bgpSession) pathAttributes(fourOctet bool) []byte {
+	cfg := p.speaker.cfg
+	ebgp := p.peer.ASN != cfg.LocalASN
+
+	var attrs []byte
+	attrs = appendAttr(attrs, attrFlagTransitive, attrOrigin, []byte{originIGP})
+	var path, as4Path []byte
+	switch {
+	case !ebgp:
+		// Routes to internal peers have an empty AS_PATH.
+	case fourOctet:
+		path = binary.BigEndian.AppendUint32([]byte{segmentASSequence, 1}, cfg.LocalASN)
+	case cfg.LocalASN > 0xffff:
+		// A peer without four-octet AS numbers sees AS_TRANS and passes the
+		// real path on in AS4_PATH.
+		path = binary.BigEndian.AppendUint16([]byte{segmentASSequence, 1}, asTrans)
+		as4Path = binary.BigEndian.AppendUint32([]byte{segmentASSequence, 1}, cfg.LocalASN)
+	default:
+		path = binary.BigEndian.AppendUint16([]byte{segmentASSequence, 1}, uint16(cfg.LocalASN))
+	}
+	attrs = appendAttr(attrs, attrFlagTransitive, attrASPath, path)
+	if cfg.MED != nil {
+		attrs = appendAttr(attrs, attrFlagOptional, attrMED, binary.BigEndian.AppendUint32(nil, *cfg.MED))
+	}
+	if !ebgp {
+		attrs = appendAttr(attrs, attrFlagTransitive, attrLocalPref, binary.BigEndian.AppendUint32(nil, localPrefDefault))
+	}
+	if len(p.speaker.communities) > 0 {
+		var communities []byte
+		for _, c := range p.speaker.communities {
+			communities = binary.BigEndian.AppendUint32(communities, c)
+		}
+		attrs = appendAttr(attrs, attrFlagOptional|attrFlagTransitive, attrCommunities, communities)
+	}
+	if as4Path != nil {
+		attrs = appendAttr(attrs, attrFlagOptional|attrFlagTransitive, attrAS4Path, as4Path)
+	}
+	return attrs
+}
+
+// updateMessage returns the body of an UPDATE message that announces or
+// withdraws routes, with this node's session address as the next hop. IPv4
+// routes go in the message's own fields, IPv6 routes in MP_REACH_NLRI and
+// MP_UNREACH_NLRI.
+func (p *bgpSession) updateMessage(announce, withdraw []*net.IPNet, attrs []byte, local net.IP) []byte {
+	var withdrawn, pathAttrs, nlri []byte
+	if p.ipv4 {
+		for _, route := range withdraw {
+			withdrawn = appendPrefix(withdrawn, route)
+		}
+		if len(announce) > 0 {
+			pathAttrs = appendAttr(append(pathAttrs, attrs...), attrFlagTransitive, attrNextHop, local.To4())
+			for _, route := range announce {
+				nlri = appendPrefix(nlri, route)
+			}
+		}
+	} else {
+		if len(withdraw) > 0 {
+			value := binary.BigEndian.AppendUint16(nil, afiIPv6)
+			value = append(value, safiUnicast)
+			for _, route := range withdraw {
+				value = appendPrefix(value, route)
+			}
+			pathAttrs = appendAttr(pathAttrs, attrFlagOptional, attrMPUnreach, value)
+		}
+		if len(announce) > 0 {
+			value := binary.BigEndian.AppendUint16(nil, afiIPv6)
+			value = append(value, safiUnicast, nextHopLenIPv6)
+			value = append(value, local.To16()...)
+			value = append(value, 0) // Reserved
+			for _, route := range announce {
+				value = appendPrefix(value, route)
+			}
+			pathAttrs = appendAttr(append(pathAttrs, attrs...), attrFlagOptional, attrMPReach, value)
+		}
+	}
+
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(withdrawn)))
+	body = append(body, withdrawn...)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(pathAttrs)))
+	body = append(body, pathAttrs...)
+	return append(body, nlri...)
+}
+
+// unexpected handles a message that is not the one the session waits for.
+func (p *bgpSession) unexpected(conn net.Conn, typ byte, body []byte) error {
+	if typ == bgpNotification {
+		return notificationError(body)
+	}
+	p.notify(conn, notifFSMError, 0)
+	return errors.Newf(errors.NetworkError, "unexpected BGP message of type %d", typ)
+}
+
+// notify sends a NOTIFICATION message; the session ends after it.
+func (p *bgpSession) notify(conn net.Conn, code, subcode byte) {
+	if err := writeMessage(conn, bgpNotification, []byte{code, subcode}); err != nil {
+		p.log.Debugf("Failed to send NOTIFICATION %d/%d: %v", code, subcode, err)
+	}
+}
+
+// notificationError describes a NOTIFICATION message the peer sent.
+func notificationError(body []byte) error {
+	if len(body) < 2 {
+		return errors.New(errors.NetworkError, "peer sent a malformed NOTIFICATION")
+	}
+	name, ok := notificationNames[body[0]]
+	if !ok {
+		name = "unknown error"
+	}
+	return errors.Newf(errors.NetworkError, "peer sent NOTIFICATION %d/%d (%s)", body[0], body[1], name)
+}
+
+// writeMessage sends a BGP message of the given type.
+func writeMessage(w io.Writer, typ byte, body []byte) error {
+	msg := bytes.Repeat([]byte{0xff}, 16) // Marker
+	msg = binary.BigEndian.AppendUint16(msg, uint16(bgpHeaderLen+len(body)))
+	msg = append(msg, typ)
+	if _, err := w.Write(append(msg, body...)); err != nil {
+		return errors.Wrap(err, errors.NetworkError, "failed to send BGP message")
+	}
+	return nil
+}
+
+// readMessage reads one BGP message and returns its type and body.
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, bgpHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(header[16:18]))
+	if !bytes.Equal(header[:16], bytes.Repeat([]byte{0xff}, 16)) || n < bgpHeaderLen || n > bgpMaxMessageLen {
+		return 0, nil, errors.New(errors.NetworkError, "malformed BGP message header")
+	}
+	body := make([]byte, n-bgpHeaderLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[18], body, nil
+}
+
+// appendAttr appends a path attribute, with an extended length if needed.
+func appendAttr(b []byte, flags, typ byte, value []byte) []byte {
+	if len(value) > 255 {
+		b = append(b, flags|attrFlagExtended, typ)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	} else {
+		b = append(b, flags, typ, byte(len(value)))
+	}
+	return append(b, value...)
+}
+
+// appendPrefix appends a prefix in NLRI encoding: its length in bits followed
+// by as many octets as that takes.
+func appendPrefix(b []byte, route *net.IPNet) []byte {
+	ones, bits := route.Mask.Size()
+	ip := route.IP.To16()
+	if bits == 32 {
+		ip = route.IP.To4()
+	}
+	return append(append(b, byte(ones)), ip[:(ones+7)/8]...)
+}
+
+// hostRoute returns the /32 (IPv4) or /128 (IPv6) route of ip.
+func hostRoute(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(128, 128)}
+}
+
+// sortRoutes sorts routes by their string form, for deterministic messages.
+func sortRoutes(routes []*net.IPNet) {
+	sort.Slice(routes, func(i, j int) bool { return routes[i].String() < routes[j].String() })
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+//Personal.AI order the ending
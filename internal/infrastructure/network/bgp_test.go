@@ -0,0 +1,368 @@
+//go:build linux
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// bgpRoute is a route as the test peer received it.
+type bgpRoute struct {
+	nextHop     string
+	asPath      []uint32
+	med         uint32
+	hasMED      bool
+	communities []uint32
+}
+
+// testPeer is a BGP router in the peer namespace. It accepts sessions from
+// AS 65001, tracks the routes it is sent and records NOTIFICATIONs.
+type testPeer struct {
+	ln  net.Listener
+	asn uint32
+
+	mu            sync.Mutex
+	conns         []net.Conn
+	sessions      int
+	routes        map[string]bgpRoute
+	notifications [][]byte
+}
+
+// listen starts a test peer in AS asn on address, in the peer namespace.
+func (tn *testNet) listen(t *testing.T, address string, asn uint32) *testPeer {
+	t.Helper()
+	netns.Set(tn.peerNs)
+	ln, err := net.Listen("tcp", address)
+	netns.Set(tn.ns)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", address, err)
+	}
+	p := &testPeer{ln: ln, asn: asn, routes: map[string]bgpRoute{}}
+	t.Cleanup(func() {
+		ln.Close()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, conn := range p.conns {
+			conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, conn)
+			p.mu.Unlock()
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+// serve answers the speaker's OPEN and reads UPDATEs until the session ends.
+func (p *testPeer) serve(conn net.Conn) {
+	defer conn.Close()
+	typ, _, err := readMessage(conn)
+	if err != nil || typ != bgpOpen {
+		return
+	}
+	open := []byte{bgpVersion, byte(asTrans >> 8), byte(asTrans & 0xff), 0, 9, 192, 0, 2, 2, 8, 2, 6, capFourOctetAS, 4}
+	open = binary.BigEndian.AppendUint32(open, p.asn)
+	writeMessage(conn, bgpOpen, open)
+	writeMessage(conn, bgpKeepalive, nil)
+	p.mu.Lock()
+	p.sessions++
+	// A new session starts without routes.
+	p.routes = map[string]bgpRoute{}
+	p.mu.Unlock()
+	for {
+		typ, body, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		switch typ {
+		case bgpUpdate:
+			p.update(body)
+		case bgpNotification:
+			p.notifications = append(p.notifications, body)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// update applies an UPDATE message to the routes.
+func (p *testPeer) update(body []byte) {
+	n := int(binary.BigEndian.Uint16(body))
+	for _, prefix := range parsePrefixes(body[2:2+n], 4) {
+		delete(p.routes, prefix)
+	}
+	body = body[2+n:]
+	n = int(binary.BigEndian.Uint16(body))
+	attrs, nlri := body[2:2+n], body[2+n:]
+
+	var route bgpRoute
+	var announced []string
+	for len(attrs) > 0 {
+		flags, typ := attrs[0], attrs[1]
+		length, offset := int(attrs[2]), 3
+		if flags&attrFlagExtended != 0 {
+			length, offset = int(binary.BigEndian.Uint16(attrs[2:4])), 4
+		}
+		value := attrs[offset : offset+length]
+		attrs = attrs[offset+length:]
+		switch typ {
+		case attrASPath:
+			if len(value) > 0 {
+				for i := 0; i < int(value[1]); i++ {
+					route.asPath = append(route.asPath, binary.BigEndian.Uint32(value[2+4*i:]))
+				}
+			}
+		case attrNextHop:
+			route.nextHop = net.IP(value).String()
+		case attrMED:
+			route.med, route.hasMED = binary.BigEndian.Uint32(value), true
+		case attrCommunities:
+			for i := 0; i < len(value); i += 4 {
+				route.communities = append(route.communities, binary.BigEndian.Uint32(value[i:]))
+			}
+		case attrMPReach:
+			nh := int(value[3])
+			route.nextHop = net.IP(value[4 : 4+nh]).String()
+			announced = append(announced, parsePrefixes(value[5+nh:], 16)...)
+		case attrMPUnreach:
+			for _, prefix := range parsePrefixes(value[3:], 16) {
+				delete(p.routes, prefix)
+			}
+		}
+	}
+	announced = append(announced, parsePrefixes(nlri, 4)...)
+	for _, prefix := range announced {
+		p.routes[prefix] = route
+	}
+}
+
+// parsePrefixes decodes NLRI-encoded prefixes of addresses of size bytes.
+func parsePrefixes(b []byte, size int) []string {
+	var prefixes []string
+	for len(b) > 0 {
+		bits := int(b[0])
+		ip := make(net.IP, size)
+		copy(ip, b[1:1+(bits+7)/8])
+		prefixes = append(prefixes, (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, size*8)}).String())
+		b = b[1+(bits+7)/8:]
+	}
+	return prefixes
+}
+
+func (p *testPeer) route(prefix string) (bgpRoute, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.routes[prefix]
+	return r, ok
+}
+
+// drop closes the peer's side of its sessions.
+func (p *testPeer) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// eventually waits up to two seconds for cond to hold.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// dialFrom returns a dial function that opens connections in ns, whichever
+// thread the session runs on.
+func dialFrom(ns netns.NsHandle) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		orig, err := netns.Get()
+		if err != nil {
+			return nil, err
+		}
+		defer orig.Close()
+		if err := netns.Set(ns); err != nil {
+			return nil, err
+		}
+		defer netns.Set(orig)
+		return (&net.Dialer{Timeout: time.Second}).DialContext(ctx, network, address)
+	}
+}
+
+// runSpeaker runs speaker until the returned stop function is called or the
+// test ends.
+func runSpeaker(t *testing.T, speaker *BGPSpeaker) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		speaker.Run(ctx)
+		close(done)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestBGPVIP(t *testing.T) {
+	tn := inNetns(t)
+	bgpConnectRetry = 20 * time.Millisecond
+	lo, _ := netlink.LinkByName("lo")
+	if err := netlink.LinkSetUp(lo); err != nil {
+		t.Fatalf("failed to bring up lo: %v", err)
+	}
+	peer4 := tn.listen(t, "192.0.2.2:1179", 64512)
+	peer6 := tn.listen(t, "[2001:db8::2]:1179", 64512)
+
+	med := uint32(50)
+	cfg := types.NetworkConfig{
+		VIP:     "198.51.100.10",
+		VIPs:    []types.VIPConfig{{Name: "api6", Address: "2001:db8:100::10", PrefixLength: 64}},
+		VIPMode: types.VIPModeBGP,
+		BGP: &types.BGPConfig{
+			LocalASN:    65001,
+			RouterID:    "192.0.2.1",
+			Peers:       []types.BGPPeer{{Address: "192.0.2.2", ASN: 64512, Port: 1179}, {Address: "2001:db8::2", ASN: 64512, Port: 1179}},
+			MED:         &med,
+			Communities: []string{"65001:100", "no-export"},
+		},
+	}
+	speaker, err := NewBGPSpeaker(logger.NewLogger("error", io.Discard, "text"), *cfg.BGP)
+	if err != nil {
+		t.Fatalf("NewBGPSpeaker failed: %v", err)
+	}
+	speaker.dial = dialFrom(tn.ns)
+	stop := runSpeaker(t, speaker)
+	p := NewBGPProvider(speaker)
+	ctx := context.Background()
+
+	// The leader advertises its VIPs as host routes, each to the peer of its family.
+	vips := cfg.AllVIPs()
+	for _, vip := range vips {
+		if err := p.Acquire(ctx, vip, self); err != nil {
+			t.Fatalf("Acquire %s failed: %v", vip.Name, err)
+		}
+	}
+	for _, vip := range []string{"198.51.100.10", "2001:db8:100::10"} {
+		if assigned, _ := hasAddr(lo, net.ParseIP(vip)); !assigned {
+			t.Errorf("expected %s on lo", vip)
+		}
+	}
+	eventually(t, "the IPv4 VIP is advertised", func() bool { _, ok := peer4.route("198.51.100.10/32"); return ok })
+	eventually(t, "the IPv6 VIP is advertised", func() bool { _, ok := peer6.route("2001:db8:100::10/128"); return ok })
+
+	r, _ := peer4.route("198.51.100.10/32")
+	if r.nextHop != "192.0.2.1" || len(r.asPath) != 1 || r.asPath[0] != 65001 {
+		t.Errorf("expected next hop 192.0.2.1 and AS path [65001], got %+v", r)
+	}
+	if !r.hasMED || r.med != 50 || len(r.communities) != 2 || r.communities[0] != 65001<<16|100 || r.communities[1] != 0xFFFFFF01 {
+		t.Errorf("expected MED 50 and the configured communities, got %+v", r)
+	}
+	if r, _ := peer6.route("2001:db8:100::10/128"); r.nextHop != "2001:db8::1" || r.med != 50 {
+		t.Errorf("expected next hop 2001:db8::1 and MED 50, got %+v", r)
+	}
+	if _, ok := peer4.route("2001:db8:100::10/128"); ok {
+		t.Errorf("expected the IPv6 VIP not to be sent to the IPv4 peer")
+	}
+	if holder, err := p.Holder(ctx, vips[0], self); err != nil || holder != self.IP {
+		t.Errorf("expected the advertised VIP to be held by this node, got %q (%v)", holder, err)
+	}
+
+	// After a lost session the speaker reconnects and advertises the VIPs again.
+	peer4.drop()
+	eventually(t, "the IPv4 session is re-established", func() bool {
+		peer4.mu.Lock()
+		defer peer4.mu.Unlock()
+		return peer4.sessions == 2 && len(peer4.routes) == 1
+	})
+
+	// On demotion the VIPs are withdrawn and removed.
+	for _, vip := range vips {
+		if err := p.Release(ctx, vip, self); err != nil {
+			t.Fatalf("Release %s failed: %v", vip.Name, err)
+		}
+	}
+	eventually(t, "the IPv4 VIP is withdrawn", func() bool { _, ok := peer4.route("198.51.100.10/32"); return !ok })
+	eventually(t, "the IPv6 VIP is withdrawn", func() bool { _, ok := peer6.route("2001:db8:100::10/128"); return !ok })
+	if assigned, _ := hasAddr(lo, net.ParseIP("198.51.100.10")); assigned {
+		t.Errorf("expected the VIP to be removed from lo")
+	}
+	if holder, err := p.Holder(ctx, vips[0], self); err != nil || holder != "" {
+		t.Errorf("expected the withdrawn VIP to have no holder, got %q (%v)", holder, err)
+	}
+
+	// Stopping the speaker closes the sessions with a Cease.
+	stop()
+	eventually(t, "the peer is notified", func() bool {
+		peer6.mu.Lock()
+		defer peer6.mu.Unlock()
+		return len(peer6.notifications) == 1 && peer6.notifications[0][0] == notifCease
+	})
+}
+
+func TestBGPSpeakerRejectsWrongPeerAS(t *testing.T) {
+	tn := inNetns(t)
+	bgpConnectRetry = 20 * time.Millisecond
+	peer := tn.listen(t, "192.0.2.2:1179", 64513)
+	speaker, err := NewBGPSpeaker(logger.NewLogger("error", io.Discard, "text"), types.BGPConfig{
+		LocalASN: 65001,
+		Peers:    []types.BGPPeer{{Address: "192.0.2.2", ASN: 64512, Port: 1179}},
+	})
+	if err != nil {
+		t.Fatalf("NewBGPSpeaker failed: %v", err)
+	}
+	speaker.dial = dialFrom(tn.ns)
+	runSpeaker(t, speaker)
+
+	eventually(t, "the session fails on the peer's AS", func() bool {
+		return strings.Contains(speaker.Status()[0].Error, "peer is in AS 64513, expected AS 64512")
+	})
+	eventually(t, "the peer is notified", func() bool {
+		peer.mu.Lock()
+		defer peer.mu.Unlock()
+		return len(peer.notifications) > 0 && peer.notifications[0][0] == notifOpenError && peer.notifications[0][1] == openBadPeerAS
+	})
+
+	// A VIP that no peer learns is not held, whatever the loopback interface says.
+	lo, _ := netlink.LinkByName("lo")
+	netlink.LinkSetUp(lo)
+	p := NewBGPProvider(speaker)
+	vip := types.VIPConfig{Name: "api", Address: "198.51.100.10"}
+	if err := p.Acquire(context.Background(), vip, self); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := p.Holder(context.Background(), vip, self); err == nil || !strings.Contains(err.Error(), "not advertised to any BGP peer: 192.0.2.2 ") {
+		t.Errorf("expected the VIP not to be held without a session, got %v", err)
+	}
+}
+
+//Personal.AI order the ending
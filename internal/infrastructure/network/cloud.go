@@ -0,0 +1,185 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// defaultCloudTimeout bounds each request to the cloud API.
+const defaultCloudTimeout = 10 * time.Second
+
+// cloudAddress is an address resource of the cloud API.
+type cloudAddress struct {
+	Address    string `json:"address,omitempty"`
+	InstanceID string `json:"instanceId"`
+}
+
+// cloudProvider holds VIPs as secondary IPs that a cloud API assigns to the
+// instance of the holding node.
+type cloudProvider struct {
+	cfg    types.CloudVIPConfig
+	client *http.Client
+}
+
+// NewCloudProvider creates the provider of the VIPs with provider "cloud".
+func NewCloudProvider(cfg types.CloudVIPConfig) (api.VIPProvider, error) {
+	if u, err := url.Parse(cfg.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Newf(errors.ConfigError, "invalid cloud API endpoint %q", cfg.Endpoint)
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultCloudTimeout
+	}
+	return &cloudProvider{cfg: cfg, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Acquire assigns the VIP to self's instance, taking it from any other
+// instance, and adds it to self's interface if configured to.
+func (p *cloudProvider) Acquire(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	instance, err := p.instance(self)
+	if err != nil {
+		return err
+	}
+	current, err := p.assignedTo(ctx, vip.Address)
+	if err != nil {
+		return err
+	}
+	if current != instance {
+		body, _ := json.Marshal(cloudAddress{InstanceID: instance})
+		if _, err := p.do(ctx, http.MethodPut, vip.Address, body); err != nil {
+			return err
+		}
+	}
+	if !p.cfg.AddToInterface {
+		return nil
+	}
+	addr, err := vipAddr(vip.Address, vip.PrefixLength)
+	if err != nil {
+		return err
+	}
+	link, err := vipLink(vip.Interface, addr.IP)
+	if err != nil {
+		return err
+	}
+	added, err := addVIP(link, addr)
+	if err != nil || !added {
+		return err
+	}
+	return announce(link, addr.IP)
+}
+
+// Release removes the VIP from self's interface if configured to, and
+// unassigns it if it is assigned to self's instance.
+func (p *cloudProvider) Release(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	instance, err := p.instance(self)
+	if err != nil {
+		return err
+	}
+	if p.cfg.AddToInterface {
+		addr, err := vipAddr(vip.Address, vip.PrefixLength)
+		if err != nil {
+			return err
+		}
+		link, err := vipLink(vip.Interface, addr.IP)
+		if err != nil {
+			return err
+		}
+		if err := delVIP(link, addr); err != nil {
+			return err
+		}
+	}
+	current, err := p.assignedTo(ctx, vip.Address)
+	if err != nil || current != instance {
+		return err
+	}
+	_, err = p.do(ctx, http.MethodDelete, vip.Address, nil)
+	return err
+}
+
+// Holder returns the IP of the node whose instance the VIP is assigned to, or
+// the instance ID if it belongs to no node.
+func (p *cloudProvider) Holder(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) (string, error) {
+	instance, err := p.assignedTo(ctx, vip.Address)
+	if err != nil || instance == "" {
+		return "", err
+	}
+	for ip, id := range p.cfg.Instances {
+		if id == instance {
+			return ip, nil
+		}
+	}
+	return "instance " + instance, nil
+}
+
+// instance returns the instance ID of node.
+func (p *cloudProvider) instance(node types.NodeIdentity) (string, error) {
+	id, ok := p.cfg.Instances[node.IP]
+	if !ok || id == "" {
+		return "", errors.Newf(errors.ConfigError, "spec.network.cloud.instances has no instance for node %s", node.IP)
+	}
+	return id, nil
+}
+
+// assignedTo returns the instance the VIP is assigned to, or "" if none.
+func (p *cloudProvider) assignedTo(ctx context.Context, vip string) (string, error) {
+	body, err := p.do(ctx, http.MethodGet, vip, nil)
+	if err != nil || body == nil {
+		return "", err
+	}
+	var addr cloudAddress
+	if err := json.Unmarshal(body, &addr); err != nil {
+		return "", errors.Wrapf(err, errors.NetworkError, "invalid cloud API response for %s", vip)
+	}
+	return addr.InstanceID, nil
+}
+
+// do sends a request for the address resource of vip and returns the response
+// body. A 404 response returns a nil body and no error.
+func (p *cloudProvider) do(ctx context.Context, method, vip string, body []byte) ([]byte, error) {
+	u := strings.TrimSuffix(p.cfg.Endpoint, "/") + "/addresses/" + url.PathEscape(vip)
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.NetworkError, "failed to create cloud API request %s %s", method, u)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.cfg.TokenFile != "" {
+		// Read on every request, so that a rotated token is picked up.
+		token, err := os.ReadFile(p.cfg.TokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ConfigError, "failed to read the cloud API token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.NetworkError, "cloud API request %s %s failed", method, u)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.NetworkError, "failed to read cloud API response to %s %s", method, u)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, errors.Newf(errors.NetworkError, "cloud API request %s %s returned %s: %s", method, u, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,133 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// mockCloud is a cloud API that assigns addresses to instances.
+type mockCloud struct {
+	mu       sync.Mutex
+	assigned map[string]string // Address -> instance
+	requests []string
+	fail     bool
+}
+
+func (c *mockCloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if c.fail {
+		http.Error(w, "quota exceeded", http.StatusInternalServerError)
+		return
+	}
+	address := strings.TrimPrefix(r.URL.Path, "/v1/addresses/")
+	switch r.Method {
+	case http.MethodGet:
+		instance, ok := c.assigned[address]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(cloudAddress{Address: address, InstanceID: instance})
+	case http.MethodPut:
+		var body cloudAddress
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.assigned[address] = body.InstanceID
+	case http.MethodDelete:
+		delete(c.assigned, address)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (c *mockCloud) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	requests := c.requests
+	c.requests = nil
+	return requests
+}
+
+func TestCloudProvider(t *testing.T) {
+	cloud := &mockCloud{assigned: map[string]string{"10.0.0.100": "i-peer"}}
+	server := httptest.NewServer(cloud)
+	defer server.Close()
+	token := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(token, []byte("secret\n"), 0o600)
+
+	p, err := NewCloudProvider(types.CloudVIPConfig{
+		Endpoint:  server.URL + "/v1/",
+		TokenFile: token,
+		Instances: map[string]string{"10.0.0.1": "i-self", "10.0.0.2": "i-peer"},
+	})
+	if err != nil {
+		t.Fatalf("NewCloudProvider failed: %v", err)
+	}
+	vip := types.VIPConfig{Name: "api", Address: "10.0.0.100"}
+	self := types.NodeIdentity{IP: "10.0.0.1", Role: types.RoleLeader}
+	peer := types.NodeIdentity{IP: "10.0.0.2", Role: types.RoleFollower}
+	ctx := context.Background()
+
+	if holder, err := p.Holder(ctx, vip, self); err != nil || holder != "10.0.0.2" {
+		t.Errorf("expected the peer to hold the VIP, got %q (%v)", holder, err)
+	}
+
+	// The new leader takes the VIP over from the old leader's instance.
+	if err := p.Acquire(ctx, vip, self); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if cloud.assigned["10.0.0.100"] != "i-self" {
+		t.Errorf("expected the VIP to be reassigned to i-self, got %v", cloud.assigned)
+	}
+	cloud.take()
+	if err := p.Acquire(ctx, vip, self); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if requests := cloud.take(); len(requests) != 1 || requests[0] != "GET /v1/addresses/10.0.0.100" {
+		t.Errorf("expected a held VIP not to be reassigned again, got %v", requests)
+	}
+	if holder, _ := p.Holder(ctx, vip, self); holder != self.IP {
+		t.Errorf("expected this node to hold the VIP, got %q", holder)
+	}
+
+	// The follower does not unassign a VIP that is not its own.
+	if err := p.Release(ctx, vip, peer); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if cloud.assigned["10.0.0.100"] != "i-self" {
+		t.Errorf("expected the VIP to stay with i-self, got %v", cloud.assigned)
+	}
+	if err := p.Release(ctx, vip, self); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if holder, _ := p.Holder(ctx, vip, self); holder != "" {
+		t.Errorf("expected the VIP to be unassigned, got %q", holder)
+	}
+
+	// Errors of the API are reported with its message.
+	cloud.fail = true
+	if err := p.Acquire(ctx, vip, self); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the API error, got %v", err)
+	}
+	if err := p.Acquire(ctx, vip, types.NodeIdentity{IP: "10.0.0.3"}); err == nil || !strings.Contains(err.Error(), "no instance for node 10.0.0.3") {
+		t.Errorf("expected an error for a node without an instance, got %v", err)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,138 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+var haproxyTemplate = template.Must(template.New("haproxy.cfg").Parse(`# Generated by the geminik8s agent for VIP {{.Name}}; local changes are overwritten.
+{{range .Ports}}
+frontend geminik8s_{{$.Name}}_{{.Port}}
+    mode tcp
+    bind {{.Bind}}
+    default_backend geminik8s_{{$.Name}}_{{.Port}}
+
+backend geminik8s_{{$.Name}}_{{.Port}}
+    mode tcp
+    server {{$.Node}} {{.Server}} check
+{{end}}`))
+
+// haproxyProvider holds VIPs on an HAProxy load balancer: a VIP's file there
+// forwards its ports to the node that holds it.
+type haproxyProvider struct {
+	sysOp api.SystemOperator
+	cfg   types.HAProxyConfig
+}
+
+// NewHAProxyProvider creates the provider of the VIPs with provider "haproxy".
+// sysOp runs on the load balancer.
+func NewHAProxyProvider(sysOp api.SystemOperator, cfg types.HAProxyConfig) api.VIPProvider {
+	return &haproxyProvider{sysOp: sysOp, cfg: cfg.WithDefaults()}
+}
+
+// Acquire points the VIP's file at self and reloads HAProxy, unless the file
+// is already up to date.
+func (p *haproxyProvider) Acquire(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	conf, err := p.Render(vip, self.IP)
+	if err != nil {
+		return err
+	}
+	current, err := p.read(vip)
+	if err != nil || bytes.Equal(current, conf) {
+		return err
+	}
+	if err := p.sysOp.WriteFile(p.path(vip), conf, 0o644); err != nil {
+		return err
+	}
+	return p.reload()
+}
+
+// Release removes the VIP's file and reloads HAProxy if the file points at
+// self. A file pointing at the other node is left alone.
+func (p *haproxyProvider) Release(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	holder, err := p.Holder(ctx, vip, self)
+	if err != nil || holder != self.IP {
+		return err
+	}
+	if _, err := p.sysOp.RunCommand("rm", "-f", p.path(vip)); err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to remove %s", p.path(vip))
+	}
+	return p.reload()
+}
+
+// Holder returns the IP of the node the VIP's file points at.
+func (p *haproxyProvider) Holder(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) (string, error) {
+	conf, err := p.read(vip)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(conf), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "server" {
+			return fields[1], nil
+		}
+	}
+	return "", nil
+}
+
+// Render returns the VIP's file for the node with the given IP.
+func (p *haproxyProvider) Render(vip types.VIPConfig, node string) ([]byte, error) {
+	if len(vip.Ports) == 0 {
+		return nil, errors.Newf(errors.ConfigError, "VIP %s has no ports for HAProxy to forward", vip.Name)
+	}
+	type port struct {
+		Port         int
+		Bind, Server string
+	}
+	data := struct {
+		Name, Node string
+		Ports      []port
+	}{Name: vip.Name, Node: node}
+	for _, n := range vip.Ports {
+		data.Ports = append(data.Ports, port{
+			Port:   n,
+			Bind:   net.JoinHostPort(vip.Address, strconv.Itoa(n)),
+			Server: net.JoinHostPort(node, strconv.Itoa(n)),
+		})
+	}
+	var buf bytes.Buffer
+	if err := haproxyTemplate.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, errors.ConfigError, "failed to render the HAProxy configuration")
+	}
+	return buf.Bytes(), nil
+}
+
+// read returns the VIP's file, or nil if it does not exist.
+func (p *haproxyProvider) read(vip types.VIPConfig) ([]byte, error) {
+	out, err := p.sysOp.RunCommand("sh", "-c", `test ! -e "$0" || cat "$0"`, p.path(vip))
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.NetworkError, "failed to read %s", p.path(vip))
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return []byte(out), nil
+}
+
+// reload runs the reload command on the load balancer.
+func (p *haproxyProvider) reload() error {
+	if out, err := p.sysOp.RunCommand("sh", "-c", p.cfg.ReloadCommand); err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to reload HAProxy: %s", strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// path returns the path of the VIP's file.
+func (p *haproxyProvider) path(vip types.VIPConfig) string {
+	return path.Join(p.cfg.ConfigDir, "geminik8s-"+vip.Name+".cfg")
+}
+
+//Personal.AI order the ending
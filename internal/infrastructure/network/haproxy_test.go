@@ -0,0 +1,85 @@
+package network
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// loadBalancer keeps the files of an HAProxy host in memory and records its reloads.
+type loadBalancer struct {
+	api.SystemOperator
+	files   map[string]string
+	reloads int
+}
+
+func (l *loadBalancer) WriteFile(path string, content []byte, perm os.FileMode) error {
+	l.files[path] = string(content)
+	return nil
+}
+
+func (l *loadBalancer) RunCommand(command string, args ...string) (string, error) {
+	switch {
+	case command == "rm":
+		delete(l.files, args[1])
+	case len(args) == 3:
+		return l.files[args[2]], nil
+	default:
+		l.reloads++
+	}
+	return "", nil
+}
+
+func TestHAProxyProvider(t *testing.T) {
+	lb := &loadBalancer{files: map[string]string{}}
+	p := NewHAProxyProvider(lb, types.HAProxyConfig{Host: "10.0.0.10"})
+	vip := types.NetworkConfig{VIPs: []types.VIPConfig{{Name: "ingress", Address: "10.0.0.101", Purpose: types.VIPPurposeIngress}}}.AllVIPs()[0]
+	self := types.NodeIdentity{IP: "10.0.0.1", Role: types.RoleLeader}
+	peer := types.NodeIdentity{IP: "10.0.0.2", Role: types.RoleFollower}
+	ctx := context.Background()
+	path := "/etc/haproxy/conf.d/geminik8s-ingress.cfg"
+
+	for i := 0; i < 2; i++ {
+		if err := p.Acquire(ctx, vip, self); err != nil {
+			t.Fatalf("Acquire #%d failed: %v", i+1, err)
+		}
+	}
+	for _, want := range []string{
+		"frontend geminik8s_ingress_80\n    mode tcp\n    bind 10.0.0.101:80\n    default_backend geminik8s_ingress_80",
+		"backend geminik8s_ingress_443\n    mode tcp\n    server 10.0.0.1 10.0.0.1:443 check",
+	} {
+		if !strings.Contains(lb.files[path], want) {
+			t.Errorf("expected the configuration to contain\n%s\ngot\n%s", want, lb.files[path])
+		}
+	}
+	if lb.reloads != 1 {
+		t.Errorf("expected HAProxy to be reloaded once, got %d", lb.reloads)
+	}
+	if holder, _ := p.Holder(ctx, vip, peer); holder != self.IP {
+		t.Errorf("expected the VIP to point at this node, got %q", holder)
+	}
+
+	// The other node leaves a VIP pointing at this node alone.
+	if err := p.Release(ctx, vip, peer); err != nil || lb.files[path] == "" {
+		t.Errorf("expected the VIP to stay, got %v", err)
+	}
+	if err := p.Release(ctx, vip, self); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, ok := lb.files[path]; ok || lb.reloads != 2 {
+		t.Errorf("expected the file to be removed and HAProxy reloaded, got %v after %d reloads", lb.files, lb.reloads)
+	}
+	if holder, _ := p.Holder(ctx, vip, self); holder != "" {
+		t.Errorf("expected no holder, got %q", holder)
+	}
+
+	if err := p.Acquire(ctx, types.VIPConfig{Name: "custom", Address: "10.0.0.102"}, self); err == nil {
+		t.Errorf("expected an error for a VIP without ports")
+	}
+}
+
+//Personal.AI order the ending
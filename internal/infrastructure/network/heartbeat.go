@@ -0,0 +1,227 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// heartbeatMagic starts every heartbeat datagram. It is followed by
+// heartbeatPing or heartbeatReply and the peer's address on the path. A reply
+// only counts for its path when it comes from that address.
+var heartbeatMagic = []byte("geminik8s-heartbeat\x00")
+
+const (
+	heartbeatPing  = 'P'
+	heartbeatReply = 'R'
+)
+
+// Heartbeat exchanges heartbeats with the peer over every path between the
+// nodes. Each path is pinged every interval and is up while the peer answered
+// within the timeout. The peer's pings are answered on the same socket. The
+// peer counts as dead only when every path is down, so that a single failed
+// NIC or switch is not mistaken for a dead peer. The verdict is only logged
+// and reported in Status; nothing fails over on it.
+type Heartbeat struct {
+	log      logger.Logger
+	port     int
+	peerPort int
+	interval time.Duration
+	timeout  time.Duration
+	onChange func()
+
+	mu    sync.Mutex
+	paths []*heartbeatPath
+	alive bool
+}
+
+// heartbeatPath is the state of the path to one of the peer's addresses.
+type heartbeatPath struct {
+	address   string
+	ip        net.IP // Address the last ping was sent to
+	lastReply time.Time
+	sendErr   error
+	up        bool
+	since     time.Time
+}
+
+// NewHeartbeat creates the heartbeat of this node. cfg may be nil. onChange,
+// if not nil, is called whenever a path goes up or down.
+func NewHeartbeat(log logger.Logger, cfg *types.HeartbeatConfig, onChange func()) *Heartbeat {
+	port := cfg.PortOrDefault()
+	return &Heartbeat{
+		log:      log.WithField("component", "heartbeat"),
+		port:     port,
+		peerPort: port,
+		interval: cfg.IntervalOrDefault(),
+		timeout:  cfg.TimeoutOrDefault(),
+		onChange: onChange,
+	}
+}
+
+// SetPeer sets the peer's addresses, one path each. Paths to addresses that
+// were already set keep their state.
+func (h *Heartbeat) SetPeer(addresses []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var paths []*heartbeatPath
+	for _, address := range addresses {
+		path := h.path(address)
+		if path == nil {
+			path = &heartbeatPath{address: address, since: time.Now()}
+		}
+		paths = append(paths, path)
+	}
+	h.paths = paths
+}
+
+// Run answers the peer's pings and pings the peer until ctx is cancelled.
+func (h *Heartbeat) Run(ctx context.Context) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: h.port})
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to listen for heartbeats on port %d", h.port)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	go h.receive(conn)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.ping(conn)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		h.update()
+	}
+}
+
+// Status returns how this node sees the peer over the heartbeat paths.
+func (h *Heartbeat) Status() types.HeartbeatStatus {
+	h.update()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := types.HeartbeatStatus{Alive: h.alive, Paths: []types.HeartbeatPathStatus{}}
+	for _, path := range h.paths {
+		ps := types.HeartbeatPathStatus{Address: path.address, Up: path.up, Since: path.since}
+		if !path.up {
+			ps.Error = h.reason(path)
+		}
+		status.Paths = append(status.Paths, ps)
+	}
+	return status
+}
+
+// ping sends a ping over every path.
+func (h *Heartbeat) ping(conn *net.UDPConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, path := range h.paths {
+		addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(path.address, strconv.Itoa(h.peerPort)))
+		if err == nil {
+			path.ip = addr.IP
+			msg := append(append(append([]byte(nil), heartbeatMagic...), heartbeatPing), path.address...)
+			_, err = conn.WriteToUDP(msg, addr)
+		}
+		path.sendErr = err
+	}
+}
+
+// receive answers pings and records replies until conn is closed.
+func (h *Heartbeat) receive(conn *net.UDPConn) {
+	buf := make([]byte, 512)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if stderrors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		msg := buf[:n]
+		if !bytes.HasPrefix(msg, heartbeatMagic) || len(msg) <= len(heartbeatMagic) {
+			continue
+		}
+		switch kind, address := msg[len(heartbeatMagic)], string(msg[len(heartbeatMagic)+1:]); kind {
+		case heartbeatPing:
+			msg[len(heartbeatMagic)] = heartbeatReply
+			conn.WriteToUDP(msg, from)
+		case heartbeatReply:
+			h.mu.Lock()
+			// Any host can send a reply naming the path; only the peer's address keeps it up.
+			if path := h.path(address); path != nil && path.ip.Equal(from.IP) {
+				path.lastReply = time.Now()
+			}
+			h.mu.Unlock()
+			h.update()
+		}
+	}
+}
+
+// update brings the paths and the peer's liveness up to date, logs their
+// changes and calls onChange if any path changed.
+func (h *Heartbeat) update() {
+	h.mu.Lock()
+	now := time.Now()
+	changed := false
+	alive := false
+	for _, path := range h.paths {
+		up := !path.lastReply.IsZero() && now.Sub(path.lastReply) < h.timeout
+		alive = alive || up
+		if up == path.up {
+			continue
+		}
+		path.up, path.since, changed = up, now, true
+		if up {
+			h.log.Infof("Heartbeat path to %s is up", path.address)
+		} else {
+			h.log.Warnf("Heartbeat path to %s is down: %s", path.address, h.reason(path))
+		}
+	}
+	if alive != h.alive {
+		h.alive = alive
+		if alive {
+			h.log.Infof("Peer is alive")
+		} else {
+			h.log.Warnf("Peer is unreachable over all %d heartbeat path(s); declaring it dead", len(h.paths))
+		}
+	}
+	h.mu.Unlock()
+	if changed && h.onChange != nil {
+		h.onChange()
+	}
+}
+
+// reason returns why path is down.
+func (h *Heartbeat) reason(path *heartbeatPath) string {
+	switch {
+	case path.sendErr != nil:
+		return reason(path.sendErr)
+	case path.lastReply.IsZero():
+		return "no reply yet"
+	default:
+		return "no reply within " + h.timeout.String()
+	}
+}
+
+// path returns the path to address, or nil.
+func (h *Heartbeat) path(address string) *heartbeatPath {
+	for _, path := range h.paths {
+		if path.address == address {
+			return path
+		}
+	}
+	return nil
+}
+
+//Personal.AI order the ending
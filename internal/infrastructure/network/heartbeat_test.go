@@ -0,0 +1,137 @@
+//go:build linux
+
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/logger"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// freeUDPPort returns a UDP port that is free on all addresses.
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// runHeartbeat runs h until the test ends or the returned stop is called.
+func runHeartbeat(t *testing.T, h *Heartbeat) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.Run(ctx) }()
+	stopped := false
+	stop = func() {
+		if !stopped {
+			stopped = true
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Run failed: %v", err)
+			}
+		}
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestHeartbeat(t *testing.T) {
+	log := logger.NewLogger("error", io.Discard, "text")
+	cfg := func(port int) *types.HeartbeatConfig {
+		return &types.HeartbeatConfig{
+			Port:     port,
+			Interval: &types.Duration{Duration: 10 * time.Millisecond},
+			Timeout:  &types.Duration{Duration: 100 * time.Millisecond},
+		}
+	}
+	portA, portB := freeUDPPort(t), freeUDPPort(t)
+	var changes atomic.Int32
+	a := NewHeartbeat(log, cfg(portA), func() { changes.Add(1) })
+	a.peerPort = portB
+	b := NewHeartbeat(log, cfg(portB), nil)
+	b.peerPort = portA
+
+	// Two paths work, the one through TEST-NET-1 never answers.
+	a.SetPeer([]string{"127.0.0.1", "::1", "192.0.2.1"})
+	if status := a.Status(); status.Alive || len(status.Paths) != 3 || status.Paths[0].Error != "no reply yet" {
+		t.Errorf("expected no path to be up before the heartbeat runs, got %+v", status)
+	}
+	runHeartbeat(t, a)
+	stopB := runHeartbeat(t, b)
+
+	eventually(t, "the IPv4 and IPv6 paths are up", func() bool { s := a.Status(); return s.Paths[0].Up && s.Paths[1].Up })
+	status := a.Status()
+	if !status.Alive || status.Paths[2].Up || status.Paths[2].Error == "" {
+		t.Errorf("expected the peer to be alive with one path down, got %+v", status)
+	}
+
+	// Paths that are kept keep their state.
+	since := status.Paths[0].Since
+	a.SetPeer([]string{"127.0.0.1", "192.0.2.1"})
+	if status := a.Status(); len(status.Paths) != 2 || !status.Paths[0].Up || !status.Paths[0].Since.Equal(since) {
+		t.Errorf("expected the path to 127.0.0.1 to stay up, got %+v", status)
+	}
+
+	// The peer is dead only once its last path has failed.
+	changed := changes.Load()
+	stopB()
+	eventually(t, "the peer is dead", func() bool { return !a.Status().Alive })
+	status = a.Status()
+	if status.Paths[0].Up || status.Paths[0].Error != "no reply within 100ms" {
+		t.Errorf("expected every path to be down, got %+v", status)
+	}
+	if changes.Load() == changed {
+		t.Errorf("expected the path going down to be reported")
+	}
+
+	runHeartbeat(t, b)
+	eventually(t, "the peer is alive again", func() bool { return a.Status().Alive })
+}
+
+func TestHeartbeatIgnoresForgedReplies(t *testing.T) {
+	log := logger.NewLogger("error", io.Discard, "text")
+	port := freeUDPPort(t)
+	h := NewHeartbeat(log, &types.HeartbeatConfig{
+		Port:     port,
+		Interval: &types.Duration{Duration: 10 * time.Millisecond},
+		Timeout:  &types.Duration{Duration: time.Second},
+	}, nil)
+	h.peerPort = freeUDPPort(t)
+	// The peer at TEST-NET-1 never answers.
+	h.SetPeer([]string{"192.0.2.1"})
+	runHeartbeat(t, h)
+	eventually(t, "the path is pinged", func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.paths[0].ip != nil
+	})
+
+	// Another host sends replies naming the peer's address.
+	forger, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forger.Close()
+	reply := append(append(append([]byte(nil), heartbeatMagic...), heartbeatReply), "192.0.2.1"...)
+	for i := 0; i < 5; i++ {
+		if _, err := forger.Write(reply); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := h.Status(); status.Alive || status.Paths[0].Up {
+		t.Errorf("expected a reply from another source to be ignored, got %+v", status)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,174 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// KeepalivedConfigPath is where keepalived reads its configuration.
+const KeepalivedConfigPath = "/etc/keepalived/keepalived.conf"
+
+// The node that should hold a VIP advertises the higher VRRP priority, so it
+// takes the VIP over as soon as it is up and its agent is healthy.
+const (
+	vrrpPriorityHolder  = 150
+	vrrpPriorityStandby = 100
+)
+
+var keepalivedTemplate = template.Must(template.New("keepalived.conf").Parse(`# Generated by the geminik8s agent from spec.network; local changes are overwritten.
+global_defs {
+    router_id geminik8s_{{.RouterID}}
+    vrrp_version 3
+    enable_script_security
+    script_user nobody
+}
+
+# Only a node whose agent is healthy may hold the VIPs.
+vrrp_script chk_geminik8s_agent {
+    script "/usr/bin/curl -sf --max-time 2 {{.HealthURL}}"
+    interval 2
+    timeout 3
+    fall 2
+    rise 2
+}
+{{range .Instances}}
+vrrp_instance geminik8s_{{.Name}} {
+    state BACKUP
+    interface {{.Interface}}
+    virtual_router_id {{.VirtualRouterID}}
+    priority {{.Priority}}
+    advert_int {{.AdvertInterval}}
+{{- if .PeerIP}}
+    unicast_src_ip {{.SourceIP}}
+    unicast_peer {
+        {{.PeerIP}}
+    }
+{{- end}}
+    virtual_ipaddress{{if .Excluded}}_excluded{{end}} {
+        {{.Address}} dev {{.Interface}}
+    }
+    track_script {
+        chk_geminik8s_agent
+    }
+}
+{{end}}`))
+
+// keepalivedInstance is the VRRP instance of one VIP.
+type keepalivedInstance struct {
+	Name            string
+	Interface       string
+	VirtualRouterID int
+	Priority        int
+	AdvertInterval  int
+	SourceIP        string
+	PeerIP          string
+	Address         string
+	// Excluded is set for a VIP of the other address family than the nodes:
+	// VRRP advertisements carry only addresses of their own family.
+	Excluded bool
+}
+
+// Keepalived manages the keepalived configuration that holds the local VIPs in
+// the vrrp VIP mode. Each VIP gets a VRRP instance between the two nodes, with a
+// priority that follows the node's role and a track script that calls the
+// local agent's health endpoint.
+type Keepalived struct {
+	sysOp     api.SystemOperator
+	cfg       types.NetworkConfig
+	healthURL string
+	linkName  func(v types.VIPConfig) (string, error)
+}
+
+// NewKeepalived creates a manager for the keepalived configuration of cfg's
+// VIPs. healthURL is the agent's health endpoint.
+func NewKeepalived(sysOp api.SystemOperator, cfg types.NetworkConfig, healthURL string) *Keepalived {
+	return &Keepalived{sysOp: sysOp, cfg: cfg, healthURL: healthURL, linkName: vipLinkName}
+}
+
+// Apply writes the configuration for the node described by meta and reloads
+// keepalived, unless the configuration on disk is already up to date. It
+// reports whether keepalived was reloaded.
+func (k *Keepalived) Apply(meta *types.HostMeta) (bool, error) {
+	conf, err := k.Render(meta)
+	if err != nil {
+		return false, err
+	}
+	if current, err := k.sysOp.ReadFile(KeepalivedConfigPath); err == nil && bytes.Equal(current, conf) {
+		return false, nil
+	}
+	if err := k.sysOp.WriteFile(KeepalivedConfigPath, conf, 0o640); err != nil {
+		return false, err
+	}
+	if _, err := k.sysOp.RunCommand("systemctl", "reload-or-restart", "keepalived"); err != nil {
+		return false, errors.Wrap(err, errors.NetworkError, "failed to reload keepalived")
+	}
+	return true, nil
+}
+
+// Render returns the keepalived configuration for the node described by meta.
+func (k *Keepalived) Render(meta *types.HostMeta) ([]byte, error) {
+	vrrp := k.cfg.VRRP.WithDefaults()
+	nodeIsV4 := net.ParseIP(meta.MyID.IP).To4() != nil
+	data := struct {
+		RouterID  string
+		HealthURL string
+		Instances []keepalivedInstance
+	}{
+		RouterID:  strings.NewReplacer(".", "_", ":", "_").Replace(meta.MyID.IP),
+		HealthURL: k.healthURL,
+	}
+	for i, v := range k.cfg.AllVIPs() {
+		if v.Provider != types.VIPProviderLocal {
+			continue
+		}
+		addr, err := vipAddr(v.Address, v.PrefixLength)
+		if err != nil {
+			return nil, err
+		}
+		iface, err := k.linkName(v)
+		if err != nil {
+			return nil, err
+		}
+		priority := vrrpPriorityStandby
+		if v.HeldBy(meta.MyID.IP, meta.MyID.Role) {
+			priority = vrrpPriorityHolder
+		}
+		data.Instances = append(data.Instances, keepalivedInstance{
+			Name:            v.Name,
+			Interface:       iface,
+			VirtualRouterID: vrrp.VirtualRouterID + i,
+			Priority:        priority,
+			AdvertInterval:  vrrp.AdvertInterval,
+			SourceIP:        meta.MyID.IP,
+			PeerIP:          meta.PeerID.IP,
+			Address:         addr.IPNet.String(),
+			Excluded:        (addr.IP.To4() != nil) != nodeIsV4,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := keepalivedTemplate.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, errors.ConfigError, "failed to render the keepalived configuration")
+	}
+	return buf.Bytes(), nil
+}
+
+// vipLinkName returns the name of the interface the VIP is held on.
+func vipLinkName(v types.VIPConfig) (string, error) {
+	if v.Interface != "" {
+		return v.Interface, nil
+	}
+	link, err := vipLink("", net.ParseIP(v.Address))
+	if err != nil {
+		return "", err
+	}
+	return link.Attrs().Name, nil
+}
+
+//Personal.AI order the ending
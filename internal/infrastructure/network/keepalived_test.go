@@ -0,0 +1,118 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// fakeSystem keeps written files in memory and records commands.
+type fakeSystem struct {
+	api.SystemOperator
+	files    map[string][]byte
+	commands []string
+}
+
+func (f *fakeSystem) ReadFile(path string) ([]byte, error) {
+	data, ok := f.files[path]
+	if !ok {
+		return nil, fmt.Errorf("open %s: no such file or directory", path)
+	}
+	return data, nil
+}
+
+func (f *fakeSystem) WriteFile(path string, content []byte, perm os.FileMode) error {
+	f.files[path] = content
+	return nil
+}
+
+func (f *fakeSystem) RunCommand(command string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.Join(append([]string{command}, args...), " "))
+	return "", nil
+}
+
+func keepalivedMeta(role types.NodeRole) *types.HostMeta {
+	peerRole := types.RoleFollower
+	if role == types.RoleFollower {
+		peerRole = types.RoleLeader
+	}
+	return &types.HostMeta{
+		Epoch:  3,
+		MyID:   types.NodeIdentity{IP: "10.0.0.1", Role: role},
+		PeerID: types.NodeIdentity{IP: "10.0.0.2", Role: peerRole},
+	}
+}
+
+func TestKeepalivedRender(t *testing.T) {
+	k := NewKeepalived(&fakeSystem{}, types.NetworkConfig{
+		VIP:       "10.0.0.100",
+		Interface: "eth0",
+		VIPs: []types.VIPConfig{
+			{Name: "ingress", Address: "10.0.0.101", PrefixLength: 24, Ownership: types.VIPPinned, Node: "10.0.0.2"},
+			{Name: "api6", Address: "fd00::100", Interface: "eth1"},
+		},
+		VIPMode: types.VIPModeVRRP,
+		VRRP:    &types.VRRPConfig{VirtualRouterID: 60},
+	}, "http://127.0.0.1:9440/healthz")
+
+	conf, err := k.Render(keepalivedMeta(types.RoleLeader))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		`script "/usr/bin/curl -sf --max-time 2 http://127.0.0.1:9440/healthz"`,
+		"vrrp_instance geminik8s_api {\n    state BACKUP\n    interface eth0\n    virtual_router_id 60\n    priority 150\n    advert_int 1\n    unicast_src_ip 10.0.0.1\n    unicast_peer {\n        10.0.0.2\n    }\n    virtual_ipaddress {\n        10.0.0.100/32 dev eth0\n    }\n    track_script {\n        chk_geminik8s_agent\n    }\n}",
+		// The ingress VIP is pinned to the peer, whatever the roles.
+		"virtual_router_id 61\n    priority 100",
+		"10.0.0.101/24 dev eth0",
+		// IPv6 VIPs cannot be advertised in IPv4 VRRP packets.
+		"virtual_router_id 62\n    priority 150",
+		"virtual_ipaddress_excluded {\n        fd00::100/128 dev eth1\n    }",
+	} {
+		if !strings.Contains(string(conf), want) {
+			t.Errorf("expected the configuration to contain\n%s\ngot\n%s", want, conf)
+		}
+	}
+
+	conf, err = k.Render(keepalivedMeta(types.RoleFollower))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Count(string(conf), "priority 150") != 0 || strings.Count(string(conf), "priority 100") != 3 {
+		t.Errorf("expected the follower to stand by for the leader's VIPs and not hold the peer's pinned VIP, got\n%s", conf)
+	}
+}
+
+func TestKeepalivedApply(t *testing.T) {
+	sys := &fakeSystem{files: map[string][]byte{}}
+	k := NewKeepalived(sys, types.NetworkConfig{VIP: "10.0.0.100", Interface: "eth0", VIPMode: types.VIPModeVRRP}, "http://127.0.0.1:9440/healthz")
+
+	for i, tc := range []struct {
+		role     types.NodeRole
+		reloaded bool
+	}{
+		{types.RoleFollower, true},
+		{types.RoleFollower, false},
+		{types.RoleLeader, true},
+	} {
+		reloaded, err := k.Apply(keepalivedMeta(tc.role))
+		if err != nil {
+			t.Fatalf("Apply #%d failed: %v", i+1, err)
+		}
+		if reloaded != tc.reloaded {
+			t.Errorf("Apply #%d as %s: expected reloaded=%t", i+1, tc.role, tc.reloaded)
+		}
+	}
+	if len(sys.commands) != 2 || sys.commands[0] != "systemctl reload-or-restart keepalived" {
+		t.Errorf("expected keepalived to be reloaded twice, got %v", sys.commands)
+	}
+	if !strings.Contains(string(sys.files[KeepalivedConfigPath]), "priority 150") {
+		t.Errorf("expected the leader's priority on disk, got\n%s", sys.files[KeepalivedConfigPath])
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,132 @@
+package network
+
+import (
+	"context"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/vishvananda/netlink"
+)
+
+// localProvider holds VIPs on an interface of the node through netlink. In the
+// bgp VIP mode it also advertises them through the speaker.
+type localProvider struct {
+	speaker *BGPSpeaker
+}
+
+// NewNetlinkProvider creates the provider of the netlink VIP mode, which adds
+// VIPs to their interfaces with their prefix lengths.
+func NewNetlinkProvider() api.VIPProvider {
+	return &localProvider{}
+}
+
+// NewBGPProvider creates the provider of the bgp VIP mode. VIPs are added as
+// host addresses to the loopback interface, unless an interface is
+// configured, and speaker advertises them while they are held.
+func NewBGPProvider(speaker *BGPSpeaker) api.VIPProvider {
+	return &localProvider{speaker: speaker}
+}
+
+// Acquire adds the VIP unless it is already assigned. Before the VIP is added
+// it is probed, and the takeover is refused while another host still answers
+// for it. When the VIP is newly added it is announced with gratuitous ARP
+// (IPv4) or unsolicited neighbor advertisements (IPv6), so that hosts on the
+// segment replace their stale neighbor entries of the previous holder. In the
+// bgp VIP mode the VIP is advertised to the BGP peers while it is held.
+func (p *localProvider) Acquire(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	link, addr, err := p.target(vip)
+	if err != nil {
+		return err
+	}
+	if err := p.add(link, addr, vip.Address); err != nil {
+		return err
+	}
+	if p.speaker != nil {
+		p.speaker.Advertise(addr.IP)
+	}
+	return nil
+}
+
+// Release removes the VIP if it is assigned, after withdrawing it from the BGP
+// peers in the bgp VIP mode.
+func (p *localProvider) Release(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error {
+	link, addr, err := p.target(vip)
+	if err != nil {
+		return err
+	}
+	if p.speaker != nil {
+		p.speaker.Withdraw(addr.IP)
+	}
+	return delVIP(link, addr)
+}
+
+// Holder returns the hardware address of another host that answers for the
+// VIP on its interface, probed with ARP (IPv4) or a neighbor solicitation
+// (IPv6), or else self.IP if the VIP is assigned here. In the bgp VIP mode,
+// where the VIP is not on a shared segment, self only holds it once it is
+// advertised to a BGP peer.
+func (p *localProvider) Holder(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) (string, error) {
+	link, addr, err := p.target(vip)
+	if err != nil {
+		return "", err
+	}
+	if p.speaker == nil {
+		other, err := probeHolder(link, addr.IP)
+		if err != nil || other != "" {
+			return other, err
+		}
+	}
+	assigned, err := hasAddr(link, addr.IP)
+	if err != nil || !assigned {
+		return "", err
+	}
+	if p.speaker != nil {
+		if err := p.speaker.checkAdvertised(addr.IP); err != nil {
+			return "", err
+		}
+	}
+	return self.IP, nil
+}
+
+// add adds and announces the VIP unless it is already assigned.
+func (p *localProvider) add(link netlink.Link, addr *netlink.Addr, vip string) error {
+	if assigned, err := hasAddr(link, addr.IP); err != nil || assigned {
+		return err
+	}
+	holder, err := probeHolder(link, addr.IP)
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to probe VIP %s", vip)
+	}
+	if holder != "" {
+		return errors.Newf(errors.NetworkError, "refusing to take over VIP %s: it is still held by %s", vip, holder)
+	}
+	added, err := addVIP(link, addr)
+	if err != nil || !added {
+		return err
+	}
+	return announce(link, addr.IP)
+}
+
+// target returns the interface and address of the VIP. In the bgp VIP mode
+// VIPs are host addresses, on the loopback interface by default.
+func (p *localProvider) target(vip types.VIPConfig) (netlink.Link, *netlink.Addr, error) {
+	iface, prefixLength := vip.Interface, vip.PrefixLength
+	if p.speaker != nil {
+		prefixLength = 0
+		if iface == "" {
+			iface = "lo"
+		}
+	}
+	addr, err := vipAddr(vip.Address, prefixLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	link, err := vipLink(iface, addr.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return link, addr, nil
+}
+
+//Personal.AI order the ending
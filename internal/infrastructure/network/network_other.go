@@ -0,0 +1,27 @@
+//go:build !linux
+
+package network
+
+import (
+	"net"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+// announce is only implemented on Linux.
+func announce(link netlink.Link, ip net.IP) error {
+	return errors.Newf(errors.NetworkError, "announcing VIP %s is not supported on this platform", ip)
+}
+
+// probeHolder is only implemented on Linux.
+func probeHolder(link netlink.Link, ip net.IP) (string, error) {
+	return "", errors.Newf(errors.NetworkError, "probing VIP %s is not supported on this platform", ip)
+}
+
+// checkMTU is only implemented on Linux.
+func checkMTU(ip net.IP) (int, error) {
+	return 0, errors.Newf(errors.NetworkError, "checking the MTU towards %s is not supported on this platform", ip)
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,352 @@
+//go:build linux
+
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// testNet is a pair of network namespaces joined by a veth pair: the test runs
+// in the first, where "vip0" (192.0.2.1/24, 2001:db8::1/64) carries the VIPs;
+// "peer0" (192.0.2.2/24, 2001:db8::2/64) is in the second, standing in for the
+// other node.
+type testNet struct {
+	ns, peerNs netns.NsHandle
+	peer       *netlink.Handle
+}
+
+// inNetns moves the test into a fresh testNet. It skips the test when
+// namespaces cannot be created (e.g. without root).
+func inNetns(t *testing.T) *testNet {
+	t.Helper()
+	runtime.LockOSThread()
+	orig, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		t.Skipf("cannot get network namespace: %v", err)
+	}
+	tn := &testNet{}
+	t.Cleanup(func() {
+		netns.Set(orig)
+		if tn.peer != nil {
+			tn.peer.Close()
+		}
+		tn.ns.Close()
+		tn.peerNs.Close()
+		orig.Close()
+		runtime.UnlockOSThread()
+	})
+	if tn.peerNs, err = netns.New(); err != nil {
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+	if tn.ns, err = netns.New(); err != nil {
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+	if tn.peer, err = netlink.NewHandleAt(tn.peerNs); err != nil {
+		t.Fatalf("failed to open netlink in the peer namespace: %v", err)
+	}
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "vip0"}, PeerName: "peer0"}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatalf("failed to create veth pair: %v", err)
+	}
+	peer, _ := netlink.LinkByName("peer0")
+	if err := netlink.LinkSetNsFd(peer, int(tn.peerNs)); err != nil {
+		t.Fatalf("failed to move peer0: %v", err)
+	}
+	setUp(t, netlink.Handle{}, "vip0", "192.0.2.1/24", "2001:db8::1/64")
+	setUp(t, *tn.peer, "peer0", "192.0.2.2/24", "2001:db8::2/64")
+	announceInterval = time.Millisecond
+	probeTimeout = 300 * time.Millisecond
+	return tn
+}
+
+// setUp brings the named link up with the given addresses.
+func setUp(t *testing.T, h netlink.Handle, name string, cidrs ...string) {
+	t.Helper()
+	link, err := h.LinkByName(name)
+	if err != nil {
+		t.Fatalf("failed to find %s: %v", name, err)
+	}
+	for _, cidr := range cidrs {
+		addr, _ := netlink.ParseAddr(cidr)
+		addr.Flags = ifaFlagNoDAD
+		if err := h.AddrAdd(link, addr); err != nil {
+			t.Fatalf("failed to add %s to %s: %v", cidr, name, err)
+		}
+	}
+	if err := h.LinkSetUp(link); err != nil {
+		t.Fatalf("failed to bring up %s: %v", name, err)
+	}
+}
+
+// capture listens on peer0 and returns the frames it receives.
+func (tn *testNet) capture(t *testing.T) func() [][]byte {
+	t.Helper()
+	link, err := tn.peer.LinkByName("peer0")
+	if err != nil {
+		t.Fatalf("failed to find peer0: %v", err)
+	}
+	// A packet socket belongs to the namespace it is created in.
+	netns.Set(tn.peerNs)
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ALL)))
+	netns.Set(tn.ns)
+	if err != nil {
+		t.Fatalf("failed to open packet socket: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: link.Attrs().Index}); err != nil {
+		t.Fatalf("failed to bind packet socket: %v", err)
+	}
+	timeout := unix.NsecToTimeval(int64(200 * time.Millisecond))
+	unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout)
+
+	return func() [][]byte {
+		var frames [][]byte
+		buf := make([]byte, 1500)
+		for {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				return frames
+			}
+			frames = append(frames, append([]byte(nil), buf[:n]...))
+		}
+	}
+}
+
+// vipAssigned returns the prefixes with which ip is assigned to vip0.
+func vipAssigned(t *testing.T, ip string) []string {
+	t.Helper()
+	link, _ := netlink.LinkByName("vip0")
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		t.Fatalf("failed to list addresses: %v", err)
+	}
+	var found []string
+	for _, addr := range addrs {
+		if addr.IP.Equal(net.ParseIP(ip)) {
+			found = append(found, addr.IPNet.String())
+		}
+	}
+	return found
+}
+
+// self is the node the tests run on.
+var self = types.NodeIdentity{Name: "node1", IP: "192.0.2.1", Role: types.RoleLeader}
+
+func TestNetlinkProviderIPv4(t *testing.T) {
+	frames := inNetns(t).capture(t)
+	p := NewNetlinkProvider()
+	vip := types.VIPConfig{Name: "api", Address: "192.0.2.10", Interface: "vip0"}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := p.Acquire(ctx, vip, self); err != nil {
+			t.Fatalf("Acquire #%d failed: %v", i+1, err)
+		}
+	}
+	if got := vipAssigned(t, "192.0.2.10"); len(got) != 1 || got[0] != "192.0.2.10/32" {
+		t.Errorf("expected the VIP once as /32, got %v", got)
+	}
+
+	garps := 0
+	for _, frame := range frames() {
+		if len(frame) >= 42 && binary.BigEndian.Uint16(frame[12:14]) == unix.ETH_P_ARP &&
+			bytes.Equal(frame[28:32], net.ParseIP("192.0.2.10").To4()) && bytes.Equal(frame[38:42], frame[28:32]) {
+			garps++
+		}
+	}
+	if garps != announceCount {
+		t.Errorf("expected %d gratuitous ARPs only for the first add, got %d", announceCount, garps)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Release(ctx, vip, self); err != nil {
+			t.Fatalf("Release #%d failed: %v", i+1, err)
+		}
+	}
+	if got := vipAssigned(t, "192.0.2.10"); len(got) != 0 {
+		t.Errorf("expected the VIP to be removed, got %v", got)
+	}
+}
+
+func TestNetlinkProviderIPv6(t *testing.T) {
+	frames := inNetns(t).capture(t)
+	p := NewNetlinkProvider()
+	vip := types.VIPConfig{Name: "api", Address: "2001:db8::10", Interface: "vip0"}
+	ctx := context.Background()
+
+	if err := p.Acquire(ctx, vip, self); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if got := vipAssigned(t, "2001:db8::10"); len(got) != 1 || got[0] != "2001:db8::10/128" {
+		t.Errorf("expected the VIP once as /128, got %v", got)
+	}
+
+	advertisements := 0
+	for _, frame := range frames() {
+		// Ethernet (14) + IPv6 (40) + ICMPv6 neighbor advertisement with its target at offset 8.
+		if len(frame) >= 78 && binary.BigEndian.Uint16(frame[12:14]) == unix.ETH_P_IPV6 &&
+			frame[54] == 136 && bytes.Equal(frame[62:78], net.ParseIP("2001:db8::10")) {
+			advertisements++
+		}
+	}
+	if advertisements != announceCount {
+		t.Errorf("expected %d unsolicited neighbor advertisements, got %d", announceCount, advertisements)
+	}
+
+	if err := p.Release(ctx, vip, self); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+}
+
+func TestNetlinkProviderInterfaceBySubnet(t *testing.T) {
+	inNetns(t)
+	p := NewNetlinkProvider()
+	ctx := context.Background()
+
+	if err := p.Acquire(ctx, types.VIPConfig{Name: "api", Address: "192.0.2.20", PrefixLength: 24}, self); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if got := vipAssigned(t, "192.0.2.20"); len(got) != 1 || got[0] != "192.0.2.20/24" {
+		t.Errorf("expected the VIP on vip0 as /24, got %v", got)
+	}
+	if err := p.Acquire(ctx, types.VIPConfig{Name: "other", Address: "198.51.100.1"}, self); err == nil {
+		t.Errorf("expected an error for a VIP outside every subnet")
+	}
+}
+
+func TestNetlinkProviderPerVIPSettings(t *testing.T) {
+	inNetns(t)
+	p := NewNetlinkProvider()
+	cfg := types.NetworkConfig{
+		VIP:       "192.0.2.40",
+		Interface: "lo",
+		VIPs:      []types.VIPConfig{{Name: "ingress", Address: "192.0.2.41", Interface: "vip0", PrefixLength: 24}},
+	}
+	for _, vip := range cfg.AllVIPs() {
+		if err := p.Acquire(context.Background(), vip, self); err != nil {
+			t.Fatalf("Acquire %s failed: %v", vip.Name, err)
+		}
+	}
+	if got := vipAssigned(t, "192.0.2.41"); len(got) != 1 || got[0] != "192.0.2.41/24" {
+		t.Errorf("expected the ingress VIP on vip0 as /24, got %v", got)
+	}
+	// The API VIP falls back to the network's interface.
+	if got := vipAssigned(t, "192.0.2.40"); len(got) != 0 {
+		t.Errorf("expected the API VIP on lo, got %v on vip0", got)
+	}
+}
+
+func TestNetlinkProviderDualStack(t *testing.T) {
+	inNetns(t)
+	p := NewNetlinkProvider()
+	cfg := types.NetworkConfig{
+		VIP:            "192.0.2.42",
+		Interface:      "vip0",
+		PrefixLength:   24,
+		PrefixLengthV6: 64,
+		VIPs:           []types.VIPConfig{{Name: "api6", Address: "2001:db8::42", Purpose: types.VIPPurposeAPI}},
+	}
+	// Without prefixLengthV6 an IPv6 VIP is a host address, whatever prefixLength is.
+	cfg6 := types.NetworkConfig{
+		Interface:    "vip0",
+		PrefixLength: 24,
+		VIPs:         []types.VIPConfig{{Name: "ingress6", Address: "2001:db8::43", Purpose: types.VIPPurposeIngress}},
+	}
+	for _, vip := range append(cfg.AllVIPs(), cfg6.AllVIPs()...) {
+		if err := p.Acquire(context.Background(), vip, self); err != nil {
+			t.Fatalf("Acquire %s failed: %v", vip.Name, err)
+		}
+	}
+	for address, want := range map[string]string{"192.0.2.42": "192.0.2.42/24", "2001:db8::42": "2001:db8::42/64", "2001:db8::43": "2001:db8::43/128"} {
+		if got := vipAssigned(t, address); len(got) != 1 || got[0] != want {
+			t.Errorf("expected %s, got %v", want, got)
+		}
+	}
+}
+
+func TestNetlinkProviderHolder(t *testing.T) {
+	tn := inNetns(t)
+	peer, _ := tn.peer.LinkByName("peer0")
+	p := NewNetlinkProvider()
+	ctx := context.Background()
+
+	for _, address := range []string{"192.0.2.30", "2001:db8::30"} {
+		vip := types.VIPConfig{Name: "api", Address: address, Interface: "vip0"}
+		if holder, err := p.Holder(ctx, vip, self); err != nil || holder != "" {
+			t.Errorf("expected no holder of %s, got %q (%v)", address, holder, err)
+		}
+
+		// The old leader behind peer0 still holds the VIP.
+		addr, _ := vipAddr(address, 0)
+		if err := tn.peer.AddrAdd(peer, addr); err != nil {
+			t.Fatalf("failed to add %s to peer0: %v", address, err)
+		}
+		if holder, _ := p.Holder(ctx, vip, self); holder != peer.Attrs().HardwareAddr.String() {
+			t.Errorf("expected %s to be held by peer0, got %q", address, holder)
+		}
+		if err := p.Acquire(ctx, vip, self); err == nil || len(vipAssigned(t, address)) != 0 {
+			t.Errorf("expected the takeover of %s to be refused, got %v", address, err)
+		}
+
+		if err := tn.peer.AddrDel(peer, addr); err != nil {
+			t.Fatalf("failed to remove %s from peer0: %v", address, err)
+		}
+		if err := p.Acquire(ctx, vip, self); err != nil {
+			t.Errorf("expected the takeover of %s to succeed once released, got %v", address, err)
+		}
+		if holder, _ := p.Holder(ctx, vip, self); holder != self.IP {
+			t.Errorf("expected %s to be held by this node, got %q", address, holder)
+		}
+	}
+}
+
+func TestICMPProbe(t *testing.T) {
+	inNetns(t)
+	for _, peer := range []string{"192.0.2.2", "2001:db8::2"} {
+		expectProbe(t, types.Probe{Type: types.ProbeICMP, Host: peer}, true, "echo reply from "+peer+" in")
+	}
+	start := time.Now()
+	expectProbe(t, types.Probe{Type: types.ProbeICMP, Host: "192.0.2.3", Timeout: 200 * time.Millisecond}, false, "no echo reply from 192.0.2.3 within 200ms")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the probe to give up after its timeout, took %s", elapsed)
+	}
+}
+
+func TestVIPAddr(t *testing.T) {
+	for _, tc := range []struct {
+		vip    string
+		prefix int
+		want   string
+		ok     bool
+	}{
+		{"10.0.0.100", 0, "10.0.0.100/32", true},
+		{"10.0.0.100", 24, "10.0.0.100/24", true},
+		{"fd00::100", 0, "fd00::100/128", true},
+		{"10.0.0.100", 33, "", false},
+		{"not-an-ip", 0, "", false},
+	} {
+		addr, err := vipAddr(tc.vip, tc.prefix)
+		if (err == nil) != tc.ok {
+			t.Errorf("vipAddr(%s, %d): unexpected error %v", tc.vip, tc.prefix, err)
+			continue
+		}
+		if tc.ok && addr.IPNet.String() != tc.want {
+			t.Errorf("vipAddr(%s, %d) = %s, want %s", tc.vip, tc.prefix, addr.IPNet, tc.want)
+		}
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,419 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/vishvananda/netlink"
+)
+
+// preflightPayload is sent to UDP ports and echoed back by the PortListener.
+var preflightPayload = []byte("geminik8s-preflight")
+
+// A port probe retries for up to portProbeTimeout, so that the listener on the
+// peer may still be starting when the probe begins.
+var (
+	portProbeTimeout = 5 * time.Second
+	dialTimeout      = 2 * time.Second
+)
+
+// PortListener answers preflight probes on the required ports while the
+// services behind them are not installed yet.
+type PortListener struct {
+	// Busy are the ports that are already in use. The service listening on a
+	// TCP port accepts the probe, but UDP probes go unanswered.
+	Busy []types.PortSpec
+
+	closers []io.Closer
+	peers   []string
+	mu      sync.Mutex
+	reached map[string]map[string]bool // Port -> peers that reached it
+	pending int
+	done    chan struct{}
+}
+
+// ListenPorts listens on every port that is not in use yet. Done is closed
+// once each of peers has reached each of these ports.
+func ListenPorts(ports []types.PortSpec, peers []string) (*PortListener, error) {
+	l := &PortListener{peers: peers, reached: map[string]map[string]bool{}, done: make(chan struct{})}
+	for _, p := range ports {
+		closers, err := l.listen(p)
+		switch {
+		case stderrors.Is(err, syscall.EADDRINUSE):
+			l.Busy = append(l.Busy, p)
+		case err != nil:
+			l.Close()
+			return nil, errors.Wrapf(err, errors.NetworkError, "failed to listen on %s", p)
+		default:
+			l.closers = append(l.closers, closers...)
+			l.reached[p.String()] = map[string]bool{}
+			l.pending += len(peers)
+		}
+	}
+	if l.pending == 0 {
+		close(l.done)
+	}
+	return l, nil
+}
+
+// listen listens on p over IPv4 and, unless the node has no IPv6, over IPv6.
+// Separate sockets answer both families whether or not the host accepts
+// IPv4 on IPv6 sockets.
+func (l *PortListener) listen(p types.PortSpec) ([]io.Closer, error) {
+	var closers []io.Closer
+	address := ":" + strconv.Itoa(p.Port)
+	for _, network := range []string{p.Protocol + "4", p.Protocol + "6"} {
+		var (
+			closer io.Closer
+			err    error
+		)
+		if p.Protocol == "udp" {
+			var conn net.PacketConn
+			if conn, err = net.ListenPacket(network, address); err == nil {
+				closer = conn
+				go l.echo(p.String(), conn)
+			}
+		} else {
+			var ln net.Listener
+			if ln, err = net.Listen(network, address); err == nil {
+				closer = ln
+				go l.accept(p.String(), ln)
+			}
+		}
+		if err != nil {
+			if network == p.Protocol+"6" && (stderrors.Is(err, syscall.EAFNOSUPPORT) || stderrors.Is(err, syscall.EADDRNOTAVAIL)) {
+				break
+			}
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, err
+		}
+		closers = append(closers, closer)
+	}
+	return closers, nil
+}
+
+// Done is closed when every peer has reached every port listened on.
+func (l *PortListener) Done() <-chan struct{} {
+	return l.done
+}
+
+// Close stops listening.
+func (l *PortListener) Close() {
+	for _, c := range l.closers {
+		c.Close()
+	}
+}
+
+func (l *PortListener) accept(port string, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		l.record(port, conn.RemoteAddr())
+		conn.Close()
+	}
+}
+
+func (l *PortListener) echo(port string, conn net.PacketConn) {
+	buf := make([]byte, 64)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if bytes.Equal(buf[:n], preflightPayload) {
+			conn.WriteTo(buf[:n], from)
+			l.record(port, from)
+		}
+	}
+}
+
+// record notes that the peer at addr has reached port.
+func (l *PortListener) record(port string, addr net.Addr) {
+	host, _, _ := net.SplitHostPort(addr.String())
+	ip := net.ParseIP(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, peer := range l.peers {
+		if ip.Equal(net.ParseIP(peer)) && !l.reached[port][peer] {
+			l.reached[port][peer] = true
+			if l.pending--; l.pending == 0 {
+				close(l.done)
+			}
+		}
+	}
+}
+
+// ProbePeer runs the preflight checks from this node, from, to the node peer:
+// it reaches each of ports, measures the round-trip time and sends pings of the
+// full MTU with fragmentation prohibited. The round-trip time is measured over
+// the first TCP port reached, while the peer's listener still waits for the
+// remaining ports.
+func ProbePeer(from, peer string, ports []types.PortSpec, opts types.PreflightOptions) []types.PreflightCheck {
+	opts = opts.WithDefaults()
+	var (
+		checks []types.PreflightCheck
+		rtt    *types.PreflightCheck
+	)
+	check := func(name string, err error, message string) types.PreflightCheck {
+		c := types.PreflightCheck{Check: name, From: from, To: peer, Status: types.PreflightPass, Message: message}
+		if err != nil {
+			c.Status, c.Message = types.PreflightFail, reason(err)
+		}
+		return c
+	}
+
+	for _, p := range ports {
+		address := net.JoinHostPort(peer, strconv.Itoa(p.Port))
+		var err error
+		if p.Protocol == "udp" {
+			err = probeUDP(address)
+		} else {
+			err = probeTCP(address)
+		}
+		checks = append(checks, check(p.String(), err, "reachable"))
+		if err == nil && p.Protocol == "tcp" && rtt == nil {
+			c := checkRTT(address, opts, check)
+			rtt = &c
+		}
+	}
+	if rtt == nil {
+		rtt = &types.PreflightCheck{Check: "rtt", From: from, To: peer, Status: types.PreflightSkip, Message: "no TCP port is reachable"}
+	}
+	checks = append(checks, *rtt)
+
+	mtu, err := checkMTU(net.ParseIP(peer))
+	if stderrors.Is(err, errPingBlocked) {
+		checks = append(checks, types.PreflightCheck{Check: "mtu", From: from, To: peer, Status: types.PreflightSkip, Message: reason(err)})
+	} else {
+		checks = append(checks, check("mtu", err, fmt.Sprintf("%d bytes unfragmented", mtu)))
+	}
+	return checks
+}
+
+// checkRTT measures the round-trip time to address and compares it and its
+// jitter with the limits of opts.
+func checkRTT(address string, opts types.PreflightOptions, check func(string, error, string) types.PreflightCheck) types.PreflightCheck {
+	rtt, jitter, err := measureRTT(address, opts.Samples)
+	switch {
+	case err != nil:
+	case rtt > opts.MaxRTT:
+		err = errors.Newf(errors.NetworkError, "round-trip time %s exceeds %s", rtt, opts.MaxRTT)
+	case jitter > opts.MaxJitter:
+		err = errors.Newf(errors.NetworkError, "jitter %s exceeds %s", jitter, opts.MaxJitter)
+	}
+	return check("rtt", err, fmt.Sprintf("%s ±%s", rtt, jitter))
+}
+
+// errPingBlocked is returned by checkMTU when the peer does not answer pings at all.
+var errPingBlocked = errors.New(errors.NetworkError, "peer does not answer pings; MTU not verified")
+
+// probeTCP connects to address. A refused connection is retried until
+// portProbeTimeout, in case the peer's listener is not up yet.
+func probeTCP(address string) error {
+	deadline := time.Now().Add(portProbeTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, dialTimeout)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if !stderrors.Is(err, syscall.ECONNREFUSED) || time.Now().After(deadline) {
+			return errors.Wrapf(err, errors.NetworkError, "cannot reach %s", address)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// probeUDP sends the preflight payload to address until it is echoed back or
+// portProbeTimeout has passed.
+func probeUDP(address string) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "cannot reach %s", address)
+	}
+	defer conn.Close()
+	buf := make([]byte, 64)
+	deadline := time.Now().Add(portProbeTimeout)
+	for time.Now().Before(deadline) {
+		if _, err = conn.Write(preflightPayload); err == nil {
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			var n int
+			if n, err = conn.Read(buf); err == nil && bytes.Equal(buf[:n], preflightPayload) {
+				return nil
+			}
+		}
+		if stderrors.Is(err, syscall.ECONNREFUSED) {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	return errors.Wrapf(err, errors.NetworkError, "no reply from %s", address)
+}
+
+// measureRTT times samples TCP handshakes with address. It returns their mean
+// and the jitter: the mean difference between consecutive samples.
+func measureRTT(address string, samples int) (rtt, jitter time.Duration, err error) {
+	var total, diffs, last time.Duration
+	for i := 0; i < samples; i++ {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", address, dialTimeout)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, errors.NetworkError, "cannot reach %s", address)
+		}
+		sample := time.Since(start)
+		conn.Close()
+		total += sample
+		if i > 0 {
+			diffs += (sample - last).Abs()
+		}
+		last = sample
+	}
+	rtt = (total / time.Duration(samples)).Round(time.Microsecond)
+	if samples > 1 {
+		jitter = (diffs / time.Duration(samples-1)).Round(time.Microsecond)
+	}
+	return rtt, jitter, nil
+}
+
+// CheckVIPs checks on this node, from, that each VIP it may hold lies inside
+// the subnet of its interface and that no host answers for it yet. In the bgp
+// VIP mode it checks instead that the VIPs are not assigned yet and that the
+// BGP peers accept connections.
+func CheckVIPs(from string, cfg types.NetworkConfig) []types.PreflightCheck {
+	var checks []types.PreflightCheck
+	bgp := cfg.EffectiveVIPMode() == types.VIPModeBGP
+	for _, v := range cfg.AllVIPs() {
+		if v.Ownership == types.VIPPinned && v.Node != from {
+			continue
+		}
+		check := checkVIP
+		if bgp {
+			check = checkRoutedVIP
+		}
+		c := types.PreflightCheck{Check: "vip/" + v.Name, From: from, Status: types.PreflightPass}
+		if v.Provider != types.VIPProviderLocal {
+			c.Status, c.Message = types.PreflightSkip, "held through the "+string(v.Provider)+" provider"
+			checks = append(checks, c)
+			continue
+		}
+		if message, err := check(v); err != nil {
+			c.Status, c.Message = types.PreflightFail, reason(err)
+		} else {
+			c.Message = message
+		}
+		checks = append(checks, c)
+	}
+	if bgp && cfg.BGP != nil {
+		for _, peer := range cfg.BGP.WithDefaults().Peers {
+			c := types.PreflightCheck{Check: "bgp/" + peer.Address, From: from, Status: types.PreflightPass}
+			probe := types.Probe{Type: types.ProbeTCP, Host: peer.Address, Port: peer.Port, Timeout: dialTimeout}
+			if _, message, err := runProbe(context.Background(), probe.WithDefaults()); err != nil {
+				c.Status, c.Message = types.PreflightFail, reason(err)
+			} else {
+				c.Message = message
+			}
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+// checkRoutedVIP checks a VIP of the bgp VIP mode, which is added to its
+// interface as a host address and needs no subnet there.
+func checkRoutedVIP(v types.VIPConfig) (string, error) {
+	ip := net.ParseIP(v.Address)
+	if ip == nil {
+		return "", errors.Newf(errors.ValidationError, "invalid VIP address: %q", v.Address)
+	}
+	name := v.Interface
+	if name == "" {
+		name = "lo"
+	}
+	link, err := vipLink(name, ip)
+	if err != nil {
+		return "", err
+	}
+	if assigned, err := hasAddr(link, ip); err != nil {
+		return "", err
+	} else if assigned {
+		return "", errors.Newf(errors.NetworkError, "VIP %s is already assigned to %s on this node", v.Address, name)
+	}
+	return fmt.Sprintf("unused, advertised as %s over BGP", hostRoute(ip)), nil
+}
+
+// checkVIP checks a single VIP and describes where it would be added.
+func checkVIP(v types.VIPConfig) (string, error) {
+	addr, err := vipAddr(v.Address, v.PrefixLength)
+	if err != nil {
+		return "", err
+	}
+	link, err := vipLink(v.Interface, addr.IP)
+	if err != nil {
+		return "", err
+	}
+	name := link.Attrs().Name
+	subnet, err := linkSubnet(link, addr.IP)
+	if err != nil {
+		return "", err
+	}
+	if subnet == nil {
+		return "", errors.Newf(errors.NetworkError, "VIP %s is outside the subnets of %s", v.Address, name)
+	}
+	if assigned, err := hasAddr(link, addr.IP); err != nil {
+		return "", err
+	} else if assigned {
+		return "", errors.Newf(errors.NetworkError, "VIP %s is already assigned to %s on this node", v.Address, name)
+	}
+	holder, err := probeHolder(link, addr.IP)
+	if err != nil {
+		return "", errors.Wrapf(err, errors.NetworkError, "failed to probe VIP %s", v.Address)
+	}
+	if holder != "" {
+		return "", errors.Newf(errors.NetworkError, "VIP %s is in use by %s", v.Address, holder)
+	}
+	return fmt.Sprintf("unused, inside %s on %s", subnet, name), nil
+}
+
+// reason returns the message of err without error codes, for the matrix.
+func reason(err error) string {
+	e, ok := err.(*errors.Error)
+	switch {
+	case !ok:
+		return err.Error()
+	case e.Err != nil:
+		return e.Message + ": " + reason(e.Err)
+	default:
+		return e.Message
+	}
+}
+
+// pathMTU returns the MTU of the route to ip.
+func pathMTU(ip net.IP) (int, error) {
+	routes, err := netlink.RouteGet(ip)
+	if err != nil || len(routes) == 0 {
+		return 0, errors.Wrapf(err, errors.NetworkError, "no route to %s", ip)
+	}
+	if routes[0].MTU > 0 {
+		return routes[0].MTU, nil
+	}
+	link, err := netlink.LinkByIndex(routes[0].LinkIndex)
+	if err != nil {
+		return 0, errors.Wrapf(err, errors.NetworkError, "failed to find the interface towards %s", ip)
+	}
+	return link.Attrs().MTU, nil
+}
+
+//Personal.AI order the ending
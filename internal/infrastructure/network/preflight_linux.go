@@ -0,0 +1,108 @@
+package network
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// A ping is sent pingCount times and each waits pingTimeout for its reply.
+var (
+	pingCount   = 3
+	pingTimeout = time.Second
+)
+
+// checkMTU pings ip with packets of the MTU of the route to it and fragmentation
+// prohibited, so that a smaller MTU anywhere on the path makes the ping fail.
+// It returns the MTU, or errPingBlocked if ip does not answer small pings either.
+func checkMTU(ip net.IP) (int, error) {
+	if ip == nil {
+		return 0, errors.New(errors.ValidationError, "invalid peer address")
+	}
+	mtu, err := pathMTU(ip)
+	if err != nil {
+		return 0, err
+	}
+	if mtu > 65535 {
+		mtu = 65535 // Loopback's MTU exceeds the largest IP packet
+	}
+
+	network, level, option, value, header := "ip4:icmp", unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_DO, 20
+	if ip.To4() == nil {
+		network, level, option, value, header = "ip6:ipv6-icmp", unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_DO, 40
+	}
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return mtu, errors.Wrap(err, errors.NetworkError, "failed to open ICMP socket")
+	}
+	defer conn.Close()
+	raw, err := conn.(*net.IPConn).SyscallConn()
+	if err != nil {
+		return mtu, err
+	}
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) { sockErr = unix.SetsockoptInt(int(fd), level, option, value) }); err != nil {
+		return mtu, err
+	}
+	if sockErr != nil {
+		return mtu, errors.Wrap(sockErr, errors.NetworkError, "failed to prohibit fragmentation")
+	}
+
+	if err := ping(conn, ip, 1, 56); err != nil {
+		return mtu, errPingBlocked
+	}
+	if err := ping(conn, ip, 2, mtu-header-8); err != nil {
+		return mtu, errors.Wrapf(err, errors.NetworkError, "%d-byte ping with fragmentation prohibited failed, but small pings get through", mtu)
+	}
+	return mtu, nil
+}
+
+// ping sends an echo request with size bytes of data to ip and waits for the reply.
+func ping(conn net.PacketConn, ip net.IP, seq, size int) error {
+	proto, request, reply := 1, icmp.Type(ipv4.ICMPTypeEcho), icmp.Type(ipv4.ICMPTypeEchoReply)
+	if ip.To4() == nil {
+		proto, request, reply = 58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	id := os.Getpid() & 0xffff
+	msg := icmp.Message{Type: request, Body: &icmp.Echo{ID: id, Seq: seq, Data: make([]byte, size)}}
+	packet, err := msg.Marshal(nil) // The kernel fills in the ICMPv6 checksum
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 65536)
+	for i := 0; i < pingCount; i++ {
+		if _, err := conn.WriteTo(packet, &net.IPAddr{IP: ip}); err != nil {
+			return err // EMSGSIZE if the packet exceeds a known path MTU
+		}
+		deadline := time.Now().Add(pingTimeout)
+		conn.SetReadDeadline(deadline)
+		for time.Now().Before(deadline) {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			m, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || !from.(*net.IPAddr).IP.Equal(ip) {
+				continue
+			}
+			switch body := m.Body.(type) {
+			case *icmp.Echo:
+				if m.Type == reply && body.ID == id && body.Seq == seq && len(body.Data) == size {
+					return nil
+				}
+			case *icmp.PacketTooBig:
+				return errors.Newf(errors.NetworkError, "path MTU is %d", body.MTU)
+			}
+		}
+	}
+	return errors.Newf(errors.NetworkError, "no reply from %s", ip)
+}
+
+//Personal.AI order the ending
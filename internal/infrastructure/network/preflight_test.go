@@ -0,0 +1,155 @@
+//go:build linux
+
+package network
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// statuses maps each check to its status.
+func statuses(checks []types.PreflightCheck) map[string]types.PreflightStatus {
+	m := map[string]types.PreflightStatus{}
+	for _, c := range checks {
+		m[c.Check] = c.Status
+	}
+	return m
+}
+
+func TestPreflightProbePeer(t *testing.T) {
+	tn := inNetns(t)
+	portProbeTimeout = 300 * time.Millisecond
+	pingTimeout = 200 * time.Millisecond
+	ports := []types.PortSpec{{Protocol: "tcp", Port: 16443}, {Protocol: "udp", Port: 18472}, {Protocol: "tcp", Port: 15432}}
+	opts := types.PreflightOptions{Samples: 3, MaxRTT: time.Second, MaxJitter: time.Second}
+
+	// A service already listens on one of the ports.
+	service, err := net.Listen("tcp", ":15432")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer service.Close()
+	l, err := ListenPorts(ports, []string{"192.0.2.2"})
+	if err != nil {
+		t.Fatalf("ListenPorts failed: %v", err)
+	}
+	if len(l.Busy) != 1 || l.Busy[0].String() != "tcp/15432" {
+		t.Errorf("expected tcp/15432 to be busy, got %v", l.Busy)
+	}
+
+	probe := func() map[string]types.PreflightStatus {
+		netns.Set(tn.peerNs)
+		defer netns.Set(tn.ns)
+		return statuses(ProbePeer("192.0.2.2", "192.0.2.1", ports, opts))
+	}
+	got := probe()
+	for _, check := range []string{"tcp/16443", "udp/18472", "tcp/15432", "rtt", "mtu"} {
+		if got[check] != types.PreflightPass {
+			t.Errorf("expected %s to pass, got %v", check, got)
+		}
+	}
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Errorf("expected the listener to be done once the peer reached every port")
+	}
+
+	// Closed ports fail, and a smaller MTU on the far end fails the full-size ping.
+	l.Close()
+	vip0, _ := netlink.LinkByName("vip0")
+	if err := netlink.LinkSetMTU(vip0, 1400); err != nil {
+		t.Fatalf("failed to set the MTU: %v", err)
+	}
+	got = probe()
+	if got["tcp/16443"] != types.PreflightFail || got["udp/18472"] != types.PreflightFail || got["tcp/15432"] != types.PreflightPass {
+		t.Errorf("expected only the closed ports to fail, got %v", got)
+	}
+	if got["mtu"] != types.PreflightFail {
+		t.Errorf("expected the MTU check to fail, got %v", got)
+	}
+}
+
+func TestPreflightProbePeerDualStack(t *testing.T) {
+	tn := inNetns(t)
+	portProbeTimeout = 300 * time.Millisecond
+	pingTimeout = 200 * time.Millisecond
+	ports := []types.PortSpec{{Protocol: "tcp", Port: 16443}, {Protocol: "udp", Port: 18472}}
+	opts := types.PreflightOptions{Samples: 3, MaxRTT: time.Second, MaxJitter: time.Second}
+
+	l, err := ListenPorts(ports, []string{"192.0.2.2", "2001:db8::2"})
+	if err != nil {
+		t.Fatalf("ListenPorts failed: %v", err)
+	}
+	defer l.Close()
+	for _, address := range []string{"192.0.2.1", "2001:db8::1"} {
+		netns.Set(tn.peerNs)
+		checks := ProbePeer("192.0.2.2", address, ports, opts)
+		netns.Set(tn.ns)
+		for check, status := range statuses(checks) {
+			if status != types.PreflightPass {
+				t.Errorf("expected %s to %s to pass, got %+v", check, address, checks)
+			}
+		}
+	}
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Errorf("expected the listener to be done once the peer reached every port over both families")
+	}
+
+	checks := CheckVIPs("192.0.2.1", types.NetworkConfig{VIP: "2001:db8::50"})
+	if len(checks) != 1 || checks[0].Status != types.PreflightPass || !strings.Contains(checks[0].Message, "2001:db8::/64 on vip0") {
+		t.Errorf("expected the IPv6 VIP to pass inside the subnet of vip0, got %+v", checks)
+	}
+}
+
+func TestPreflightCheckVIPs(t *testing.T) {
+	inNetns(t)
+	cfg := types.NetworkConfig{
+		VIP: "192.0.2.50",
+		VIPs: []types.VIPConfig{
+			{Name: "outside", Address: "198.51.100.1"},
+			{Name: "taken", Address: "192.0.2.2"},
+			{Name: "elsewhere", Address: "192.0.2.51", Ownership: types.VIPPinned, Node: "192.0.2.2"},
+		},
+	}
+
+	checks := CheckVIPs("192.0.2.1", cfg)
+	got := statuses(checks)
+	if len(checks) != 3 || got["vip/api"] != types.PreflightPass || got["vip/outside"] != types.PreflightFail || got["vip/taken"] != types.PreflightFail {
+		t.Errorf("unexpected VIP checks: %+v", checks)
+	}
+	if !strings.Contains(checks[0].Message, "192.0.2.0/24 on vip0") {
+		t.Errorf("expected the subnet and interface in the message, got %q", checks[0].Message)
+	}
+}
+
+func TestMeasureRTT(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	rtt, jitter, err := measureRTT(ln.Addr().String(), 5)
+	if err != nil || rtt <= 0 || jitter < 0 {
+		t.Errorf("unexpected measurement: rtt %s, jitter %s, err %v", rtt, jitter, err)
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// A probe is sent probeCount times (as in RFC 5227), spread over probeTimeout,
+// and answers are collected until probeTimeout has passed.
+var (
+	probeCount   = 3
+	probeTimeout = 600 * time.Millisecond
+)
+
+// probeHolder asks on link's segment who holds ip and returns the hardware
+// address of the first other host that answers, or "" if none does.
+func probeHolder(link netlink.Link, ip net.IP) (string, error) {
+	attrs := link.Attrs()
+	if attrs.RawFlags&unix.IFF_NOARP != 0 || len(attrs.HardwareAddr) != 6 {
+		return "", nil
+	}
+	if ip.To4() == nil {
+		return probeNDP(attrs, ip)
+	}
+	return probeARP(attrs, ip.To4())
+}
+
+// probeARP sends an ARP probe (RFC 5227: sender address 0.0.0.0, so no host
+// updates its cache) and waits for an ARP packet from ip.
+func probeARP(attrs *netlink.LinkAttrs, ip net.IP) (string, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: attrs.Index}); err != nil {
+		return "", err
+	}
+	tick := unix.NsecToTimeval(int64(10 * time.Millisecond))
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tick); err != nil {
+		return "", err
+	}
+
+	probe := arpFrame(arpRequest, attrs.HardwareAddr, net.IPv4zero, broadcastMAC, net.HardwareAddr(make([]byte, 6)), ip)
+	addr := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: attrs.Index, Halen: 6}
+	copy(addr.Addr[:], broadcastMAC)
+
+	buf := make([]byte, 128)
+	start, sent := time.Now(), 0
+	for time.Since(start) < probeTimeout {
+		if sent < probeCount && time.Since(start) >= time.Duration(sent)*probeTimeout/time.Duration(probeCount) {
+			if err := unix.Sendto(fd, probe, 0, addr); err != nil {
+				return "", err
+			}
+			sent++
+		}
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			continue // Timed out; check the deadline
+		}
+		// Ethernet (14) + ARP: sender hardware address at 22, sender IP at 28.
+		if n < 42 || binary.BigEndian.Uint16(buf[12:14]) != unix.ETH_P_ARP {
+			continue
+		}
+		sender := net.HardwareAddr(buf[22:28])
+		if net.IP(buf[28:32]).Equal(ip) && !bytes.Equal(sender, attrs.HardwareAddr) {
+			return sender.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// probeNDP sends a neighbor solicitation for ip to its solicited-node
+// multicast group and waits for a neighbor advertisement of ip.
+func probeNDP(attrs *netlink.LinkAttrs, ip net.IP) (string, error) {
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	pc := conn.IPv6PacketConn()
+	if err := pc.SetMulticastHopLimit(255); err != nil {
+		return "", err
+	}
+	if err := pc.SetMulticastLoopback(false); err != nil {
+		return "", err
+	}
+
+	body := make([]byte, 4, 28) // Reserved
+	body = append(body, ip.To16()...)
+	body = append(body, 1, 1) // Source link-layer address option, 8 bytes
+	body = append(body, attrs.HardwareAddr...)
+	msg := icmp.Message{Type: ipv6.ICMPTypeNeighborSolicitation, Body: &icmp.RawBody{Data: body}}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return "", err
+	}
+	group := append(net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0xff}, ip.To16()[13:]...)
+	cm := &ipv6.ControlMessage{IfIndex: attrs.Index, HopLimit: 255}
+
+	buf := make([]byte, 1500)
+	start, sent := time.Now(), 0
+	for time.Since(start) < probeTimeout {
+		if sent < probeCount && time.Since(start) >= time.Duration(sent)*probeTimeout/time.Duration(probeCount) {
+			if _, err := pc.WriteTo(packet, cm, &net.IPAddr{IP: group, Zone: attrs.Name}); err != nil {
+				return "", err
+			}
+			sent++
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+			return "", err
+		}
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return "", err
+		}
+		// Type 136, then flags (4) and the target address at 8.
+		if n < 24 || buf[0] != byte(ipv6.ICMPTypeNeighborAdvertisement) || !net.IP(buf[8:24]).Equal(ip) {
+			continue
+		}
+		holder := targetLinkLayer(buf[24:n])
+		switch {
+		case holder == nil:
+			return from.String(), nil
+		case !bytes.Equal(holder, attrs.HardwareAddr):
+			return holder.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// targetLinkLayer returns the target link-layer address option of a neighbor
+// advertisement's options, or nil if the advertisement has none.
+func targetLinkLayer(options []byte) net.HardwareAddr {
+	for len(options) >= 8 && options[1] > 0 {
+		length := int(options[1]) * 8
+		if length > len(options) {
+			break
+		}
+		if options[0] == 2 {
+			return append(net.HardwareAddr(nil), options[2:length]...)
+		}
+		options = options[length:]
+	}
+	return nil
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,298 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/types"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// echoSeq numbers the echo requests of ICMP probes, so that concurrent probes
+// of the same host tell their replies apart.
+var echoSeq atomic.Uint32
+
+// RunProbe runs probe and returns its latency and result. The latency is the
+// time until the target answered: the TCP handshake, the echo reply, the
+// response headers, the TLS handshake or the server's first message.
+func RunProbe(ctx context.Context, probe types.Probe) (time.Duration, types.HealthCheckResult) {
+	probe = probe.WithDefaults()
+	start := time.Now()
+	latency, message, err := runProbe(ctx, probe)
+	result := types.HealthCheckResult{
+		CheckName:  probe.Name,
+		Success:    err == nil,
+		Message:    message,
+		Timestamp:  start,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Message = reason(err)
+	}
+	return latency, result
+}
+
+// runProbe runs probe, with its defaults filled in, within its timeout.
+func runProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probe.Timeout)
+	defer cancel()
+	switch probe.Type {
+	case types.ProbeTCP:
+		return tcpProbe(ctx, probe)
+	case types.ProbeICMP:
+		return icmpProbe(ctx, probe)
+	case types.ProbeHTTP:
+		return httpProbe(ctx, probe)
+	case types.ProbeTLS:
+		return tlsProbe(ctx, probe)
+	case types.ProbePostgres:
+		return postgresProbe(ctx, probe)
+	default:
+		return 0, "", errors.Newf(errors.ValidationError, "unknown probe type %q", probe.Type)
+	}
+}
+
+// tcpProbe opens a TCP connection.
+func tcpProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", probe.Address())
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "cannot reach %s", probe.Address())
+	}
+	latency := time.Since(start)
+	conn.Close()
+	return latency, fmt.Sprintf("accepts connections on %s", probe.Address()), nil
+}
+
+// icmpProbe sends an echo request and waits for the reply. It uses an
+// unprivileged ICMP socket where net.ipv4.ping_group_range allows one, and a
+// raw socket otherwise.
+func icmpProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", probe.Host)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "cannot resolve %s", probe.Host)
+	}
+	ip := ips[0]
+	network, rawNetwork, proto, request := "udp4", "ip4:icmp", 1, icmp.Type(ipv4.ICMPTypeEcho)
+	if ip.To4() == nil {
+		network, rawNetwork, proto, request = "udp6", "ip6:ipv6-icmp", 58, ipv6.ICMPTypeEchoRequest
+	}
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		network, dst = rawNetwork, &net.IPAddr{IP: ip}
+		if conn, err = icmp.ListenPacket(network, ""); err != nil {
+			return 0, "", errors.Wrap(err, errors.NetworkError, "failed to open ICMP socket")
+		}
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// The kernel replaces the ID of unprivileged sockets, so replies are
+	// matched by sequence number and source.
+	seq := int(echoSeq.Add(1) & 0xffff)
+	msg := icmp.Message{Type: request, Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: preflightPayload}}
+	packet, err := msg.Marshal(nil) // The kernel fills in the ICMPv6 checksum
+	if err != nil {
+		return 0, "", err
+	}
+	start := time.Now()
+	if _, err := conn.WriteTo(packet, dst); err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "failed to send echo request to %s", ip)
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, "", errors.Newf(errors.NetworkError, "no echo reply from %s within %s", ip, probe.Timeout)
+			}
+			return 0, "", errors.Wrapf(err, errors.NetworkError, "failed to receive echo reply from %s", ip)
+		}
+		m, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || !addrIP(from).Equal(ip) {
+			continue
+		}
+		if echo, ok := m.Body.(*icmp.Echo); ok && (m.Type == ipv4.ICMPTypeEchoReply || m.Type == ipv6.ICMPTypeEchoReply) && echo.Seq == seq {
+			latency := time.Since(start)
+			return latency, fmt.Sprintf("echo reply from %s in %s", ip, latency.Round(time.Microsecond)), nil
+		}
+	}
+}
+
+// httpProbe sends a GET request and compares the status code with the
+// expected one. Redirects are not followed.
+func httpProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	scheme := "http"
+	if probe.HTTPS {
+		scheme = "https"
+	}
+	url := scheme + "://" + probe.Address() + probe.Path
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{ServerName: probe.ServerName, InsecureSkipVerify: probe.InsecureSkipVerify},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.ValidationError, "invalid probe URL %s", url)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "GET %s failed", url)
+	}
+	latency := time.Since(start)
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != probe.ExpectedStatus {
+		return latency, "", errors.Newf(errors.NetworkError, "GET %s returned %s, expected %d", url, resp.Status, probe.ExpectedStatus)
+	}
+	return latency, fmt.Sprintf("GET %s returned %s", url, resp.Status), nil
+}
+
+// tlsProbe completes a TLS handshake and checks that the server's certificate
+// is valid for at least MinCertValidity.
+func tlsProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	d := tls.Dialer{Config: &tls.Config{ServerName: probe.ServerName, InsecureSkipVerify: probe.InsecureSkipVerify}}
+	if len(probe.RootCAs) > 0 {
+		d.Config.RootCAs = x509.NewCertPool()
+		if !d.Config.RootCAs.AppendCertsFromPEM(probe.RootCAs) {
+			return 0, "", errors.New(errors.ValidationError, "no CA certificate found in the probe's root CAs")
+		}
+	}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", probe.Address())
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "TLS handshake with %s failed", probe.Address())
+	}
+	latency := time.Since(start)
+	defer conn.Close()
+
+	cert := conn.(*tls.Conn).ConnectionState().PeerCertificates[0]
+	name := cert.Subject.CommonName
+	if name == "" && len(cert.DNSNames) > 0 {
+		name = cert.DNSNames[0]
+	}
+	expiry := cert.NotAfter.UTC().Format(time.RFC3339)
+	left := time.Until(cert.NotAfter)
+	switch {
+	case left <= 0:
+		return latency, "", errors.Newf(errors.NetworkError, "certificate %q of %s expired on %s", name, probe.Address(), expiry)
+	case left < probe.MinCertValidity:
+		return latency, "", errors.Newf(errors.NetworkError, "certificate %q of %s expires on %s, in %s", name, probe.Address(), expiry, days(left))
+	}
+	return latency, fmt.Sprintf("certificate %q valid until %s (%s)", name, expiry, days(left)), nil
+}
+
+// days formats d as a number of whole days.
+func days(d time.Duration) string {
+	if n := int(d.Hours() / 24); n != 1 {
+		return fmt.Sprintf("%d days", n)
+	}
+	return "1 day"
+}
+
+// postgresProbe sends a startup message. A server that accepts connections
+// answers with an authentication request, or with an authorization error when
+// pg_hba.conf has no entry for the prober; both count as up. A server that is
+// starting up, shutting down or in recovery without hot standby answers with
+// another error.
+func postgresProbe(ctx context.Context, probe types.Probe) (time.Duration, string, error) {
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", probe.Address())
+	if err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "cannot reach %s", probe.Address())
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write(startupMessage(probe.User, probe.Database)); err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "failed to send startup message to %s", probe.Address())
+	}
+	var header [5]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return 0, "", errors.Wrapf(err, errors.NetworkError, "no response to startup message from %s", probe.Address())
+	}
+	latency := time.Since(start)
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 || length > 8192 {
+		return latency, "", errors.Newf(errors.NetworkError, "%s does not speak the PostgreSQL protocol", probe.Address())
+	}
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return latency, "", errors.Wrapf(err, errors.NetworkError, "incomplete response to startup message from %s", probe.Address())
+	}
+	switch header[0] {
+	case 'R':
+		return latency, fmt.Sprintf("PostgreSQL on %s accepts connections", probe.Address()), nil
+	case 'E':
+		fields := postgresFields(body)
+		if strings.HasPrefix(fields['C'], "28") {
+			return latency, fmt.Sprintf("PostgreSQL on %s is up but rejects %s: %s", probe.Address(), probe.User, fields['M']), nil
+		}
+		return latency, "", errors.Newf(errors.NetworkError, "PostgreSQL on %s refused the connection: %s (SQLSTATE %s)", probe.Address(), fields['M'], fields['C'])
+	default:
+		return latency, "", errors.Newf(errors.NetworkError, "unexpected response %q to startup message from %s", header[0], probe.Address())
+	}
+}
+
+// startupMessage returns a protocol 3.0 startup message.
+func startupMessage(user, database string) []byte {
+	var params bytes.Buffer
+	for _, kv := range []string{"user", user, "database", database, "application_name", "geminik8s-probe"} {
+		params.WriteString(kv)
+		params.WriteByte(0)
+	}
+	params.WriteByte(0)
+	msg := binary.BigEndian.AppendUint32(nil, uint32(8+params.Len()))
+	msg = binary.BigEndian.AppendUint32(msg, 3<<16)
+	return append(msg, params.Bytes()...)
+}
+
+// postgresFields parses the fields of an ErrorResponse body.
+func postgresFields(body []byte) map[byte]string {
+	fields := map[byte]string{}
+	for len(body) > 1 && body[0] != 0 {
+		end := bytes.IndexByte(body[1:], 0)
+		if end < 0 {
+			break
+		}
+		fields[body[0]] = string(body[1 : 1+end])
+		body = body[end+2:]
+	}
+	return fields
+}
+
+// addrIP returns the IP of an address read from an ICMP socket.
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+//Personal.AI order the ending
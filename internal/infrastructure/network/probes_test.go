@@ -0,0 +1,171 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// hostPort splits the address of a test server into a probe's host and port.
+func hostPort(t *testing.T, address string) (string, int) {
+	t.Helper()
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := strconv.Atoi(port)
+	return host, n
+}
+
+// expectProbe runs probe and checks whether it succeeds and its message.
+func expectProbe(t *testing.T, probe types.Probe, success bool, message string) {
+	t.Helper()
+	latency, result := RunProbe(context.Background(), probe)
+	if result.Success != success || !strings.Contains(result.Message, message) {
+		t.Errorf("expected %s probe success %v with %q, got %+v", probe.Type, success, message, result)
+	}
+	if success && latency <= 0 {
+		t.Errorf("expected a latency, got %s", latency)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port := hostPort(t, ln.Addr().String())
+	_, result := RunProbe(context.Background(), types.Probe{Type: types.ProbeTCP, Host: host, Port: port})
+	if !result.Success || result.CheckName != "tcp/"+ln.Addr().String() {
+		t.Errorf("expected the listener to be reached, got %+v", result)
+	}
+	ln.Close()
+	expectProbe(t, types.Probe{Type: types.ProbeTCP, Host: host, Port: port}, false, "connection refused")
+	expectProbe(t, types.Probe{Type: "smtp", Host: host}, false, `unknown probe type "smtp"`)
+}
+
+func TestHTTPProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/readyz", http.StatusFound) })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	host, port := hostPort(t, server.Listener.Addr().String())
+
+	expectProbe(t, types.Probe{Type: types.ProbeHTTP, Host: host, Port: port, Path: "/readyz"}, true, "returned 200 OK")
+	expectProbe(t, types.Probe{Type: types.ProbeHTTP, Host: host, Port: port, Path: "/missing"}, false, "returned 404 Not Found, expected 200")
+	expectProbe(t, types.Probe{Type: types.ProbeHTTP, Host: host, Port: port, Path: "/moved", ExpectedStatus: http.StatusFound}, true, "returned 302 Found")
+
+	tlsServer := newTLSServer(mux)
+	defer tlsServer.Close()
+	host, port = hostPort(t, tlsServer.Listener.Addr().String())
+	expectProbe(t, types.Probe{Type: types.ProbeHTTP, HTTPS: true, Host: host, Port: port, Path: "/readyz"}, false, "certificate")
+	expectProbe(t, types.Probe{Type: types.ProbeHTTP, HTTPS: true, Host: host, Port: port, Path: "/readyz", InsecureSkipVerify: true}, true, "https://")
+}
+
+func TestTLSProbe(t *testing.T) {
+	server := newTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := hostPort(t, server.Listener.Addr().String())
+	probe := types.Probe{Type: types.ProbeTLS, Host: host, Port: port, InsecureSkipVerify: true}
+
+	// The certificate of httptest expires in 2084.
+	expectProbe(t, probe, true, `certificate "example.com" valid until 2084-01-29T16:00:00Z`)
+	probe.MinCertValidity = 100 * 365 * 24 * time.Hour
+	expectProbe(t, probe, false, "expires on 2084-01-29T16:00:00Z, in")
+	probe.InsecureSkipVerify = false
+	expectProbe(t, probe, false, "TLS handshake with "+server.Listener.Addr().String()+" failed")
+
+	// Verified against the server's CA, the certificate must name the server.
+	probe.MinCertValidity = 0
+	probe.RootCAs = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	expectProbe(t, probe, true, `certificate "example.com" valid until`)
+	probe.ServerName = "10.0.0.100"
+	expectProbe(t, probe, false, "not 10.0.0.100")
+}
+
+// newTLSServer starts an httptest TLS server that does not log the handshakes
+// the probes fail.
+func newTLSServer(handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	return server
+}
+
+// servePostgres answers the startup message of every connection with a
+// message of type typ, and records the parameters of the startup messages.
+func servePostgres(t *testing.T, typ byte, body []byte) (string, int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	params := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var length uint32
+			binary.Read(conn, binary.BigEndian, &length)
+			msg := make([]byte, length-4)
+			io.ReadFull(conn, msg)
+			params <- strings.Join(strings.Split(string(bytes.TrimRight(msg[4:], "\x00")), "\x00"), " ")
+			conn.Write(append([]byte{typ}, binary.BigEndian.AppendUint32(nil, uint32(4+len(body)))...))
+			conn.Write(body)
+			conn.Close()
+		}
+	}()
+	host, port := hostPort(t, ln.Addr().String())
+	return host, port, params
+}
+
+// errorResponse returns the body of an ErrorResponse.
+func errorResponse(code, message string) []byte {
+	return []byte("SFATAL\x00C" + code + "\x00M" + message + "\x00\x00")
+}
+
+func TestPostgresProbe(t *testing.T) {
+	// AuthenticationMD5Password
+	host, port, params := servePostgres(t, 'R', []byte{0, 0, 0, 5, 1, 2, 3, 4})
+	expectProbe(t, types.Probe{Type: types.ProbePostgres, Host: host, Port: port, Database: "kubernetes"}, true, "accepts connections")
+	if p := <-params; p != "user postgres database kubernetes application_name geminik8s-probe" {
+		t.Errorf("unexpected startup parameters: %s", p)
+	}
+
+	host, port, _ = servePostgres(t, 'E', errorResponse("28000", `no pg_hba.conf entry for host "10.0.0.9"`))
+	expectProbe(t, types.Probe{Type: types.ProbePostgres, Host: host, Port: port}, true, "is up but rejects postgres: no pg_hba.conf entry")
+
+	host, port, _ = servePostgres(t, 'E', errorResponse("57P03", "the database system is starting up"))
+	expectProbe(t, types.Probe{Type: types.ProbePostgres, Host: host, Port: port}, false, "refused the connection: the database system is starting up (SQLSTATE 57P03)")
+
+	// A server that never answers fails within the timeout.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	host, port = hostPort(t, ln.Addr().String())
+	start := time.Now()
+	expectProbe(t, types.Probe{Type: types.ProbePostgres, Host: host, Port: port, Timeout: 100 * time.Millisecond}, false, "no response to startup message")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the probe to give up after its timeout, took %s", elapsed)
+	}
+}
+
+//Personal.AI order the ending
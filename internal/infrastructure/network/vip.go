@@ -0,0 +1,129 @@
+package network
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+// ifaFlagNoDAD is IFA_F_NODAD: the address is usable at once, without duplicate
+// address detection.
+const ifaFlagNoDAD = 0x02
+
+// vipAddr returns the address the VIP is added as. A prefixLength of 0 means a
+// host route: /32 for IPv4 and /128 for IPv6.
+func vipAddr(vip string, prefixLength int) (*netlink.Addr, error) {
+	ip := net.ParseIP(vip)
+	if ip == nil {
+		return nil, errors.Newf(errors.ValidationError, "invalid VIP address: %q", vip)
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	if prefixLength == 0 {
+		prefixLength = bits
+	}
+	if prefixLength < 0 || prefixLength > bits {
+		return nil, errors.Newf(errors.ValidationError, "invalid prefix length %d for VIP %s", prefixLength, vip)
+	}
+
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLength, bits)}}
+	if bits == 128 {
+		// The VIP moves between nodes on purpose; duplicate address detection
+		// would hold it tentative and block the advertisement.
+		addr.Flags = ifaFlagNoDAD
+	}
+	return addr, nil
+}
+
+// vipLink returns the named interface or, if name is empty, the interface with
+// an address in the same subnet as ip.
+func vipLink(name string, ip net.IP) (netlink.Link, error) {
+	if name != "" {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, errors.NetworkError, "failed to find VIP interface %s", name)
+		}
+		return link, nil
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.NetworkError, "failed to list interfaces")
+	}
+	for _, link := range links {
+		subnet, err := linkSubnet(link, ip)
+		if err != nil {
+			return nil, err
+		}
+		if subnet != nil {
+			return link, nil
+		}
+	}
+	return nil, errors.Newf(errors.NetworkError, "no interface has a subnet containing VIP %s; set spec.network.interface", ip)
+}
+
+// linkSubnet returns the subnet of one of link's addresses that contains ip,
+// or nil if there is none. Host routes and ip itself do not count.
+func linkSubnet(link netlink.Link, ip net.IP) (*net.IPNet, error) {
+	addrs, err := netlink.AddrList(link, family(ip))
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.NetworkError, "failed to list addresses of %s", link.Attrs().Name)
+	}
+	for _, addr := range addrs {
+		ones, bits := addr.Mask.Size()
+		if ones < bits && !addr.IP.Equal(ip) && addr.Contains(ip) {
+			return &net.IPNet{IP: addr.IP.Mask(addr.Mask), Mask: addr.Mask}, nil
+		}
+	}
+	return nil, nil
+}
+
+// addVIP adds addr to link unless it is already assigned. It reports whether it added it.
+func addVIP(link netlink.Link, addr *netlink.Addr) (bool, error) {
+	if assigned, err := hasAddr(link, addr.IP); err != nil || assigned {
+		return false, err
+	}
+	if err := netlink.AddrAdd(link, addr); err != nil {
+		return false, errors.Wrapf(err, errors.NetworkError, "failed to add VIP %s to %s", addr.IPNet, link.Attrs().Name)
+	}
+	return true, nil
+}
+
+// delVIP removes addr from link if it is assigned.
+func delVIP(link netlink.Link, addr *netlink.Addr) error {
+	if assigned, err := hasAddr(link, addr.IP); err != nil || !assigned {
+		return err
+	}
+	if err := netlink.AddrDel(link, addr); err != nil {
+		return errors.Wrapf(err, errors.NetworkError, "failed to remove VIP %s from %s", addr.IPNet, link.Attrs().Name)
+	}
+	return nil
+}
+
+// hasAddr reports whether ip is assigned to link, with any prefix length.
+func hasAddr(link netlink.Link, ip net.IP) (bool, error) {
+	addrs, err := netlink.AddrList(link, family(ip))
+	if err != nil {
+		return false, errors.Wrapf(err, errors.NetworkError, "failed to list addresses of %s", link.Attrs().Name)
+	}
+	for _, addr := range addrs {
+		if addr.IP.Equal(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// family returns the netlink address family of ip.
+func family(ip net.IP) int {
+	if ip.To4() != nil {
+		return syscall.AF_INET
+	}
+	return syscall.AF_INET6
+}
+
+//Personal.AI order the ending
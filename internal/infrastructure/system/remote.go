@@ -0,0 +1,77 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+)
+
+// remoteOperator implements the api.SystemOperator interface on another node over SSH.
+// Users, keys and jump hosts are taken from the caller's ~/.ssh/config.
+type remoteOperator struct {
+	host string
+}
+
+// NewRemoteOperator creates a system operator that runs on the given host over SSH.
+func NewRemoteOperator(host string) api.SystemOperator {
+	return &remoteOperator{host: host}
+}
+
+// sshArgs builds the ssh command line for a remote command.
+func (o *remoteOperator) sshArgs(remoteCmd string) []string {
+	return []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10", o.host, remoteCmd}
+}
+
+// RunCommand executes a command on the remote host and returns its combined output.
+func (o *remoteOperator) RunCommand(command string, args ...string) (string, error) {
+	cmd := exec.Command("ssh", o.sshArgs(shellJoin(append([]string{command}, args...)))...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), errors.Wrapf(err, errors.OrchestratorError, "command failed on %s: %s %v", o.host, command, args)
+	}
+	return string(output), nil
+}
+
+// WriteFile writes data to a file on the remote host.
+func (o *remoteOperator) WriteFile(path string, content []byte, perm os.FileMode) error {
+	script := fmt.Sprintf("umask 077 && cat > %s && chmod %o %s", shellQuote(path), perm.Perm(), shellQuote(path))
+	cmd := exec.Command("ssh", o.sshArgs(script)...)
+	cmd.Stdin = bytes.NewReader(content)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, errors.IOError, "failed to write file %s on %s: %s", path, o.host, string(output))
+	}
+	return nil
+}
+
+// ReadFile reads data from a file on the remote host.
+func (o *remoteOperator) ReadFile(path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", o.sshArgs("cat "+shellQuote(path))...)
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.IOError, "failed to read file %s on %s: %s", path, o.host, stderr.String())
+	}
+	return data, nil
+}
+
+// shellJoin quotes each argument so the remote shell sees them unchanged.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote wraps s in single quotes for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+//Personal.AI order the ending
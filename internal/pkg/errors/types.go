@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"fmt"
+)
 
 // ErrorCode defines the type for error codes.
 type ErrorCode string
@@ -26,6 +29,8 @@ const (
 	ValidationError ErrorCode = "ValidationError"
 	// IO Error represents a file system I/O error.
 	IOError ErrorCode = "IOError"
+	// NotImplemented represents an operation this build cannot perform.
+	NotImplemented ErrorCode = "NotImplemented"
 )
 
 // Error is a custom error type that includes a code, a message, and an optional underlying error.
@@ -82,4 +87,14 @@ func Wrapf(err error, code ErrorCode, format string, a ...interface{}) *Error {
 	}
 }
 
+// HasCode reports whether err, or an error it wraps, has the given code.
+func HasCode(err error, code ErrorCode) bool {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if e, ok := err.(*Error); ok && e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
 //Personal.AI order the ending
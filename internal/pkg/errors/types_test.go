@@ -75,4 +75,14 @@ func TestUnwrap(t *testing.T) {
 	}
 }
 
+func TestHasCode(t *testing.T) {
+	err := Wrap(New(NotImplemented, "upgrade is not implemented"), OrchestratorError, "upgrade failed")
+	if !HasCode(err, NotImplemented) || !HasCode(err, OrchestratorError) {
+		t.Errorf("expected both codes in the chain of %v", err)
+	}
+	if HasCode(err, DatabaseError) || HasCode(errors.New("plain"), NotImplemented) {
+		t.Errorf("expected no match for a code that is not in the chain")
+	}
+}
+
 //Personal.AI order the ending
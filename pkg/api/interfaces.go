@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/turtacn/geminik8s/pkg/types"
 )
@@ -17,6 +18,10 @@ type Orchestrator interface {
 	ReplaceNode(ctx context.Context, cfg *types.ClusterConfig, oldNode, newNode string) error
 	Backup(ctx context.Context, cfg *types.ClusterConfig, destination string) error
 	Restore(ctx context.Context, cfg *types.ClusterConfig, source string) error
+	RotateCredentials(ctx context.Context, cfg *types.ClusterConfig, onlyIfDue bool) (*PluginResult, error)
+	Kubeconfig(ctx context.Context, cfg *types.ClusterConfig, opts types.KubeconfigOptions) (*PluginResult, error)
+	// Preflight checks the network between the nodes without changing them.
+	Preflight(ctx context.Context, cfg *types.ClusterConfig, opts types.PreflightOptions) (*types.PreflightReport, error)
 }
 
 // PluginParams is a map for passing parameters to a plugin.
@@ -66,8 +71,65 @@ type DBClient interface {
 // K8sClient defines the interface for interacting with the Kubernetes API.
 type K8sClient interface {
 	GetNodes(ctx context.Context) ([]types.Node, error)
-	Apply(ctx context.Context, manifest []byte) error
-	Delete(ctx context.Context, manifest []byte) error
+	// Apply server-side applies every object of a multi-document YAML manifest.
+	Apply(ctx context.Context, manifest []byte) ([]types.ObjectResult, error)
+	// Delete deletes every object of a manifest, in reverse dependency order.
+	Delete(ctx context.Context, manifest []byte) ([]types.ObjectResult, error)
+	// Cordon marks the node unschedulable.
+	Cordon(ctx context.Context, nodeName string) error
+	// Drain cordons the node and evicts its pods through the eviction API,
+	// honoring PodDisruptionBudgets. DaemonSet pods are left in place.
+	Drain(ctx context.Context, nodeName string, opts types.DrainOptions) error
+	// Uncordon marks the node schedulable again.
+	Uncordon(ctx context.Context, nodeName string) error
+	// CreateToken issues a token for a service account that expires after ttl,
+	// or earlier if the API server caps it, and returns when it expires.
+	CreateToken(ctx context.Context, namespace, serviceAccount string, ttl time.Duration) (string, time.Time, error)
+	// GetClusterStatus returns the status of the named GeminiCluster.
+	GetClusterStatus(ctx context.Context, name string) (*types.GeminiClusterStatus, error)
+	// UpdateClusterStatus lets update change the status of the named GeminiCluster
+	// and writes it back through the status subresource.
+	UpdateClusterStatus(ctx context.Context, name string, update func(*types.GeminiClusterStatus)) error
+	// WatchClusterConfigs calls handler, one call at a time, for every ClusterConfig
+	// when it changes and again every resync. It blocks until ctx is cancelled.
+	// A ClusterConfig that cannot be decoded is passed with the error and an
+	// empty spec.
+	WatchClusterConfigs(ctx context.Context, resync time.Duration, handler func(types.ClusterConfigResource, error)) error
+	// UpdateClusterConfigStatus lets update change the status of the named ClusterConfig.
+	UpdateClusterConfigStatus(ctx context.Context, name string, update func(*types.ClusterConfigStatus)) error
+	// RunWithLeaderElection calls run while identity holds the named Lease.
+	// It blocks until ctx is cancelled.
+	RunWithLeaderElection(ctx context.Context, namespace, name, identity string, run func(ctx context.Context)) error
+	// CheckControlPlane checks the API server, the critical workloads and writes
+	// through Kine, returning one result per check.
+	CheckControlPlane(ctx context.Context) []types.HealthCheckResult
+	// EventRecorder returns a recorder that reports events as component and
+	// spools them to spoolPath while the API server is unreachable.
+	EventRecorder(component, spoolPath string) EventRecorder
+	// StartNodeCache starts watching Nodes and their kubelet Leases and returns
+	// once the cache is filled. The watches stop when ctx is cancelled.
+	StartNodeCache(ctx context.Context) (NodeCache, error)
+	// SetNodeRole labels the node with its role and fencing epoch and sets the
+	// taints configured for the role, refusing to overwrite a newer epoch.
+	SetNodeRole(ctx context.Context, nodeName string, role types.NodeRole, epoch int64, cfg *types.NodeLabelsConfig) error
+}
+
+// NodeCache is a view of the cluster's nodes kept current by watches, so
+// reading it does not call the API server.
+type NodeCache interface {
+	// GetNodes returns the cached nodes. A node whose kubelet Lease has expired
+	// is unhealthy even before the node controller marks it NotReady.
+	GetNodes(ctx context.Context) ([]types.Node, error)
+	// Subscribe calls handler with the node whenever the node or its Lease
+	// changes, and when its Lease expires.
+	Subscribe(handler func(types.Node))
+}
+
+// EventRecorder records Kubernetes Events about geminik8s operations.
+type EventRecorder interface {
+	// Record sends the event. It never fails: events over the rate limit are
+	// dropped, and events the API server cannot take are spooled and sent later.
+	Record(ctx context.Context, event types.Event)
 }
 
 // SystemOperator defines the interface for system-level operations.
@@ -79,8 +141,24 @@ type SystemOperator interface {
 
 // NetworkOperator defines the interface for network-related operations.
 type NetworkOperator interface {
-	CheckConnectivity(host string, port int) error
-	ManageVIP(action string, vip string) error // e.g., action="add" or "del"
+	// CheckConnectivity runs probe within its timeout and returns the time the
+	// target took to answer along with the result. The result fails, with the
+	// reason as its message, if the target did not answer as expected.
+	CheckConnectivity(ctx context.Context, probe types.Probe) (time.Duration, types.HealthCheckResult)
+}
+
+// VIPProvider holds virtual IPs through one mechanism: a local interface, a
+// cloud API or a load balancer. self is the node the agent runs on.
+type VIPProvider interface {
+	// Acquire makes self hold the VIP. It is idempotent.
+	Acquire(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error
+	// Release makes self stop holding the VIP, if it does. It is idempotent.
+	Release(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) error
+	// Holder returns who holds the VIP: self.IP if self does, the IP of
+	// another node or an identifier of another host (e.g. a hardware address)
+	// if it does, or "" if nobody does. If both self and another host hold
+	// the VIP, the other host is returned.
+	Holder(ctx context.Context, vip types.VIPConfig, self types.NodeIdentity) (string, error)
 }
 
 //Personal.AI order the ending
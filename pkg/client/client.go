@@ -48,6 +48,8 @@ func NewClient(clientCfg *Config) (Client, error) {
 
 	// 3. Initialize Domain Services
 	// These would take real infrastructure clients.
+	// A long-running client would check node health against a node cache,
+	// with node.NewCachedService and the K8s client's StartNodeCache.
 	nodeSvc := node.NewService(nil, nil, nil)
 	storageSvc := storage.NewService(nil, nil)
 	clusterSvc := cluster.NewService(nil, nodeSvc, storageSvc)
@@ -1,5 +1,12 @@
 package types
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // ClusterStatus represents the status of the cluster.
 type ClusterStatus string
 
@@ -37,26 +44,642 @@ type Metadata struct {
 
 // ClusterSpec defines the desired state of the cluster.
 type ClusterSpec struct {
-	Network NetworkConfig `yaml:"network" json:"network"`
-	Nodes   []NodeInfo    `yaml:"nodes" json:"nodes"`
-	Storage StorageConfig `yaml:"storage" json:"storage"`
+	// Version is the k3s version the cluster should run, e.g. "v1.29.4+k3s1".
+	Version  string          `yaml:"version,omitempty" json:"version,omitempty"`
+	Network  NetworkConfig   `yaml:"network" json:"network"`
+	Nodes    []NodeInfo      `yaml:"nodes" json:"nodes"`
+	Storage  StorageConfig   `yaml:"storage" json:"storage"`
+	Backup   *BackupConfig   `yaml:"backup,omitempty" json:"backup,omitempty"`
+	Failover *FailoverConfig `yaml:"failover,omitempty" json:"failover,omitempty"`
+	// NodeLabels controls the role labels and taints on the Kubernetes nodes.
+	NodeLabels *NodeLabelsConfig `yaml:"nodeLabels,omitempty" json:"nodeLabels,omitempty"`
+	// Heartbeat controls the heartbeats between the agents.
+	Heartbeat *HeartbeatConfig `yaml:"heartbeat,omitempty" json:"heartbeat,omitempty"`
+}
+
+// HeartbeatConfig controls the heartbeats the agents exchange over every path
+// between the nodes. The peer counts as dead only when no path is up.
+type HeartbeatConfig struct {
+	// Port is the UDP port the agents heartbeat each other on. Defaults to 9441.
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// Interval is the time between heartbeats on each path. Defaults to 1s.
+	Interval *Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Timeout is how long a path stays up without a reply. Defaults to 5s.
+	Timeout *Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Defaults of HeartbeatConfig.
+const (
+	DefaultHeartbeatPort     = 9441
+	DefaultHeartbeatInterval = time.Second
+	DefaultHeartbeatTimeout  = 5 * time.Second
+)
+
+// PortOrDefault returns Port, or DefaultHeartbeatPort if it is not set.
+func (h *HeartbeatConfig) PortOrDefault() int {
+	if h == nil || h.Port == 0 {
+		return DefaultHeartbeatPort
+	}
+	return h.Port
+}
+
+// IntervalOrDefault returns Interval, or DefaultHeartbeatInterval if it is not set.
+func (h *HeartbeatConfig) IntervalOrDefault() time.Duration {
+	if h == nil {
+		return DefaultHeartbeatInterval
+	}
+	return h.Interval.OrDefault(DefaultHeartbeatInterval)
+}
+
+// TimeoutOrDefault returns Timeout, or DefaultHeartbeatTimeout if it is not set.
+func (h *HeartbeatConfig) TimeoutOrDefault() time.Duration {
+	if h == nil {
+		return DefaultHeartbeatTimeout
+	}
+	return h.Timeout.OrDefault(DefaultHeartbeatTimeout)
+}
+
+// HeartbeatPaths returns the addresses the node with the given IP is
+// heartbeated on: its addresses followed by its heartbeat addresses.
+func (c *ClusterSpec) HeartbeatPaths(ip string) []string {
+	n, ok := c.Node(ip)
+	if !ok {
+		return []string{ip}
+	}
+	return append(n.AllAddresses(), n.HeartbeatAddresses...)
+}
+
+// Node returns the node with the given IP.
+func (c *ClusterSpec) Node(ip string) (NodeInfo, bool) {
+	for _, n := range c.Nodes {
+		if n.IP == ip {
+			return n, true
+		}
+	}
+	return NodeInfo{}, false
+}
+
+// BackupConfig holds the backup schedule of the cluster.
+type BackupConfig struct {
+	// Interval is how often a backup is taken. No backups are scheduled if it is not set.
+	Interval *Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Destination is the directory on the leader the backups are written to.
+	Destination string `yaml:"destination,omitempty" json:"destination,omitempty"`
+}
+
+// FailoverConfig holds the failover policy of the cluster.
+type FailoverConfig struct {
+	// PreferredLeader is the IP of the node that should be leader. When the other
+	// node leads, the operator fails back to this one.
+	PreferredLeader string `yaml:"preferredLeader,omitempty" json:"preferredLeader,omitempty"`
+}
+
+// NodeLabelsConfig controls how the agents mark the Kubernetes nodes with their
+// geminik8s role. The geminik8s.io/role and geminik8s.io/epoch labels are set
+// unless Disabled; the taints are only set when configured.
+type NodeLabelsConfig struct {
+	Disabled       bool    `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	LeaderTaints   []Taint `yaml:"leaderTaints,omitempty" json:"leaderTaints,omitempty"`
+	FollowerTaints []Taint `yaml:"followerTaints,omitempty" json:"followerTaints,omitempty"`
+}
+
+// Enabled reports whether role labels are maintained. They are by default.
+func (c *NodeLabelsConfig) Enabled() bool {
+	return c == nil || !c.Disabled
+}
+
+// TaintsFor returns the taints a node with the given role should carry.
+func (c *NodeLabelsConfig) TaintsFor(role NodeRole) []Taint {
+	switch {
+	case c == nil:
+		return nil
+	case role == RoleLeader:
+		return c.LeaderTaints
+	default:
+		return c.FollowerTaints
+	}
+}
+
+// Taint is a Kubernetes node taint. Effect is NoSchedule, PreferNoSchedule or NoExecute.
+type Taint struct {
+	Key    string `yaml:"key" json:"key"`
+	Value  string `yaml:"value,omitempty" json:"value,omitempty"`
+	Effect string `yaml:"effect" json:"effect"`
 }
 
 // NetworkConfig holds the network configuration for the cluster.
 type NetworkConfig struct {
-	VIP string `yaml:"vip" json:"vip"`
+	// VIP is the virtual IP the API server is reached through. It is managed as
+	// the VIP named "api" and may be left empty if VIPs has a VIP with purpose api.
+	VIP string `yaml:"vip,omitempty" json:"vip,omitempty"`
+	// Interface is the network interface VIPs are added to. By default it is
+	// the interface with an address in the same subnet as the VIP.
+	Interface string `yaml:"interface,omitempty" json:"interface,omitempty"`
+	// PrefixLength is the prefix length IPv4 VIPs are added with. It defaults
+	// to 32.
+	PrefixLength int `yaml:"prefixLength,omitempty" json:"prefixLength,omitempty"`
+	// PrefixLengthV6 is the prefix length IPv6 VIPs are added with. It
+	// defaults to 128.
+	PrefixLengthV6 int `yaml:"prefixLengthV6,omitempty" json:"prefixLengthV6,omitempty"`
+	// PodCIDRs and ServiceCIDRs are the k3s cluster and service CIDRs, at most
+	// one per IP family. They default to 10.42.0.0/16 and 10.43.0.0/16 for
+	// IPv4 and to fd00:42::/56 and fd00:43::/112 for IPv6, for the families
+	// of the nodes' addresses.
+	PodCIDRs     []string `yaml:"podCIDRs,omitempty" json:"podCIDRs,omitempty"`
+	ServiceCIDRs []string `yaml:"serviceCIDRs,omitempty" json:"serviceCIDRs,omitempty"`
+	// VIPs are further virtual IPs, e.g. for an ingress controller.
+	VIPs []VIPConfig `yaml:"vips,omitempty" json:"vips,omitempty"`
+	// VIPMode selects how the VIPs are held: "netlink" (default), where the
+	// agent adds them itself, "vrrp", where it configures keepalived, or
+	// "bgp", where it adds them to the loopback interface and advertises them
+	// as host routes to BGP peers.
+	VIPMode VIPMode `yaml:"vipMode,omitempty" json:"vipMode,omitempty"`
+	// VRRP configures keepalived in the vrrp VIP mode.
+	VRRP *VRRPConfig `yaml:"vrrp,omitempty" json:"vrrp,omitempty"`
+	// BGP configures the agent's BGP speaker in the bgp VIP mode.
+	BGP *BGPConfig `yaml:"bgp,omitempty" json:"bgp,omitempty"`
+	// Cloud configures the provider of the VIPs with provider "cloud".
+	Cloud *CloudVIPConfig `yaml:"cloud,omitempty" json:"cloud,omitempty"`
+	// HAProxy configures the provider of the VIPs with provider "haproxy".
+	HAProxy *HAProxyConfig `yaml:"haproxy,omitempty" json:"haproxy,omitempty"`
+}
+
+// VIPMode says which mechanism holds the VIPs.
+type VIPMode string
+
+const (
+	VIPModeNetlink VIPMode = "netlink"
+	VIPModeVRRP    VIPMode = "vrrp"
+	VIPModeBGP     VIPMode = "bgp"
+)
+
+// EffectiveVIPMode returns VIPMode, defaulting to VIPModeNetlink.
+func (n NetworkConfig) EffectiveVIPMode() VIPMode {
+	if n.VIPMode == "" {
+		return VIPModeNetlink
+	}
+	return n.VIPMode
+}
+
+// VRRPConfig configures the VRRP instances keepalived runs for the VIPs, one per VIP.
+type VRRPConfig struct {
+	// VirtualRouterID is the virtual router ID of the first VIP's instance; the
+	// following VIPs use the next IDs. It must be unique on the segment and
+	// defaults to 51.
+	VirtualRouterID int `yaml:"virtualRouterID,omitempty" json:"virtualRouterID,omitempty"`
+	// AdvertInterval is the interval between VRRP advertisements in seconds.
+	// Defaults to 1.
+	AdvertInterval int `yaml:"advertInterval,omitempty" json:"advertInterval,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with unset fields filled in.
+func (v *VRRPConfig) WithDefaults() VRRPConfig {
+	out := VRRPConfig{VirtualRouterID: 51, AdvertInterval: 1}
+	if v == nil {
+		return out
+	}
+	if v.VirtualRouterID != 0 {
+		out.VirtualRouterID = v.VirtualRouterID
+	}
+	if v.AdvertInterval != 0 {
+		out.AdvertInterval = v.AdvertInterval
+	}
+	return out
+}
+
+// BGPConfig configures the BGP sessions over which the node holding a VIP
+// advertises it as a /32 (IPv4) or /128 (IPv6) host route.
+type BGPConfig struct {
+	// LocalASN is the AS number of the nodes.
+	LocalASN uint32 `yaml:"localASN" json:"localASN"`
+	// RouterID is the BGP identifier, an IPv4 address. It defaults to the
+	// local address of each session, which must then be IPv4.
+	RouterID string `yaml:"routerID,omitempty" json:"routerID,omitempty"`
+	// HoldTime is the proposed hold time in seconds. Defaults to 90.
+	HoldTime int `yaml:"holdTime,omitempty" json:"holdTime,omitempty"`
+	// Peers are the routers the nodes open sessions to. An IPv6 VIP is only
+	// advertised over sessions to IPv6 peers, and an IPv4 VIP over sessions
+	// to IPv4 peers.
+	Peers []BGPPeer `yaml:"peers" json:"peers"`
+	// MED is sent as the MULTI_EXIT_DISC of the routes when set.
+	MED *uint32 `yaml:"med,omitempty" json:"med,omitempty"`
+	// Communities are attached to the routes, as "ASN:value" or one of the
+	// well-known "no-export", "no-advertise" and "no-export-subconfed".
+	Communities []string `yaml:"communities,omitempty" json:"communities,omitempty"`
+}
+
+// BGPPeer is a router the nodes open a BGP session to.
+type BGPPeer struct {
+	Address string `yaml:"address" json:"address"`
+	ASN     uint32 `yaml:"asn" json:"asn"`
+	// Port defaults to 179.
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with unset fields filled in.
+func (b BGPConfig) WithDefaults() BGPConfig {
+	if b.HoldTime == 0 {
+		b.HoldTime = 90
+	}
+	b.Peers = append([]BGPPeer(nil), b.Peers...)
+	for i := range b.Peers {
+		if b.Peers[i].Port == 0 {
+			b.Peers[i].Port = 179
+		}
+	}
+	return b
+}
+
+// wellKnownCommunities are the communities of RFC 1997.
+var wellKnownCommunities = map[string]uint32{
+	"no-export":           0xFFFFFF01,
+	"no-advertise":        0xFFFFFF02,
+	"no-export-subconfed": 0xFFFFFF03,
+}
+
+// ParseCommunity parses a BGP community, "ASN:value" with 16-bit parts or a
+// well-known community name.
+func ParseCommunity(s string) (uint32, error) {
+	if c, ok := wellKnownCommunities[s]; ok {
+		return c, nil
+	}
+	asn, value, ok := strings.Cut(s, ":")
+	if ok {
+		a, errA := strconv.ParseUint(asn, 10, 16)
+		v, errV := strconv.ParseUint(value, 10, 16)
+		if errA == nil && errV == nil {
+			return uint32(a)<<16 | uint32(v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid BGP community %q: want ASN:value with 16-bit numbers or a well-known community", s)
+}
+
+// VIPPurpose says what a VIP is used for.
+type VIPPurpose string
+
+const (
+	VIPPurposeAPI     VIPPurpose = "api"
+	VIPPurposeIngress VIPPurpose = "ingress"
+	VIPPurposeCustom  VIPPurpose = "custom"
+)
+
+// VIPOwnership says which node holds a VIP.
+type VIPOwnership string
+
+const (
+	// VIPFollowsLeader VIPs are held by the leader and move with it on failover.
+	VIPFollowsLeader VIPOwnership = "leader"
+	// VIPPinned VIPs stay on the node named in VIPConfig.Node.
+	VIPPinned VIPOwnership = "pinned"
+)
+
+// VIPConfig is one virtual IP of the cluster.
+type VIPConfig struct {
+	Name    string     `yaml:"name" json:"name"`
+	Address string     `yaml:"address" json:"address"`
+	Purpose VIPPurpose `yaml:"purpose,omitempty" json:"purpose,omitempty"`
+	// Interface and PrefixLength default to those of NetworkConfig.
+	Interface    string `yaml:"interface,omitempty" json:"interface,omitempty"`
+	PrefixLength int    `yaml:"prefixLength,omitempty" json:"prefixLength,omitempty"`
+	// Ownership defaults to VIPFollowsLeader.
+	Ownership VIPOwnership `yaml:"ownership,omitempty" json:"ownership,omitempty"`
+	// Node is the IP of the node a pinned VIP stays on.
+	Node string `yaml:"node,omitempty" json:"node,omitempty"`
+	// Provider defaults to VIPProviderLocal.
+	Provider VIPProviderType `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Ports are the TCP ports the haproxy provider forwards. They default to
+	// 6443 for purpose api and to 80 and 443 for purpose ingress.
+	Ports []int `yaml:"ports,omitempty" json:"ports,omitempty"`
+}
+
+// VIPProviderType says what holds a VIP.
+type VIPProviderType string
+
+const (
+	// VIPProviderLocal VIPs are held on the node by the mechanism of the VIP
+	// mode: netlink, keepalived or BGP.
+	VIPProviderLocal VIPProviderType = "local"
+	// VIPProviderCloud VIPs are secondary IPs that a cloud API reassigns to
+	// the instance of the node holding them.
+	VIPProviderCloud VIPProviderType = "cloud"
+	// VIPProviderHAProxy VIPs are served by an HAProxy load balancer that
+	// forwards them to the node holding them.
+	VIPProviderHAProxy VIPProviderType = "haproxy"
+)
+
+// CloudVIPConfig configures the cloud VIP provider. It drives an HTTP API
+// that assigns secondary IPs to instances:
+//
+//	GET    <endpoint>/addresses/<ip>  -> 200 {"address": ip, "instanceId": id}, or 404
+//	PUT    <endpoint>/addresses/<ip>  <- {"instanceId": id}, moving ip from any other instance
+//	DELETE <endpoint>/addresses/<ip>
+type CloudVIPConfig struct {
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// TokenFile holds a bearer token sent with every request, if set.
+	TokenFile string `yaml:"tokenFile,omitempty" json:"tokenFile,omitempty"`
+	// Instances maps the IP of each node to the ID of its instance.
+	Instances map[string]string `yaml:"instances" json:"instances"`
+	// AddToInterface also adds the VIP to the node's interface, for clouds
+	// that do not configure secondary IPs inside the instance.
+	AddToInterface bool `yaml:"addToInterface,omitempty" json:"addToInterface,omitempty"`
+	// Timeout of each request. Defaults to 10s.
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// HAProxyConfig configures the haproxy VIP provider, which writes one
+// configuration file per VIP on the load balancer, forwarding the VIP's ports
+// to the node that holds it.
+type HAProxyConfig struct {
+	// Host is the load balancer, reached over SSH.
+	Host string `yaml:"host" json:"host"`
+	// ConfigDir is the directory HAProxy loads the files from. Defaults to
+	// /etc/haproxy/conf.d.
+	ConfigDir string `yaml:"configDir,omitempty" json:"configDir,omitempty"`
+	// ReloadCommand is run on the load balancer after a file changed.
+	// Defaults to "systemctl reload haproxy".
+	ReloadCommand string `yaml:"reloadCommand,omitempty" json:"reloadCommand,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with unset fields filled in.
+func (h HAProxyConfig) WithDefaults() HAProxyConfig {
+	if h.ConfigDir == "" {
+		h.ConfigDir = "/etc/haproxy/conf.d"
+	}
+	if h.ReloadCommand == "" {
+		h.ReloadCommand = "systemctl reload haproxy"
+	}
+	return h
+}
+
+// AllVIPs returns every VIP of the cluster with its defaults filled in: VIP as
+// the VIP named "api", followed by VIPs.
+func (n NetworkConfig) AllVIPs() []VIPConfig {
+	var vips []VIPConfig
+	if n.VIP != "" {
+		vips = append(vips, VIPConfig{Name: "api", Address: n.VIP, Purpose: VIPPurposeAPI})
+	}
+	vips = append(vips, n.VIPs...)
+	for i := range vips {
+		v := &vips[i]
+		if v.Purpose == "" {
+			v.Purpose = VIPPurposeCustom
+		}
+		if v.Interface == "" {
+			v.Interface = n.Interface
+		}
+		if v.PrefixLength == 0 {
+			v.PrefixLength = n.PrefixLength
+			if FamilyOf(v.Address) == IPv6 {
+				// PrefixLength is an IPv4 prefix: it is never applied to IPv6 VIPs.
+				v.PrefixLength = n.PrefixLengthV6
+				if v.PrefixLength == 0 {
+					v.PrefixLength = 128
+				}
+			}
+		}
+		if v.Ownership == "" {
+			v.Ownership = VIPFollowsLeader
+		}
+		if v.Provider == "" {
+			v.Provider = VIPProviderLocal
+		}
+		if len(v.Ports) == 0 {
+			switch v.Purpose {
+			case VIPPurposeAPI:
+				v.Ports = []int{6443}
+			case VIPPurposeIngress:
+				v.Ports = []int{80, 443}
+			}
+		}
+	}
+	return vips
+}
+
+// APIVIP returns the address the API server is reached through: VIP, or else
+// the first VIP with purpose api.
+func (n NetworkConfig) APIVIP() string {
+	for _, v := range n.AllVIPs() {
+		if v.Purpose == VIPPurposeAPI {
+			return v.Address
+		}
+	}
+	return ""
+}
+
+// FindVIP returns the VIP with the given address.
+func (n NetworkConfig) FindVIP(address string) (VIPConfig, bool) {
+	for _, v := range n.AllVIPs() {
+		if v.Address == address {
+			return v, true
+		}
+	}
+	return VIPConfig{}, false
+}
+
+// HeldBy reports whether the node with the given IP and role should hold the VIP.
+func (v VIPConfig) HeldBy(ip string, role NodeRole) bool {
+	if v.Ownership == VIPPinned {
+		return v.Node == ip
+	}
+	return role == RoleLeader
 }
 
 // NodeInfo contains basic information about a node in the cluster.
 type NodeInfo struct {
+	// IP is the node's primary address, which identifies it.
 	IP   string   `yaml:"ip" json:"ip"`
 	Role NodeRole `yaml:"role" json:"role"`
+	// Addresses are further addresses of the node, e.g. its IPv6 address in
+	// a dual-stack cluster. A node has at most one address per IP family.
+	Addresses []string `yaml:"addresses,omitempty" json:"addresses,omitempty"`
+	// HeartbeatAddresses are further addresses the peer heartbeats the node
+	// on, e.g. on a dedicated crossover link. IP is always a heartbeat path.
+	HeartbeatAddresses []string `yaml:"heartbeatAddresses,omitempty" json:"heartbeatAddresses,omitempty"`
+}
+
+// AllAddresses returns IP followed by Addresses.
+func (n NodeInfo) AllAddresses() []string {
+	return append([]string{n.IP}, n.Addresses...)
+}
+
+// Address returns the node's address of the given family, or "".
+func (n NodeInfo) Address(family IPFamily) string {
+	for _, address := range n.AllAddresses() {
+		if FamilyOf(address) == family {
+			return address
+		}
+	}
+	return ""
 }
 
+// Storage backend types accepted in StorageConfig.Type.
+const (
+	StorageTypePostgreSQL = "postgresql"
+	StorageTypeMySQL      = "mysql"
+	StorageTypeSQLite     = "sqlite"
+)
+
 // StorageConfig holds the storage configuration for the cluster.
 type StorageConfig struct {
-	// For now, this is a placeholder. We can add PostgreSQL/Kine specific configs here.
-	Type string `yaml:"type" json:"type"` // e.g., "postgresql"
+	// Type selects the datastore backend behind Kine: "postgresql" (default),
+	// "mysql" (MySQL or MariaDB with GTID replication) or "sqlite" (single node only).
+	Type        string             `yaml:"type" json:"type"`
+	Postgres    *PostgresConfig    `yaml:"postgres,omitempty" json:"postgres,omitempty"`
+	MySQL       *MySQLConfig       `yaml:"mysql,omitempty" json:"mysql,omitempty"`
+	SQLite      *SQLiteConfig      `yaml:"sqlite,omitempty" json:"sqlite,omitempty"`
+	Replication *ReplicationConfig `yaml:"replication,omitempty" json:"replication,omitempty"`
+	Credentials *CredentialsConfig `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+// PostgresConfig describes how to reach the PostgreSQL instance on each node.
+// Passwords are never stored here; the admin password is taken from PGPASSWORD.
+type PostgresConfig struct {
+	Port      int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Database  string `yaml:"database,omitempty" json:"database,omitempty"`
+	AdminUser string `yaml:"adminUser,omitempty" json:"adminUser,omitempty"`
+	SSLMode   string `yaml:"sslMode,omitempty" json:"sslMode,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with unset fields filled in.
+func (p *PostgresConfig) WithDefaults() PostgresConfig {
+	out := PostgresConfig{Port: 5432, Database: "kubernetes", AdminUser: "postgres", SSLMode: "disable"}
+	if p == nil {
+		return out
+	}
+	if p.Port != 0 {
+		out.Port = p.Port
+	}
+	if p.Database != "" {
+		out.Database = p.Database
+	}
+	if p.AdminUser != "" {
+		out.AdminUser = p.AdminUser
+	}
+	if p.SSLMode != "" {
+		out.SSLMode = p.SSLMode
+	}
+	return out
+}
+
+// MySQLConfig describes how to reach MySQL or MariaDB on each node.
+// The admin password is taken from MYSQL_PWD.
+type MySQLConfig struct {
+	Port      int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Database  string `yaml:"database,omitempty" json:"database,omitempty"`
+	AdminUser string `yaml:"adminUser,omitempty" json:"adminUser,omitempty"`
+	// TLS is passed to the driver's tls parameter (e.g. "true", "skip-verify"). Off by default.
+	TLS string `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with unset fields filled in.
+func (m *MySQLConfig) WithDefaults() MySQLConfig {
+	out := MySQLConfig{Port: 3306, Database: "kubernetes", AdminUser: "root", TLS: "false"}
+	if m == nil {
+		return out
+	}
+	if m.Port != 0 {
+		out.Port = m.Port
+	}
+	if m.Database != "" {
+		out.Database = m.Database
+	}
+	if m.AdminUser != "" {
+		out.AdminUser = m.AdminUser
+	}
+	if m.TLS != "" {
+		out.TLS = m.TLS
+	}
+	return out
+}
+
+// SQLiteConfig describes the SQLite database file used on a single node.
+type SQLiteConfig struct {
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+}
+
+// PathOrDefault returns Path, or the default location under /var/lib/geminik8s.
+func (s *SQLiteConfig) PathOrDefault() string {
+	if s == nil || s.Path == "" {
+		return "/var/lib/geminik8s/db/state.db"
+	}
+	return s.Path
+}
+
+// BackendType returns the configured storage type, defaulting to PostgreSQL.
+func (s StorageConfig) BackendType() string {
+	if s.Type == "" {
+		return StorageTypePostgreSQL
+	}
+	return s.Type
+}
+
+// ServerPort returns the port the database server listens on, or 0 for
+// backends without a server (sqlite).
+func (s StorageConfig) ServerPort() int {
+	switch s.BackendType() {
+	case StorageTypePostgreSQL:
+		return s.Postgres.WithDefaults().Port
+	case StorageTypeMySQL:
+		return s.MySQL.WithDefaults().Port
+	default:
+		return 0
+	}
+}
+
+// CredentialsConfig holds the rotation policy for the Kine and replication credentials.
+type CredentialsConfig struct {
+	// RotationInterval is the maximum age of the credentials. Defaults to 90 days.
+	RotationInterval *Duration `yaml:"rotationInterval,omitempty" json:"rotationInterval,omitempty"`
+}
+
+// DefaultCredentialRotationInterval is used when credentials.rotationInterval is not set.
+const DefaultCredentialRotationInterval = 90 * 24 * time.Hour
+
+// RotationIntervalOrDefault returns RotationInterval, or DefaultCredentialRotationInterval if it is not set.
+func (c *CredentialsConfig) RotationIntervalOrDefault() time.Duration {
+	if c == nil {
+		return DefaultCredentialRotationInterval
+	}
+	return c.RotationInterval.OrDefault(DefaultCredentialRotationInterval)
+}
+
+// ReplicationMode selects how the follower database is kept in sync.
+type ReplicationMode string
+
+const (
+	// ReplicationModeAsync commits on the primary without waiting for the follower.
+	ReplicationModeAsync ReplicationMode = "async"
+	// ReplicationModeSync makes the follower a synchronous standby, so a commit
+	// only returns once the follower has the data.
+	ReplicationModeSync ReplicationMode = "sync"
+)
+
+// ReplicationConfig holds the replication settings between the two databases.
+type ReplicationConfig struct {
+	// Mode is either "async" (default) or "sync".
+	Mode ReplicationMode `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// DegradeTimeout is how long a synchronous follower may be unreachable before
+	// the leader falls back to async replication to stay writable. Defaults to 30s.
+	DegradeTimeout *Duration `yaml:"degradeTimeout,omitempty" json:"degradeTimeout,omitempty"`
+}
+
+// EffectiveMode returns the configured replication mode, defaulting to async.
+func (r *ReplicationConfig) EffectiveMode() ReplicationMode {
+	if r == nil || r.Mode == "" {
+		return ReplicationModeAsync
+	}
+	return r.Mode
+}
+
+// DefaultDegradeTimeout is used when replication.degradeTimeout is not set.
+const DefaultDegradeTimeout = 30 * time.Second
+
+// DegradeTimeoutOrDefault returns DegradeTimeout, or DefaultDegradeTimeout if it is not set.
+func (r *ReplicationConfig) DegradeTimeoutOrDefault() time.Duration {
+	if r == nil {
+		return DefaultDegradeTimeout
+	}
+	return r.DegradeTimeout.OrDefault(DefaultDegradeTimeout)
 }
 
 //Personal.AI order the ending
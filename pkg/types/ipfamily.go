@@ -0,0 +1,102 @@
+package types
+
+import "net"
+
+// IPFamily is the family of an address or CIDR.
+type IPFamily string
+
+const (
+	IPv4 IPFamily = "IPv4"
+	IPv6 IPFamily = "IPv6"
+)
+
+// Default k3s cluster and service CIDRs per family.
+var (
+	DefaultPodCIDRs     = map[IPFamily]string{IPv4: "10.42.0.0/16", IPv6: "fd00:42::/56"}
+	DefaultServiceCIDRs = map[IPFamily]string{IPv4: "10.43.0.0/16", IPv6: "fd00:43::/112"}
+)
+
+// FamilyOf returns the family of an IP address or CIDR, or "" if s is
+// neither.
+func FamilyOf(s string) IPFamily {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		var err error
+		if ip, _, err = net.ParseCIDR(s); err != nil {
+			return ""
+		}
+	}
+	if ip.To4() != nil {
+		return IPv4
+	}
+	return IPv6
+}
+
+// HostCIDR returns the CIDR that matches the address alone: /32 for IPv4 and
+// /128 for IPv6.
+func HostCIDR(address string) string {
+	if FamilyOf(address) == IPv4 {
+		return address + "/32"
+	}
+	return address + "/128"
+}
+
+// IPFamilies returns the families of the nodes' addresses, the family of the
+// first node's IP first. Both families make the cluster dual-stack.
+func (c *ClusterSpec) IPFamilies() []IPFamily {
+	var families []IPFamily
+	for _, n := range c.Nodes {
+		for _, address := range n.AllAddresses() {
+			if f := FamilyOf(address); f != "" && !containsFamily(families, f) {
+				families = append(families, f)
+			}
+		}
+	}
+	return families
+}
+
+// DualStack reports whether the nodes have addresses of both families.
+func (c *ClusterSpec) DualStack() bool {
+	return len(c.IPFamilies()) == 2
+}
+
+// PodCIDRsOrDefault returns spec.network.podCIDRs, or the defaults for the
+// families of the nodes, in the order of IPFamilies.
+func (c *ClusterSpec) PodCIDRsOrDefault() []string {
+	return c.cidrsOrDefault(c.Network.PodCIDRs, DefaultPodCIDRs)
+}
+
+// ServiceCIDRsOrDefault returns spec.network.serviceCIDRs, or the defaults for
+// the families of the nodes, in the order of IPFamilies.
+func (c *ClusterSpec) ServiceCIDRsOrDefault() []string {
+	return c.cidrsOrDefault(c.Network.ServiceCIDRs, DefaultServiceCIDRs)
+}
+
+// cidrsOrDefault returns one CIDR per family of the nodes, taken from cidrs
+// or else from defaults.
+func (c *ClusterSpec) cidrsOrDefault(cidrs []string, defaults map[IPFamily]string) []string {
+	var out []string
+	for _, family := range c.IPFamilies() {
+		cidr := defaults[family]
+		for _, configured := range cidrs {
+			if FamilyOf(configured) == family {
+				cidr = configured
+				break
+			}
+		}
+		out = append(out, cidr)
+	}
+	return out
+}
+
+// containsFamily reports whether family is one of families.
+func containsFamily(families []IPFamily, family IPFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+//Personal.AI order the ending
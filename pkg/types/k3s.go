@@ -0,0 +1,40 @@
+package types
+
+import "strings"
+
+// K3sServerArgs returns the k3s server flags of the node with the given IP
+// that follow from the cluster's addressing: the node's addresses, the
+// cluster and service CIDRs and the API VIPs as extra certificate SANs. In a
+// dual-stack cluster the lists have one entry per family, in the same order
+// of families, as k3s requires.
+func (c *ClusterSpec) K3sServerArgs(ip string) []string {
+	n, ok := c.Node(ip)
+	if !ok {
+		n = NodeInfo{IP: ip}
+	}
+	families := c.IPFamilies()
+	var addresses []string
+	for _, family := range families {
+		if address := n.Address(family); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	args := []string{
+		"--node-ip=" + strings.Join(addresses, ","),
+		"--cluster-cidr=" + strings.Join(c.PodCIDRsOrDefault(), ","),
+		"--service-cidr=" + strings.Join(c.ServiceCIDRsOrDefault(), ","),
+	}
+	for _, v := range c.Network.AllVIPs() {
+		if v.Purpose == VIPPurposeAPI {
+			args = append(args, "--tls-san="+v.Address)
+		}
+	}
+	if containsFamily(families, IPv6) {
+		// The default IPv6 CIDRs are unique local addresses, which are not
+		// routed beyond the cluster.
+		args = append(args, "--flannel-ipv6-masq")
+	}
+	return args
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,222 @@
+package types
+
+import "time"
+
+// ObjectAction is what happened to one object of a manifest.
+type ObjectAction string
+
+const (
+	ObjectApplied  ObjectAction = "Applied"
+	ObjectDeleted  ObjectAction = "Deleted"
+	ObjectNotFound ObjectAction = "NotFound" // Delete of an object that was already gone
+	ObjectFailed   ObjectAction = "Failed"
+	ObjectSkipped  ObjectAction = "Skipped" // Not attempted because an earlier object failed
+)
+
+// ObjectResult is the outcome of applying or deleting a single object.
+type ObjectResult struct {
+	APIVersion string       `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string       `yaml:"kind" json:"kind"`
+	Namespace  string       `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Name       string       `yaml:"name" json:"name"`
+	Action     ObjectAction `yaml:"action" json:"action"`
+	Error      string       `yaml:"error,omitempty" json:"error,omitempty"`
+}
+
+// DrainOptions controls how a node is drained.
+type DrainOptions struct {
+	// GracePeriod overrides the termination grace period of every evicted pod.
+	// Nil keeps each pod's own grace period.
+	GracePeriod *time.Duration
+	// Timeout bounds the whole drain, including waiting for pods to terminate.
+	// Zero means no limit beyond the context.
+	Timeout time.Duration
+	// Progress, if set, is called whenever a pod changes phase. Calls are serialized.
+	Progress func(PodDrainProgress)
+}
+
+// PodDrainPhase is where a pod is in the drain.
+type PodDrainPhase string
+
+const (
+	PodDrainSkipped  PodDrainPhase = "Skipped"  // DaemonSet, mirror or finished pod; left alone
+	PodDrainEvicting PodDrainPhase = "Evicting" // Eviction requested
+	PodDrainBlocked  PodDrainPhase = "Blocked"  // A PodDisruptionBudget refused the eviction; will retry
+	PodDrainEvicted  PodDrainPhase = "Evicted"  // Pod is gone from the node
+	PodDrainFailed   PodDrainPhase = "Failed"
+)
+
+// PodDrainProgress reports a change in a pod's drain phase.
+type PodDrainProgress struct {
+	Namespace string        `yaml:"namespace" json:"namespace"`
+	Name      string        `yaml:"name" json:"name"`
+	Phase     PodDrainPhase `yaml:"phase" json:"phase"`
+	Message   string        `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// KubeconfigOptions controls the kubeconfig generated for a cluster.
+type KubeconfigOptions struct {
+	// TTL, if set, replaces the admin credential with a service account token
+	// that expires after TTL.
+	TTL time.Duration
+	// ClusterRole is bound to that service account. Defaults to "view".
+	ClusterRole string
+}
+
+// GeminiClusterStatus is the status of the GeminiCluster custom resource, which
+// the agent on the leader publishes so HA state is visible from inside the cluster.
+type GeminiClusterStatus struct {
+	// Leader is the name of the node running the primary database.
+	Leader string `yaml:"leader,omitempty" json:"leader,omitempty"`
+	// Epoch is the fencing epoch of the current leader.
+	Epoch int64                     `yaml:"epoch" json:"epoch"`
+	Nodes []GeminiClusterNodeStatus `yaml:"nodes,omitempty" json:"nodes,omitempty"`
+	VIP   string                    `yaml:"vip,omitempty" json:"vip,omitempty"`
+	// VIPHolder is the name of the node the VIP is assigned to, empty if none.
+	VIPHolder string `yaml:"vipHolder,omitempty" json:"vipHolder,omitempty"`
+	// VIPs lists every VIP of the cluster, including the API server's.
+	VIPs                 []GeminiClusterVIPStatus `yaml:"vips,omitempty" json:"vips,omitempty"`
+	ReplicationStreaming bool                     `yaml:"replicationStreaming" json:"replicationStreaming"`
+	// ReplicationLag is how far the follower is behind, e.g. "1.5s".
+	ReplicationLag string `yaml:"replicationLag,omitempty" json:"replicationLag,omitempty"`
+	// ReplicationMode is the replication mode the primary runs in.
+	ReplicationMode ReplicationMode `yaml:"replicationMode,omitempty" json:"replicationMode,omitempty"`
+	// Degraded is true while the primary runs async although sync is configured.
+	Degraded         bool       `yaml:"degraded" json:"degraded"`
+	LastFailoverTime *time.Time `yaml:"lastFailoverTime,omitempty" json:"lastFailoverTime,omitempty"`
+	LastBackupTime   *time.Time `yaml:"lastBackupTime,omitempty" json:"lastBackupTime,omitempty"`
+	// HealthChecks are checks only the leader can run, such as the probe for
+	// other hosts answering for the VIP.
+	HealthChecks []HealthCheckResult `yaml:"healthChecks,omitempty" json:"healthChecks,omitempty"`
+	// LastUpdateTime is when the leader last published the status.
+	LastUpdateTime time.Time `yaml:"lastUpdateTime" json:"lastUpdateTime"`
+}
+
+// GeminiClusterVIPStatus is one VIP in GeminiClusterStatus.
+type GeminiClusterVIPStatus struct {
+	Name    string     `yaml:"name" json:"name"`
+	Address string     `yaml:"address" json:"address"`
+	Purpose VIPPurpose `yaml:"purpose" json:"purpose"`
+	// Owner is the name of the node that should hold the VIP.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	// Holder is the name of the leader if it holds the VIP. Whether the
+	// follower holds a VIP pinned to it is not known to the leader.
+	Holder string `yaml:"holder,omitempty" json:"holder,omitempty"`
+}
+
+// GeminiClusterNodeStatus is the role of one node in GeminiClusterStatus.
+type GeminiClusterNodeStatus struct {
+	Name string   `yaml:"name" json:"name"`
+	IP   string   `yaml:"ip" json:"ip"`
+	Role NodeRole `yaml:"role" json:"role"`
+	// Heartbeat is how the leader sees the follower over the heartbeat paths.
+	// It is only set on the follower.
+	Heartbeat *HeartbeatStatus `yaml:"heartbeat,omitempty" json:"heartbeat,omitempty"`
+}
+
+// ConditionStatus is the status of a Condition.
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition is one aspect of the state of a custom resource, in the shape
+// Kubernetes tools expect under status.conditions.
+type Condition struct {
+	Type               string          `yaml:"type" json:"type"`
+	Status             ConditionStatus `yaml:"status" json:"status"`
+	Reason             string          `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Message            string          `yaml:"message,omitempty" json:"message,omitempty"`
+	ObservedGeneration int64           `yaml:"observedGeneration,omitempty" json:"observedGeneration,omitempty"`
+	LastTransitionTime time.Time       `yaml:"lastTransitionTime" json:"lastTransitionTime"`
+	// Retries counts the failed attempts in a row of a False condition, and
+	// RetryAfter is when the next attempt is due.
+	Retries    int        `yaml:"retries,omitempty" json:"retries,omitempty"`
+	RetryAfter *time.Time `yaml:"retryAfter,omitempty" json:"retryAfter,omitempty"`
+}
+
+// ClusterConfigStatus is the status of the ClusterConfig custom resource, which
+// the operator writes while reconciling it.
+type ClusterConfigStatus struct {
+	ObservedGeneration int64 `yaml:"observedGeneration,omitempty" json:"observedGeneration,omitempty"`
+	// Version is the k3s version the operator last upgraded the cluster to.
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// Leader is the IP of the node the operator last made leader.
+	Leader         string      `yaml:"leader,omitempty" json:"leader,omitempty"`
+	LastBackupTime *time.Time  `yaml:"lastBackupTime,omitempty" json:"lastBackupTime,omitempty"`
+	Conditions     []Condition `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+}
+
+// SetCondition adds or replaces the condition of the same type. The transition
+// time is only moved when the status of the condition changes.
+func (s *ClusterConfigStatus) SetCondition(c Condition) {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type != c.Type {
+			continue
+		}
+		if s.Conditions[i].Status == c.Status {
+			c.LastTransitionTime = s.Conditions[i].LastTransitionTime
+		}
+		s.Conditions[i] = c
+		return
+	}
+	s.Conditions = append(s.Conditions, c)
+}
+
+// Condition returns the condition of the given type, or nil.
+func (s *ClusterConfigStatus) Condition(conditionType string) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// ClusterConfigResource is a ClusterConfig custom resource: the cluster
+// configuration in its spec, plus the operator's status.
+type ClusterConfigResource struct {
+	Generation int64
+	Config     ClusterConfig
+	Status     ClusterConfigStatus
+}
+
+// EventType is the type of a Kubernetes Event.
+type EventType string
+
+const (
+	EventNormal  EventType = "Normal"
+	EventWarning EventType = "Warning"
+)
+
+// EventObject identifies the cluster-scoped object an Event is about.
+type EventObject struct {
+	Kind string `yaml:"kind" json:"kind"` // "Node" or "GeminiCluster"
+	Name string `yaml:"name" json:"name"`
+}
+
+// NodeObject returns the EventObject of the named Kubernetes node.
+func NodeObject(name string) EventObject {
+	return EventObject{Kind: "Node", Name: name}
+}
+
+// GeminiClusterObject returns the EventObject of the named GeminiCluster.
+func GeminiClusterObject(name string) EventObject {
+	return EventObject{Kind: "GeminiCluster", Name: name}
+}
+
+// Event is something that happened to a node or the cluster, recorded as a
+// Kubernetes Event so cluster users can see it.
+type Event struct {
+	Object  EventObject `yaml:"object" json:"object"`
+	Type    EventType   `yaml:"type" json:"type"`
+	Reason  string      `yaml:"reason" json:"reason"` // UpperCamelCase, e.g. "FailoverSucceeded"
+	Message string      `yaml:"message" json:"message"`
+	// Time is when it happened. Zero means now.
+	Time time.Time `yaml:"time" json:"time"`
+}
+
+//Personal.AI order the ending
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"net"
+	"time"
+)
 
 // NodeStatusType represents the health status of a node.
 type NodeStatusType string
@@ -19,18 +22,34 @@ type Node struct {
 
 // NodeConfig holds the configuration for a single node.
 type NodeConfig struct {
-	Name string   `yaml:"name" json:"name"`
-	IP   string   `yaml:"ip" json:"ip"`
-	Role NodeRole `yaml:"role" json:"role"`
+	Name string `yaml:"name" json:"name"`
+	IP   string `yaml:"ip" json:"ip"`
+	// Addresses are the node's further addresses, such as its address of the
+	// other IP family on a dual-stack node.
+	Addresses []string `yaml:"addresses,omitempty" json:"addresses,omitempty"`
+	Role      NodeRole `yaml:"role" json:"role"`
+}
+
+// HasAddress reports whether ip is IP or one of Addresses.
+func (c NodeConfig) HasAddress(ip string) bool {
+	want := net.ParseIP(ip)
+	for _, address := range append([]string{c.IP}, c.Addresses...) {
+		if address == ip || (want != nil && want.Equal(net.ParseIP(address))) {
+			return true
+		}
+	}
+	return false
 }
 
 // NodeStatus represents the observed state of a node.
 type NodeStatus struct {
-	Status            NodeStatusType      `yaml:"status" json:"status"`
-	Message           string              `yaml:"message" json:"message"`
-	LastHeartbeatTime time.Time           `yaml:"lastHeartbeatTime" json:"lastHeartbeatTime"`
-	Services          []ServiceStatus     `yaml:"services" json:"services"`
-	HealthChecks      []HealthCheckResult `yaml:"healthChecks" json:"healthChecks"`
+	Status            NodeStatusType `yaml:"status" json:"status"`
+	Message           string         `yaml:"message" json:"message"`
+	LastHeartbeatTime time.Time      `yaml:"lastHeartbeatTime" json:"lastHeartbeatTime"`
+	// KubeletVersion is the version the node's kubelet reports, e.g. "v1.29.4+k3s1".
+	KubeletVersion string              `yaml:"kubeletVersion,omitempty" json:"kubeletVersion,omitempty"`
+	Services       []ServiceStatus     `yaml:"services" json:"services"`
+	HealthChecks   []HealthCheckResult `yaml:"healthChecks" json:"healthChecks"`
 }
 
 // ServiceStatus represents the status of a service running on the node.
@@ -38,6 +57,8 @@ type ServiceStatus struct {
 	Name   string `yaml:"name" json:"name"` // e.g., "k3s", "postgresql", "kine"
 	Active bool   `yaml:"active" json:"active"`
 	Error  string `yaml:"error,omitempty" json:"error,omitempty"`
+	// Restarts counts how often a supervised service has been restarted.
+	Restarts int `yaml:"restarts,omitempty" json:"restarts,omitempty"`
 }
 
 // HealthCheckResult holds the outcome of a single health check.
@@ -49,6 +70,9 @@ type HealthCheckResult struct {
 	DurationMs int64     `yaml:"durationMs" json:"durationMs"`
 }
 
+// DefaultHostMetaPath is where the deploy workflow writes hostMeta.yaml on each node.
+const DefaultHostMetaPath = "/var/lib/geminik8s/hostMeta.yaml"
+
 // HostMeta is the metadata stored on each node to describe the cluster topology
 // from its own perspective. This is crucial for the failover mechanism.
 type HostMeta struct {
@@ -61,6 +85,24 @@ type HostMeta struct {
 	// LastModified is the timestamp of the last modification to this file.
 	// Used as a simple fencing mechanism during network partitions.
 	LastModified time.Time `yaml:"lastModified" json:"lastModified"`
+	// Epoch is the fencing epoch. It is incremented whenever a node is promoted;
+	// a primary from an older epoch is fenced and must no longer receive writes.
+	Epoch int64 `yaml:"epoch" json:"epoch"`
+}
+
+// Primary returns the node this HostMeta names as leader. It reports false
+// unless exactly one of the two nodes is the leader.
+func (h *HostMeta) Primary() (NodeIdentity, bool) {
+	myLeader := h.MyID.Role == RoleLeader
+	peerLeader := h.PeerID.Role == RoleLeader
+	switch {
+	case myLeader && !peerLeader:
+		return h.MyID, true
+	case peerLeader && !myLeader:
+		return h.PeerID, true
+	default:
+		return NodeIdentity{}, false
+	}
 }
 
 // NodeIdentity holds the identifying information for a node.
@@ -68,6 +110,28 @@ type NodeIdentity struct {
 	Name string   `yaml:"name" json:"name"`
 	IP   string   `yaml:"ip" json:"ip"`
 	Role NodeRole `yaml:"role" json:"role"`
+	// HeartbeatAddresses are further addresses the node is heartbeated on,
+	// in addition to spec.nodes[].heartbeatAddresses of the cluster.
+	HeartbeatAddresses []string `yaml:"heartbeatAddresses,omitempty" json:"heartbeatAddresses,omitempty"`
+}
+
+// HeartbeatStatus is how a node sees its peer over the heartbeat paths.
+type HeartbeatStatus struct {
+	// Alive is true while at least one path is up.
+	Alive bool                  `yaml:"alive" json:"alive"`
+	Paths []HeartbeatPathStatus `yaml:"paths" json:"paths"`
+}
+
+// HeartbeatPathStatus is the state of one heartbeat path to the peer.
+type HeartbeatPathStatus struct {
+	// Address is the peer's address on the path.
+	Address string `yaml:"address" json:"address"`
+	// Up is true while the peer answered within the heartbeat timeout.
+	Up bool `yaml:"up" json:"up"`
+	// Since is when the path last went up or down.
+	Since time.Time `yaml:"since" json:"since"`
+	// Error is why the path is down.
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
 }
 
 //Personal.AI order the ending
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortSpec is a port the nodes must be able to reach on each other.
+type PortSpec struct {
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+	Port     int    `json:"port"`
+	Purpose  string `json:"purpose,omitempty"`
+}
+
+// String returns the port as "tcp/6443".
+func (p PortSpec) String() string {
+	return fmt.Sprintf("%s/%d", p.Protocol, p.Port)
+}
+
+// ParsePortSpec parses a port written as "tcp/6443" or "udp/8472".
+func ParsePortSpec(s string) (PortSpec, error) {
+	protocol, port, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(port)
+	if !ok || err != nil || n < 1 || n > 65535 || (protocol != "tcp" && protocol != "udp") {
+		return PortSpec{}, fmt.Errorf("invalid port %q: expected tcp/<port> or udp/<port>", s)
+	}
+	return PortSpec{Protocol: protocol, Port: n}, nil
+}
+
+// RequiredPorts returns the ports the nodes of the cluster talk to each other
+// on: the k3s API server, the kubelet, flannel's VXLAN, the agents' heartbeat
+// and the datastore.
+func (c *ClusterConfig) RequiredPorts() []PortSpec {
+	ports := []PortSpec{
+		{Protocol: "tcp", Port: 6443, Purpose: "k3s API server"},
+		{Protocol: "tcp", Port: 10250, Purpose: "kubelet"},
+		{Protocol: "udp", Port: 8472, Purpose: "flannel VXLAN"},
+		{Protocol: "udp", Port: c.Spec.Heartbeat.PortOrDefault(), Purpose: "agent heartbeat"},
+	}
+	switch c.Spec.Storage.BackendType() {
+	case StorageTypePostgreSQL:
+		ports = append(ports, PortSpec{Protocol: "tcp", Port: c.Spec.Storage.Postgres.WithDefaults().Port, Purpose: "PostgreSQL"})
+	case StorageTypeMySQL:
+		ports = append(ports, PortSpec{Protocol: "tcp", Port: c.Spec.Storage.MySQL.WithDefaults().Port, Purpose: "MySQL"})
+	}
+	return ports
+}
+
+// PreflightStatus is the outcome of a preflight check.
+type PreflightStatus string
+
+const (
+	PreflightPass PreflightStatus = "pass"
+	PreflightFail PreflightStatus = "fail"
+	// PreflightSkip means the check could not be carried out, e.g. because the
+	// port is already in use by a service that does not answer the probe.
+	PreflightSkip PreflightStatus = "skip"
+)
+
+// PreflightCheck is the result of one check run on node From. Checks between
+// two nodes name the other node in To; checks of From alone leave it empty.
+type PreflightCheck struct {
+	Check   string          `json:"check"` // e.g. "tcp/6443", "rtt", "mtu", "vip/api"
+	From    string          `json:"from"`
+	To      string          `json:"to,omitempty"`
+	Status  PreflightStatus `json:"status"`
+	Message string          `json:"message,omitempty"`
+}
+
+// PreflightReport holds the results of a network preflight.
+type PreflightReport struct {
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// Passed reports whether no check failed.
+func (r *PreflightReport) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Status == PreflightFail {
+			return false
+		}
+	}
+	return true
+}
+
+// PreflightOptions sets the limits of a network preflight.
+type PreflightOptions struct {
+	// MaxRTT is the highest average round-trip time between the nodes. Defaults to 20ms.
+	MaxRTT time.Duration
+	// MaxJitter is the highest mean difference between consecutive round-trip
+	// times. Defaults to 10ms.
+	MaxJitter time.Duration
+	// Samples is the number of round trips measured. Defaults to 10.
+	Samples int
+}
+
+// WithDefaults returns a copy of the options with unset fields filled in.
+func (o PreflightOptions) WithDefaults() PreflightOptions {
+	if o.MaxRTT == 0 {
+		o.MaxRTT = 20 * time.Millisecond
+	}
+	if o.MaxJitter == 0 {
+		o.MaxJitter = 10 * time.Millisecond
+	}
+	if o.Samples == 0 {
+		o.Samples = 10
+	}
+	return o
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// ProbeType is the protocol a connectivity probe speaks.
+type ProbeType string
+
+const (
+	// ProbeTCP opens a TCP connection.
+	ProbeTCP ProbeType = "tcp"
+	// ProbeICMP sends an ICMP or ICMPv6 echo request.
+	ProbeICMP ProbeType = "icmp"
+	// ProbeHTTP sends a GET request and expects a status code.
+	ProbeHTTP ProbeType = "http"
+	// ProbeTLS completes a TLS handshake and checks the expiry of the
+	// server's certificate.
+	ProbeTLS ProbeType = "tls"
+	// ProbePostgres sends a PostgreSQL startup message and expects the server
+	// to ask for authentication.
+	ProbePostgres ProbeType = "postgres"
+)
+
+// Probe describes a connectivity probe of a host.
+type Probe struct {
+	// Name is the check name of the result. Defaults to "<type>/<address>".
+	Name string
+	Type ProbeType
+	Host string
+	// Port defaults to 80 for HTTP, 443 for HTTPS and TLS and 5432 for
+	// PostgreSQL. ICMP probes have no port.
+	Port int
+	// Timeout bounds the whole probe. Defaults to 5s.
+	Timeout time.Duration
+
+	// HTTPS makes an HTTP probe use TLS.
+	HTTPS bool
+	// Path is the path of an HTTP probe. Defaults to "/".
+	Path string
+	// ExpectedStatus is the status code of a successful HTTP probe. Defaults to 200.
+	ExpectedStatus int
+
+	// ServerName is the name the server's certificate is verified against.
+	// Defaults to Host.
+	ServerName string
+	// InsecureSkipVerify accepts any certificate, e.g. the self-signed ones of
+	// k3s; TLS probes still fail on expired certificates.
+	InsecureSkipVerify bool
+	// RootCAs are PEM-encoded CA certificates the server's certificate is
+	// verified against instead of the system roots, e.g. the CA of k3s.
+	RootCAs []byte
+	// MinCertValidity makes a TLS probe fail when the server's certificate
+	// expires within it. Defaults to 7 days.
+	MinCertValidity time.Duration
+
+	// User and Database are sent in the startup message of a PostgreSQL probe.
+	// Both default to "postgres".
+	User     string
+	Database string
+}
+
+// WithDefaults returns a copy of the probe with unset fields filled in.
+func (p Probe) WithDefaults() Probe {
+	if p.Port == 0 {
+		switch {
+		case p.Type == ProbeHTTP && !p.HTTPS:
+			p.Port = 80
+		case p.Type == ProbeHTTP, p.Type == ProbeTLS:
+			p.Port = 443
+		case p.Type == ProbePostgres:
+			p.Port = 5432
+		}
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 5 * time.Second
+	}
+	if p.Path == "" {
+		p.Path = "/"
+	}
+	if p.ExpectedStatus == 0 {
+		p.ExpectedStatus = 200
+	}
+	if p.ServerName == "" {
+		p.ServerName = p.Host
+	}
+	if p.MinCertValidity == 0 {
+		p.MinCertValidity = 7 * 24 * time.Hour
+	}
+	if p.User == "" {
+		p.User = "postgres"
+	}
+	if p.Database == "" {
+		p.Database = "postgres"
+	}
+	if p.Name == "" {
+		p.Name = string(p.Type) + "/" + p.Address()
+	}
+	return p
+}
+
+// Address returns the host:port the probe connects to, or the host alone for
+// ICMP probes.
+func (p Probe) Address() string {
+	if p.Type == ProbeICMP {
+		return p.Host
+	}
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+//Personal.AI order the ending
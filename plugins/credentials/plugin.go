@@ -0,0 +1,141 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/turtacn/geminik8s/internal/domain/cluster"
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/infrastructure/database"
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// CredentialsPlugin rotates the Kine and replication database credentials.
+type CredentialsPlugin struct {
+	store  storage.CredentialStore
+	system func(nodeIP string) api.SystemOperator
+}
+
+// New creates a new CredentialsPlugin that keeps the current credentials in
+// store and reaches the nodes over SSH.
+func New(store storage.CredentialStore) api.Plugin {
+	return &CredentialsPlugin{
+		store:  store,
+		system: func(nodeIP string) api.SystemOperator { return system.NewRemoteOperator(nodeIP) },
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *CredentialsPlugin) Name() string {
+	return "credentials"
+}
+
+// Version returns the version of the plugin.
+func (p *CredentialsPlugin) Version() string {
+	return "v0.1.0"
+}
+
+// Validate checks if the required parameters are provided for execution.
+func (p *CredentialsPlugin) Validate(params api.PluginParams) error {
+	if _, ok := params["config"]; !ok {
+		return errors.New(errors.ValidationError, "missing 'config' parameter for credentials plugin")
+	}
+	return nil
+}
+
+// Execute rotates the credentials. With the 'onlyIfDue' parameter set, it does
+// nothing unless the current credentials are older than the rotation interval.
+func (p *CredentialsPlugin) Execute(ctx context.Context, params api.PluginParams) (*api.PluginResult, error) {
+	cfg, ok := params["config"].(*types.ClusterConfig)
+	if !ok {
+		return nil, errors.New(errors.ValidationError, "'config' parameter is not a valid ClusterConfig")
+	}
+	onlyIfDue, _ := params["onlyIfDue"].(bool)
+	if t := cfg.Spec.Storage.BackendType(); t != types.StorageTypePostgreSQL {
+		return nil, errors.Newf(errors.ValidationError, "credential rotation is only supported with the 'postgresql' storage type, got '%s'", t)
+	}
+
+	// After a failover the leader is no longer the one in the config.
+	leaderIP, err := cluster.CurrentLeader(cfg, p.system)
+	if err != nil {
+		return nil, err
+	}
+	var followerIP string
+	for _, n := range cfg.Spec.Nodes {
+		if n.IP != leaderIP {
+			followerIP = n.IP
+		}
+	}
+	if followerIP == "" {
+		return nil, errors.New(errors.ValidationError, "cluster config must have one leader and one follower")
+	}
+
+	backend, err := database.NewBackend(cfg.Spec.Storage, p.system)
+	if err != nil {
+		return nil, err
+	}
+	rotator := storage.NewCredentialRotator(p.store, &nodeAccess{pg: cfg.Spec.Storage.Postgres.WithDefaults(), system: p.system}, backend, cfg.Spec.Storage.Postgres)
+	if onlyIfDue {
+		interval := cfg.Spec.Storage.Credentials.RotationIntervalOrDefault()
+		due, err := rotator.Due(interval)
+		if err != nil {
+			return nil, err
+		}
+		if !due {
+			return &api.PluginResult{
+				Success: true,
+				Message: fmt.Sprintf("Credentials of cluster '%s' are younger than %s; nothing to do.", cfg.Metadata.Name, interval),
+				Data:    map[string]interface{}{"rotated": false},
+			}, nil
+		}
+	}
+
+	creds, err := rotator.Rotate(ctx, leaderIP, followerIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.PluginResult{
+		Success: true,
+		Message: fmt.Sprintf("Credentials of cluster '%s' rotated to generation %d.", cfg.Metadata.Name, creds.Generation),
+		Data: map[string]interface{}{
+			"rotated":    true,
+			"generation": creds.Generation,
+			"rotatedAt":  creds.RotatedAt,
+		},
+	}, nil
+}
+
+// Cleanup performs any cleanup operations after execution.
+func (p *CredentialsPlugin) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// nodeAccess reaches the nodes' databases directly and their hosts through system.
+type nodeAccess struct {
+	pg     types.PostgresConfig
+	system func(nodeIP string) api.SystemOperator
+}
+
+// AdminDB connects to the node's database as the admin user.
+// The password, if any, is taken from the PGPASSWORD environment variable.
+func (a *nodeAccess) AdminDB(nodeIP string) (api.DBClient, error) {
+	db := database.NewPostgresClient(fmt.Sprintf("postgres://%s@%s/%s?sslmode=%s",
+		a.pg.AdminUser, net.JoinHostPort(nodeIP, strconv.Itoa(a.pg.Port)), a.pg.Database, a.pg.SSLMode))
+	if err := db.Connect(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// System returns a system operator for the node.
+func (a *nodeAccess) System(nodeIP string) api.SystemOperator {
+	return a.system(nodeIP)
+}
+
+//Personal.AI order the ending
@@ -3,22 +3,32 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
 	"github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
+	"sigs.k8s.io/yaml"
 )
 
+// K3sConfigPath is the k3s configuration drop-in deploy writes the server
+// flags to. k3s merges it into its configuration when it starts.
+const K3sConfigPath = "/etc/rancher/k3s/config.yaml.d/50-geminik8s.yaml"
+
 // DeployPlugin implements the deployment logic for a geminik8s cluster.
 type DeployPlugin struct {
-	// In a real implementation, you would inject infrastructure clients here.
-	// e.g., sysOp api.SystemOperator
+	system func(nodeIP string) api.SystemOperator
 	// e.g., k8sClient api.K8sClient
 }
 
-// New creates a new DeployPlugin.
+// New creates a new DeployPlugin that reaches the nodes over SSH.
 func New() api.Plugin {
-	return &DeployPlugin{}
+	return &DeployPlugin{
+		system: func(nodeIP string) api.SystemOperator { return system.NewRemoteOperator(nodeIP) },
+	}
 }
 
 // Name returns the name of the plugin.
@@ -58,6 +68,32 @@ func (p *DeployPlugin) Execute(ctx context.Context, params api.PluginParams) (*a
 	// 7. Start all services.
 	// 8. Run post-flight checks to verify the cluster is up.
 
+	// Only the settings that follow from the cluster's addressing, single- or
+	// dual-stack, are applied so far: the k3s server flags, and the
+	// pg_hba.conf entries that let Kine and replication in from every address.
+	var hba []string
+	if cfg.Spec.Storage.BackendType() == types.StorageTypePostgreSQL {
+		hba = storage.HBAEntries(cfg.Spec.Storage.Postgres, cfg.Spec.Nodes)
+	}
+	for _, n := range cfg.Spec.Nodes {
+		sys := p.system(n.IP)
+		content, err := k3sConfig(cfg.Spec.K3sServerArgs(n.IP))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := sys.RunCommand("mkdir", "-p", path.Dir(K3sConfigPath)); err != nil {
+			return nil, errors.Wrapf(err, errors.OrchestratorError, "failed to create %s on %s", path.Dir(K3sConfigPath), n.IP)
+		}
+		if err := sys.WriteFile(K3sConfigPath, content, 0o600); err != nil {
+			return nil, errors.Wrapf(err, errors.OrchestratorError, "failed to write the k3s configuration on %s", n.IP)
+		}
+		if hba != nil {
+			if err := storage.ApplyHBAEntries(sys, cfg.Spec.Storage.Postgres, hba); err != nil {
+				return nil, errors.Wrapf(err, errors.DatabaseError, "failed to update pg_hba.conf on %s", n.IP)
+			}
+		}
+	}
+
 	fmt.Println("Deployment logic placeholder: Simulating successful deployment.")
 
 	return &api.PluginResult{
@@ -73,4 +109,26 @@ func (p *DeployPlugin) Cleanup(ctx context.Context) error {
 	return nil
 }
 
+// k3sConfig turns k3s flags into the keys of a k3s configuration file. A flag
+// given more than once becomes a list and a flag without a value a boolean.
+func k3sConfig(args []string) ([]byte, error) {
+	config := map[string]interface{}{}
+	for _, arg := range args {
+		key, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch existing := config[key].(type) {
+		case nil:
+			if hasValue {
+				config[key] = value
+			} else {
+				config[key] = true
+			}
+		case string:
+			config[key] = []string{existing, value}
+		case []string:
+			config[key] = append(existing, value)
+		}
+	}
+	return yaml.Marshal(config)
+}
+
 //Personal.AI order the ending
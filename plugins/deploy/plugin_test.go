@@ -2,6 +2,8 @@ package deploy
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/turtacn/geminik8s/pkg/api"
@@ -56,4 +58,78 @@ func TestDeployPlugin_Execute(t *testing.T) {
 	}
 }
 
+// fakeSystem records the files written on a node and answers psql with the
+// path of pg_hba.conf.
+type fakeSystem struct {
+	files    map[string]string
+	commands []string
+}
+
+func (f *fakeSystem) RunCommand(command string, args ...string) (string, error) {
+	f.commands = append(f.commands, command+" "+strings.Join(args, " "))
+	if command == "psql" && strings.HasSuffix(args[len(args)-1], "SHOW hba_file") {
+		return "/etc/postgresql/16/main/pg_hba.conf\n", nil
+	}
+	return "", nil
+}
+func (f *fakeSystem) WriteFile(path string, content []byte, perm os.FileMode) error {
+	f.files[path] = string(content)
+	return nil
+}
+func (f *fakeSystem) ReadFile(path string) ([]byte, error) { return []byte(f.files[path]), nil }
+
+func TestDeployPlugin_K3sArgs(t *testing.T) {
+	nodes := []types.NodeInfo{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}
+	tests := []struct {
+		name      string
+		network   types.NetworkConfig
+		addresses [][]string
+		want      string
+	}{
+		{
+			name:    "IPv4",
+			network: types.NetworkConfig{VIP: "10.0.0.100"},
+			want:    "cluster-cidr: 10.42.0.0/16\nnode-ip: 10.0.0.2\nservice-cidr: 10.43.0.0/16\ntls-san: 10.0.0.100\n",
+		},
+		{
+			name: "dual-stack",
+			network: types.NetworkConfig{VIP: "10.0.0.100", PodCIDRs: []string{"2001:db8:42::/56"},
+				VIPs: []types.VIPConfig{{Name: "api6", Address: "fd00::100", Purpose: types.VIPPurposeAPI}}},
+			addresses: [][]string{{"fd00::1"}, {"fd00::2"}},
+			want: "cluster-cidr: 10.42.0.0/16,2001:db8:42::/56\nflannel-ipv6-masq: true\nnode-ip: 10.0.0.2,fd00::2\n" +
+				"service-cidr: 10.43.0.0/16,fd00:43::/112\ntls-san:\n- 10.0.0.100\n- fd00::100\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &types.ClusterConfig{Spec: types.ClusterSpec{Network: tt.network, Nodes: append([]types.NodeInfo(nil), nodes...)}}
+			for i, addresses := range tt.addresses {
+				cfg.Spec.Nodes[i].Addresses = addresses
+			}
+			systems := map[string]*fakeSystem{}
+			p := &DeployPlugin{system: func(nodeIP string) api.SystemOperator {
+				if systems[nodeIP] == nil {
+					systems[nodeIP] = &fakeSystem{files: map[string]string{"/etc/postgresql/16/main/pg_hba.conf": "local all postgres peer\n"}}
+				}
+				return systems[nodeIP]
+			}}
+			if _, err := p.Execute(context.Background(), api.PluginParams{"config": cfg}); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			sys := systems["10.0.0.2"]
+			if got := sys.files[K3sConfigPath]; got != tt.want {
+				t.Errorf("expected k3s configuration:\n%s\ngot:\n%s", tt.want, got)
+			}
+			// Two entries per address, one for Kine and one for the subscription, between the markers.
+			hba := strings.Split(sys.files["/etc/postgresql/16/main/pg_hba.conf"], "\n")
+			if want := 2*(len(nodes)+len(tt.addresses)) + 2; len(hba) < want || hba[want-1] != "# END geminik8s" || hba[want] != "local all postgres peer" {
+				t.Errorf("expected pg_hba.conf entries for every address above the existing ones, got %v", hba)
+			}
+			if last := sys.commands[len(sys.commands)-1]; !strings.HasSuffix(last, "SELECT pg_reload_conf()") {
+				t.Errorf("expected PostgreSQL to be reloaded, got %v", sys.commands)
+			}
+		})
+	}
+}
+
 //Personal.AI order the ending
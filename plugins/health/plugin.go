@@ -3,21 +3,36 @@ package health
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/turtacn/geminik8s/internal/domain/cluster"
+	"github.com/turtacn/geminik8s/internal/domain/node"
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/internal/infrastructure/database"
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
 	"github.com/turtacn/geminik8s/internal/pkg/errors"
 	"github.com/turtacn/geminik8s/pkg/api"
 	"github.com/turtacn/geminik8s/pkg/types"
 )
 
+// ClientFunc returns a Kubernetes client for the cluster.
+type ClientFunc func(ctx context.Context, cfg *types.ClusterConfig) (api.K8sClient, error)
+
 // HealthPlugin implements the health checking logic for a geminik8s cluster.
 type HealthPlugin struct {
-	// e.g., netOp api.NetworkOperator
-	// e.g., k8sClient api.K8sClient
+	clientFor ClientFunc
+	netOp     api.NetworkOperator
+	system    func(nodeIP string) api.SystemOperator
+	backend   func(cfg types.StorageConfig, system func(nodeIP string) api.SystemOperator) (storage.Backend, error)
 }
 
-// New creates a new HealthPlugin.
-func New() api.Plugin {
-	return &HealthPlugin{}
+// New creates a new HealthPlugin that reaches the cluster through clientFor
+// and probes the API server and the datastores through netOp. The nodes are
+// reached over SSH to find the leader whose replication is checked.
+func New(clientFor ClientFunc, netOp api.NetworkOperator) api.Plugin {
+	return &HealthPlugin{clientFor: clientFor, netOp: netOp, system: system.NewRemoteOperator, backend: database.NewBackend}
 }
 
 // Name returns the name of the plugin.
@@ -38,34 +53,160 @@ func (p *HealthPlugin) Validate(params api.PluginParams) error {
 	return nil
 }
 
-// Execute performs the health check.
+// Execute checks the control plane and the Kubernetes nodes, the replication
+// between the datastores, and probes the certificate of the API server behind
+// the VIP and the datastore of every node. The cluster is
+// Running when every check passes, Degraded when some fail and Unknown when
+// it cannot be reached. The individual results are returned in Data["checks"].
 func (p *HealthPlugin) Execute(ctx context.Context, params api.PluginParams) (*api.PluginResult, error) {
 	cfg, ok := params["config"].(*types.ClusterConfig)
 	if !ok {
 		return nil, errors.New(errors.ValidationError, "'config' parameter is not a valid ClusterConfig")
 	}
 
-	fmt.Printf("Executing health plugin for cluster: %s\n", cfg.Metadata.Name)
-
-	// Here would be the core health checking logic:
-	// 1. Check connectivity to both nodes.
-	// 2. Check if k3s service is running on both nodes.
-	// 3. Check if postgresql service is running on both nodes.
-	// 4. Check if the Kubernetes API is responsive.
-	// 5. Check PostgreSQL replication status.
+	start := time.Now()
+	client, err := p.clientFor(ctx, cfg)
+	if err != nil {
+		check := types.HealthCheckResult{
+			CheckName:  "apiserver.connect",
+			Message:    err.Error(),
+			Timestamp:  start,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		return &api.PluginResult{
+			Success: true,
+			Message: fmt.Sprintf("Cluster '%s' cannot be reached: %v", cfg.Metadata.Name, err),
+			Data: map[string]interface{}{
+				"status": string(types.StatusUnknown),
+				"checks": append(append([]types.HealthCheckResult{check}, p.replication(ctx, cfg)...), p.probe(ctx, cfg)...),
+			},
+		}, nil
+	}
 
-	fmt.Println("Health check logic placeholder: Simulating a healthy cluster.")
+	checks := client.CheckControlPlane(ctx)
+	checks = append(checks, nodeChecks(ctx, client, cfg)...)
+	checks = append(checks, p.replication(ctx, cfg)...)
+	checks = append(checks, p.probe(ctx, cfg)...)
 	status := types.StatusRunning
+	var failed []string
+	for _, check := range checks {
+		if !check.Success {
+			status = types.StatusDegraded
+			failed = append(failed, check.CheckName)
+		}
+	}
 
+	message := fmt.Sprintf("Cluster '%s' is healthy: %d checks passed.", cfg.Metadata.Name, len(checks))
+	if len(failed) > 0 {
+		message = fmt.Sprintf("Cluster '%s' is degraded: %d of %d checks failed (%s).",
+			cfg.Metadata.Name, len(failed), len(checks), strings.Join(failed, ", "))
+	}
 	return &api.PluginResult{
 		Success: true,
-		Message: fmt.Sprintf("Cluster '%s' is healthy.", cfg.Metadata.Name),
+		Message: message,
 		Data: map[string]interface{}{
 			"status": string(status),
+			"checks": checks,
 		},
 	}, nil
 }
 
+// nodeChecks reports whether the Kubernetes node of every node in the config is Ready.
+func nodeChecks(ctx context.Context, client api.K8sClient, cfg *types.ClusterConfig) []types.HealthCheckResult {
+	nodes := node.NewService(nil, nil, client)
+	var checks []types.HealthCheckResult
+	for _, n := range cfg.Spec.Nodes {
+		start := time.Now()
+		check := types.HealthCheckResult{CheckName: "node.ready." + n.IP, Timestamp: start}
+		healthy, err := nodes.CheckNodeHealth(ctx, n.IP)
+		switch {
+		case err != nil:
+			check.Message = err.Error()
+		case !healthy:
+			check.Message = "node is not ready"
+		default:
+			check.Success = true
+		}
+		check.DurationMs = time.Since(start).Milliseconds()
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// replication checks that the follower's datastore is streaming from the
+// leader's within the lag tolerance. It is skipped for sqlite storage and for
+// clusters that do not have two nodes.
+func (p *HealthPlugin) replication(ctx context.Context, cfg *types.ClusterConfig) []types.HealthCheckResult {
+	if cfg.Spec.Storage.BackendType() == types.StorageTypeSQLite || len(cfg.Spec.Nodes) != 2 {
+		return nil
+	}
+	start := time.Now()
+	check := types.HealthCheckResult{CheckName: "datastore.replication", Timestamp: start}
+
+	state, err := p.replicationState(ctx, cfg)
+	switch {
+	case err != nil:
+		check.Message = err.Error()
+	case !state.Streaming:
+		check.Message = "the follower is not streaming from the leader"
+	case !state.Healthy():
+		check.Message = fmt.Sprintf("the follower lags %s behind the leader", state.Lag)
+	default:
+		check.Success = true
+		check.Message = fmt.Sprintf("lag %s", state.Lag)
+	}
+	check.DurationMs = time.Since(start).Milliseconds()
+	return []types.HealthCheckResult{check}
+}
+
+// replicationState reads the follower's replication state from the current leader.
+func (p *HealthPlugin) replicationState(ctx context.Context, cfg *types.ClusterConfig) (*storage.ReplicaState, error) {
+	leader, err := cluster.CurrentLeader(cfg, p.system)
+	if err != nil {
+		return nil, err
+	}
+	follower := cfg.Spec.Nodes[0].IP
+	if follower == leader {
+		follower = cfg.Spec.Nodes[1].IP
+	}
+	backend, err := p.backend(cfg.Spec.Storage, p.system)
+	if err != nil {
+		return nil, err
+	}
+	return backend.ReplicationState(ctx, leader, follower)
+}
+
+// probe runs the network probes of the cluster concurrently and returns their
+// results in a fixed order.
+func (p *HealthPlugin) probe(ctx context.Context, cfg *types.ClusterConfig) []types.HealthCheckResult {
+	var probes []types.Probe
+	if vip := cfg.Spec.Network.APIVIP(); vip != "" {
+		// k3s signs the API server's certificate with its own CA.
+		probes = append(probes, types.Probe{Name: "apiserver.certificate", Type: types.ProbeTLS, Host: vip, Port: 6443, InsecureSkipVerify: true})
+	}
+	for _, n := range cfg.Spec.Nodes {
+		switch cfg.Spec.Storage.BackendType() {
+		case types.StorageTypePostgreSQL:
+			pg := cfg.Spec.Storage.Postgres.WithDefaults()
+			probes = append(probes, types.Probe{Name: "datastore." + n.IP, Type: types.ProbePostgres, Host: n.IP, Port: pg.Port, User: pg.AdminUser, Database: pg.Database})
+		case types.StorageTypeMySQL:
+			probes = append(probes, types.Probe{Name: "datastore." + n.IP, Type: types.ProbeTCP, Host: n.IP, Port: cfg.Spec.Storage.ServerPort()})
+		}
+	}
+
+	results := make([]types.HealthCheckResult, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe types.Probe) {
+			defer wg.Done()
+			_, results[i] = p.netOp.CheckConnectivity(ctx, probe)
+		}(i, probe)
+	}
+	wg.Wait()
+	return results
+}
+
 // Cleanup performs any cleanup operations after execution.
 func (p *HealthPlugin) Cleanup(ctx context.Context) error {
 	return nil
@@ -0,0 +1,190 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/storage"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+type fakeClient struct {
+	api.K8sClient
+	checks []types.HealthCheckResult
+	nodes  []types.Node
+}
+
+func (c *fakeClient) CheckControlPlane(ctx context.Context) []types.HealthCheckResult {
+	return c.checks
+}
+
+func (c *fakeClient) GetNodes(ctx context.Context) ([]types.Node, error) {
+	return c.nodes, nil
+}
+
+// unreachableSystem fails every command, as for nodes that cannot be reached over SSH.
+type unreachableSystem struct {
+	api.SystemOperator
+}
+
+func (unreachableSystem) ReadFile(path string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+// fakeBackend reports state for the follower and records whom it was asked.
+type fakeBackend struct {
+	storage.Backend
+	state            storage.ReplicaState
+	primary, replica string
+}
+
+func (b *fakeBackend) ReplicationState(ctx context.Context, primaryIP, replicaIP string) (*storage.ReplicaState, error) {
+	b.primary, b.replica = primaryIP, replicaIP
+	return &b.state, nil
+}
+
+// fakeNetworkOperator fails the probes of the hosts in down.
+type fakeNetworkOperator struct {
+	mu     sync.Mutex
+	probes []types.Probe
+	down   map[string]bool
+}
+
+func (f *fakeNetworkOperator) CheckConnectivity(ctx context.Context, probe types.Probe) (time.Duration, types.HealthCheckResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probes = append(f.probes, probe)
+	return time.Millisecond, types.HealthCheckResult{CheckName: probe.Name, Success: !f.down[probe.Host]}
+}
+
+func TestExecute(t *testing.T) {
+	cfg := &types.ClusterConfig{Metadata: types.Metadata{Name: "demo"}}
+	params := api.PluginParams{"config": cfg}
+
+	testCases := []struct {
+		name      string
+		clientFor ClientFunc
+		want      types.ClusterStatus
+	}{
+		{"all checks pass", func(context.Context, *types.ClusterConfig) (api.K8sClient, error) {
+			return &fakeClient{checks: []types.HealthCheckResult{{CheckName: "a", Success: true}, {CheckName: "b", Success: true}}}, nil
+		}, types.StatusRunning},
+		{"a check fails", func(context.Context, *types.ClusterConfig) (api.K8sClient, error) {
+			return &fakeClient{checks: []types.HealthCheckResult{{CheckName: "a", Success: true}, {CheckName: "b"}}}, nil
+		}, types.StatusDegraded},
+		{"cluster unreachable", func(context.Context, *types.ClusterConfig) (api.K8sClient, error) {
+			return nil, errors.New("no route to host")
+		}, types.StatusUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := New(tc.clientFor, &fakeNetworkOperator{}).Execute(context.Background(), params)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if status := result.Data["status"]; status != string(tc.want) {
+				t.Errorf("expected status %s, got %v (%s)", tc.want, status, result.Message)
+			}
+			if checks, _ := result.Data["checks"].([]types.HealthCheckResult); len(checks) == 0 {
+				t.Errorf("expected the check results in Data[\"checks\"]")
+			}
+		})
+	}
+}
+
+func TestExecuteProbes(t *testing.T) {
+	cfg := &types.ClusterConfig{Metadata: types.Metadata{Name: "demo"}}
+	cfg.Spec.Network.VIP = "10.0.0.100"
+	cfg.Spec.Nodes = []types.NodeInfo{{IP: "10.0.0.1", Role: types.RoleFollower}, {IP: "10.0.0.2", Role: types.RoleLeader}}
+	netOp := &fakeNetworkOperator{down: map[string]bool{"10.0.0.2": true}}
+	clientFor := func(context.Context, *types.ClusterConfig) (api.K8sClient, error) {
+		return &fakeClient{
+			checks: []types.HealthCheckResult{{CheckName: "apiserver.readyz", Success: true}},
+			nodes: []types.Node{
+				{Config: types.NodeConfig{Name: "node1", IP: "10.0.0.1"}, Status: types.NodeStatus{Status: types.NodeStatusHealthy}},
+				{Config: types.NodeConfig{Name: "node2", IP: "10.0.0.2"}, Status: types.NodeStatus{Status: types.NodeStatusUnhealthy}},
+			},
+		}, nil
+	}
+	backend := &fakeBackend{state: storage.ReplicaState{Streaming: true, Lag: time.Second}}
+	p := New(clientFor, netOp).(*HealthPlugin)
+	p.system = func(string) api.SystemOperator { return unreachableSystem{} }
+	p.backend = func(types.StorageConfig, func(string) api.SystemOperator) (storage.Backend, error) {
+		return backend, nil
+	}
+
+	result, err := p.Execute(context.Background(), api.PluginParams{"config": cfg})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.Data["status"] != string(types.StatusDegraded) {
+		t.Errorf("expected the cluster to be degraded by the failed datastore probe, got %v (%s)", result.Data["status"], result.Message)
+	}
+	var names []string
+	for _, check := range result.Data["checks"].([]types.HealthCheckResult) {
+		names = append(names, check.CheckName)
+	}
+	if fmt.Sprint(names) != "[apiserver.readyz node.ready.10.0.0.1 node.ready.10.0.0.2 datastore.replication apiserver.certificate datastore.10.0.0.1 datastore.10.0.0.2]" {
+		t.Errorf("unexpected checks: %v", names)
+	}
+	for _, check := range result.Data["checks"].([]types.HealthCheckResult) {
+		want := check.CheckName != "node.ready.10.0.0.2" && check.CheckName != "datastore.10.0.0.2"
+		if check.Success != want {
+			t.Errorf("expected %s to succeed: %v, got %+v", check.CheckName, want, check)
+		}
+	}
+	if backend.primary != "10.0.0.2" || backend.replica != "10.0.0.1" {
+		t.Errorf("expected the replication to be read from the leader 10.0.0.2 for 10.0.0.1, got %s for %s", backend.primary, backend.replica)
+	}
+	for _, probe := range netOp.probes {
+		if probe.Type == types.ProbePostgres && (probe.Port != 5432 || probe.User != "postgres" || probe.Database != "kubernetes") {
+			t.Errorf("expected the datastore to be probed with the configured port, user and database, got %+v", probe)
+		}
+		if probe.Type == types.ProbeTLS && probe.Address() != "10.0.0.100:6443" {
+			t.Errorf("expected the API server certificate to be probed behind the VIP, got %+v", probe)
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	testCases := []struct {
+		name    string
+		storage string
+		state   storage.ReplicaState
+		want    []bool
+	}{
+		{"streaming", types.StorageTypePostgreSQL, storage.ReplicaState{Streaming: true, Lag: time.Second}, []bool{true}},
+		{"lagging", types.StorageTypePostgreSQL, storage.ReplicaState{Streaming: true, Lag: time.Minute}, []bool{false}},
+		{"not streaming", types.StorageTypeMySQL, storage.ReplicaState{}, []bool{false}},
+		{"sqlite is skipped", types.StorageTypeSQLite, storage.ReplicaState{}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &types.ClusterConfig{}
+			cfg.Spec.Storage.Type = tc.storage
+			cfg.Spec.Nodes = []types.NodeInfo{{IP: "10.0.0.1", Role: types.RoleLeader}, {IP: "10.0.0.2", Role: types.RoleFollower}}
+			p := &HealthPlugin{
+				system: func(string) api.SystemOperator { return unreachableSystem{} },
+				backend: func(types.StorageConfig, func(string) api.SystemOperator) (storage.Backend, error) {
+					return &fakeBackend{state: tc.state}, nil
+				},
+			}
+			var got []bool
+			for _, check := range p.replication(context.Background(), cfg) {
+				got = append(got, check.Success)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,177 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/turtacn/geminik8s/internal/domain/cluster"
+	"github.com/turtacn/geminik8s/internal/infrastructure/kubernetes"
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// tokenNamespace holds the service accounts behind reduced-privilege credentials.
+const tokenNamespace = "kube-system"
+
+// KubeconfigPlugin fetches the admin kubeconfig from the current leader and
+// rewrites it for use from outside the cluster.
+type KubeconfigPlugin struct {
+	system    func(nodeIP string) api.SystemOperator
+	newClient func(kubeconfig []byte) (api.K8sClient, error)
+	netOp     api.NetworkOperator
+}
+
+// New creates a new KubeconfigPlugin that reaches the nodes over SSH and
+// probes the API server's certificate through netOp.
+func New(netOp api.NetworkOperator) api.Plugin {
+	return &KubeconfigPlugin{
+		system:    func(nodeIP string) api.SystemOperator { return system.NewRemoteOperator(nodeIP) },
+		newClient: kubernetes.NewK8sClientFromKubeconfig,
+		netOp:     netOp,
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *KubeconfigPlugin) Name() string {
+	return "kubeconfig"
+}
+
+// Version returns the version of the plugin.
+func (p *KubeconfigPlugin) Version() string {
+	return "v0.1.0"
+}
+
+// Validate checks if the required parameters are provided for execution.
+func (p *KubeconfigPlugin) Validate(params api.PluginParams) error {
+	if _, ok := params["config"]; !ok {
+		return errors.New(errors.ValidationError, "missing 'config' parameter for kubeconfig plugin")
+	}
+	return nil
+}
+
+// Execute builds the kubeconfig. With options.TTL set, the admin credential is
+// swapped for a token of a service account bound to options.ClusterRole.
+func (p *KubeconfigPlugin) Execute(ctx context.Context, params api.PluginParams) (*api.PluginResult, error) {
+	cfg, ok := params["config"].(*types.ClusterConfig)
+	if !ok {
+		return nil, errors.New(errors.ValidationError, "'config' parameter is not a valid ClusterConfig")
+	}
+	opts, _ := params["options"].(types.KubeconfigOptions)
+
+	leaderIP, err := cluster.CurrentLeader(cfg, p.system)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.system(leaderIP).ReadFile(kubernetes.K3sKubeconfigPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.KubernetesError, "failed to read %s from leader %s", kubernetes.K3sKubeconfigPath, leaderIP)
+	}
+	vip := cfg.Spec.Network.APIVIP()
+	kubeconfig, err := kubernetes.RewriteKubeconfig(raw, cfg.Metadata.Name, vip)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verifyServingCert(ctx, kubeconfig, vip); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{"leader": leaderIP, "context": cfg.Metadata.Name}
+	message := fmt.Sprintf("Admin kubeconfig for cluster '%s' fetched from leader %s.", cfg.Metadata.Name, leaderIP)
+	if opts.TTL > 0 {
+		role := opts.ClusterRole
+		if role == "" {
+			role = "view"
+		}
+		var expiresAt time.Time
+		kubeconfig, expiresAt, err = p.reducedCredential(ctx, kubeconfig, cfg.Metadata.Name, role, opts.TTL)
+		if err != nil {
+			return nil, err
+		}
+		data["expiresAt"] = expiresAt
+		message = fmt.Sprintf("Kubeconfig for cluster '%s' with cluster role '%s', valid until %s.", cfg.Metadata.Name, role, expiresAt.Format(time.RFC3339))
+	}
+	data["kubeconfig"] = kubeconfig
+
+	return &api.PluginResult{Success: true, Message: message, Data: data}, nil
+}
+
+// Cleanup performs any cleanup operations after execution.
+func (p *KubeconfigPlugin) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// verifyServingCert checks that the API server on the VIP presents a
+// certificate the kubeconfig's CA trusts for the VIP. The kubeconfig keeps the
+// CA of k3s, so without the VIP in the certificate every client would fail.
+func (p *KubeconfigPlugin) verifyServingCert(ctx context.Context, kubeconfig []byte, vip string) error {
+	server, ca, err := kubernetes.ServerCA(kubeconfig)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return errors.Wrapf(err, errors.KubernetesError, "invalid server URL '%s'", server)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	_, result := p.netOp.CheckConnectivity(ctx, types.Probe{
+		Name:    "apiserver.certificate",
+		Type:    types.ProbeTLS,
+		Host:    vip,
+		Port:    port,
+		RootCAs: ca,
+		// Only whether the certificate covers the VIP matters here; its
+		// expiry is reported by 'status'.
+		MinCertValidity: time.Nanosecond,
+	})
+	if !result.Success {
+		return errors.Newf(errors.KubernetesError, "the API server certificate is not valid for the API VIP %s (%s); "+
+			"add the VIP to the k3s --tls-san flags and restart k3s before fetching a kubeconfig", vip, result.Message)
+	}
+	return nil
+}
+
+// reducedCredential binds a service account to role and replaces the admin user
+// in kubeconfig with a token for it that expires after ttl, or earlier if the
+// API server caps token lifetimes. It returns when the token expires.
+func (p *KubeconfigPlugin) reducedCredential(ctx context.Context, kubeconfig []byte, clusterName, role string, ttl time.Duration) ([]byte, time.Time, error) {
+	client, err := p.newClient(kubeconfig)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	account := "geminik8s-" + role
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %[1]s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %[3]s
+subjects:
+- kind: ServiceAccount
+  name: %[1]s
+  namespace: %[2]s
+`, account, tokenNamespace, role)
+	if _, err := client.Apply(ctx, []byte(manifest)); err != nil {
+		return nil, time.Time{}, err
+	}
+	token, expiresAt, err := client.CreateToken(ctx, tokenNamespace, account, ttl)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	kubeconfig, err = kubernetes.WithTokenCredential(kubeconfig, clusterName+"-"+role, token)
+	return kubeconfig, expiresAt, err
+}
+
+//Personal.AI order the ending
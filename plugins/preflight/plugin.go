@@ -0,0 +1,226 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/turtacn/geminik8s/internal/infrastructure/system"
+	"github.com/turtacn/geminik8s/internal/pkg/errors"
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// uploadPath is where gemin_k8s is copied to on nodes that do not have it installed.
+const uploadPath = "/tmp/gemin_k8s-preflight"
+
+// serveTimeout bounds how long the listeners on the nodes wait for the probes.
+const serveTimeout = "60s"
+
+// PreflightPlugin checks the network between the nodes before anything is
+// changed on them. It runs 'gemin_k8s preflight serve' on every node to answer
+// on the required ports and 'gemin_k8s preflight probe' to test them, the
+// round-trip time and the MTU towards the other nodes, and the VIPs.
+type PreflightPlugin struct {
+	system     func(nodeIP string) api.SystemOperator
+	executable func() ([]byte, error)
+}
+
+// New creates a new PreflightPlugin that reaches the nodes over SSH and copies
+// the running binary to nodes without gemin_k8s.
+func New() api.Plugin {
+	return &PreflightPlugin{
+		system: func(nodeIP string) api.SystemOperator { return system.NewRemoteOperator(nodeIP) },
+		executable: func() ([]byte, error) {
+			path, err := os.Executable()
+			if err != nil {
+				return nil, err
+			}
+			return os.ReadFile(path)
+		},
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *PreflightPlugin) Name() string {
+	return "preflight"
+}
+
+// Version returns the version of the plugin.
+func (p *PreflightPlugin) Version() string {
+	return "v0.1.0"
+}
+
+// Validate checks if the required parameters are provided for execution.
+func (p *PreflightPlugin) Validate(params api.PluginParams) error {
+	if _, ok := params["config"]; !ok {
+		return errors.New(errors.ValidationError, "missing 'config' parameter for preflight plugin")
+	}
+	return nil
+}
+
+// Execute runs the network preflight. The report is returned in the result's
+// Data["report"]; the result is only successful if no check failed.
+func (p *PreflightPlugin) Execute(ctx context.Context, params api.PluginParams) (*api.PluginResult, error) {
+	cfg, ok := params["config"].(*types.ClusterConfig)
+	if !ok {
+		return nil, errors.New(errors.ValidationError, "'config' parameter is not a valid ClusterConfig")
+	}
+	opts, _ := params["options"].(types.PreflightOptions)
+	opts = opts.WithDefaults()
+	network, err := json.Marshal(cfg.Spec.Network)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ValidationError, "failed to encode spec.network")
+	}
+	var ports []string
+	for _, port := range cfg.RequiredPorts() {
+		ports = append(ports, port.String())
+	}
+
+	report := &types.PreflightReport{}
+	binaries := map[string]string{}
+	var nodes []string
+	// In a dual-stack cluster the nodes are probed over every address.
+	addresses, owners := map[string][]string{}, map[string]string{}
+	for _, n := range cfg.Spec.Nodes {
+		addresses[n.IP] = n.AllAddresses()
+		for _, address := range n.AllAddresses() {
+			owners[address] = n.IP
+		}
+	}
+	for _, n := range cfg.Spec.Nodes {
+		bin, err := p.install(n.IP)
+		if err != nil {
+			report.Checks = append(report.Checks, types.PreflightCheck{Check: "ssh", From: n.IP, Status: types.PreflightFail, Message: err.Error()})
+			continue
+		}
+		binaries[n.IP] = bin
+		nodes = append(nodes, n.IP)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		busy   = map[string]map[string]bool{} // Node -> ports in use there
+		probes = map[string][]types.PreflightCheck{}
+	)
+	fail := func(check, node string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		probes[node] = append(probes[node], types.PreflightCheck{Check: check, From: node, Status: types.PreflightFail, Message: err.Error()})
+	}
+	for _, node := range nodes {
+		var peerAddresses []string
+		for _, peer := range others(nodes, node) {
+			peerAddresses = append(peerAddresses, addresses[peer]...)
+		}
+		node, peers := node, strings.Join(peerAddresses, ",")
+		sys := p.system(node)
+		if peers != "" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				out, err := sys.RunCommand(binaries[node], "preflight", "serve", "--ports", strings.Join(ports, ","), "--peers", peers, "--timeout", serveTimeout)
+				var served struct {
+					Busy []string `json:"busy"`
+				}
+				if err == nil {
+					err = json.Unmarshal([]byte(lastLine(out)), &served)
+				}
+				if err != nil {
+					fail("listen", node, errors.Wrapf(err, errors.NetworkError, "failed to listen on %s: %s", node, strings.TrimSpace(out)))
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				busy[node] = map[string]bool{}
+				for _, port := range served.Busy {
+					busy[node][port] = true
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := sys.RunCommand(binaries[node], "preflight", "probe", "--node", node, "--peers", peers,
+				"--ports", strings.Join(ports, ","), "--network", string(network),
+				"--max-rtt", opts.MaxRTT.String(), "--max-jitter", opts.MaxJitter.String(), "--samples", fmt.Sprint(opts.Samples))
+			var checks []types.PreflightCheck
+			if err == nil {
+				err = json.Unmarshal([]byte(lastLine(out)), &checks)
+			}
+			if err != nil {
+				fail("probe", node, errors.Wrapf(err, errors.NetworkError, "failed to probe from %s: %s", node, strings.TrimSpace(out)))
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			probes[node] = append(probes[node], checks...)
+		}()
+	}
+	wg.Wait()
+
+	for _, node := range nodes {
+		for _, c := range probes[node] {
+			// A UDP port that a service on the peer already holds cannot be
+			// probed: the service does not echo.
+			if c.Status == types.PreflightFail && strings.HasPrefix(c.Check, "udp/") && busy[owners[c.To]][c.Check] {
+				c.Status, c.Message = types.PreflightSkip, fmt.Sprintf("in use on %s, not verified", c.To)
+			}
+			report.Checks = append(report.Checks, c)
+		}
+	}
+
+	result := &api.PluginResult{Success: report.Passed(), Data: map[string]interface{}{"report": report}}
+	if result.Success {
+		result.Message = fmt.Sprintf("Network preflight for cluster '%s' passed.", cfg.Metadata.Name)
+	} else {
+		result.Message = fmt.Sprintf("Network preflight for cluster '%s' failed.", cfg.Metadata.Name)
+	}
+	return result, nil
+}
+
+// Cleanup performs any cleanup operations after execution.
+func (p *PreflightPlugin) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// install returns the path of gemin_k8s on the node, copying this binary
+// there if it is not installed.
+func (p *PreflightPlugin) install(nodeIP string) (string, error) {
+	sys := p.system(nodeIP)
+	if out, err := sys.RunCommand("sh", "-c", "command -v gemin_k8s"); err == nil {
+		return strings.TrimSpace(out), nil
+	}
+	bin, err := p.executable()
+	if err != nil {
+		return "", errors.Wrap(err, errors.IOError, "failed to read the gemin_k8s binary")
+	}
+	if err := sys.WriteFile(uploadPath, bin, 0o755); err != nil {
+		return "", err
+	}
+	return uploadPath, nil
+}
+
+// others returns nodes without node.
+func others(nodes []string, node string) []string {
+	var out []string
+	for _, n := range nodes {
+		if n != node {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// lastLine returns the last non-empty line of out, where the node-side
+// commands print their JSON result.
+func lastLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return lines[len(lines)-1]
+}
+
+//Personal.AI order the ending
@@ -0,0 +1,187 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/turtacn/geminik8s/pkg/api"
+	"github.com/turtacn/geminik8s/pkg/types"
+)
+
+// fakeNode answers the commands the plugin runs on a node.
+type fakeNode struct {
+	api.SystemOperator
+	ip        string
+	installed bool
+	down      bool
+	busy      []string
+	failing   map[string]bool // Checks that fail when probing the peer
+
+	mu       sync.Mutex
+	uploaded string
+	commands []string
+}
+
+func (n *fakeNode) RunCommand(command string, args ...string) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.commands = append(n.commands, strings.Join(append([]string{command}, args...), " "))
+	if n.down {
+		return "ssh: connect to host " + n.ip + ": Connection refused", fmt.Errorf("exit status 255")
+	}
+	switch {
+	case command == "sh":
+		if !n.installed {
+			return "", fmt.Errorf("exit status 1")
+		}
+		return "/usr/local/bin/gemin_k8s\n", nil
+	case args[1] == "serve":
+		out, _ := json.Marshal(map[string][]string{"busy": n.busy})
+		return string(out), nil
+	default:
+		flags := map[string]string{}
+		for i := 2; i+1 < len(args); i += 2 {
+			flags[args[i]] = args[i+1]
+		}
+		var checks []types.PreflightCheck
+		if peers := flags["--peers"]; peers != "" {
+			for _, peer := range strings.Split(peers, ",") {
+				for _, check := range append(strings.Split(flags["--ports"], ","), "rtt", "mtu") {
+					c := types.PreflightCheck{Check: check, From: n.ip, To: peer, Status: types.PreflightPass}
+					if n.failing[check] {
+						c.Status = types.PreflightFail
+					}
+					checks = append(checks, c)
+				}
+			}
+		}
+		checks = append(checks, types.PreflightCheck{Check: "vip/api", From: n.ip, Status: types.PreflightPass})
+		out, _ := json.Marshal(checks)
+		return "some log line\n" + string(out), nil
+	}
+}
+
+func (n *fakeNode) WriteFile(path string, content []byte, perm os.FileMode) error {
+	if n.down {
+		return fmt.Errorf("failed to write file %s on %s", path, n.ip)
+	}
+	n.uploaded = path
+	return nil
+}
+
+func newTestPlugin(nodes ...*fakeNode) *PreflightPlugin {
+	byIP := map[string]*fakeNode{}
+	for _, n := range nodes {
+		byIP[n.ip] = n
+	}
+	return &PreflightPlugin{
+		system:     func(nodeIP string) api.SystemOperator { return byIP[nodeIP] },
+		executable: func() ([]byte, error) { return []byte("binary"), nil },
+	}
+}
+
+func testConfig() *types.ClusterConfig {
+	return &types.ClusterConfig{
+		Metadata: types.Metadata{Name: "demo"},
+		Spec: types.ClusterSpec{
+			Network: types.NetworkConfig{VIP: "10.0.0.100"},
+			Nodes:   []types.NodeInfo{{IP: "10.0.0.1", Role: types.RoleLeader}, {IP: "10.0.0.2", Role: types.RoleFollower}},
+			Storage: types.StorageConfig{Type: types.StorageTypePostgreSQL},
+		},
+	}
+}
+
+func report(t *testing.T, result *api.PluginResult) map[string]types.PreflightCheck {
+	t.Helper()
+	r, ok := result.Data["report"].(*types.PreflightReport)
+	if !ok {
+		t.Fatalf("expected a report, got %+v", result.Data)
+	}
+	checks := map[string]types.PreflightCheck{}
+	for _, c := range r.Checks {
+		checks[c.From+" "+c.To+" "+c.Check] = c
+	}
+	return checks
+}
+
+func TestPreflightPlugin(t *testing.T) {
+	leader := &fakeNode{ip: "10.0.0.1", installed: true, failing: map[string]bool{"udp/8472": true, "tcp/5432": true}}
+	follower := &fakeNode{ip: "10.0.0.2", busy: []string{"udp/8472"}}
+
+	result, err := newTestPlugin(leader, follower).Execute(context.Background(), api.PluginParams{"config": testConfig()})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	checks := report(t, result)
+	if len(checks) != 2*8 {
+		t.Errorf("expected 7 checks per direction and a VIP check per node, got %d", len(checks))
+	}
+	if result.Success || checks["10.0.0.1 10.0.0.2 tcp/5432"].Status != types.PreflightFail {
+		t.Errorf("expected the blocked PostgreSQL port to fail the preflight")
+	}
+	if c := checks["10.0.0.1 10.0.0.2 udp/8472"]; c.Status != types.PreflightSkip || !strings.Contains(c.Message, "in use on 10.0.0.2") {
+		t.Errorf("expected the UDP port in use on the follower to be skipped, got %+v", c)
+	}
+	if follower.uploaded != uploadPath || !strings.HasPrefix(follower.commands[1], uploadPath+" preflight") {
+		t.Errorf("expected the binary to be copied to the follower, got %v", follower.commands)
+	}
+	if !strings.HasPrefix(leader.commands[1], "/usr/local/bin/gemin_k8s preflight") {
+		t.Errorf("expected the installed binary to be used on the leader, got %v", leader.commands)
+	}
+}
+
+func TestPreflightPluginDualStack(t *testing.T) {
+	leader := &fakeNode{ip: "10.0.0.1", installed: true, failing: map[string]bool{"udp/8472": true}}
+	follower := &fakeNode{ip: "10.0.0.2", installed: true, busy: []string{"udp/8472"}}
+	cfg := testConfig()
+	cfg.Spec.Nodes[0].Addresses = []string{"fd00::1"}
+	cfg.Spec.Nodes[1].Addresses = []string{"fd00::2"}
+
+	result, err := newTestPlugin(leader, follower).Execute(context.Background(), api.PluginParams{"config": cfg})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	checks := report(t, result)
+	if len(checks) != 2*(2*7+1) {
+		t.Errorf("expected 7 checks per direction and family and a VIP check per node, got %d", len(checks))
+	}
+	if c := checks["10.0.0.1 fd00::2 tcp/6443"]; c.Status != types.PreflightPass {
+		t.Errorf("expected the follower to be probed over IPv6, got %+v", c)
+	}
+	if c := checks["10.0.0.1 fd00::2 udp/8472"]; c.Status != types.PreflightSkip {
+		t.Errorf("expected the UDP port in use on the follower to be skipped over IPv6 too, got %+v", c)
+	}
+	if !strings.Contains(follower.commands[1], "--peers 10.0.0.1,fd00::1") {
+		t.Errorf("expected the follower to expect probes from both addresses of the leader, got %v", follower.commands)
+	}
+}
+
+func TestPreflightPluginUnreachableNode(t *testing.T) {
+	leader := &fakeNode{ip: "10.0.0.1", installed: true}
+	follower := &fakeNode{ip: "10.0.0.2", down: true}
+
+	result, err := newTestPlugin(leader, follower).Execute(context.Background(), api.PluginParams{"config": testConfig()})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	checks := report(t, result)
+	if result.Success || checks["10.0.0.2  ssh"].Status != types.PreflightFail {
+		t.Errorf("expected the unreachable follower to fail the preflight, got %+v", checks)
+	}
+	// Without a peer, the leader only checks the VIPs.
+	for _, command := range leader.commands {
+		if strings.Contains(command, "serve") {
+			t.Errorf("expected no listener without peers, got %q", command)
+		}
+	}
+	if len(checks) != 2 {
+		t.Errorf("expected the SSH and VIP checks only, got %+v", checks)
+	}
+}
+
+//Personal.AI order the ending